@@ -0,0 +1,578 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/mellanox-senior-design/docker-volume-rdma/admin"
+	"github.com/mellanox-senior-design/docker-volume-rdma/audit"
+	"github.com/mellanox-senior-design/docker-volume-rdma/backup"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"github.com/mellanox-senior-design/docker-volume-rdma/gc"
+	"github.com/mellanox-senior-design/docker-volume-rdma/migrate"
+)
+
+// adminUsage lists the admin subcommands.
+const adminUsage = `usage:
+  volumes ls [-json] [-l selector]
+  volumes inspect [-json] <volume>
+  volumes rm [-force] <volume>
+  volumes backup [-live] [-zstd] [-o file|s3://bucket/key] <volume>
+  volumes restore [-i file|s3://bucket/key] [-name volume]
+  volumes resize <volume> <size>
+  volumes migrate -to <backend> <volume>
+  volumes gc [-json] [-grace duration] [-delete] [-dry-run]
+  mounts ls [-json] [volume]
+  mounts release <volume> <id>
+  tenants ls [-json]
+  audit ls [-json] [-volume volume] [-operation operation] [-since time] [-until time]`
+
+// runAdmin runs an admin subcommand, e.g. ["volumes", "ls"], printing its output, and returns the exit code.
+func runAdmin(args []string) int {
+	api, done, err := adminAPI()
+	if err == nil {
+		err = adminCommand(api, args, os.Stdin, os.Stdout)
+		done()
+	}
+
+	glog.Flush()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
+	}
+
+	return 0
+}
+
+// adminAPI returns the admin API of the daemon at -admin-url, or manages the configured database and storage
+// controllers directly. done must be called once the API is no longer needed.
+func adminAPI() (admin.API, func(), error) {
+	if adminURL != "" {
+		token, err := readAdminToken(adminTokenFile)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return admin.NewClient(adminURL, token), func() {}, nil
+	}
+
+	driver, _, err := configure()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = driver.Connect()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	service := admin.NewService(*driver)
+	service.Audit, _ = driver.Audit.(audit.Log)
+
+	return service, func() { driver.Disconnect() }, nil
+}
+
+// readAdminToken reads the token that authenticates requests to the admin API.
+func readAdminToken(path string) (string, error) {
+	if path == "" {
+		return "", errors.New("the admin API requires -admin-token-file")
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	token := strings.TrimSpace(string(contents))
+	if token == "" {
+		return "", errors.New(path + " does not contain a token")
+	}
+
+	return token, nil
+}
+
+// readAdminTokens reads the tokens that the admin API accepts from -admin-tokens or, if it is not set, grants the
+// token in -admin-token-file the admin role.
+func readAdminTokens() ([]admin.Token, error) {
+	if adminTokensPath == "" {
+		token, err := readAdminToken(adminTokenFile)
+		if err != nil {
+			return nil, err
+		}
+
+		return []admin.Token{{Name: "admin", Role: admin.Admin, Secret: token}}, nil
+	}
+
+	file, err := os.Open(adminTokensPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	tokens, err := admin.ParseTokens(file)
+	if err != nil {
+		return nil, errors.New(adminTokensPath + ": " + err.Error())
+	}
+
+	return tokens, nil
+}
+
+// checkAdminAddress refuses to serve the admin API without TLS on an address other hosts can reach, as its bearer
+// tokens would cross the network in the clear.
+func checkAdminAddress(address string, secure bool) error {
+	if secure {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return errors.New("invalid admin address " + address + ": " + err.Error())
+	}
+
+	if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		return nil
+	}
+
+	return errors.New("the admin API would be served without tls on " + address + ", please set -tls-cert and -tls-key or use a loopback address such as 127.0.0.1")
+}
+
+// adminCommand runs the subcommand in args against api, writing a table, or json with -json, to out. Archives are
+// read from in and written to out unless a file or s3 url is given.
+func adminCommand(api admin.API, args []string, in io.Reader, out io.Writer) error {
+	if len(args) < 2 {
+		return errors.New(adminUsage)
+	}
+
+	flags := flag.NewFlagSet(args[0]+" "+args[1], flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	asJSON := flags.Bool("json", false, "print json rather than a table")
+	force := flags.Bool("force", false, "release the volume's mounts before removing it")
+	live := flags.Bool("live", false, "back up a volume that is in use without a snapshot")
+	compress := flags.Bool("zstd", false, "compress the archive with zstd")
+	output := flags.String("o", "", "file or s3 url to write the archive to (default is stdout)")
+	input := flags.String("i", "", "file or s3 url to read the archive from (default is stdin)")
+	name := flags.String("name", "", "name of the restored volume (default is the archived volume's name)")
+	to := flags.String("to", "", "backend to migrate the volume to")
+	selector := flags.String("l", "", "only list volumes whose labels match the selector, e.g. team=ml,env!=prod")
+	volumeName := flags.String("volume", "", "only list the audit entries of this volume")
+	operation := flags.String("operation", "", "only list audit entries of this operation, e.g. remove")
+	since := flags.String("since", "", "only list audit entries from this RFC 3339 time, or this long ago, e.g. 24h")
+	until := flags.String("until", "", "only list audit entries before this RFC 3339 time, or this long ago")
+	grace := flags.Duration("grace", gc.DefaultGrace, "only collect storage that has not changed for this long")
+	deleteOrphans := flags.Bool("delete", false, "delete storage that no volume owns, rather than quarantining it")
+	dryRun := flags.Bool("dry-run", false, "only report the storage that would be collected")
+	if err := flags.Parse(args[2:]); err != nil {
+		return errors.New(err.Error() + "\n" + adminUsage)
+	}
+	operands := flags.Args()
+
+	switch {
+	case args[0] == "volumes" && args[1] == "ls" && len(operands) == 0:
+		volumes, err := api.ListVolumes(*selector)
+		if err != nil {
+			return err
+		}
+
+		if *asJSON {
+			return writeJSON(out, volumes)
+		}
+		return writeVolumesTable(out, volumes)
+	case args[0] == "volumes" && args[1] == "inspect" && len(operands) == 1:
+		vol, err := api.InspectVolume(operands[0])
+		if err != nil {
+			return err
+		}
+
+		if *asJSON {
+			return writeJSON(out, vol)
+		}
+		return writeVolumeDetails(out, vol)
+	case args[0] == "volumes" && args[1] == "rm" && len(operands) == 1:
+		err := api.RemoveVolume(operands[0], *force)
+		if err == nil {
+			fmt.Fprintln(out, operands[0])
+		}
+		return err
+	case args[0] == "volumes" && args[1] == "backup" && len(operands) == 1:
+		return backupVolume(api, operands[0], admin.BackupOptions{Live: *live, Compress: *compress}, *output, out)
+	case args[0] == "volumes" && args[1] == "restore" && len(operands) == 0:
+		vol, err := restoreVolume(api, *input, *name, in)
+		if err == nil {
+			fmt.Fprintln(out, vol.Name)
+		}
+		return err
+	case args[0] == "volumes" && args[1] == "resize" && len(operands) == 2:
+		vol, err := api.Resize(operands[0], operands[1])
+		if err == nil {
+			fmt.Fprintln(out, vol.Name, vol.Options["size"])
+		}
+		return err
+	case args[0] == "volumes" && args[1] == "migrate" && len(operands) == 1:
+		vol, err := api.Migrate(operands[0], *to, migrateProgress(out))
+		if err == nil {
+			fmt.Fprintln(out, vol.Name)
+		}
+		return err
+	case args[0] == "volumes" && args[1] == "gc" && len(operands) == 0:
+		report, err := api.CollectGarbage(gc.Options{Grace: *grace, Delete: *deleteOrphans, DryRun: *dryRun})
+		if err != nil {
+			return err
+		}
+
+		if *asJSON {
+			return writeJSON(out, report)
+		}
+		return writeGCReport(out, report)
+	case args[0] == "mounts" && args[1] == "ls" && len(operands) <= 1:
+		var volumeName string
+		if len(operands) == 1 {
+			volumeName = operands[0]
+		}
+
+		mounts, err := api.ListMounts(volumeName)
+		if err != nil {
+			return err
+		}
+
+		if *asJSON {
+			return writeJSON(out, mounts)
+		}
+		return writeMountsTable(out, mounts)
+	case args[0] == "tenants" && args[1] == "ls" && len(operands) == 0:
+		tenants, err := api.ListTenants()
+		if err != nil {
+			return err
+		}
+
+		if *asJSON {
+			return writeJSON(out, tenants)
+		}
+		return writeTenantsTable(out, tenants)
+	case args[0] == "audit" && args[1] == "ls" && len(operands) == 0:
+		filter, err := auditFilter(*volumeName, *operation, *since, *until, time.Now())
+		if err != nil {
+			return err
+		}
+
+		entries, err := api.AuditEvents(filter)
+		if err != nil {
+			return err
+		}
+
+		if *asJSON {
+			return writeJSON(out, entries)
+		}
+		return writeAuditTable(out, entries)
+	case args[0] == "mounts" && args[1] == "release" && len(operands) == 2:
+		err := api.ReleaseMount(operands[0], operands[1])
+		if err == nil {
+			fmt.Fprintln(out, operands[1])
+		}
+		return err
+	}
+
+	return errors.New("invalid command: " + strings.Join(args, " ") + "\n" + adminUsage)
+}
+
+// backupVolume writes a volume's archive to output, a file or s3 url, or to out if output is empty. Archives are
+// written to a temporary file before they are uploaded, as uploads must state their size.
+func backupVolume(api admin.API, volumeName string, options admin.BackupOptions, output string, out io.Writer) error {
+	if output == "" || output == "-" {
+		return api.Backup(volumeName, options, out)
+	}
+
+	var bucket, key string
+	if strings.HasPrefix(output, "s3://") {
+		var err error
+		bucket, key, err = backup.ParseS3URL(output)
+		if err != nil {
+			return err
+		}
+
+		temp, err := ioutil.TempFile("", "docker-volume-rdma-backup")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(temp.Name())
+		temp.Close()
+
+		output = temp.Name()
+	}
+
+	file, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+
+	err = api.Backup(volumeName, options, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(output)
+		return err
+	}
+
+	if bucket == "" {
+		return nil
+	}
+
+	file, err = os.Open(output)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	return s3Client().Put(bucket, key, file, info.Size())
+}
+
+// restoreVolume restores a volume from the archive at input, a file or s3 url, or from in if input is empty.
+func restoreVolume(api admin.API, input string, volumeName string, in io.Reader) (admin.Volume, error) {
+	if input == "" || input == "-" {
+		return api.Restore(in, volumeName)
+	}
+
+	var archive io.ReadCloser
+	if strings.HasPrefix(input, "s3://") {
+		bucket, key, err := backup.ParseS3URL(input)
+		if err != nil {
+			return admin.Volume{}, err
+		}
+
+		archive, err = s3Client().Get(bucket, key)
+		if err != nil {
+			return admin.Volume{}, err
+		}
+	} else {
+		file, err := os.Open(input)
+		if err != nil {
+			return admin.Volume{}, err
+		}
+		archive = file
+	}
+	defer archive.Close()
+
+	return api.Restore(archive, volumeName)
+}
+
+// migrateProgress returns a function that prints the progress of a migration to out, each time another percent of the
+// volume has been copied.
+func migrateProgress(out io.Writer) func(migrate.Progress) {
+	printed := -1
+	return func(progress migrate.Progress) {
+		percent := int(progress.Percent())
+		if percent == printed {
+			return
+		}
+		printed = percent
+
+		fmt.Fprintf(out, "copied %d of %d files, %d of %d bytes (%d%%)\n", progress.Files, progress.TotalFiles, progress.Bytes, progress.TotalBytes, percent)
+	}
+}
+
+// s3Client returns a client for -s3-endpoint, using the usual AWS environment variables for its keys.
+func s3Client() backup.S3Client {
+	return backup.NewS3Client(s3Endpoint, s3Region, os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"))
+}
+
+func writeJSON(out io.Writer, value interface{}) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "    ")
+	return encoder.Encode(value)
+}
+
+func writeVolumesTable(out io.Writer, volumes []admin.Volume) error {
+	table := tabwriter.NewWriter(out, 0, 8, 3, ' ', 0)
+	fmt.Fprintln(table, "NAME\tBACKEND\tSIZE\tMOUNTS\tMOUNTPOINT")
+	for _, vol := range volumes {
+		requests := 0
+		for _, count := range vol.Mounts {
+			requests += count
+		}
+
+		fmt.Fprintf(table, "%s\t%s\t%s\t%d\t%s\n", vol.Name, vol.Options["backend"], vol.Options["size"], requests, vol.Mountpoint)
+	}
+
+	return table.Flush()
+}
+
+func writeVolumeDetails(out io.Writer, vol admin.Volume) error {
+	table := tabwriter.NewWriter(out, 0, 8, 3, ' ', 0)
+	fmt.Fprintf(table, "Name:\t%s\n", vol.Name)
+	fmt.Fprintf(table, "Mountpoint:\t%s\n", vol.Mountpoint)
+
+	fmt.Fprintln(table, "Options:")
+	for _, name := range sortedKeys(vol.Options) {
+		fmt.Fprintf(table, "  %s\t%s\n", name, vol.Options[name])
+	}
+
+	fmt.Fprintln(table, "Labels:")
+	for _, name := range sortedKeys(vol.Labels) {
+		fmt.Fprintf(table, "  %s\t%s\n", name, vol.Labels[name])
+	}
+
+	fmt.Fprintln(table, "Mounts:")
+	mounts := make(map[string]string, len(vol.Mounts))
+	for id, count := range vol.Mounts {
+		mounts[id] = strconv.Itoa(count)
+	}
+	for _, id := range sortedKeys(mounts) {
+		fmt.Fprintf(table, "  %s\t%s\n", id, mounts[id])
+	}
+
+	return table.Flush()
+}
+
+func writeMountsTable(out io.Writer, mounts []admin.Mount) error {
+	table := tabwriter.NewWriter(out, 0, 8, 3, ' ', 0)
+	fmt.Fprintln(table, "VOLUME\tID\tCOUNT\tMOUNTPOINT")
+	for _, mount := range mounts {
+		fmt.Fprintf(table, "%s\t%s\t%d\t%s\n", mount.Volume, mount.ID, mount.Count, mount.Mountpoint)
+	}
+
+	return table.Flush()
+}
+
+func writeTenantsTable(out io.Writer, tenants []drivers.TenantUsage) error {
+	limit := func(value int64) string {
+		if value == 0 {
+			return "-"
+		}
+		return strconv.FormatInt(value, 10)
+	}
+
+	table := tabwriter.NewWriter(out, 0, 8, 3, ' ', 0)
+	fmt.Fprintln(table, "TENANT\tVOLUMES\tVOLUME QUOTA\tBYTES\tBYTE QUOTA")
+	for _, tenant := range tenants {
+		fmt.Fprintf(table, "%s\t%d\t%s\t%d\t%s\n", tenant.Tenant, tenant.Volumes, limit(int64(tenant.Quota.Volumes)), tenant.Bytes, limit(tenant.Quota.Bytes))
+	}
+
+	return table.Flush()
+}
+
+// auditFilter selects the audit entries of volumeName and operation between since and until, which are either RFC 3339
+// times or durations before now.
+func auditFilter(volumeName string, operation string, since string, until string, now time.Time) (audit.Filter, error) {
+	filter := audit.Filter{Volume: volumeName, Action: operation}
+
+	parse := func(value string) (time.Time, error) {
+		if value == "" {
+			return time.Time{}, nil
+		}
+
+		if ago, err := time.ParseDuration(value); err == nil {
+			return now.Add(-ago), nil
+		}
+
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, errors.New("invalid time " + value + ", please give an RFC 3339 time or a duration such as 24h")
+		}
+		return parsed, nil
+	}
+
+	var err error
+	filter.Since, err = parse(since)
+	if err == nil {
+		filter.Until, err = parse(until)
+	}
+
+	return filter, err
+}
+
+// writeAuditTable writes a table of audit entries, oldest first.
+func writeAuditTable(out io.Writer, entries []audit.Entry) error {
+	dash := func(value string) string {
+		if value == "" {
+			return "-"
+		}
+		return value
+	}
+
+	table := tabwriter.NewWriter(out, 0, 8, 3, ' ', 0)
+	fmt.Fprintln(table, "TIME\tAPI\tOPERATION\tVOLUME\tBY\tHOST\tOUTCOME\tDURATION")
+	for _, entry := range entries {
+		outcome := entry.Outcome()
+		if entry.Err != "" {
+			outcome += ": " + entry.Err
+		} else if entry.Reason != "" {
+			outcome += ": " + entry.Reason
+		}
+
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", entry.Time.Format(time.RFC3339), entry.API, entry.Action,
+			dash(entry.Volume), dash(entry.Subject), dash(entry.Host), outcome, entry.Duration)
+	}
+
+	return table.Flush()
+}
+
+// writeGCReport writes a table of the orphans a collection found, followed by how many bytes it reclaimed and the
+// backends it could not collect.
+func writeGCReport(out io.Writer, report gc.Report) error {
+	table := tabwriter.NewWriter(out, 0, 8, 3, ' ', 0)
+	fmt.Fprintln(table, "BACKEND\tVOLUME\tBYTES\tMODIFIED\tACTION")
+	for _, orphan := range report.Orphans {
+		backend := orphan.Backend
+		if backend == "" {
+			backend = "-"
+		}
+
+		action := orphan.Action
+		if report.DryRun && action != gc.Kept {
+			action = "would be " + action
+		}
+		if orphan.Reason != "" {
+			action += ": " + orphan.Reason
+		}
+
+		fmt.Fprintf(table, "%s\t%s\t%d\t%s\t%s\n", backend, orphan.Volume, orphan.Bytes, orphan.Modified.Format(time.RFC3339), action)
+	}
+
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	if report.DryRun {
+		fmt.Fprintf(out, "would reclaim %d bytes, would quarantine %d bytes\n", report.Reclaimed, report.Quarantined)
+	} else {
+		fmt.Fprintf(out, "reclaimed %d bytes, quarantined %d bytes\n", report.Reclaimed, report.Quarantined)
+	}
+
+	for _, backend := range report.Unlisted {
+		if backend == "" {
+			fmt.Fprintln(out, "the storage controller can not list its volumes, so was not collected")
+			continue
+		}
+		fmt.Fprintln(out, "backend", backend, "can not list its volumes, so was not collected")
+	}
+
+	return nil
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
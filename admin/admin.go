@@ -0,0 +1,242 @@
+// Package admin lets operators inspect and manage the volumes and mount requests recorded in the volume database,
+// either directly or through the admin API of a running plugin daemon.
+package admin
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/audit"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"github.com/mellanox-senior-design/docker-volume-rdma/gc"
+	"github.com/mellanox-senior-design/docker-volume-rdma/migrate"
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
+)
+
+// Volume describes a volume, the labels Docker recorded for it, and the requests to mount it.
+type Volume struct {
+	Name       string
+	Mountpoint string            `json:",omitempty"`
+	Options    map[string]string `json:",omitempty"`
+	Labels     map[string]string `json:",omitempty"`
+	Mounts     map[string]int    `json:",omitempty"`
+}
+
+// Mount describes the outstanding requests of one ID to mount a volume.
+type Mount struct {
+	Volume     string
+	ID         string
+	Count      int
+	Mountpoint string `json:",omitempty"`
+}
+
+// API is implemented by Service, which manages volumes directly, and by Client, which manages them through a daemon.
+type API interface {
+	// ListVolumes returns the volumes whose labels match selector, e.g. team=ml,env!=prod, sorted by name. Every
+	// volume is returned if selector is empty.
+	ListVolumes(selector string) ([]Volume, error)
+
+	// InspectVolume returns a particular volume.
+	InspectVolume(volumeName string) (Volume, error)
+
+	// ListMounts returns the mount requests of a particular volume, or of every volume if volumeName is empty.
+	ListMounts(volumeName string) ([]Mount, error)
+
+	// ReleaseMount drops every mount request of id on a particular volume.
+	ReleaseMount(volumeName string, id string) error
+
+	// RemoveVolume removes a particular volume, releasing its mount requests first if force is set.
+	RemoveVolume(volumeName string, force bool) error
+
+	// Backup writes a particular volume's files and metadata to w as a tar archive.
+	Backup(volumeName string, options BackupOptions, w io.Writer) error
+
+	// Restore creates a volume from an archive, named volumeName or, if empty, after the archived volume.
+	Restore(r io.Reader, volumeName string) (Volume, error)
+
+	// Resize grows a particular volume, and its filesystem, to size.
+	Resize(volumeName string, size string) (Volume, error)
+
+	// Migrate moves a particular volume to another backend, calling report, if not nil, as its files are copied.
+	Migrate(volumeName string, backend string, report func(migrate.Progress)) (Volume, error)
+
+	// ListTenants returns what the volumes of each tenant use, and their quotas, sorted by tenant.
+	ListTenants() ([]drivers.TenantUsage, error)
+
+	// AuditEvents returns the audited calls and volume operations selected by filter, oldest first.
+	AuditEvents(filter audit.Filter) ([]audit.Entry, error)
+
+	// CollectGarbage quarantines, or deletes, the storage of the backends that no volume owns.
+	CollectGarbage(options gc.Options) (gc.Report, error)
+}
+
+// Service manages volumes using the volume database and storage controllers of a driver.
+type Service struct {
+	Driver drivers.RDMAVolumeDriver
+
+	// Audit is the log that AuditEvents reads, if one is kept.
+	Audit audit.Log
+}
+
+// NewService creates a new Service for driver.
+func NewService(driver drivers.RDMAVolumeDriver) Service {
+	return Service{Driver: driver}
+}
+
+// ListVolumes returns the volumes whose labels match selector, sorted by name.
+func (s Service) ListVolumes(selector string) ([]Volume, error) {
+	parsed, err := ParseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	listed, err := s.Driver.VolumeDatabase.List()
+	if err != nil {
+		return nil, err
+	}
+
+	volumes := make([]Volume, 0, len(listed))
+	for _, vol := range listed {
+		described, err := s.describe(vol)
+		if err != nil {
+			return nil, err
+		}
+
+		if parsed.Matches(described.Labels) {
+			volumes = append(volumes, described)
+		}
+	}
+
+	sort.Slice(volumes, func(i, j int) bool { return volumes[i].Name < volumes[j].Name })
+	return volumes, nil
+}
+
+// InspectVolume returns a particular volume.
+func (s Service) InspectVolume(volumeName string) (Volume, error) {
+	vol, err := s.Driver.VolumeDatabase.Get(volumeName)
+	if err != nil {
+		return Volume{}, err
+	}
+
+	return s.describe(vol)
+}
+
+// ListMounts returns the mount requests of a particular volume, or of every volume if volumeName is empty, sorted by
+// volume and ID.
+func (s Service) ListMounts(volumeName string) ([]Mount, error) {
+	var volumes []Volume
+	if volumeName == "" {
+		var err error
+		volumes, err = s.ListVolumes("")
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		vol, err := s.InspectVolume(volumeName)
+		if err != nil {
+			return nil, err
+		}
+		volumes = []Volume{vol}
+	}
+
+	mounts := []Mount{}
+	for _, vol := range volumes {
+		ids := make([]string, 0, len(vol.Mounts))
+		for id := range vol.Mounts {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		for _, id := range ids {
+			mounts = append(mounts, Mount{Volume: vol.Name, ID: id, Count: vol.Mounts[id], Mountpoint: vol.Mountpoint})
+		}
+	}
+
+	return mounts, nil
+}
+
+// ReleaseMount drops every mount request of id on a particular volume, unmounting it once none remain.
+func (s Service) ReleaseMount(volumeName string, id string) error {
+	return s.Driver.Release(volumeName, id)
+}
+
+// RemoveVolume removes a particular volume. Volumes with mount requests are only removed if force is set, releasing
+// their mount requests first.
+func (s Service) RemoveVolume(volumeName string, force bool) error {
+	mounts, err := s.Driver.VolumeDatabase.Mounts(volumeName)
+	if err != nil {
+		return err
+	}
+
+	if len(mounts) > 0 && !force {
+		return errors.New("volume " + volumeName + " is in use, release its mounts or use -force")
+	}
+
+	for id := range mounts {
+		err = s.Driver.Release(volumeName, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	response := s.Driver.Remove(volume.Request{Name: volumeName})
+	if response.Err != "" {
+		return errors.New(response.Err)
+	}
+
+	return nil
+}
+
+// Resize grows a particular volume, and its filesystem, to size, recording the new size in its options. Volumes may be
+// resized while they are in use.
+func (s Service) Resize(volumeName string, size string) (Volume, error) {
+	err := s.Driver.Resize(volumeName, size)
+	if err != nil {
+		return Volume{}, err
+	}
+
+	return s.InspectVolume(volumeName)
+}
+
+// ListTenants returns what the volumes of each tenant use, and their quotas, sorted by tenant. There are none unless
+// tenants are configured.
+func (s Service) ListTenants() ([]drivers.TenantUsage, error) {
+	return s.Driver.TenantUsage()
+}
+
+// AuditEvents returns the audited calls and volume operations selected by filter, oldest first.
+func (s Service) AuditEvents(filter audit.Filter) ([]audit.Entry, error) {
+	if s.Audit.Path == "" {
+		return nil, errors.New("no audit log is kept, please set -audit-log")
+	}
+
+	return s.Audit.Query(filter)
+}
+
+// CollectGarbage quarantines, or deletes, the storage of the backends that no volume owns, once it has gone unchanged
+// for the grace period.
+func (s Service) CollectGarbage(options gc.Options) (gc.Report, error) {
+	return gc.Collect(s.Driver, options, time.Now())
+}
+
+// describe adds the options, labels and mount requests of a volume to it.
+func (s Service) describe(vol *volume.Volume) (Volume, error) {
+	options, err := s.Driver.VolumeDatabase.Options(vol.Name)
+	if err != nil {
+		return Volume{}, err
+	}
+
+	labels, err := s.Driver.VolumeDatabase.Labels(vol.Name)
+	if err != nil {
+		return Volume{}, err
+	}
+
+	mounts, err := s.Driver.VolumeDatabase.Mounts(vol.Name)
+	if err != nil {
+		return Volume{}, err
+	}
+
+	return Volume{Name: vol.Name, Mountpoint: vol.Mountpoint, Options: options, Labels: labels, Mounts: mounts}, nil
+}
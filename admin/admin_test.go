@@ -0,0 +1,190 @@
+package admin
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/db"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
+)
+
+// newTestService creates a Service backed by the on-disk storage controller and an in-memory database, with volume
+// "idle" and volume "busy" mounted by "a" twice and "b" once.
+func newTestService(t *testing.T) (Service, string) {
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-admin")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+
+	driver := drivers.NewRDMAVolumeDriver(drivers.NewOnDiskStorageController(path.Join(tempDir, "volumes")), db.NewInMemoryVolumeDatabase())
+	err = driver.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"idle", "busy"} {
+		if response := driver.Create(volume.Request{Name: name, Options: map[string]string{"size": "1G"}}); response.Err != "" {
+			t.Fatal(response.Err)
+		}
+	}
+
+	for _, id := range []string{"a", "a", "b"} {
+		if response := driver.Mount(volume.MountRequest{Name: "busy", ID: id}); response.Err != "" {
+			t.Fatal(response.Err)
+		}
+	}
+
+	return NewService(driver), tempDir
+}
+
+func TestListVolumes(t *testing.T) {
+	t.Parallel()
+	service, tempDir := newTestService(t)
+	defer os.RemoveAll(tempDir)
+
+	volumes, err := service.ListVolumes("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(volumes) != 2 || volumes[0].Name != "busy" || volumes[1].Name != "idle" {
+		t.Fatal("Expected busy and idle, got ", volumes)
+	}
+
+	if volumes[0].Mounts["a"] != 2 || volumes[0].Mounts["b"] != 1 || volumes[0].Options["size"] != "1G" {
+		t.Error("The busy volume's options and mounts were not listed, got ", volumes[0])
+	}
+
+	if len(volumes[1].Mounts) != 0 {
+		t.Error("The idle volume should have no mounts, got ", volumes[1].Mounts)
+	}
+
+	if err = service.Driver.VolumeDatabase.SetLabels("busy", map[string]string{"team": "ml", "env": "prod"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = service.Driver.VolumeDatabase.SetLabels("idle", map[string]string{"team": "ml"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		selector string
+		expected []string
+	}{
+		{"team=ml", []string{"busy", "idle"}},
+		{"team=ml,env!=prod", []string{"idle"}},
+		{"env", []string{"busy"}},
+		{"team=web", []string{}},
+	}
+
+	for _, test := range tests {
+		volumes, err = service.ListVolumes(test.selector)
+		names := []string{}
+		for _, vol := range volumes {
+			names = append(names, vol.Name)
+		}
+
+		if err != nil || strings.Join(names, ",") != strings.Join(test.expected, ",") {
+			t.Error("Expected ", test.selector, " to select ", test.expected, ", got ", names, err)
+		}
+	}
+
+	if volumes, err = service.ListVolumes("team=ml"); err == nil && volumes[0].Labels["env"] != "prod" {
+		t.Error("The volumes' labels should be listed, got ", volumes[0])
+	}
+
+	if _, err = service.ListVolumes("team=ml,"); err == nil {
+		t.Error("An invalid selector should fail")
+	}
+}
+
+func TestListMounts(t *testing.T) {
+	t.Parallel()
+	service, tempDir := newTestService(t)
+	defer os.RemoveAll(tempDir)
+
+	mounts, err := service.ListMounts("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(mounts) != 2 || mounts[0].ID != "a" || mounts[0].Count != 2 || mounts[1].ID != "b" || mounts[1].Volume != "busy" {
+		t.Error("Expected the mounts of a and b on busy, got ", mounts)
+	}
+
+	mounts, err = service.ListMounts("idle")
+	if err != nil || len(mounts) != 0 {
+		t.Error("The idle volume should have no mounts, got ", mounts, err)
+	}
+
+	if _, err = service.ListMounts("missing"); err == nil {
+		t.Error("Listing the mounts of a missing volume should fail")
+	}
+}
+
+func TestRemoveVolume(t *testing.T) {
+	t.Parallel()
+	service, tempDir := newTestService(t)
+	defer os.RemoveAll(tempDir)
+
+	if err := service.RemoveVolume("busy", false); err == nil {
+		t.Error("A volume with mounts should not be removed without force")
+	}
+
+	if err := service.ReleaseMount("busy", "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	vol, err := service.InspectVolume("busy")
+	if err != nil || len(vol.Mounts) != 1 {
+		t.Error("Only a's mounts should remain, got ", vol.Mounts, err)
+	}
+
+	if err = service.RemoveVolume("busy", true); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = service.InspectVolume("busy"); err == nil {
+		t.Error("The busy volume should be removed")
+	}
+
+	if err = service.RemoveVolume("idle", false); err != nil {
+		t.Error(err)
+	}
+}
+
+// resizableOnDisk adds resizing to the on-disk storage controller, recording the sizes volumes were grown to.
+type resizableOnDisk struct {
+	drivers.OnDiskStorageController
+	sizes map[string]string
+}
+
+func (r resizableOnDisk) Resize(volumeName string, size string, options map[string]string) error {
+	r.sizes[volumeName] = size
+	return nil
+}
+
+func TestResize(t *testing.T) {
+	t.Parallel()
+	service, tempDir := newTestService(t)
+	defer os.RemoveAll(tempDir)
+
+	if _, err := service.Resize("busy", "2G"); err == nil {
+		t.Error("Resizing should fail when the storage controller does not support it")
+	}
+
+	resizable := resizableOnDisk{service.Driver.StorageController.(drivers.OnDiskStorageController), map[string]string{}}
+	service.Driver.StorageController = resizable
+
+	vol, err := service.Resize("busy", "2G")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resizable.sizes["busy"] != "2G" || vol.Options["size"] != "2G" || vol.Mounts["a"] != 2 {
+		t.Error("Expected busy to grow to 2G while it stays mounted, got ", vol, resizable.sizes)
+	}
+}
@@ -0,0 +1,164 @@
+package admin
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/mellanox-senior-design/docker-volume-rdma/backup"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
+)
+
+// Mount requests made while a volume is backed up or restored, so that it is not unmounted from under the archive.
+const (
+	backupRequester  = "admin-backup"
+	restoreRequester = "admin-restore"
+)
+
+// BackupOptions control how a volume is archived.
+type BackupOptions struct {
+	// Live allows a volume that is in use to be archived as it changes, rather than from a snapshot.
+	Live bool
+
+	// Compress compresses the archive with zstd.
+	Compress bool
+}
+
+// Backup writes a particular volume's files and metadata to w as a tar archive. A volume that is in use is archived
+// from a snapshot if its storage controller can take one, otherwise options.Live must be set.
+func (s Service) Backup(volumeName string, options BackupOptions, w io.Writer) error {
+	vol, err := s.InspectVolume(volumeName)
+	if err != nil {
+		return err
+	}
+
+	manifest := backup.Manifest{Volume: vol.Name, Options: vol.Options, Labels: vol.Labels, Created: time.Now().UTC()}
+
+	// Mount requests by the admin API itself, e.g. a concurrent backup, do not count as use.
+	inUse := false
+	for id := range vol.Mounts {
+		inUse = inUse || (id != backupRequester && id != restoreRequester)
+	}
+
+	if inUse && !options.Live {
+		storageController, err := s.Driver.Backend(vol.Options[drivers.BackendOption])
+		if err != nil {
+			return err
+		}
+
+		snapshotter, ok := storageController.(drivers.StorageSnapshotter)
+		if !ok {
+			return errors.New("volume " + volumeName + " is in use and its storage controller can not snapshot it, use -live to back it up as it changes")
+		}
+
+		return s.backupSnapshot(storageController, snapshotter, manifest, options, w)
+	}
+
+	manifest.Live = inUse
+	response := s.Driver.Mount(volume.MountRequest{Name: volumeName, ID: backupRequester})
+	if response.Err != "" {
+		return errors.New(response.Err)
+	}
+	defer s.release(volumeName, backupRequester)
+
+	glog.Info("Backing up ", volumeName, " from ", response.Mountpoint)
+	return backup.Write(w, manifest, response.Mountpoint, options.Compress)
+}
+
+// backupSnapshot archives a snapshot of a volume, deleting the snapshot afterwards.
+func (s Service) backupSnapshot(storageController drivers.StorageController, snapshotter drivers.StorageSnapshotter, manifest backup.Manifest, options BackupOptions, w io.Writer) error {
+	snapshotName := manifest.Volume + "-backup-" + strconv.FormatInt(manifest.Created.Unix(), 10)
+	err := snapshotter.Snapshot(manifest.Volume, snapshotName)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := storageController.Delete(snapshotName); err != nil {
+			glog.Error("Unable to delete snapshot ", snapshotName, ": ", err)
+		}
+	}()
+
+	mountpoint, err := storageController.Mount(snapshotName)
+	if err != nil {
+		return err
+	}
+
+	glog.Info("Backing up ", manifest.Volume, " from snapshot ", snapshotName)
+	return backup.Write(w, manifest, mountpoint, options.Compress)
+}
+
+// Restore creates a volume from an archive read from r, named volumeName or, if empty, after the archived volume. The
+// volume is created with the archived options and labels, and removed again if it can not be restored.
+func (s Service) Restore(r io.Reader, volumeName string) (Volume, error) {
+	created := false
+	manifest, err := backup.Read(r, func(manifest backup.Manifest) (string, error) {
+		if volumeName == "" {
+			volumeName = manifest.Volume
+		}
+
+		if _, err := s.Driver.VolumeDatabase.Get(volumeName); err == nil {
+			return "", errors.New("volume " + volumeName + " already exists")
+		}
+
+		// A restored encrypted volume gets a key of its own, rather than sharing the archived volume's, and a volume
+		// that expires does so counting from when it is restored.
+		options := map[string]string{}
+		for name, value := range manifest.Options {
+			switch name {
+			case drivers.KeyIDOption, drivers.ExpiresAtOption, drivers.IdleExpiresAtOption:
+			default:
+				options[name] = value
+			}
+		}
+
+		response := s.Driver.Create(volume.Request{Name: volumeName, Options: options})
+		if response.Err != "" {
+			return "", errors.New(response.Err)
+		}
+		created = true
+
+		if err := s.Driver.VolumeDatabase.SetLabels(volumeName, manifest.Labels); err != nil {
+			return "", err
+		}
+
+		response = s.Driver.Mount(volume.MountRequest{Name: volumeName, ID: restoreRequester})
+		if response.Err != "" {
+			return "", errors.New(response.Err)
+		}
+
+		glog.Info("Restoring ", manifest.Volume, " to ", volumeName)
+		return response.Mountpoint, nil
+	})
+
+	if created {
+		s.release(volumeName, restoreRequester)
+	}
+
+	if err != nil {
+		if created {
+			if response := s.Driver.Remove(volume.Request{Name: volumeName}); response.Err != "" {
+				glog.Error("Unable to remove partially restored volume ", volumeName, ": ", response.Err)
+			}
+		}
+		return Volume{}, err
+	}
+
+	glog.Info("Restored ", manifest.Volume, " to ", volumeName)
+	return s.InspectVolume(volumeName)
+}
+
+// release drops a mount request made by the admin API, if it was made.
+func (s Service) release(volumeName string, id string) {
+	mounts, err := s.Driver.VolumeDatabase.Mounts(volumeName)
+	if err != nil || mounts[id] == 0 {
+		return
+	}
+
+	if err = s.Driver.Release(volumeName, id); err != nil {
+		glog.Error("Unable to release ", volumeName, " from ", id, ": ", err)
+	}
+}
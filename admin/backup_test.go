@@ -0,0 +1,205 @@
+package admin
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/backup"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
+)
+
+// snapshotOnDisk adds snapshots to the on-disk storage controller by copying a volume's files.
+type snapshotOnDisk struct {
+	drivers.OnDiskStorageController
+	snapshots []string
+}
+
+func (s *snapshotOnDisk) Snapshot(volumeName string, snapshotName string) error {
+	s.snapshots = append(s.snapshots, snapshotName)
+	source := path.Join(s.FSPath, volumeName)
+	target := path.Join(s.FSPath, snapshotName)
+
+	return filepath.Walk(source, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relative, _ := filepath.Rel(source, name)
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(target, relative), info.Mode())
+		}
+
+		contents, err := ioutil.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filepath.Join(target, relative), contents, info.Mode())
+	})
+}
+
+// writeVolumeFile writes a file into a volume through its mountpoint.
+func writeVolumeFile(t *testing.T, service Service, volumeName string, name string, contents string) {
+	response := service.Driver.Mount(volume.MountRequest{Name: volumeName, ID: "writer"})
+	if response.Err != "" {
+		t.Fatal(response.Err)
+	}
+	defer service.Driver.Release(volumeName, "writer")
+
+	err := ioutil.WriteFile(path.Join(response.Mountpoint, name), []byte(contents), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBackupRestore(t *testing.T) {
+	t.Parallel()
+	service, tempDir := newTestService(t)
+	defer os.RemoveAll(tempDir)
+
+	writeVolumeFile(t, service, "idle", "hello.txt", "hello")
+	if err := service.Driver.VolumeDatabase.SetLabels("idle", map[string]string{"team": "ml"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var archive bytes.Buffer
+	err := service.Backup("idle", BackupOptions{Compress: true}, &archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vol, err := service.Restore(bytes.NewReader(archive.Bytes()), "copy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if vol.Name != "copy" || vol.Options["size"] != "1G" || vol.Labels["team"] != "ml" || len(vol.Mounts) != 0 {
+		t.Error("The copy should be created with the archived options and labels and left unmounted, got ", vol)
+	}
+
+	response := service.Driver.Mount(volume.MountRequest{Name: "copy", ID: "reader"})
+	contents, err := ioutil.ReadFile(path.Join(response.Mountpoint, "hello.txt"))
+	if err != nil || string(contents) != "hello" {
+		t.Error("The copy should hold the archived file, got ", string(contents), err)
+	}
+
+	if _, err = service.Restore(bytes.NewReader(archive.Bytes()), ""); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Error("The archive should not be restored over the idle volume, got ", err)
+	}
+
+	// A volume that can not be restored is removed again.
+	truncated := archive.Bytes()[:archive.Len()/2]
+	if _, err = service.Restore(bytes.NewReader(truncated), "broken"); err == nil {
+		t.Error("A truncated archive should not be restored")
+	}
+
+	if _, err = service.InspectVolume("broken"); err == nil {
+		t.Error("The partially restored volume should be removed")
+	}
+
+	mounts, err := service.Driver.VolumeDatabase.Mounts("idle")
+	if err != nil || len(mounts) != 0 {
+		t.Error("The backup's mount request should be released, got ", mounts, err)
+	}
+}
+
+func TestRestore_expiring(t *testing.T) {
+	t.Parallel()
+	service, tempDir := newTestService(t)
+	defer os.RemoveAll(tempDir)
+
+	if response := service.Driver.Create(volume.Request{Name: "scratch", Options: map[string]string{drivers.TTLOption: "72h"}}); response.Err != "" {
+		t.Fatal(response.Err)
+	}
+
+	// The archived volume has already expired, the copy expires counting from when it is restored.
+	if err := service.Driver.VolumeDatabase.SetOption("scratch", drivers.ExpiresAtOption, "2000-01-01T00:00:00Z"); err != nil {
+		t.Fatal(err)
+	}
+
+	var archive bytes.Buffer
+	if err := service.Backup("scratch", BackupOptions{}, &archive); err != nil {
+		t.Fatal(err)
+	}
+
+	vol, err := service.Restore(bytes.NewReader(archive.Bytes()), "copy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expiry, expires, err := drivers.Expiry(vol.Options)
+	if err != nil || !expires || !expiry.After(time.Now().Add(71*time.Hour)) || vol.Options[drivers.TTLOption] != "72h" {
+		t.Error("The copy should expire 72h after it was restored, got ", vol.Options, err)
+	}
+}
+
+func TestBackup_inUse(t *testing.T) {
+	t.Parallel()
+	service, tempDir := newTestService(t)
+	defer os.RemoveAll(tempDir)
+
+	err := service.Backup("busy", BackupOptions{}, ioutil.Discard)
+	if err == nil || !strings.Contains(err.Error(), "-live") {
+		t.Error("A volume in use should not be backed up without a snapshot or -live, got ", err)
+	}
+
+	var archive bytes.Buffer
+	err = service.Backup("busy", BackupOptions{Live: true}, &archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := backup.Read(&archive, func(backup.Manifest) (string, error) {
+		return ioutil.TempDir(tempDir, "restore")
+	})
+	if err != nil || !manifest.Live {
+		t.Error("The manifest should record that the backup was live, got ", manifest, err)
+	}
+
+	mounts, err := service.Driver.VolumeDatabase.Mounts("busy")
+	if err != nil || len(mounts) != 2 {
+		t.Error("Only the backup's mount request should be released, got ", mounts, err)
+	}
+}
+
+func TestBackup_snapshot(t *testing.T) {
+	t.Parallel()
+	service, tempDir := newTestService(t)
+	defer os.RemoveAll(tempDir)
+
+	snapshots := &snapshotOnDisk{OnDiskStorageController: service.Driver.StorageController.(drivers.OnDiskStorageController)}
+	service.Driver.StorageController = snapshots
+
+	writeVolumeFile(t, service, "busy", "table.db", "rows")
+
+	var archive bytes.Buffer
+	err := service.Backup("busy", BackupOptions{}, &archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(snapshots.snapshots) != 1 || !strings.HasPrefix(snapshots.snapshots[0], "busy-backup-") {
+		t.Fatal("The volume should be backed up from a snapshot, got ", snapshots.snapshots)
+	}
+
+	if _, err = os.Stat(path.Join(snapshots.FSPath, snapshots.snapshots[0])); !os.IsNotExist(err) {
+		t.Error("The snapshot should be deleted after the backup, got ", err)
+	}
+
+	target, _ := ioutil.TempDir(tempDir, "restore")
+	manifest, err := backup.Read(&archive, func(backup.Manifest) (string, error) { return target, nil })
+	if err != nil || manifest.Live {
+		t.Error("A snapshot backup is not live, got ", manifest, err)
+	}
+
+	contents, err := ioutil.ReadFile(path.Join(target, "table.db"))
+	if err != nil || string(contents) != "rows" {
+		t.Error("The snapshot's file should be archived, got ", string(contents), err)
+	}
+}
@@ -0,0 +1,238 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/audit"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"github.com/mellanox-senior-design/docker-volume-rdma/gc"
+	"github.com/mellanox-senior-design/docker-volume-rdma/migrate"
+)
+
+// DefaultTimeout bounds each request to the admin API. Removing a volume may take a while on some storage.
+const DefaultTimeout = 5 * time.Minute
+
+// Client manages volumes through the admin API of a running plugin daemon.
+type Client struct {
+	URL    string
+	Token  string
+	Client *http.Client
+}
+
+// NewClient creates a Client for the admin API at url, e.g. http://localhost:8081, authenticating with token.
+func NewClient(url string, token string) Client {
+	return Client{
+		URL:    strings.TrimSuffix(url, "/"),
+		Token:  token,
+		Client: &http.Client{Timeout: DefaultTimeout}}
+}
+
+// ListVolumes returns the volumes whose labels match selector, sorted by name.
+func (c Client) ListVolumes(selector string) ([]Volume, error) {
+	query := url.Values{}
+	if selector != "" {
+		query.Set("selector", selector)
+	}
+
+	var volumes []Volume
+	err := c.call(http.MethodGet, volumesPath, query, &volumes)
+	return volumes, err
+}
+
+// InspectVolume returns a particular volume.
+func (c Client) InspectVolume(volumeName string) (Volume, error) {
+	var vol Volume
+	err := c.call(http.MethodGet, volumePath(volumeName), nil, &vol)
+	return vol, err
+}
+
+// ListMounts returns the mount requests of a particular volume, or of every volume if volumeName is empty.
+func (c Client) ListMounts(volumeName string) ([]Mount, error) {
+	query := url.Values{}
+	if volumeName != "" {
+		query.Set("volume", volumeName)
+	}
+
+	var mounts []Mount
+	err := c.call(http.MethodGet, mountsPath, query, &mounts)
+	return mounts, err
+}
+
+// ReleaseMount drops every mount request of id on a particular volume.
+func (c Client) ReleaseMount(volumeName string, id string) error {
+	return c.call(http.MethodDelete, mountsPath, url.Values{"volume": {volumeName}, "id": {id}}, nil)
+}
+
+// RemoveVolume removes a particular volume, releasing its mount requests first if force is set.
+func (c Client) RemoveVolume(volumeName string, force bool) error {
+	return c.call(http.MethodDelete, volumePath(volumeName), url.Values{"force": {strconv.FormatBool(force)}}, nil)
+}
+
+// Backup writes a particular volume's archive to w. The archive is streamed as it is written, so an error may be
+// returned after part of it has been written.
+func (c Client) Backup(volumeName string, options BackupOptions, w io.Writer) error {
+	query := url.Values{"live": {strconv.FormatBool(options.Live)}, "compress": {strconv.FormatBool(options.Compress)}}
+	response, err := c.stream(http.MethodGet, backupURLPath(volumeName), query, nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	_, err = io.Copy(w, response.Body)
+	return err
+}
+
+// Restore creates a volume from the archive read from r, named volumeName or, if empty, after the archived volume.
+func (c Client) Restore(r io.Reader, volumeName string) (Volume, error) {
+	query := url.Values{}
+	if volumeName != "" {
+		query.Set("name", volumeName)
+	}
+
+	var vol Volume
+	response, err := c.stream(http.MethodPost, restorePath, query, r)
+	if err != nil {
+		return vol, err
+	}
+	defer response.Body.Close()
+
+	err = json.NewDecoder(response.Body).Decode(&vol)
+	return vol, err
+}
+
+// Resize grows a particular volume, and its filesystem, to size.
+func (c Client) Resize(volumeName string, size string) (Volume, error) {
+	var vol Volume
+	err := c.call(http.MethodPost, resizeURLPath(volumeName), url.Values{"size": {size}}, &vol)
+	return vol, err
+}
+
+// ListTenants returns what the volumes of each tenant use, and their quotas, sorted by tenant.
+func (c Client) ListTenants() ([]drivers.TenantUsage, error) {
+	var tenants []drivers.TenantUsage
+	err := c.call(http.MethodGet, tenantsPath, nil, &tenants)
+	return tenants, err
+}
+
+// AuditEvents returns the audited calls and volume operations selected by filter, oldest first.
+func (c Client) AuditEvents(filter audit.Filter) ([]audit.Entry, error) {
+	var entries []audit.Entry
+	err := c.call(http.MethodGet, auditPath, filterQuery(filter), &entries)
+	return entries, err
+}
+
+// CollectGarbage quarantines, or deletes, storage that no volume owns.
+func (c Client) CollectGarbage(options gc.Options) (gc.Report, error) {
+	var report gc.Report
+	err := c.call(http.MethodPost, gcPath, gcQuery(options), &report)
+	return report, err
+}
+
+// Migrate moves a particular volume to another backend, calling report, if not nil, with the progress the daemon streams.
+func (c Client) Migrate(volumeName string, backend string, report func(migrate.Progress)) (Volume, error) {
+	response, err := c.stream(http.MethodPost, migrateURLPath(volumeName), url.Values{"to": {backend}}, nil)
+	if err != nil {
+		return Volume{}, err
+	}
+	defer response.Body.Close()
+
+	decoder := json.NewDecoder(response.Body)
+	for {
+		var event migrateEvent
+		if err = decoder.Decode(&event); err == io.EOF {
+			return Volume{}, errors.New("the migration of " + volumeName + " ended without a result")
+		} else if err != nil {
+			return Volume{}, err
+		}
+
+		switch {
+		case event.Err != "":
+			return Volume{}, errors.New(event.Err)
+		case event.Volume != nil:
+			return *event.Volume, nil
+		case event.Progress != nil && report != nil:
+			report(*event.Progress)
+		}
+	}
+}
+
+// stream makes an authenticated request that may take as long as the archive or migration it streams, returning the
+// response if it succeeded.
+func (c Client) stream(method string, path string, query url.Values, body io.Reader) (*http.Response, error) {
+	request, err := c.request(method, path, query, body)
+	if err != nil {
+		return nil, err
+	}
+
+	streaming := *c.Client
+	streaming.Timeout = 0
+
+	response, err := streaming.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		defer response.Body.Close()
+		return nil, responseError(method, path, response)
+	}
+
+	return response, nil
+}
+
+// call makes an authenticated request to the admin API, decoding its response into result unless result is nil.
+func (c Client) call(method string, path string, query url.Values, result interface{}) error {
+	request, err := c.request(method, path, query, nil)
+	if err != nil {
+		return err
+	}
+
+	response, err := c.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return responseError(method, path, response)
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.NewDecoder(response.Body).Decode(result)
+}
+
+// request creates an authenticated request to the admin API.
+func (c Client) request(method string, path string, query url.Values, body io.Reader) (*http.Request, error) {
+	address := c.URL + path
+	if len(query) > 0 {
+		address += "?" + query.Encode()
+	}
+
+	request, err := http.NewRequest(method, address, body)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Authorization", "Bearer "+c.Token)
+
+	return request, nil
+}
+
+// responseError returns the error reported by the admin API for a failed request.
+func responseError(method string, path string, response *http.Response) error {
+	var failed errorResponse
+	if err := json.NewDecoder(response.Body).Decode(&failed); err != nil || failed.Err == "" {
+		return errors.New(method + " " + path + " failed: " + response.Status)
+	}
+
+	return errors.New(failed.Err)
+}
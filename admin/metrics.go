@@ -0,0 +1,58 @@
+package admin
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+)
+
+// metricsNamespace prefixes the name of every metric.
+const metricsNamespace = "docker_volume_rdma_"
+
+// metrics writes the usage of each tenant in the Prometheus text exposition format. Quotas are only reported for the
+// resources that are limited.
+func (h Handler) metrics(w http.ResponseWriter) {
+	tenants, err := h.API.ListTenants()
+	if err != nil {
+		respond(w, nil, err)
+		return
+	}
+
+	var out bytes.Buffer
+	writeGauge(&out, "tenant_volumes", "Volumes of each tenant.", tenants, func(usage drivers.TenantUsage) (int64, bool) {
+		return int64(usage.Volumes), true
+	})
+	writeGauge(&out, "tenant_bytes", "Bytes provisioned for the volumes of each tenant.", tenants, func(usage drivers.TenantUsage) (int64, bool) {
+		return usage.Bytes, true
+	})
+	writeGauge(&out, "tenant_quota_volumes", "Volumes each tenant may have.", tenants, func(usage drivers.TenantUsage) (int64, bool) {
+		return int64(usage.Quota.Volumes), usage.Quota.Volumes > 0
+	})
+	writeGauge(&out, "tenant_quota_bytes", "Bytes each tenant may provision.", tenants, func(usage drivers.TenantUsage) (int64, bool) {
+		return usage.Quota.Bytes, usage.Quota.Bytes > 0
+	})
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.WriteHeader(http.StatusOK)
+	w.Write(out.Bytes())
+}
+
+// writeGauge writes a gauge with a sample for each tenant that value reports one for.
+func writeGauge(out *bytes.Buffer, name string, help string, tenants []drivers.TenantUsage, value func(drivers.TenantUsage) (int64, bool)) {
+	fmt.Fprintf(out, "# HELP %s%s %s\n", metricsNamespace, name, help)
+	fmt.Fprintf(out, "# TYPE %s%s gauge\n", metricsNamespace, name)
+	for _, usage := range tenants {
+		if sample, ok := value(usage); ok {
+			fmt.Fprintf(out, "%s%s{tenant=\"%s\"} %s\n", metricsNamespace, name, escapeLabelValue(usage.Tenant), strconv.FormatInt(sample, 10))
+		}
+	}
+}
+
+// escapeLabelValue escapes the characters that may not appear as they are in a label value.
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
@@ -0,0 +1,65 @@
+package admin
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/audit"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+	service, tempDir := newTestService(t)
+	defer os.RemoveAll(tempDir)
+
+	tenants, err := drivers.NewTenants("", "-", map[string]drivers.Quota{"ml": {Volumes: 5, Bytes: 1 << 40}, "w\"eb": {Volumes: 1}}, drivers.Quota{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.Driver.Tenants = tenants
+
+	if response := service.Driver.Create(volume.Request{Name: "ml-data", Options: map[string]string{"size": "2G"}}); response.Err != "" {
+		t.Fatal(response.Err)
+	}
+
+	server := httptest.NewServer(NewHandler(service, testTokens, audit.Log{}))
+	defer server.Close()
+
+	request, _ := http.NewRequest(http.MethodGet, server.URL+metricsPath, nil)
+	request.Header.Set("Authorization", "Bearer secret")
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil || response.StatusCode != http.StatusOK {
+		t.Fatal("Expected the metrics, got ", response.Status, err)
+	}
+
+	expected := []string{
+		"# TYPE docker_volume_rdma_tenant_volumes gauge",
+		`docker_volume_rdma_tenant_volumes{tenant="ml"} 1`,
+		`docker_volume_rdma_tenant_volumes{tenant="w\"eb"} 0`,
+		`docker_volume_rdma_tenant_bytes{tenant="ml"} 2147483648`,
+		`docker_volume_rdma_tenant_quota_volumes{tenant="ml"} 5`,
+		`docker_volume_rdma_tenant_quota_bytes{tenant="ml"} 1099511627776`,
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Error("Expected the metrics to contain ", line, ", got ", string(body))
+		}
+	}
+
+	if strings.Contains(string(body), `docker_volume_rdma_tenant_quota_bytes{tenant="w\"eb"}`) {
+		t.Error("Unlimited resources should have no quota metric, got ", string(body))
+	}
+}
@@ -0,0 +1,166 @@
+package admin
+
+import (
+	"errors"
+
+	"github.com/golang/glog"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"github.com/mellanox-senior-design/docker-volume-rdma/migrate"
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
+)
+
+// migrateRequester mounts a volume while its files are copied to another backend, so that it stays mounted and so
+// that any other mount request made meanwhile is noticed.
+const migrateRequester = "admin-migrate"
+
+// Migrate moves a particular volume to another backend. The volume is created on the target backend, its files are
+// copied and verified, the backend recorded for it is switched, and only then is it deleted from the source backend.
+// Volumes that are in use are refused, as their containers would keep using the source, as are encrypted volumes when
+// the target backend can not encrypt them with the same key, and backends that mount volumes beneath the same folder.
+func (s Service) Migrate(volumeName string, backend string, report func(migrate.Progress)) (Volume, error) {
+	if backend == "" {
+		return Volume{}, errors.New("the backend to migrate " + volumeName + " to is required")
+	}
+
+	vol, err := s.InspectVolume(volumeName)
+	if err != nil {
+		return Volume{}, err
+	}
+
+	if len(vol.Mounts) > 0 {
+		return Volume{}, errors.New("volume " + volumeName + " is in use, stop the containers using it before migrating it")
+	}
+
+	source := vol.Options[drivers.BackendOption]
+	if source == "" {
+		source = s.Driver.DefaultBackend
+	}
+
+	if backend == source {
+		return Volume{}, errors.New("volume " + volumeName + " is already stored on backend " + backend)
+	}
+
+	sourceController, err := s.Driver.Backend(source)
+	if err != nil {
+		return Volume{}, err
+	}
+
+	targetController, err := s.Driver.Backend(backend)
+	if err != nil {
+		return Volume{}, err
+	}
+
+	// The copy of a volume on a backend that mounts volumes beneath the same folder would be mounted over the volume,
+	// and verified against itself.
+	if root := drivers.MountRoot(sourceController); root != "" && root == drivers.MountRoot(targetController) {
+		return Volume{}, errors.New("backends " + source + " and " + backend + " both mount volumes beneath " + root + ", so " + volumeName + " can not be migrated between them")
+	}
+
+	// The copy is encrypted with the volume's key, so the target backend must be able to fetch the same key.
+	if keyID := vol.Options[drivers.KeyIDOption]; keyID != "" {
+		if err = drivers.SharesKey(sourceController, targetController, keyID); err != nil {
+			return Volume{}, errors.New("volume " + volumeName + " is encrypted and can not be migrated to backend " + backend + ": " + err.Error())
+		}
+	}
+
+	options := map[string]string{}
+	for name, value := range vol.Options {
+		options[name] = value
+	}
+	options[drivers.BackendOption] = backend
+
+	glog.Info("Migrating ", volumeName, " from backend ", source, " to ", backend)
+	err = targetController.Create(volumeName, options)
+	if err != nil {
+		return Volume{}, err
+	}
+
+	err = s.copyVolume(volumeName, targetController, report)
+	if err == nil {
+		err = s.switchBackend(volumeName, source, backend)
+	}
+
+	if err != nil {
+		if deleteErr := targetController.Delete(volumeName); deleteErr != nil {
+			glog.Error("Unable to delete ", volumeName, " from backend ", backend, " after a failed migration: ", deleteErr)
+		}
+		return Volume{}, err
+	}
+
+	glog.Info("Migrated ", volumeName, " to backend ", backend, ", deleting it from backend ", source)
+	if err = sourceController.Delete(volumeName); err != nil {
+		return Volume{}, errors.New("volume " + volumeName + " was migrated to backend " + backend + " but could not be deleted from backend " + source + ": " + err.Error())
+	}
+
+	return s.InspectVolume(volumeName)
+}
+
+// copyVolume mounts a volume and its copy on the target backend, copies its files and verifies the copy. The copy is
+// unmounted afterwards, and the volume's mount request released.
+func (s Service) copyVolume(volumeName string, targetController drivers.StorageController, report func(migrate.Progress)) error {
+	response := s.Driver.Mount(volume.MountRequest{Name: volumeName, ID: migrateRequester})
+	if response.Err != "" {
+		return errors.New(response.Err)
+	}
+	defer s.release(volumeName, migrateRequester)
+
+	targetMountpoint, err := targetController.Mount(volumeName)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := targetController.Unmount(volumeName); err != nil {
+			glog.Error("Unable to unmount the copy of ", volumeName, ": ", err)
+		}
+	}()
+
+	if targetMountpoint == response.Mountpoint {
+		return errors.New("the copy of " + volumeName + " was mounted over it at " + targetMountpoint + ", the backends must mount volumes beneath different folders")
+	}
+
+	err = migrate.Copy(response.Mountpoint, targetMountpoint, report)
+	if err != nil {
+		return err
+	}
+
+	err = migrate.Verify(response.Mountpoint, targetMountpoint)
+	if err != nil {
+		return err
+	}
+
+	return s.checkUnused(volumeName)
+}
+
+// switchBackend records that a volume is stored on backend rather than source. If the volume was mounted while it was
+// switched, the mount may be of the source, so the switch is undone.
+func (s Service) switchBackend(volumeName string, source string, backend string) error {
+	err := s.Driver.VolumeDatabase.SetOption(volumeName, drivers.BackendOption, backend)
+	if err != nil {
+		return err
+	}
+
+	err = s.checkUnused(volumeName)
+	if err != nil {
+		if undoErr := s.Driver.VolumeDatabase.SetOption(volumeName, drivers.BackendOption, source); undoErr != nil {
+			glog.Error("Unable to record that ", volumeName, " is still stored on backend ", source, ": ", undoErr)
+		}
+	}
+
+	return err
+}
+
+// checkUnused returns an error if anything other than the migration has requested that a volume be mounted.
+func (s Service) checkUnused(volumeName string) error {
+	mounts, err := s.Driver.VolumeDatabase.Mounts(volumeName)
+	if err != nil {
+		return err
+	}
+
+	for id := range mounts {
+		if id != migrateRequester {
+			return errors.New("volume " + volumeName + " was mounted by " + id + " while it was migrated, its files may have changed")
+		}
+	}
+
+	return nil
+}
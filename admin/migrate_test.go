@@ -0,0 +1,214 @@
+package admin
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/db"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"github.com/mellanox-senior-design/docker-volume-rdma/keys"
+	"github.com/mellanox-senior-design/docker-volume-rdma/migrate"
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
+)
+
+// unmountableOnDisk is an on-disk storage controller whose volumes can be created but never mounted.
+type unmountableOnDisk struct {
+	drivers.OnDiskStorageController
+}
+
+func (u unmountableOnDisk) Mount(volumeName string) (string, error) {
+	return "", errors.New("unable to mount " + volumeName)
+}
+
+// encryptingOnDisk is an on-disk storage controller that accepts encrypted volumes, though it stores them in the
+// clear.
+type encryptingOnDisk struct {
+	drivers.OnDiskStorageController
+	keys keys.KeyProvider
+}
+
+func (e encryptingOnDisk) KeyProvider() keys.KeyProvider {
+	return e.keys
+}
+
+// Mount creates the volume's key the first time it is mounted, as the Storage Controllers that encrypt volumes do.
+func (e encryptingOnDisk) Mount(volumeName string) (string, error) {
+	if _, err := e.keys.GetKey(volumeName); err != nil {
+		if _, err = e.keys.CreateKey(volumeName); err != nil {
+			return "", err
+		}
+	}
+
+	return e.OnDiskStorageController.Mount(volumeName)
+}
+
+// newMigrateService creates a Service with on-disk backends "slow", the default, "fast" and "broken", whose volumes
+// can not be mounted, and volume "data" on slow holding a file.
+func newMigrateService(t *testing.T) (Service, string) {
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-migrate")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+
+	backends := map[string]drivers.StorageController{
+		"slow":   drivers.NewOnDiskStorageController(path.Join(tempDir, "slow")),
+		"fast":   drivers.NewOnDiskStorageController(path.Join(tempDir, "fast")),
+		"broken": unmountableOnDisk{drivers.NewOnDiskStorageController(path.Join(tempDir, "broken"))},
+		"shared": drivers.NewOnDiskStorageController(path.Join(tempDir, "slow") + "/"),
+	}
+
+	driver, err := drivers.NewMultiBackendRDMAVolumeDriver(backends, "slow", db.NewInMemoryVolumeDatabase())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = driver.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	if response := driver.Create(volume.Request{Name: "data", Options: map[string]string{"size": "1G"}}); response.Err != "" {
+		t.Fatal(response.Err)
+	}
+
+	service := NewService(driver)
+	writeVolumeFile(t, service, "data", "hello.txt", "hello world")
+
+	return service, tempDir
+}
+
+func TestMigrate(t *testing.T) {
+	t.Parallel()
+	service, tempDir := newMigrateService(t)
+	defer os.RemoveAll(tempDir)
+
+	var reports []migrate.Progress
+	vol, err := service.Migrate("data", "fast", func(progress migrate.Progress) { reports = append(reports, progress) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if vol.Options[drivers.BackendOption] != "fast" || vol.Options["size"] != "1G" || len(vol.Mounts) != 0 {
+		t.Error("Expected data to be recorded on fast with its other options, got ", vol)
+	}
+
+	if len(reports) == 0 || reports[len(reports)-1].Files != 1 || reports[len(reports)-1].Bytes != int64(len("hello world")) {
+		t.Error("Expected the copy of one file to be reported, got ", reports)
+	}
+
+	if _, err = os.Stat(path.Join(tempDir, "slow", "data.unmounted")); !os.IsNotExist(err) {
+		t.Error("The volume should have been deleted from the source backend, got ", err)
+	}
+
+	contents, err := ioutil.ReadFile(path.Join(tempDir, "fast", "data.unmounted", "hello.txt"))
+	if err != nil || string(contents) != "hello world" {
+		t.Error("The volume's file should be on the target backend, got ", string(contents), err)
+	}
+
+	// Mounts now go to the target backend.
+	response := service.Driver.Mount(volume.MountRequest{Name: "data", ID: "a"})
+	if response.Mountpoint != path.Join(tempDir, "fast", "data") {
+		t.Error("Expected data to be mounted from fast, got ", response)
+	}
+}
+
+func TestMigrate_encrypted(t *testing.T) {
+	t.Parallel()
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-migrate")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	provider := keys.NewDirectoryProvider(path.Join(tempDir, "keys"))
+	backends := map[string]drivers.StorageController{
+		"secure": encryptingOnDisk{drivers.NewOnDiskStorageController(path.Join(tempDir, "secure")), provider},
+		"vault":  encryptingOnDisk{drivers.NewOnDiskStorageController(path.Join(tempDir, "vault")), provider},
+		"other":  encryptingOnDisk{drivers.NewOnDiskStorageController(path.Join(tempDir, "other")), keys.NewDirectoryProvider(path.Join(tempDir, "other-keys"))},
+		"plain":  drivers.NewOnDiskStorageController(path.Join(tempDir, "plain")),
+	}
+
+	driver, err := drivers.NewMultiBackendRDMAVolumeDriver(backends, "secure", db.NewInMemoryVolumeDatabase())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response := driver.Create(volume.Request{Name: "secret", Options: map[string]string{drivers.EncryptedOption: "true"}}); response.Err != "" {
+		t.Fatal(response.Err)
+	}
+
+	service := NewService(driver)
+	writeVolumeFile(t, service, "secret", "key.txt", "hunter2")
+
+	if _, err = service.Migrate("secret", "plain", nil); err == nil {
+		t.Error("An encrypted volume should not be migrated to a backend that can not encrypt it")
+	}
+
+	if _, err = service.Migrate("secret", "other", nil); err == nil {
+		t.Error("An encrypted volume should not be migrated to a backend that does not have its key")
+	}
+
+	vol, err := service.InspectVolume("secret")
+	if err != nil || vol.Options[drivers.BackendOption] != "secure" {
+		t.Error("The volume should still be recorded on its source backend, got ", vol, err)
+	}
+
+	if _, err = os.Stat(path.Join(tempDir, "plain", "secret.unmounted")); !os.IsNotExist(err) {
+		t.Error("The volume should not be created on the refused backend, got ", err)
+	}
+
+	vol, err = service.Migrate("secret", "vault", nil)
+	if err != nil || vol.Options[drivers.BackendOption] != "vault" || vol.Options[drivers.KeyIDOption] != "secret" {
+		t.Fatal("Expected the volume to be migrated with its key, got ", vol, err)
+	}
+
+	if err = service.RemoveVolume("secret", false); err != nil {
+		t.Error("The migrated volume should be removed along with its key, got ", err)
+	}
+}
+
+func TestMigrate_refused(t *testing.T) {
+	t.Parallel()
+	service, tempDir := newMigrateService(t)
+	defer os.RemoveAll(tempDir)
+
+	var tests = []struct {
+		name    string
+		volume  string
+		backend string
+	}{
+		{"missing volume", "missing", "fast"},
+		{"no backend", "data", ""},
+		{"same backend", "data", "slow"},
+		{"unknown backend", "data", "tape"},
+		{"unmountable backend", "data", "broken"},
+		{"backend sharing the mount path", "data", "shared"},
+	}
+
+	for _, test := range tests {
+		if _, err := service.Migrate(test.volume, test.backend, nil); err == nil {
+			t.Error(test.name, " should fail")
+		}
+	}
+
+	if response := service.Driver.Mount(volume.MountRequest{Name: "data", ID: "a"}); response.Err != "" {
+		t.Fatal(response.Err)
+	}
+
+	if _, err := service.Migrate("data", "fast", nil); err == nil {
+		t.Error("A volume that is in use should not be migrated")
+	}
+
+	// The volume is still recorded on, and stored on, the source backend.
+	vol, err := service.InspectVolume("data")
+	if err != nil || vol.Options[drivers.BackendOption] != "slow" || vol.Mounts["a"] != 1 {
+		t.Error("The volume should be unchanged, got ", vol, err)
+	}
+
+	contents, err := ioutil.ReadFile(path.Join(tempDir, "slow", "data", "hello.txt"))
+	if err != nil || string(contents) != "hello world" {
+		t.Error("The volume's file should still be on the source backend, got ", string(contents), err)
+	}
+}
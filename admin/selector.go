@@ -0,0 +1,83 @@
+package admin
+
+import (
+	"errors"
+	"strings"
+)
+
+// Selector matches volumes by their labels. It is parsed from a comma separated list of requirements, all of which
+// must be met, written as they are for kubectl: team=ml requires a label's value, env!=prod forbids it, gpu requires
+// the label to be set, and !gpu requires it not to be.
+type Selector []Requirement
+
+// Requirement is one of the requirements of a Selector.
+type Requirement struct {
+	Key    string
+	Value  string
+	Negate bool
+
+	// Exists requires only that the label is, or with Negate is not, set.
+	Exists bool
+}
+
+// ParseSelector parses a selector such as team=ml,env!=prod. The empty selector matches every volume.
+func ParseSelector(selector string) (Selector, error) {
+	var parsed Selector
+	for _, term := range strings.Split(selector, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			if strings.TrimSpace(selector) != "" {
+				return nil, errors.New("invalid selector " + selector + ", it has an empty requirement")
+			}
+			continue
+		}
+
+		var requirement Requirement
+		switch {
+		case strings.Contains(term, "!="):
+			parts := strings.SplitN(term, "!=", 2)
+			requirement = Requirement{Key: parts[0], Value: parts[1], Negate: true}
+		case strings.Contains(term, "=="):
+			parts := strings.SplitN(term, "==", 2)
+			requirement = Requirement{Key: parts[0], Value: parts[1]}
+		case strings.Contains(term, "="):
+			parts := strings.SplitN(term, "=", 2)
+			requirement = Requirement{Key: parts[0], Value: parts[1]}
+		case strings.HasPrefix(term, "!"):
+			requirement = Requirement{Key: strings.TrimPrefix(term, "!"), Negate: true, Exists: true}
+		default:
+			requirement = Requirement{Key: term, Exists: true}
+		}
+
+		requirement.Key = strings.TrimSpace(requirement.Key)
+		requirement.Value = strings.TrimSpace(requirement.Value)
+		if requirement.Key == "" || strings.ContainsAny(requirement.Key, "!=") || strings.ContainsAny(requirement.Value, "!=") {
+			return nil, errors.New("invalid selector requirement " + term)
+		}
+
+		parsed = append(parsed, requirement)
+	}
+
+	return parsed, nil
+}
+
+// Matches reports whether labels meet every requirement of the selector.
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, requirement := range s {
+		if !requirement.Matches(labels) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Matches reports whether labels meet the requirement. A label that is not set does not equal any value.
+func (r Requirement) Matches(labels map[string]string) bool {
+	value, set := labels[r.Key]
+	if r.Exists {
+		return set != r.Negate
+	}
+
+	return (set && value == r.Value) != r.Negate
+}
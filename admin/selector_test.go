@@ -0,0 +1,73 @@
+package admin
+
+import "testing"
+
+func TestParseSelector(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		selector string
+		expected Selector
+	}{
+		{"", nil},
+		{"team=ml", Selector{{Key: "team", Value: "ml"}}},
+		{"team==ml", Selector{{Key: "team", Value: "ml"}}},
+		{" team = ml , env != prod ", Selector{{Key: "team", Value: "ml"}, {Key: "env", Value: "prod", Negate: true}}},
+		{"gpu,!spot", Selector{{Key: "gpu", Exists: true}, {Key: "spot", Negate: true, Exists: true}}},
+		{"team=", Selector{{Key: "team"}}},
+	}
+
+	for _, test := range tests {
+		selector, err := ParseSelector(test.selector)
+		if err != nil || len(selector) != len(test.expected) {
+			t.Error("Expected ", test.selector, " to parse as ", test.expected, ", got ", selector, err)
+			continue
+		}
+
+		for i := range selector {
+			if selector[i] != test.expected[i] {
+				t.Error("Expected ", test.selector, " to parse as ", test.expected, ", got ", selector)
+			}
+		}
+	}
+
+	for _, invalid := range []string{"=ml", "team=ml,", ",", "!", "team=m=l", "team!"} {
+		if selector, err := ParseSelector(invalid); err == nil {
+			t.Error("Parsing ", invalid, " should fail, got ", selector)
+		}
+	}
+}
+
+func TestSelector_Matches(t *testing.T) {
+	t.Parallel()
+	labels := map[string]string{"team": "ml", "env": "prod"}
+	tests := []struct {
+		selector string
+		matches  bool
+	}{
+		{"", true},
+		{"team=ml", true},
+		{"team=web", false},
+		{"team=ml,env!=prod", false},
+		{"team=ml,env!=dev", true},
+		{"owner!=alice", true},
+		{"owner=", false},
+		{"env", true},
+		{"!env", false},
+		{"!owner", true},
+	}
+
+	for _, test := range tests {
+		selector, err := ParseSelector(test.selector)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if selector.Matches(labels) != test.matches {
+			t.Error("Expected ", test.selector, " matching ", labels, " to be ", test.matches)
+		}
+	}
+
+	if selector, _ := ParseSelector("!env"); !selector.Matches(nil) {
+		t.Error("Volumes without labels should match a selector that forbids one")
+	}
+}
@@ -0,0 +1,421 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/mellanox-senior-design/docker-volume-rdma/audit"
+	"github.com/mellanox-senior-design/docker-volume-rdma/gc"
+	"github.com/mellanox-senior-design/docker-volume-rdma/migrate"
+)
+
+// The admin API serves:
+//
+//	GET    /volumes?selector=<selector>     list volumes, only those whose labels match the selector if one is given
+//	GET    /volumes/<name>                  inspect a volume
+//	DELETE /volumes/<name>?force=true       remove a volume
+//	GET    /volumes/<name>/backup?live=true&compress=true
+//	                                        stream a tar archive of a volume
+//	POST   /volumes/restore?name=<name>     create a volume from the tar archive in the body
+//	POST   /volumes/gc?grace=<duration>&delete=true&dry-run=true
+//	                                        quarantine, or delete, storage that no volume owns
+//	POST   /volumes/<name>/resize?size=<size>
+//	                                        grow a volume
+//	POST   /volumes/<name>/migrate?to=<backend>
+//	                                        move a volume to another backend, streaming its progress
+//	GET    /mounts?volume=<name>            list mount requests, of every volume if no volume is given
+//	DELETE /mounts?volume=<name>&id=<id>    release the mount requests of an ID
+//	GET    /tenants                         list what each tenant uses, and its quota
+//	GET    /metrics                         the usage of each tenant in the Prometheus text format
+//	GET    /audit?volume=<name>&operation=<operation>&since=<time>&until=<time>
+//	                                        list audited calls and volume operations, times are RFC 3339
+//
+// Every request must carry an admin token as "Authorization: Bearer <token>", whose role allows it: DELETE /volumes/<name>
+// and POST /volumes/gc need admin, the other requests that are not GETs, backups and the audit log need operator, and the rest need
+// read-only. Errors are returned as {"Err": ""}. Denied requests are audited, as are allowed requests that are not GETs.
+const (
+	volumesPath = "/volumes"
+	mountsPath  = "/mounts"
+	restorePath = volumesPath + "/restore"
+	gcPath      = volumesPath + "/gc"
+	tenantsPath = "/tenants"
+	metricsPath = "/metrics"
+	auditPath   = "/audit"
+)
+
+// The actions on a particular volume, the segment of the path that follows its name.
+const (
+	backupAction  = "backup"
+	resizeAction  = "resize"
+	migrateAction = "migrate"
+)
+
+// errorResponse is the body of every failed request.
+type errorResponse struct {
+	Err string
+}
+
+// migrateEvent is one line of the response to a migration. Every event but the last reports progress, the last holds
+// the migrated volume or an error.
+type migrateEvent struct {
+	Progress *migrate.Progress `json:",omitempty"`
+	Volume   *Volume           `json:",omitempty"`
+	Err      string            `json:",omitempty"`
+}
+
+// Handler serves the admin API for api to clients presenting one of Tokens, recording denied requests in Audit.
+type Handler struct {
+	API    API
+	Tokens []Token
+	Audit  audit.Log
+}
+
+// NewHandler creates a Handler. Without tokens every request is denied, as the API can remove any volume.
+func NewHandler(api API, tokens []Token, auditLog audit.Log) Handler {
+	return Handler{API: api, Tokens: tokens, Audit: auditLog}
+}
+
+// ServeHTTP authenticates, authorizes and answers a request to the admin API.
+func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token, authenticated := authenticate(h.Tokens, r)
+	if !authenticated {
+		h.deny(r, "", "a valid admin token is required")
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Err: "a valid admin token is required"})
+		return
+	}
+
+	if required := requiredRole(r); token.Role < required {
+		reason := r.Method + " " + r.URL.Path + " requires the " + required.String() + " role, the token has " + token.Role.String()
+		h.deny(r, token.Name, reason)
+		writeJSON(w, http.StatusForbidden, errorResponse{Err: reason})
+		return
+	}
+
+	glog.Info("Admin request by ", token.Name, ": ", r.Method, " ", r.URL.String())
+
+	if r.Method != http.MethodGet {
+		start := time.Now()
+		status := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		defer h.allow(r, token.Name, start, status)
+		w = status
+	}
+
+	query := r.URL.Query()
+	volumeName, action, isVolume := volumeAction(r.URL.Path)
+	switch {
+	case r.URL.Path == volumesPath && r.Method == http.MethodGet:
+		volumes, err := h.API.ListVolumes(query.Get("selector"))
+		respond(w, volumes, err)
+	case isVolume && action == backupAction && r.Method == http.MethodGet:
+		h.backup(w, volumeName, query)
+	case r.URL.Path == restorePath && r.Method == http.MethodPost:
+		vol, err := h.API.Restore(r.Body, query.Get("name"))
+		respond(w, vol, err)
+	case r.URL.Path == gcPath && r.Method == http.MethodPost:
+		options, err := parseGCOptions(query)
+		if err != nil {
+			respond(w, nil, err)
+			return
+		}
+
+		report, err := h.API.CollectGarbage(options)
+		respond(w, report, err)
+	case isVolume && action == resizeAction && r.Method == http.MethodPost:
+		vol, err := h.API.Resize(volumeName, query.Get("size"))
+		respond(w, vol, err)
+	case isVolume && action == migrateAction && r.Method == http.MethodPost:
+		h.migrate(w, volumeName, query.Get("to"))
+	case isVolume && action == "" && r.Method == http.MethodGet:
+		vol, err := h.API.InspectVolume(volumeName)
+		respond(w, vol, err)
+	case isVolume && action == "" && r.Method == http.MethodDelete:
+		force, _ := strconv.ParseBool(query.Get("force"))
+		err := h.API.RemoveVolume(volumeName, force)
+		respond(w, struct{}{}, err)
+	case r.URL.Path == mountsPath && r.Method == http.MethodGet:
+		mounts, err := h.API.ListMounts(query.Get("volume"))
+		respond(w, mounts, err)
+	case r.URL.Path == mountsPath && r.Method == http.MethodDelete:
+		err := h.API.ReleaseMount(query.Get("volume"), query.Get("id"))
+		respond(w, struct{}{}, err)
+	case r.URL.Path == tenantsPath && r.Method == http.MethodGet:
+		tenants, err := h.API.ListTenants()
+		respond(w, tenants, err)
+	case r.URL.Path == metricsPath && r.Method == http.MethodGet:
+		h.metrics(w)
+	case r.URL.Path == auditPath && r.Method == http.MethodGet:
+		filter, err := parseFilter(query)
+		if err != nil {
+			respond(w, nil, err)
+			return
+		}
+
+		entries, err := h.API.AuditEvents(filter)
+		respond(w, entries, err)
+	default:
+		writeJSON(w, http.StatusNotFound, errorResponse{Err: r.Method + " " + r.URL.Path + " is not part of the admin API"})
+	}
+}
+
+// backup streams an archive of a volume. Errors before the archive has started are returned as usual, later errors
+// abort the response so that the client does not mistake a truncated archive for a complete one.
+func (h Handler) backup(w http.ResponseWriter, volumeName string, query url.Values) {
+	live, _ := strconv.ParseBool(query.Get("live"))
+	compress, _ := strconv.ParseBool(query.Get("compress"))
+
+	archive := &startedWriter{ResponseWriter: w}
+	err := h.API.Backup(volumeName, BackupOptions{Live: live, Compress: compress}, archive)
+	if err == nil {
+		return
+	}
+
+	if !archive.started {
+		respond(w, nil, err)
+		return
+	}
+
+	glog.Error("Backup of ", volumeName, " failed after it started: ", err)
+	panic(http.ErrAbortHandler)
+}
+
+// migrate moves a volume to another backend, streaming a line of json for each progress report so that the client can
+// show it, and a last line with the migrated volume or the error that stopped the migration.
+func (h Handler) migrate(w http.ResponseWriter, volumeName string, backend string) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+	send := func(event migrateEvent) {
+		if err := encoder.Encode(event); err != nil {
+			glog.Error(err)
+		}
+
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+	}
+
+	vol, err := h.API.Migrate(volumeName, backend, func(progress migrate.Progress) {
+		send(migrateEvent{Progress: &progress})
+	})
+	if err != nil {
+		send(migrateEvent{Err: err.Error()})
+		return
+	}
+
+	send(migrateEvent{Volume: &vol})
+}
+
+// startedWriter records whether anything has been written, and so whether the response's status has been sent.
+type startedWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (s *startedWriter) Write(p []byte) (int, error) {
+	if !s.started {
+		s.started = true
+		s.Header().Set("Content-Type", "application/x-tar")
+	}
+
+	return s.ResponseWriter.Write(p)
+}
+
+// statusWriter records the status of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusWriter) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusWriter) Flush() {
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// allow records a request that was answered, by the holder of the token tokenName, in the audit log.
+func (h Handler) allow(r *http.Request, tokenName string, start time.Time, response *statusWriter) {
+	var err string
+	if response.status >= http.StatusBadRequest {
+		err = http.StatusText(response.status)
+	}
+
+	h.Audit.Record(audit.Entry{
+		Time:     start.UTC(),
+		API:      "admin",
+		Action:   r.Method + " " + r.URL.Path,
+		Volume:   requestVolume(r),
+		Subject:  tokenName,
+		Remote:   r.RemoteAddr,
+		Allowed:  true,
+		Err:      err,
+		Duration: time.Since(start)})
+}
+
+// deny records a request that was refused, and why, in the audit log.
+func (h Handler) deny(r *http.Request, tokenName string, reason string) {
+	h.Audit.Record(audit.Entry{
+		API:     "admin",
+		Action:  r.Method + " " + r.URL.Path,
+		Volume:  requestVolume(r),
+		Subject: tokenName,
+		Remote:  r.RemoteAddr,
+		Reason:  reason})
+}
+
+// requestVolume returns the volume that a request is about, if any.
+func requestVolume(r *http.Request) string {
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == restorePath:
+		return r.URL.Query().Get("name")
+	case r.Method == http.MethodPost && r.URL.Path == gcPath:
+		return ""
+	}
+
+	if volumeName, _, isVolume := volumeAction(r.URL.Path); isVolume {
+		return volumeName
+	}
+
+	return r.URL.Query().Get("volume")
+}
+
+// volumeAction splits the path of a request about a particular volume, /volumes/<name> or /volumes/<name>/<action>,
+// into the volume's name and the action, which is empty for the volume itself. Actions are only matched after a name,
+// so that volumes may be named after them. isVolume is false for other paths.
+func volumeAction(urlPath string) (volumeName string, action string, isVolume bool) {
+	if !strings.HasPrefix(urlPath, volumesPath+"/") {
+		return "", "", false
+	}
+
+	segments := strings.Split(strings.TrimPrefix(urlPath, volumesPath+"/"), "/")
+	if segments[0] == "" || len(segments) > 2 {
+		return "", "", false
+	}
+
+	if len(segments) == 2 {
+		return segments[0], segments[1], true
+	}
+
+	return segments[0], "", true
+}
+
+// parseFilter reads the filter of a request to the audit log.
+func parseFilter(query url.Values) (audit.Filter, error) {
+	filter := audit.Filter{Volume: query.Get("volume"), Action: query.Get("operation")}
+
+	var err error
+	if query.Get("since") != "" {
+		filter.Since, err = time.Parse(time.RFC3339, query.Get("since"))
+		if err != nil {
+			return audit.Filter{}, errors.New("invalid since: " + err.Error())
+		}
+	}
+
+	if query.Get("until") != "" {
+		filter.Until, err = time.Parse(time.RFC3339, query.Get("until"))
+		if err != nil {
+			return audit.Filter{}, errors.New("invalid until: " + err.Error())
+		}
+	}
+
+	return filter, nil
+}
+
+// parseGCOptions reads the options of a request to collect garbage. The grace period defaults to gc.DefaultGrace.
+func parseGCOptions(query url.Values) (gc.Options, error) {
+	options := gc.Options{Grace: gc.DefaultGrace}
+
+	var err error
+	if query.Get("grace") != "" {
+		options.Grace, err = time.ParseDuration(query.Get("grace"))
+		if err != nil {
+			return gc.Options{}, errors.New("invalid grace: " + err.Error())
+		}
+	}
+
+	options.Delete, _ = strconv.ParseBool(query.Get("delete"))
+	options.DryRun, _ = strconv.ParseBool(query.Get("dry-run"))
+	return options, nil
+}
+
+// gcQuery encodes the options of a collection as the query of a request.
+func gcQuery(options gc.Options) url.Values {
+	return url.Values{
+		"grace":   {options.Grace.String()},
+		"delete":  {strconv.FormatBool(options.Delete)},
+		"dry-run": {strconv.FormatBool(options.DryRun)}}
+}
+
+// filterQuery encodes a filter of the audit log as the query of a request.
+func filterQuery(filter audit.Filter) url.Values {
+	query := url.Values{}
+	if filter.Volume != "" {
+		query.Set("volume", filter.Volume)
+	}
+
+	if filter.Action != "" {
+		query.Set("operation", filter.Action)
+	}
+
+	if !filter.Since.IsZero() {
+		query.Set("since", filter.Since.Format(time.RFC3339Nano))
+	}
+
+	if !filter.Until.IsZero() {
+		query.Set("until", filter.Until.Format(time.RFC3339Nano))
+	}
+
+	return query
+}
+
+// respond writes value, or err if the request failed.
+func respond(w http.ResponseWriter, value interface{}, err error) {
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Err: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, value)
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(value)
+	if err != nil {
+		glog.Error(err)
+	}
+}
+
+// backupURLPath returns the path of a particular volume's archive in the admin API.
+func backupURLPath(volumeName string) string {
+	return volumePath(volumeName) + "/" + backupAction
+}
+
+// resizeURLPath returns the path that resizes a particular volume in the admin API.
+func resizeURLPath(volumeName string) string {
+	return volumePath(volumeName) + "/" + resizeAction
+}
+
+// migrateURLPath returns the path that migrates a particular volume in the admin API.
+func migrateURLPath(volumeName string) string {
+	return volumePath(volumeName) + "/" + migrateAction
+}
+
+// volumePath returns the path of a particular volume in the admin API.
+func volumePath(volumeName string) string {
+	return volumesPath + "/" + url.PathEscape(volumeName)
+}
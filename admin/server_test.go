@@ -0,0 +1,329 @@
+package admin
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/audit"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"github.com/mellanox-senior-design/docker-volume-rdma/gc"
+	"github.com/mellanox-senior-design/docker-volume-rdma/migrate"
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
+)
+
+// testTokens grants the token secret the admin role.
+var testTokens = []Token{{Name: "test", Role: Admin, Secret: "secret"}}
+
+func TestHandler_unauthorized(t *testing.T) {
+	t.Parallel()
+	service, tempDir := newTestService(t)
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(NewHandler(service, testTokens, audit.Log{}))
+	defer server.Close()
+
+	var tests = []struct {
+		name  string
+		token string
+	}{
+		{"no token", ""},
+		{"wrong token", "guess"},
+	}
+
+	for _, test := range tests {
+		client := NewClient(server.URL, test.token)
+		if err := client.RemoveVolume("idle", true); err == nil {
+			t.Error(test.name, ": the request should be rejected")
+		}
+	}
+
+	if _, err := service.InspectVolume("idle"); err != nil {
+		t.Error("The volume should not be removed by a rejected request: ", err)
+	}
+
+	// Without tokens the admin API rejects every request.
+	open := httptest.NewServer(NewHandler(service, nil, audit.Log{}))
+	defer open.Close()
+
+	response, err := http.Get(open.URL + volumesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusUnauthorized {
+		t.Error("Expected 401 without a configured token, got ", response.Status)
+	}
+}
+
+func TestHandler_roles(t *testing.T) {
+	t.Parallel()
+	service, tempDir := newTestService(t)
+	defer os.RemoveAll(tempDir)
+
+	auditPath := filepath.Join(tempDir, "audit.log")
+	auditLog, err := audit.NewLog(auditPath, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer auditLog.Close()
+
+	tokens := []Token{
+		{Name: "dashboard", Role: ReadOnly, Secret: "read"},
+		{Name: "oncall", Role: Operator, Secret: "operate"},
+		{Name: "ops", Role: Admin, Secret: "administer"},
+	}
+	service.Audit = auditLog
+	server := httptest.NewServer(NewHandler(service, tokens, auditLog))
+	defer server.Close()
+
+	readOnly := NewClient(server.URL, "read")
+	if _, err = readOnly.ListVolumes(""); err != nil {
+		t.Error("A read-only token should list volumes, got ", err)
+	}
+
+	if err = readOnly.ReleaseMount("busy", "b"); err == nil {
+		t.Error("A read-only token should not release mount requests")
+	}
+
+	if err = readOnly.Backup("idle", BackupOptions{}, ioutil.Discard); err == nil {
+		t.Error("A read-only token should not read the data of volumes")
+	}
+
+	operator := NewClient(server.URL, "operate")
+	if err = operator.ReleaseMount("busy", "b"); err != nil {
+		t.Error("An operator token should release mount requests, got ", err)
+	}
+
+	if err = operator.RemoveVolume("idle", false); err == nil {
+		t.Error("An operator token should not remove volumes")
+	}
+
+	if err = NewClient(server.URL, "administer").RemoveVolume("idle", false); err != nil {
+		t.Error("An admin token should remove volumes, got ", err)
+	}
+
+	entries, err := auditLog.Query(audit.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		subject string
+		action  string
+		outcome string
+	}{
+		{"dashboard", "DELETE /mounts", "denied"},
+		{"dashboard", "GET /volumes/idle/backup", "denied"},
+		{"oncall", "DELETE /mounts", "succeeded"},
+		{"oncall", "DELETE /volumes/idle", "denied"},
+		{"ops", "DELETE /volumes/idle", "succeeded"},
+	}
+
+	if len(entries) != len(expected) {
+		t.Fatal("Expected denied requests and requests that change volumes to be audited, got ", entries)
+	}
+
+	for i, entry := range entries {
+		if entry.Subject != expected[i].subject || entry.Action != expected[i].action || entry.Outcome() != expected[i].outcome || entry.API != "admin" || entry.Remote == "" {
+			t.Error("Expected ", expected[i], ", got ", entry)
+		}
+	}
+
+	if _, err = readOnly.AuditEvents(audit.Filter{}); err == nil {
+		t.Error("A read-only token should not read the audit log")
+	}
+
+	entries, err = operator.AuditEvents(audit.Filter{Volume: "idle", Since: entries[0].Time})
+	if err != nil || len(entries) != 3 {
+		t.Error("Expected the three requests about idle, got ", entries, err)
+	}
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+	service, tempDir := newTestService(t)
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(NewHandler(service, testTokens, audit.Log{}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/", "secret")
+
+	volumes, err := client.ListVolumes("")
+	if err != nil || len(volumes) != 2 {
+		t.Fatal("Expected 2 volumes, got ", volumes, err)
+	}
+
+	if err = service.Driver.VolumeDatabase.SetLabels("idle", map[string]string{"team": "ml"}); err != nil {
+		t.Fatal(err)
+	}
+
+	volumes, err = client.ListVolumes("team=ml")
+	if err != nil || len(volumes) != 1 || volumes[0].Name != "idle" || volumes[0].Labels["team"] != "ml" {
+		t.Error("Expected only the idle volume to be selected, got ", volumes, err)
+	}
+
+	vol, err := client.InspectVolume("busy")
+	if err != nil || vol.Mounts["a"] != 2 {
+		t.Error("Expected busy to be mounted twice by a, got ", vol, err)
+	}
+
+	if _, err = client.InspectVolume("missing"); err == nil || err.Error() != "volume does not exist" {
+		t.Error("The service's error should be returned, got ", err)
+	}
+
+	if err = client.ReleaseMount("busy", "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	mounts, err := client.ListMounts("busy")
+	if err != nil || len(mounts) != 1 || mounts[0].ID != "b" {
+		t.Error("Only b's mount should remain, got ", mounts, err)
+	}
+
+	if _, err = client.Resize("busy", "2G"); err == nil || !strings.Contains(err.Error(), "does not support resizing") {
+		t.Error("The service's error should be returned when resizing, got ", err)
+	}
+
+	tenants, err := client.ListTenants()
+	if err != nil || len(tenants) != 0 {
+		t.Error("There should be no tenants unless they are configured, got ", tenants, err)
+	}
+
+	// Storage that no volume owns is collected, once it has gone unchanged for the grace period.
+	if err = os.MkdirAll(filepath.Join(tempDir, "volumes", "orphan.unmounted"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := client.CollectGarbage(gc.Options{Grace: gc.DefaultGrace, Delete: true})
+	if err != nil || len(report.Orphans) != 1 || report.Orphans[0].Volume != "orphan" || report.Orphans[0].Action != gc.Kept {
+		t.Error("Expected the new orphan to be kept, got ", report, err)
+	}
+
+	report, err = client.CollectGarbage(gc.Options{Delete: true})
+	if err != nil || len(report.Orphans) != 1 || report.Orphans[0].Action != gc.Deleted {
+		t.Error("Expected the orphan to be deleted, got ", report, err)
+	}
+
+	if _, err = client.CollectGarbage(gc.Options{Grace: -time.Hour}); err == nil {
+		t.Error("The service's error should be returned when collecting garbage")
+	}
+
+	if err = client.RemoveVolume("busy", false); err == nil {
+		t.Error("A volume with mounts should not be removed without force")
+	}
+
+	if err = client.RemoveVolume("busy", true); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestClient_actionNames(t *testing.T) {
+	t.Parallel()
+	service, tempDir := newTestService(t)
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(NewHandler(service, testTokens, audit.Log{}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "secret")
+
+	// Volumes may be named after the actions on volumes.
+	for _, name := range []string{backupAction, resizeAction, migrateAction} {
+		if response := service.Driver.Create(volume.Request{Name: name}); response.Err != "" {
+			t.Fatal(response.Err)
+		}
+
+		if vol, err := client.InspectVolume(name); err != nil || vol.Name != name {
+			t.Error("Expected to inspect the volume ", name, ", got ", vol, err)
+		}
+
+		var archive bytes.Buffer
+		if err := client.Backup(name, BackupOptions{}, &archive); err != nil || archive.Len() == 0 {
+			t.Error("Expected an archive of the volume ", name, ", got ", err)
+		}
+
+		if err := client.RemoveVolume(name, false); err != nil {
+			t.Error("Expected to remove the volume ", name, ", got ", err)
+		}
+	}
+
+	request, err := http.NewRequest(http.MethodGet, server.URL+"/volumes/idle/backup/extra", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set("Authorization", "Bearer secret")
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusNotFound {
+		t.Error("Expected a path with too many segments not to be found, got ", response.Status)
+	}
+}
+
+func TestClient_backupRestore(t *testing.T) {
+	t.Parallel()
+	service, tempDir := newTestService(t)
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(NewHandler(service, testTokens, audit.Log{}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "secret")
+
+	var archive bytes.Buffer
+	if err := client.Backup("busy", BackupOptions{}, &archive); err == nil || archive.Len() != 0 {
+		t.Error("The error should be returned before the archive starts, got ", err, archive.Len())
+	}
+
+	err := client.Backup("idle", BackupOptions{Compress: true}, &archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vol, err := client.Restore(&archive, "restored")
+	if err != nil || vol.Name != "restored" {
+		t.Error("Expected the restored volume, got ", vol, err)
+	}
+
+	if _, err = client.Restore(strings.NewReader("not an archive"), ""); err == nil {
+		t.Error("An invalid archive should not be restored")
+	}
+}
+
+func TestClient_migrate(t *testing.T) {
+	t.Parallel()
+	service, tempDir := newMigrateService(t)
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(NewHandler(service, testTokens, audit.Log{}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "secret")
+
+	if _, err := client.Migrate("data", "tape", nil); err == nil || err.Error() != "unknown backend: tape" {
+		t.Error("The service's error should be returned, got ", err)
+	}
+
+	var reports []migrate.Progress
+	vol, err := client.Migrate("data", "fast", func(progress migrate.Progress) { reports = append(reports, progress) })
+	if err != nil || vol.Options[drivers.BackendOption] != "fast" {
+		t.Fatal("Expected data to be migrated to fast, got ", vol, err)
+	}
+
+	if len(reports) == 0 || reports[len(reports)-1].Files != 1 {
+		t.Error("Expected the progress to be streamed, got ", reports)
+	}
+}
@@ -0,0 +1,135 @@
+package admin
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Role is what the holder of a token may do with the admin API. Each role may do everything the roles before it may.
+type Role int
+
+const (
+	// ReadOnly may list and inspect volumes, mounts and tenants.
+	ReadOnly Role = iota + 1
+
+	// Operator may also back up, restore, resize and migrate volumes, release mount requests and read the audit log.
+	Operator
+
+	// Admin may also remove volumes.
+	Admin
+)
+
+var roleNames = map[Role]string{ReadOnly: "read-only", Operator: "operator", Admin: "admin"}
+
+// ParseRole parses the name of a role.
+func ParseRole(name string) (Role, error) {
+	for role, roleName := range roleNames {
+		if name == roleName {
+			return role, nil
+		}
+	}
+
+	return 0, errors.New("unknown role " + name + ", please choose read-only, operator or admin")
+}
+
+func (r Role) String() string {
+	if name, exists := roleNames[r]; exists {
+		return name
+	}
+
+	return "role " + strconv.Itoa(int(r))
+}
+
+// Token authenticates requests to the admin API, granting them Role. Name identifies the token's holder in the audit
+// log, so that the secret itself is never logged.
+type Token struct {
+	Name   string
+	Role   Role
+	Secret string
+}
+
+// ParseTokens reads tokens, one per line as "<role> <name> <secret>". Blank lines and lines starting with # are
+// skipped.
+func ParseTokens(r io.Reader) ([]Token, error) {
+	tokens := []Token{}
+	names := map[string]bool{}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 3 {
+			return nil, errors.New("line " + strconv.Itoa(line) + " should be <role> <name> <secret>")
+		}
+
+		role, err := ParseRole(fields[0])
+		if err != nil {
+			return nil, errors.New("line " + strconv.Itoa(line) + ": " + err.Error())
+		}
+
+		if names[fields[1]] {
+			return nil, errors.New("line " + strconv.Itoa(line) + ": the token name " + fields[1] + " is used twice")
+		}
+		names[fields[1]] = true
+
+		tokens = append(tokens, Token{Name: fields[1], Role: role, Secret: fields[2]})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(tokens) == 0 {
+		return nil, errors.New("no admin tokens are defined")
+	}
+
+	return tokens, nil
+}
+
+// authenticate returns the token a request carries, or false if it carries none of tokens. Every token is compared,
+// in constant time, so that the time taken does not reveal which one is closest.
+func authenticate(tokens []Token, r *http.Request) (Token, bool) {
+	presented := []byte(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+
+	var found Token
+	matched := false
+	for _, token := range tokens {
+		if token.Secret != "" && subtle.ConstantTimeCompare(presented, []byte(token.Secret)) == 1 {
+			found = token
+			matched = true
+		}
+	}
+
+	return found, matched
+}
+
+// requiredRole returns the role needed to make a request. Anything but reading volumes, mounts and tenants needs at least
+// an operator.
+func requiredRole(r *http.Request) Role {
+	_, action, isVolume := volumeAction(r.URL.Path)
+	switch {
+	case r.Method == http.MethodDelete && isVolume && action == "":
+		return Admin
+	case r.Method == http.MethodPost && r.URL.Path == gcPath:
+		// Collecting garbage deletes storage without a volume to name in the audit log.
+		return Admin
+	case r.Method == http.MethodGet && isVolume && action == backupAction:
+		// Archives hold the data of volumes, not only their metadata.
+		return Operator
+	case r.Method == http.MethodGet && r.URL.Path == auditPath:
+		return Operator
+	case r.Method == http.MethodGet:
+		return ReadOnly
+	}
+
+	return Operator
+}
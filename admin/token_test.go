@@ -0,0 +1,101 @@
+package admin
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseTokens(t *testing.T) {
+	t.Parallel()
+	tokens, err := ParseTokens(strings.NewReader("# dashboards\nread-only grafana s3cret\n\noperator  oncall\tt0ken\nadmin ops k3y\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Token{
+		{Name: "grafana", Role: ReadOnly, Secret: "s3cret"},
+		{Name: "oncall", Role: Operator, Secret: "t0ken"},
+		{Name: "ops", Role: Admin, Secret: "k3y"},
+	}
+	if len(tokens) != len(expected) {
+		t.Fatal("Expected ", expected, ", got ", tokens)
+	}
+
+	for i := range expected {
+		if tokens[i] != expected[i] {
+			t.Error("Expected ", expected[i], ", got ", tokens[i])
+		}
+	}
+
+	invalid := []string{
+		"",
+		"# no tokens\n",
+		"admin ops\n",
+		"root ops k3y\n",
+		"admin ops k3y extra\n",
+		"admin ops k3y\nread-only ops s3cret\n",
+	}
+
+	for _, contents := range invalid {
+		if _, err = ParseTokens(strings.NewReader(contents)); err == nil {
+			t.Error("Parsing ", contents, " should fail")
+		}
+	}
+}
+
+func TestRequiredRole(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		method   string
+		target   string
+		expected Role
+	}{
+		{"GET", "/volumes?selector=team", ReadOnly},
+		{"GET", "/volumes/vol1", ReadOnly},
+		{"GET", "/mounts", ReadOnly},
+		{"GET", "/tenants", ReadOnly},
+		{"GET", "/metrics", ReadOnly},
+		{"GET", "/volumes/vol1/backup", Operator},
+		{"GET", "/volumes/backup", ReadOnly},
+		{"POST", "/volumes/restore?name=vol1", Operator},
+		{"POST", "/volumes/vol1/resize?size=2G", Operator},
+		{"POST", "/volumes/vol1/migrate?to=rbd", Operator},
+		{"DELETE", "/mounts?volume=vol1&id=a", Operator},
+		{"GET", "/audit?volume=vol1", Operator},
+		{"DELETE", "/volumes/vol1", Admin},
+		{"POST", "/volumes/gc?dry-run=true", Admin},
+		{"GET", "/volumes/gc", ReadOnly},
+	}
+
+	for _, test := range tests {
+		if role := requiredRole(httptest.NewRequest(test.method, test.target, nil)); role != test.expected {
+			t.Error("Expected ", test.method, " ", test.target, " to require ", test.expected, ", got ", role)
+		}
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	t.Parallel()
+	tokens := []Token{{Name: "grafana", Role: ReadOnly, Secret: "s3cret"}, {Name: "empty", Role: Admin}}
+
+	tests := []struct {
+		header   string
+		expected string
+	}{
+		{"Bearer s3cret", "grafana"},
+		{"Bearer wrong", ""},
+		{"Bearer ", ""},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest("GET", "/volumes", nil)
+		request.Header.Set("Authorization", test.header)
+
+		token, ok := authenticate(tokens, request)
+		if ok != (test.expected != "") || token.Name != test.expected {
+			t.Error("Expected ", test.header, " to authenticate as ", test.expected, ", got ", token, ok)
+		}
+	}
+}
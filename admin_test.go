@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/admin"
+	"github.com/mellanox-senior-design/docker-volume-rdma/audit"
+	"github.com/mellanox-senior-design/docker-volume-rdma/db"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"github.com/mellanox-senior-design/docker-volume-rdma/gc"
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
+)
+
+func TestAdminCommand(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-admin")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	driver := drivers.NewRDMAVolumeDriver(drivers.NewOnDiskStorageController(tempDir), db.NewInMemoryVolumeDatabase())
+	driver.Create(volume.Request{Name: "data"})
+	driver.Mount(volume.MountRequest{Name: "data", ID: "container1"})
+	api := admin.NewService(driver)
+
+	var out bytes.Buffer
+	err = adminCommand(api, []string{"volumes", "ls"}, nil, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "NAME") || !strings.HasPrefix(lines[1], "data ") {
+		t.Error("Expected a table of one volume, got ", out.String())
+	}
+
+	out.Reset()
+	err = adminCommand(api, []string{"volumes", "ls", "-l", "team=ml"}, nil, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lines = strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 1 {
+		t.Error("Expected no volumes to match, got ", out.String())
+	}
+
+	out.Reset()
+	err = adminCommand(api, []string{"mounts", "ls", "-json", "data"}, nil, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mounts []admin.Mount
+	err = json.Unmarshal(out.Bytes(), &mounts)
+	if err != nil || len(mounts) != 1 || mounts[0].ID != "container1" || mounts[0].Mountpoint != path.Join(tempDir, "data") {
+		t.Error("Expected container1's mount as json, got ", out.String(), err)
+	}
+
+	out.Reset()
+	err = adminCommand(api, []string{"tenants", "ls"}, nil, &out)
+	if err != nil || strings.TrimSpace(out.String()) != "TENANT   VOLUMES   VOLUME QUOTA   BYTES   BYTE QUOTA" {
+		t.Error("Expected an empty table of tenants, got ", out.String(), err)
+	}
+
+	var tests = []struct {
+		name string
+		args []string
+	}{
+		{"no verb", []string{"volumes"}},
+		{"unknown verb", []string{"volumes", "create", "x"}},
+		{"missing volume", []string{"volumes", "inspect"}},
+		{"unknown flag", []string{"volumes", "ls", "-wide"}},
+		{"invalid selector", []string{"volumes", "ls", "-l", "team!"}},
+		{"missing id", []string{"mounts", "release", "data"}},
+		{"tenant operand", []string{"tenants", "ls", "ml"}},
+		{"audit without a log", []string{"audit", "ls"}},
+		{"in use", []string{"volumes", "rm", "data"}},
+		{"resize without size", []string{"volumes", "resize", "data"}},
+		{"resize unsupported", []string{"volumes", "resize", "data", "2G"}},
+		{"migrate in use", []string{"volumes", "migrate", "-to", "fast", "data"}},
+		{"migrate without volume", []string{"volumes", "migrate", "-to", "fast"}},
+	}
+
+	for _, test := range tests {
+		if err := adminCommand(api, test.args, nil, ioutil.Discard); err == nil {
+			t.Error(test.name, " should fail")
+		}
+	}
+
+	// Back the volume up to a file, and restore it under another name.
+	archive := path.Join(tempDir, "data.tar.zst")
+	err = adminCommand(api, []string{"volumes", "backup", "-live", "-zstd", "-o", archive, "data"}, nil, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out.Reset()
+	err = adminCommand(api, []string{"volumes", "restore", "-i", archive, "-name", "copy"}, nil, &out)
+	if err != nil || strings.TrimSpace(out.String()) != "copy" {
+		t.Error("Expected the volume to be restored as copy, got ", out.String(), err)
+	}
+
+	if err = adminCommand(api, []string{"volumes", "backup", "-o", path.Join(tempDir, "in-use.tar"), "data"}, nil, ioutil.Discard); err == nil {
+		t.Error("A volume in use should not be backed up without -live")
+	}
+
+	if _, err = os.Stat(path.Join(tempDir, "in-use.tar")); !os.IsNotExist(err) {
+		t.Error("The archive of a failed backup should be removed, got ", err)
+	}
+
+	err = adminCommand(api, []string{"volumes", "rm", "-force", "data"}, nil, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = api.InspectVolume("data"); err == nil {
+		t.Error("The volume should be removed with -force")
+	}
+}
+
+func TestAuditCommand(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-admin")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	auditLog, err := audit.NewLog(path.Join(tempDir, "audit.log"), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer auditLog.Close()
+
+	driver := drivers.NewRDMAVolumeDriver(drivers.NewOnDiskStorageController(path.Join(tempDir, "volumes")), db.NewInMemoryVolumeDatabase())
+	driver.Audit = auditLog
+	driver.Create(volume.Request{Name: "data"})
+	driver.Mount(volume.MountRequest{Name: "data", ID: "container1"})
+	driver.Mount(volume.MountRequest{Name: "missing", ID: "container2"})
+
+	api := admin.NewService(driver)
+	api.Audit = auditLog
+
+	var out bytes.Buffer
+	err = adminCommand(api, []string{"audit", "ls", "-operation", "mount", "-since", "1h"}, nil, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "TIME") || !strings.Contains(lines[1], "container1") || !strings.Contains(lines[2], "failed: ") {
+		t.Error("Expected a table of both mounts, got ", out.String())
+	}
+
+	out.Reset()
+	err = adminCommand(api, []string{"audit", "ls", "-json", "-volume", "data", "-until", "1h"}, nil, &out)
+	if err != nil || strings.TrimSpace(out.String()) != "[]" {
+		t.Error("Expected no entries from over an hour ago, got ", out.String(), err)
+	}
+
+	if err = adminCommand(api, []string{"audit", "ls", "-since", "yesterday"}, nil, ioutil.Discard); err == nil {
+		t.Error("An invalid time should fail")
+	}
+}
+
+func TestGCCommand(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-admin")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	driver := drivers.NewRDMAVolumeDriver(drivers.NewOnDiskStorageController(tempDir), db.NewInMemoryVolumeDatabase())
+	driver.Create(volume.Request{Name: "data"})
+	driver.Mount(volume.MountRequest{Name: "data", ID: "container1"})
+	if err = os.MkdirAll(path.Join(tempDir, "orphan"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	api := admin.NewService(driver)
+
+	var out bytes.Buffer
+	err = adminCommand(api, []string{"volumes", "gc", "-grace", "0", "-dry-run"}, nil, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "BACKEND") || !strings.Contains(lines[1], "would be quarantined") || !strings.HasPrefix(lines[2], "would reclaim") {
+		t.Error("Expected a table of the orphan, got ", out.String())
+	}
+
+	out.Reset()
+	err = adminCommand(api, []string{"volumes", "gc", "-json", "-grace", "0", "-delete"}, nil, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var report gc.Report
+	err = json.Unmarshal(out.Bytes(), &report)
+	if err != nil || len(report.Orphans) != 1 || report.Orphans[0].Volume != "orphan" || report.Orphans[0].Action != gc.Deleted {
+		t.Error("Expected the orphan to be deleted, got ", out.String(), err)
+	}
+
+	if _, err = os.Stat(path.Join(tempDir, "orphan")); !os.IsNotExist(err) {
+		t.Error("The orphan should be deleted, got ", err)
+	}
+
+	if _, err = os.Stat(path.Join(tempDir, "data")); err != nil {
+		t.Error("The volume should be kept, got ", err)
+	}
+
+	if err = adminCommand(api, []string{"volumes", "gc", "-grace", "-1h"}, nil, ioutil.Discard); err == nil {
+		t.Error("A negative grace period should fail")
+	}
+}
+
+func TestAuditFilter(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	filter, err := auditFilter("data", "remove", "24h", "2026-03-01T06:00:00Z", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := audit.Filter{Volume: "data", Action: "remove", Since: now.Add(-24 * time.Hour), Until: time.Date(2026, 3, 1, 6, 0, 0, 0, time.UTC)}
+	if filter != expected {
+		t.Error("Expected ", expected, ", got ", filter)
+	}
+
+	if filter, err = auditFilter("", "", "", "", now); err != nil || filter != (audit.Filter{}) {
+		t.Error("Expected an empty filter, got ", filter, err)
+	}
+}
+
+func TestCheckAdminAddress(t *testing.T) {
+	t.Parallel()
+	var tests = []struct {
+		address string
+		secure  bool
+		allowed bool
+	}{
+		{"127.0.0.1:8081", false, true},
+		{"[::1]:8081", false, true},
+		{"localhost:8081", false, true},
+		{":8081", false, false},
+		{"0.0.0.0:8081", false, false},
+		{"10.0.0.5:8081", false, false},
+		{"storage.example.com:8081", false, false},
+		{":8081", true, true},
+		{"10.0.0.5:8081", true, true},
+		{"127.0.0.1", false, false},
+	}
+
+	for _, test := range tests {
+		err := checkAdminAddress(test.address, test.secure)
+		if (err == nil) != test.allowed {
+			t.Error("Expected ", test.address, " with tls ", test.secure, " to be allowed ", test.allowed, ", got ", err)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+// Package audit records the calls made to docker-volume-rdma's APIs, and the volume operations they lead to, so that
+// operators can find out who did, or tried to do, what and when.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// Entry is one audited call or operation.
+type Entry struct {
+	Time time.Time
+
+	// API is the API that was called, e.g. plugin or admin.
+	API string
+
+	// Action is what the caller tried to do, e.g. GET /volumes, or the operation on a volume, e.g. remove.
+	Action string
+
+	// Volume is the volume that was operated on, if any.
+	Volume string `json:",omitempty"`
+
+	// Subject identifies the caller, by the name of its token, the subject of its certificate or the ID it mounted a
+	// volume with, if it is known.
+	Subject string `json:",omitempty"`
+	Remote  string `json:",omitempty"`
+
+	// Host is the host the operation was handled on.
+	Host string `json:",omitempty"`
+
+	// Allowed is false for calls that were denied, Reason says why. Err is set for allowed calls that failed.
+	Allowed bool
+	Reason  string `json:",omitempty"`
+	Err     string `json:",omitempty"`
+
+	// Duration is how long the operation took, in nanoseconds when encoded.
+	Duration time.Duration `json:",omitempty"`
+}
+
+// Outcome summarises an entry as denied, failed or succeeded.
+func (e Entry) Outcome() string {
+	switch {
+	case !e.Allowed:
+		return "denied"
+	case e.Err != "":
+		return "failed"
+	}
+
+	return "succeeded"
+}
+
+// Filter selects entries. Fields that are not set select every entry.
+type Filter struct {
+	Volume string
+	Action string
+	Since  time.Time
+	Until  time.Time
+}
+
+// Matches reports whether an entry is selected by the filter. Since is inclusive and Until is exclusive.
+func (f Filter) Matches(entry Entry) bool {
+	return (f.Volume == "" || entry.Volume == f.Volume) &&
+		(f.Action == "" || entry.Action == f.Action) &&
+		(f.Since.IsZero() || !entry.Time.Before(f.Since)) &&
+		(f.Until.IsZero() || entry.Time.Before(f.Until))
+}
+
+// Log appends entries to a file, one json object per line, as well as logging them. Entries are only logged if there
+// is no file. Once the file grows past MaxBytes it is rotated, to Path.1, Path.2 and so on up to Backups files, the
+// oldest being removed.
+type Log struct {
+	Path     string
+	MaxBytes int64
+	Backups  int
+
+	state *logState
+}
+
+// logState is the file a Log appends to, shared by every copy of the Log.
+type logState struct {
+	lock sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewLog creates a Log that appends to the file at path, creating it if needed, or that only logs entries if path is
+// empty. The file is rotated after maxBytes, keeping backups old files, or never if maxBytes is 0.
+func NewLog(path string, maxBytes int64, backups int) (Log, error) {
+	if maxBytes < 0 || backups < 0 {
+		return Log{}, errors.New("the audit log can not be limited to " + strconv.FormatInt(maxBytes, 10) + " bytes and " + strconv.Itoa(backups) + " old files")
+	}
+
+	log := Log{Path: path, MaxBytes: maxBytes, Backups: backups}
+	if path == "" {
+		return log, nil
+	}
+
+	file, size, err := openLog(path)
+	if err != nil {
+		return Log{}, err
+	}
+	log.state = &logState{file: file, size: size}
+
+	return log, nil
+}
+
+// Record adds an entry to the log, setting its time if it has none. Entries that can not be written are logged as
+// errors, so that a full disk does not stop the call being audited.
+func (l Log) Record(entry Entry) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+
+	switch entry.Outcome() {
+	case "denied":
+		glog.Warning("Audit: ", entry.API, " ", entry.Action, " by ", describeCaller(entry), " denied: ", entry.Reason)
+	case "failed":
+		glog.Info("Audit: ", entry.API, " ", entry.Action, " ", entry.Volume, " by ", describeCaller(entry), " failed: ", entry.Err)
+	default:
+		glog.Info("Audit: ", entry.API, " ", entry.Action, " ", entry.Volume, " by ", describeCaller(entry), " succeeded")
+	}
+
+	if l.state == nil {
+		return
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		glog.Error("Unable to encode audit entry: ", err)
+		return
+	}
+	line = append(line, '\n')
+
+	l.state.lock.Lock()
+	defer l.state.lock.Unlock()
+
+	if l.MaxBytes > 0 && l.state.size > 0 && l.state.size+int64(len(line)) > l.MaxBytes {
+		if err = l.rotate(); err != nil {
+			glog.Error("Unable to rotate the audit log ", l.Path, ": ", err)
+		}
+	}
+
+	written, err := l.state.file.Write(line)
+	l.state.size += int64(written)
+	if err != nil {
+		glog.Error("Unable to write audit entry to ", l.Path, ": ", err)
+	}
+}
+
+// Query returns the entries selected by filter, oldest first, from the log's file and the files it was rotated to.
+func (l Log) Query(filter Filter) ([]Entry, error) {
+	if l.state == nil {
+		return nil, errors.New("the audit log is not kept in a file")
+	}
+
+	l.state.lock.Lock()
+	defer l.state.lock.Unlock()
+
+	entries := []Entry{}
+	for backup := l.Backups; backup >= 0; backup-- {
+		file, err := os.Open(l.backupPath(backup))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		entries, err = readEntries(file, filter, entries)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
+
+// Close closes the log's file, if it has one.
+func (l Log) Close() error {
+	if l.state == nil {
+		return nil
+	}
+
+	l.state.lock.Lock()
+	defer l.state.lock.Unlock()
+
+	return l.state.file.Close()
+}
+
+// rotate moves each file of the log to the next backup, dropping the last, and starts a new file. The state must be
+// locked.
+func (l Log) rotate() error {
+	err := l.state.file.Close()
+	if err != nil {
+		return err
+	}
+
+	if l.Backups == 0 {
+		err = os.Remove(l.Path)
+	}
+
+	for backup := l.Backups; backup > 0 && err == nil; backup-- {
+		err = os.Rename(l.backupPath(backup-1), l.backupPath(backup))
+		if os.IsNotExist(err) {
+			err = nil
+		}
+	}
+
+	// A new file is opened even if the old one could not be moved, so that entries are not lost.
+	file, size, openErr := openLog(l.Path)
+	if openErr != nil {
+		return openErr
+	}
+	l.state.file = file
+	l.state.size = size
+
+	return err
+}
+
+// backupPath returns the path of the log's nth backup, or of its file if n is 0.
+func (l Log) backupPath(n int) string {
+	if n == 0 {
+		return l.Path
+	}
+
+	return l.Path + "." + strconv.Itoa(n)
+}
+
+// openLog opens a log file for appending, returning its size.
+func openLog(path string) (*os.File, int64, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+
+	return file, info.Size(), nil
+}
+
+// readEntries appends the entries of a log file selected by filter to entries.
+func readEntries(file *os.File, filter Filter, entries []Entry) ([]Entry, error) {
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			glog.Warning("Skipping unreadable audit entry in ", file.Name(), ": ", err)
+			continue
+		}
+
+		if filter.Matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, scanner.Err()
+}
+
+// describeCaller names the caller of an entry for the log.
+func describeCaller(entry Entry) string {
+	switch {
+	case entry.Subject != "" && entry.Remote != "":
+		return entry.Subject + " (" + entry.Remote + ")"
+	case entry.Subject != "":
+		return entry.Subject
+	case entry.Remote != "":
+		return entry.Remote
+	}
+
+	return "unknown caller"
+}
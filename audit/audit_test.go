@@ -0,0 +1,158 @@
+package audit
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLog(t *testing.T) {
+	t.Parallel()
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-audit")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, "audit.log")
+	for i := 0; i < 2; i++ {
+		log, err := NewLog(path, 0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		log.Record(Entry{API: "admin", Action: "DELETE /volumes/vol1", Subject: "ci", Remote: "10.0.0.1:4000", Reason: "forbidden"})
+		if err = log.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(contents)), "\n")
+	if len(lines) != 2 {
+		t.Fatal("Entries should be appended to the log, got ", lines)
+	}
+
+	var entry Entry
+	if err = json.Unmarshal([]byte(lines[1]), &entry); err != nil {
+		t.Fatal(err)
+	}
+
+	if entry.Time.IsZero() || entry.Subject != "ci" || entry.Allowed || entry.Reason != "forbidden" {
+		t.Error("Unexpected entry: ", entry)
+	}
+}
+
+func TestLog_withoutFile(t *testing.T) {
+	t.Parallel()
+	log, err := NewLog("", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log.Record(Entry{API: "plugin", Action: "tls handshake", Reason: "bad certificate"})
+	Log{}.Record(Entry{API: "admin", Action: "GET /volumes", Allowed: true})
+
+	if err = log.Close(); err != nil {
+		t.Error(err)
+	}
+
+	if _, err = NewLog(filepath.Join(os.DevNull, "audit.log"), 0, 0); err == nil {
+		t.Error("Opening a log that can not be created should fail")
+	}
+
+	if _, err = NewLog("", -1, 0); err == nil {
+		t.Error("A negative size limit should fail")
+	}
+
+	if _, err = log.Query(Filter{}); err == nil {
+		t.Error("Querying a log that is not kept in a file should fail")
+	}
+}
+
+func TestLog_rotateAndQuery(t *testing.T) {
+	t.Parallel()
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-audit")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, "audit.log")
+	log, err := NewLog(path, 600, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	actions := []string{"create", "mount", "unmount", "remove"}
+	for i := 0; i < 20; i++ {
+		log.Record(Entry{
+			Time:    start.Add(time.Duration(i) * time.Hour),
+			API:     "plugin",
+			Action:  actions[i%len(actions)],
+			Volume:  "vol" + strconv.Itoa(i%2),
+			Allowed: true})
+	}
+
+	if _, err = os.Stat(path + ".2"); err != nil {
+		t.Error("The log should have been rotated twice, got ", err)
+	}
+
+	if _, err = os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("Only two old files should be kept, got ", err)
+	}
+
+	entries, err := log.Query(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) == 0 || len(entries) >= 20 || !entries[len(entries)-1].Time.Equal(start.Add(19*time.Hour)) {
+		t.Fatal("Expected the newest entries that were kept, got ", entries)
+	}
+
+	for i := 1; i < len(entries); i++ {
+		if !entries[i-1].Time.Before(entries[i].Time) {
+			t.Fatal("Entries should be returned oldest first, got ", entries)
+		}
+	}
+
+	filter := Filter{Volume: "vol1", Action: "mount", Since: start.Add(13 * time.Hour), Until: start.Add(17 * time.Hour)}
+	entries, err = log.Query(filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 || !entries[0].Time.Equal(start.Add(13*time.Hour)) {
+		t.Error("Expected the mount of vol1 at 13:00, got ", entries)
+	}
+}
+
+func TestEntry_Outcome(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		entry    Entry
+		expected string
+	}{
+		{Entry{Reason: "forbidden"}, "denied"},
+		{Entry{Allowed: true, Err: "no such volume"}, "failed"},
+		{Entry{Allowed: true}, "succeeded"},
+	}
+
+	for _, test := range tests {
+		if outcome := test.entry.Outcome(); outcome != test.expected {
+			t.Error("Expected ", test.entry, " to have ", test.expected, ", got ", outcome)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+// Package backup writes the contents of a volume to a tar archive, optionally compressed with zstd, together with a
+// manifest of the volume's metadata, and restores volumes from such archives.
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/klauspost/compress/zstd"
+)
+
+// ManifestVersion is the version of the archive format written by Write.
+const ManifestVersion = 1
+
+// The manifest is the first entry of an archive, followed by the volume's files under the data directory.
+const (
+	manifestName = "manifest.json"
+	dataDir      = "data"
+)
+
+// zstdMagic starts every zstd frame, so that compressed archives are recognised when they are read.
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// Manifest describes the volume an archive was taken from.
+type Manifest struct {
+	Version int
+	Volume  string
+	Options map[string]string `json:",omitempty"`
+	Labels  map[string]string `json:",omitempty"`
+	Created time.Time
+
+	// Live is set if the volume was in use, and its files may have changed, while the archive was written.
+	Live bool `json:",omitempty"`
+}
+
+// Write archives the files in dir, after the manifest, to w. The archive is compressed with zstd if compress is set.
+func Write(w io.Writer, manifest Manifest, dir string, compress bool) error {
+	if compress {
+		encoder, err := zstd.NewWriter(w)
+		if err != nil {
+			return err
+		}
+
+		err = Write(encoder, manifest, dir, false)
+		if closeErr := encoder.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}
+
+	archive := tar.NewWriter(w)
+
+	manifest.Version = ManifestVersion
+	contents, err := json.MarshalIndent(manifest, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	err = archive.WriteHeader(&tar.Header{Name: manifestName, Mode: 0644, Size: int64(len(contents)), ModTime: manifest.Created, Typeflag: tar.TypeReg})
+	if err != nil {
+		return err
+	}
+
+	if _, err = archive.Write(contents); err != nil {
+		return err
+	}
+
+	err = filepath.Walk(dir, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relative, err := filepath.Rel(dir, name)
+		if err != nil {
+			return err
+		}
+
+		return writeEntry(archive, name, path.Join(dataDir, filepath.ToSlash(relative)), info)
+	})
+	if err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+// writeEntry adds a file, directory or symbolic link to the archive as entryName. Other files, such as sockets, are
+// skipped as they can not be restored meaningfully.
+func writeEntry(archive *tar.Writer, name string, entryName string, info os.FileInfo) error {
+	var link string
+	switch mode := info.Mode(); {
+	case mode.IsRegular(), mode.IsDir():
+	case mode&os.ModeSymlink != 0:
+		var err error
+		link, err = os.Readlink(name)
+		if err != nil {
+			return err
+		}
+	default:
+		glog.Warning("Skipping ", name, " as it is not a file, directory or symbolic link")
+		return nil
+	}
+
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+
+	header.Name = entryName
+	if info.IsDir() {
+		header.Name += "/"
+	}
+
+	err = archive.WriteHeader(header)
+	if err != nil || !info.Mode().IsRegular() {
+		return err
+	}
+
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(archive, file)
+	return err
+}
+
+// Read restores an archive from r, compressed or not. The manifest is passed to restore, which prepares the volume and
+// returns the directory its files are extracted to.
+func Read(r io.Reader, restore func(Manifest) (string, error)) (Manifest, error) {
+	var manifest Manifest
+
+	buffered := bufio.NewReader(r)
+	magic, _ := buffered.Peek(len(zstdMagic))
+	if bytes.Equal(magic, zstdMagic) {
+		decoder, err := zstd.NewReader(buffered)
+		if err != nil {
+			return manifest, err
+		}
+		defer decoder.Close()
+
+		return Read(decoder, restore)
+	}
+
+	archive := tar.NewReader(buffered)
+	header, err := archive.Next()
+	if err != nil {
+		return manifest, errors.New("unable to read the archive: " + err.Error())
+	}
+
+	if header.Name != manifestName {
+		return manifest, errors.New("not a volume archive, the first entry should be " + manifestName + " but is " + header.Name)
+	}
+
+	err = json.NewDecoder(archive).Decode(&manifest)
+	if err != nil {
+		return manifest, errors.New("unable to parse " + manifestName + ": " + err.Error())
+	}
+
+	if manifest.Version != ManifestVersion {
+		return manifest, fmt.Errorf("unsupported archive version %d, expected %d", manifest.Version, ManifestVersion)
+	}
+
+	dir, err := restore(manifest)
+	if err != nil {
+		return manifest, err
+	}
+
+	for {
+		header, err = archive.Next()
+		if err == io.EOF {
+			return manifest, nil
+		} else if err != nil {
+			return manifest, err
+		}
+
+		err = extractEntry(archive, header, dir)
+		if err != nil {
+			return manifest, err
+		}
+	}
+}
+
+// extractEntry writes an entry of the archive under dir, refusing entries that would be written outside of it.
+func extractEntry(archive *tar.Reader, header *tar.Header, dir string) error {
+	name := path.Clean(header.Name)
+	if name == dataDir {
+		return nil
+	}
+
+	if !strings.HasPrefix(name, dataDir+"/") {
+		return errors.New("unexpected entry " + header.Name + " outside of " + dataDir)
+	}
+
+	target := filepath.Join(dir, filepath.FromSlash(strings.TrimPrefix(name, dataDir+"/")))
+	err := checkWithin(dir, filepath.Dir(target))
+	if err != nil {
+		return errors.New("refusing to extract " + header.Name + ": " + err.Error())
+	}
+
+	mode := os.FileMode(header.Mode).Perm()
+	if header.Typeflag != tar.TypeDir {
+		if err = os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+	}
+
+	switch header.Typeflag {
+	case tar.TypeDir:
+		err = os.MkdirAll(target, mode)
+		if err == nil {
+			err = os.Chmod(target, mode)
+		}
+	case tar.TypeReg:
+		var file *os.File
+		file, err = os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(file, archive)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Chtimes(target, header.ModTime, header.ModTime)
+		}
+	case tar.TypeSymlink:
+		os.Remove(target)
+		err = os.Symlink(header.Linkname, target)
+	default:
+		return errors.New("unsupported entry " + header.Name)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// Ownership can only be restored by root.
+	if os.Geteuid() == 0 {
+		return os.Lchown(target, header.Uid, header.Gid)
+	}
+
+	return nil
+}
+
+// checkWithin returns an error if dir, after following symbolic links, is not root or a directory inside it.
+func checkWithin(root string, dir string) error {
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return err
+	}
+
+	resolved, err := filepath.EvalSymlinks(dir)
+	if os.IsNotExist(err) {
+		// Directories are archived before their contents, so a missing parent is created below the deepest one that
+		// exists.
+		return checkWithin(root, filepath.Dir(dir))
+	} else if err != nil {
+		return err
+	}
+
+	if resolved != resolvedRoot && !strings.HasPrefix(resolved, resolvedRoot+string(filepath.Separator)) {
+		return errors.New(dir + " is outside of the volume")
+	}
+
+	return nil
+}
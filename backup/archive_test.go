@@ -0,0 +1,135 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTree creates a small volume in dir: a file, a nested file and a symbolic link.
+func writeTree(t *testing.T, dir string) {
+	err := os.MkdirAll(filepath.Join(dir, "nested"), 0750)
+	if err == nil {
+		err = ioutil.WriteFile(filepath.Join(dir, "top.txt"), []byte("top"), 0600)
+	}
+	if err == nil {
+		err = ioutil.WriteFile(filepath.Join(dir, "nested", "inner.txt"), []byte("inner"), 0644)
+	}
+	if err == nil {
+		err = os.Symlink("nested/inner.txt", filepath.Join(dir, "link"))
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	t.Parallel()
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-backup")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	source := filepath.Join(tempDir, "source")
+	writeTree(t, source)
+
+	manifest := Manifest{Volume: "data", Options: map[string]string{"size": "1G"}, Labels: map[string]string{"team": "ml"}, Created: time.Now().UTC()}
+	for _, compress := range []bool{false, true} {
+		var archive bytes.Buffer
+		err = Write(&archive, manifest, source, compress)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if compressed := bytes.HasPrefix(archive.Bytes(), zstdMagic); compressed != compress {
+			t.Error("Expected the archive to be compressed: ", compress)
+		}
+
+		target := filepath.Join(tempDir, "target", map[bool]string{false: "tar", true: "zstd"}[compress])
+		restored, err := Read(&archive, func(read Manifest) (string, error) {
+			return target, os.MkdirAll(target, 0755)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if restored.Volume != "data" || restored.Options["size"] != "1G" || restored.Labels["team"] != "ml" || restored.Version != ManifestVersion {
+			t.Error("The manifest was not restored, got ", restored)
+		}
+
+		contents, err := ioutil.ReadFile(filepath.Join(target, "link"))
+		if err != nil || string(contents) != "inner" {
+			t.Error("The link should point at the inner file, got ", string(contents), err)
+		}
+
+		info, err := os.Stat(filepath.Join(target, "top.txt"))
+		if err != nil || info.Mode().Perm() != 0600 {
+			t.Error("The file's permissions should be restored, got ", info, err)
+		}
+	}
+}
+
+func TestRead_bad(t *testing.T) {
+	t.Parallel()
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-backup")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	archive := func(entries ...*tar.Header) []byte {
+		var buffer bytes.Buffer
+		writer := tar.NewWriter(&buffer)
+		for _, entry := range entries {
+			writer.WriteHeader(entry)
+		}
+		writer.Close()
+		return buffer.Bytes()
+	}
+
+	manifest := []byte(`{"Version": 1, "Volume": "evil"}`)
+	withManifest := func(entries ...*tar.Header) []byte {
+		var buffer bytes.Buffer
+		writer := tar.NewWriter(&buffer)
+		writer.WriteHeader(&tar.Header{Name: manifestName, Mode: 0644, Size: int64(len(manifest)), Typeflag: tar.TypeReg})
+		writer.Write(manifest)
+		for _, entry := range entries {
+			writer.WriteHeader(entry)
+		}
+		writer.Close()
+		return buffer.Bytes()
+	}
+
+	var tests = []struct {
+		name    string
+		archive []byte
+	}{
+		{"empty", nil},
+		{"no manifest", archive(&tar.Header{Name: "data/", Typeflag: tar.TypeDir, Mode: 0755})},
+		{"parent directory", withManifest(&tar.Header{Name: "data/../../escaped", Typeflag: tar.TypeReg, Mode: 0644})},
+		{"outside data", withManifest(&tar.Header{Name: "other/file", Typeflag: tar.TypeReg, Mode: 0644})},
+		{"through a link", withManifest(
+			&tar.Header{Name: "data/out", Typeflag: tar.TypeSymlink, Linkname: tempDir},
+			&tar.Header{Name: "data/out/escaped", Typeflag: tar.TypeReg, Mode: 0644})},
+		{"device", withManifest(&tar.Header{Name: "data/sda", Typeflag: tar.TypeBlock, Mode: 0600})},
+	}
+
+	for _, test := range tests {
+		target := filepath.Join(tempDir, "target", test.name)
+		_, err := Read(bytes.NewReader(test.archive), func(Manifest) (string, error) {
+			return target, os.MkdirAll(target, 0755)
+		})
+		if err == nil {
+			t.Error(test.name, ": the archive should not be restored")
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(tempDir, "escaped")); !os.IsNotExist(err) {
+		t.Error("No file should be written outside of the volume")
+	}
+}
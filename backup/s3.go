@@ -0,0 +1,174 @@
+package backup
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultRegion is used to sign requests to S3-compatible endpoints that do not care about regions, such as MinIO.
+const DefaultRegion = "us-east-1"
+
+// unsignedPayload is signed in place of the payload's hash, so that archives can be uploaded without hashing them
+// first.
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// S3Client stores archives in an S3-compatible object store, addressing buckets by path so that local endpoints work
+// without DNS.
+type S3Client struct {
+	Endpoint  string
+	Region    string
+	AccessKey string
+	SecretKey string
+	Client    *http.Client
+}
+
+// NewS3Client creates an S3Client for endpoint, e.g. http://localhost:9000, signing requests with the access and
+// secret keys. The region is optional.
+func NewS3Client(endpoint string, region string, accessKey string, secretKey string) S3Client {
+	if region == "" {
+		region = DefaultRegion
+	}
+
+	return S3Client{
+		Endpoint:  strings.TrimSuffix(endpoint, "/"),
+		Region:    region,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		Client:    &http.Client{}}
+}
+
+// ParseS3URL splits a url such as s3://backups/volumes/data.tar.zst into its bucket and key.
+func ParseS3URL(location string) (string, string, error) {
+	u, err := url.Parse(location)
+	if err != nil || u.Scheme != "s3" || u.Host == "" || strings.Trim(u.Path, "/") == "" {
+		return "", "", errors.New("invalid s3 url " + location + ", expected s3://bucket/key")
+	}
+
+	return u.Host, strings.TrimPrefix(u.Path, "/"), nil
+}
+
+// Put uploads size bytes from body as the object key in bucket.
+func (s S3Client) Put(bucket string, key string, body io.Reader, size int64) error {
+	request, err := http.NewRequest(http.MethodPut, s.objectURL(bucket, key), body)
+	if err != nil {
+		return err
+	}
+	request.ContentLength = size
+
+	response, err := s.do(request)
+	if err != nil {
+		return err
+	}
+
+	return response.Body.Close()
+}
+
+// Get downloads the object key in bucket, which must be closed once read.
+func (s S3Client) Get(bucket string, key string) (io.ReadCloser, error) {
+	request, err := http.NewRequest(http.MethodGet, s.objectURL(bucket, key), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := s.do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Body, nil
+}
+
+// do signs and sends a request, returning an error for any response other than 200 OK.
+func (s S3Client) do(request *http.Request) (*http.Response, error) {
+	s.sign(request, time.Now().UTC())
+
+	response, err := s.Client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		defer response.Body.Close()
+		body, _ := ioutil.ReadAll(io.LimitReader(response.Body, 4096))
+		return nil, fmt.Errorf("%s %s failed: %s %s", request.Method, request.URL.Path, response.Status, strings.TrimSpace(string(body)))
+	}
+
+	return response, nil
+}
+
+func (s S3Client) objectURL(bucket string, key string) string {
+	return s.Endpoint + "/" + uriEncode(bucket, false) + "/" + uriEncode(key, true)
+}
+
+// sign adds an AWS Signature Version 4 Authorization header to a request.
+func (s S3Client) sign(request *http.Request, now time.Time) {
+	date := now.Format("20060102")
+	timestamp := now.Format("20060102T150405Z")
+
+	request.Header.Set("X-Amz-Date", timestamp)
+	request.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		request.URL.EscapedPath(),
+		request.URL.RawQuery,
+		"host:" + request.URL.Host,
+		"x-amz-content-sha256:" + unsignedPayload,
+		"x-amz-date:" + timestamp,
+		"",
+		signedHeaders,
+		unsignedPayload,
+	}, "\n")
+
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + timestamp + "\n" + scope + "\n" + hexSHA256(canonicalRequest)
+	signature := hex.EncodeToString(hmacSHA256(signingKey(s.SecretKey, date, s.Region, "s3"), stringToSign))
+
+	request.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.AccessKey+"/"+scope+", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// signingKey derives the key requests are signed with on date, for a region and service.
+func signingKey(secretKey string, date string, region string, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hexSHA256(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// uriEncode escapes every byte but the unreserved characters, as S3 expects, keeping slashes if keepSlash is set.
+func uriEncode(value string, keepSlash bool) string {
+	var encoded strings.Builder
+	for _, b := range []byte(value) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9', b == '-', b == '_', b == '.', b == '~':
+			encoded.WriteByte(b)
+		case b == '/' && keepSlash:
+			encoded.WriteByte(b)
+		default:
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+
+	return encoded.String()
+}
@@ -0,0 +1,126 @@
+package backup
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 stores objects in memory, checking the signature of every request.
+type fakeS3 struct {
+	client  S3Client
+	objects map[string][]byte
+	lock    sync.Mutex
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Sign a copy of the request with the shared secret and compare the signatures.
+	expected := r.Clone(r.Context())
+	expected.URL.Host = r.Host
+	timestamp, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		http.Error(w, "bad date", http.StatusBadRequest)
+		return
+	}
+	f.client.sign(expected, timestamp)
+	if expected.Header.Get("Authorization") != r.Header.Get("Authorization") {
+		http.Error(w, "SignatureDoesNotMatch", http.StatusForbidden)
+		return
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		if r.ContentLength < 0 {
+			http.Error(w, "MissingContentLength", http.StatusLengthRequired)
+			return
+		}
+		f.objects[r.URL.Path], _ = ioutil.ReadAll(r.Body)
+	case http.MethodGet:
+		object, exists := f.objects[r.URL.Path]
+		if !exists {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Write(object)
+	}
+}
+
+func TestS3Client(t *testing.T) {
+	t.Parallel()
+	fake := &fakeS3{objects: map[string][]byte{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	fake.client = NewS3Client(server.URL, "", "access", "secret")
+	client := NewS3Client(server.URL+"/", "", "access", "secret")
+
+	err := client.Put("backups", "volumes/data 1.tar", strings.NewReader("archive"), int64(len("archive")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, exists := fake.objects["/backups/volumes/data 1.tar"]; !exists {
+		t.Error("Expected the object to be stored by its key, got ", fake.objects)
+	}
+
+	object, err := client.Get("backups", "volumes/data 1.tar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer object.Close()
+
+	contents, err := ioutil.ReadAll(object)
+	if err != nil || string(contents) != "archive" {
+		t.Error("Expected the archive back, got ", string(contents), err)
+	}
+
+	if _, err = client.Get("backups", "missing"); err == nil || !strings.Contains(err.Error(), "NoSuchKey") {
+		t.Error("Expected the endpoint's error, got ", err)
+	}
+
+	wrongKey := NewS3Client(server.URL, "", "access", "guess")
+	if err = wrongKey.Put("backups", "x", strings.NewReader(""), 0); err == nil {
+		t.Error("A request signed with the wrong key should be rejected")
+	}
+}
+
+func TestSigningKey(t *testing.T) {
+	t.Parallel()
+
+	// The example from the AWS Signature Version 4 documentation.
+	key := signingKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	if hex.EncodeToString(key) != "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d" {
+		t.Error("Unexpected signing key ", hex.EncodeToString(key))
+	}
+}
+
+func TestParseS3URL(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		location string
+		bucket   string
+		key      string
+		valid    bool
+	}{
+		{"s3://backups/volumes/data.tar.zst", "backups", "volumes/data.tar.zst", true},
+		{"s3://backups/", "", "", false},
+		{"s3:///data.tar", "", "", false},
+		{"/tmp/data.tar", "", "", false},
+	}
+
+	for _, test := range tests {
+		bucket, key, err := ParseS3URL(test.location)
+		if (err == nil) != test.valid || bucket != test.bucket || key != test.key {
+			t.Error(test.location, ": expected ", test.bucket, " ", test.key, ", got ", bucket, " ", key, " ", err)
+		}
+	}
+}
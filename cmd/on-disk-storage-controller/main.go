@@ -0,0 +1,28 @@
+// A reference out of process storage controller, serving the on-disk Storage Controller over gRPC. Run it, then
+// start docker-volume-rdma with -sc=external -sc-endpoint set to the same endpoint.
+package main
+
+import (
+	"flag"
+
+	"github.com/golang/glog"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers/external"
+)
+
+// Endpoint to serve the storage controller on.
+var endpoint string
+
+// Path to put the volumes in on the host.
+var storagePath string
+
+func init() {
+	flag.StringVar(&endpoint, "endpoint", "unix:///run/docker-volume-rdma/on-disk.sock", "endpoint to serve the storage controller on, unix:// or tcp://")
+	flag.StringVar(&storagePath, "scpath", "", "set the storage path used to know where to put the volumes on the host")
+}
+
+func main() {
+	flag.Parse()
+
+	glog.Fatal(external.Serve(endpoint, drivers.NewOnDiskStorageController(storagePath)))
+}
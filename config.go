@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"github.com/mellanox-senior-design/docker-volume-rdma/webhook"
+)
+
+// configFile is the configuration file passed with -config, e.g.
+//
+//	{
+//	    "default-backend": "bulk",
+//	    "backends": {
+//	        "fast": {"sc": "lvm", "sc-vg": "nvme", "sc-thinpool": "pool", "scpath": "/mnt/fast"},
+//	        "bulk": {"sc": "glusterfs"},
+//	        "scratch": {"sc": "tmpfs", "scpath": "/mnt/scratch"}
+//	    }
+//	}
+type configFile struct {
+	DefaultBackend string                            `json:"default-backend"`
+	Backends       map[string]map[string]interface{} `json:"backends"`
+}
+
+// backendSettings converts the settings of a backend in the configuration file to the strings that the equivalent
+// flags would hold, so that "sc-data-threshold": 75 and -sc-data-threshold=75 mean the same thing.
+func backendSettings(backend map[string]interface{}) (map[string]string, error) {
+	settings := map[string]string{}
+	for name, value := range backend {
+		switch value := value.(type) {
+		case string:
+			settings[name] = value
+		case float64:
+			settings[name] = strconv.FormatFloat(value, 'f', -1, 64)
+		case bool:
+			settings[name] = strconv.FormatBool(value)
+		default:
+			return nil, errors.New(name + " must be a string, number or boolean")
+		}
+	}
+
+	return settings, nil
+}
+
+// loadConfigFile reads the backends, and the name of the default backend, from a configuration file.
+func loadConfigFile(path string) (map[string]map[string]string, string, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var file configFile
+	err = json.Unmarshal(contents, &file)
+	if err != nil {
+		return nil, "", errors.New("unable to parse " + path + ": " + err.Error())
+	}
+
+	if len(file.Backends) == 0 {
+		return nil, "", errors.New(path + " does not define any backends")
+	}
+
+	backends := map[string]map[string]string{}
+	for name, backend := range file.Backends {
+		settings, err := backendSettings(backend)
+		if err != nil {
+			return nil, "", errors.New("unable to parse backend " + name + ": " + err.Error())
+		}
+
+		backends[name] = settings
+	}
+
+	// With only one backend there is no choice to make.
+	defaultBackend := file.DefaultBackend
+	if defaultBackend == "" && len(backends) == 1 {
+		for name := range backends {
+			defaultBackend = name
+		}
+	}
+
+	if _, exists := backends[defaultBackend]; !exists {
+		return nil, "", errors.New(path + " must set default-backend to one of its backends")
+	}
+
+	return backends, defaultBackend, nil
+}
+
+// getBackends creates the named Storage Controllers described by a configuration file.
+func getBackends(path string) (map[string]drivers.StorageController, string, error) {
+	configs, defaultBackend, err := loadConfigFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	backends := map[string]drivers.StorageController{}
+	// Backends mounting volumes beneath the same path would mount and remove each other's volumes.
+	mountRoots := map[string]string{}
+	for _, name := range names {
+		glog.Info("Configuring backend: ", name)
+		// The "sc" setting names the kind of Storage Controller, the rest configure it.
+		settings := configs[name]
+		driver := settings["sc"]
+		delete(settings, "sc")
+
+		backends[name], err = getStorageConnection(driver, settings)
+		if err != nil {
+			return nil, "", errors.New("backend " + name + ": " + err.Error())
+		}
+
+		mountRoot := drivers.MountRoot(backends[name])
+		if other, ok := mountRoots[mountRoot]; ok && mountRoot != "" {
+			return nil, "", errors.New("backends " + other + " and " + name + " both mount volumes beneath " + mountRoot + ", please give each its own scpath")
+		}
+		mountRoots[mountRoot] = name
+	}
+
+	glog.Info("Volumes will be stored on the ", defaultBackend, " backend by default.")
+	return backends, defaultBackend, nil
+}
+
+// quotasFile is the file of tenant quotas passed with -tenant-quotas, e.g.
+//
+//	{
+//	    "default": {"volumes": 20, "size": "1T"},
+//	    "tenants": {
+//	        "ml": {"volumes": 100, "size": "10T"},
+//	        "web": {"volumes": 50}
+//	    }
+//	}
+//
+// Limits that are left out, or 0, are unlimited.
+type quotasFile struct {
+	Default quotaSettings            `json:"default"`
+	Tenants map[string]quotaSettings `json:"tenants"`
+}
+
+// quotaSettings are the limits of one tenant, with size given like the size option.
+type quotaSettings struct {
+	Volumes int    `json:"volumes"`
+	Size    string `json:"size"`
+}
+
+// loadQuotasFile reads the quotas of each tenant, and the default quota, from a quotas file.
+func loadQuotasFile(path string) (map[string]drivers.Quota, drivers.Quota, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, drivers.Quota{}, err
+	}
+
+	var file quotasFile
+	err = json.Unmarshal(contents, &file)
+	if err != nil {
+		return nil, drivers.Quota{}, errors.New("unable to parse " + path + ": " + err.Error())
+	}
+
+	defaultQuota, err := drivers.NewQuota(file.Default.Volumes, file.Default.Size)
+	if err != nil {
+		return nil, drivers.Quota{}, errors.New("unable to parse the default quota: " + err.Error())
+	}
+
+	quotas := map[string]drivers.Quota{}
+	for tenant, settings := range file.Tenants {
+		quotas[tenant], err = drivers.NewQuota(settings.Volumes, settings.Size)
+		if err != nil {
+			return nil, drivers.Quota{}, errors.New("unable to parse the quota of tenant " + tenant + ": " + err.Error())
+		}
+	}
+
+	return quotas, defaultQuota, nil
+}
+
+// getTenants divides volumes between tenants, as configured by the -tenant flags.
+func getTenants(label string, separator string, quotasPath string) (drivers.Tenants, error) {
+	quotas := map[string]drivers.Quota{}
+	var defaultQuota drivers.Quota
+	if quotasPath != "" {
+		var err error
+		quotas, defaultQuota, err = loadQuotasFile(quotasPath)
+		if err != nil {
+			return drivers.Tenants{}, err
+		}
+	}
+
+	return drivers.NewTenants(label, separator, quotas, defaultQuota)
+}
+
+// webhooksFile is the file of webhook endpoints passed with -webhooks, e.g.
+//
+//	{
+//	    "outbox": "/var/lib/docker-volume-rdma/outbox",
+//	    "endpoints": [
+//	        {"url": "https://provisioning.example.com/hooks/volumes", "secret": "s3cret"},
+//	        {"url": "https://billing.example.com/volumes", "secret": "other", "events": ["volume.created", "volume.removed"]}
+//	    ]
+//	}
+//
+// The outbox defaults to webhook.DefaultOutboxDir, and endpoints that list no events receive all of them.
+type webhooksFile struct {
+	Outbox    string `json:"outbox"`
+	Endpoints []struct {
+		URL    string   `json:"url"`
+		Secret string   `json:"secret"`
+		Events []string `json:"events"`
+	} `json:"endpoints"`
+}
+
+// webhookEvents are the events that endpoints can ask for.
+var webhookEvents = []string{drivers.VolumeCreated, drivers.VolumeMounted, drivers.VolumeUnmounted, drivers.VolumeRemoved}
+
+// getNotifier reads a webhooks file, returning a notifier that delivers events to its endpoints.
+func getNotifier(path string) (webhook.Notifier, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return webhook.Notifier{}, err
+	}
+
+	var file webhooksFile
+	err = json.Unmarshal(contents, &file)
+	if err != nil {
+		return webhook.Notifier{}, errors.New("unable to parse " + path + ": " + err.Error())
+	}
+
+	if file.Outbox == "" {
+		file.Outbox = webhook.DefaultOutboxDir
+	}
+
+	endpoints := []webhook.Endpoint{}
+	for _, settings := range file.Endpoints {
+		for _, event := range settings.Events {
+			if !containsEvent(webhookEvents, event) {
+				return webhook.Notifier{}, errors.New("unknown webhook event " + event + ", expected one of: " + strings.Join(webhookEvents, ", "))
+			}
+		}
+
+		endpoints = append(endpoints, webhook.Endpoint{URL: settings.URL, Secret: settings.Secret, Events: settings.Events})
+	}
+
+	return webhook.NewNotifier(endpoints, file.Outbox)
+}
+
+// containsEvent reports whether events holds event.
+func containsEvent(events []string, event string) bool {
+	for _, e := range events {
+		if e == event {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,66 @@
+// Package config describes the settings that volume databases and storage controllers accept, so that they can be
+// configured from command line flags or a configuration file without main knowing about each backend.
+package config
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+)
+
+// Option describes a setting that a backend accepts. Name is also the name of the command line flag.
+type Option struct {
+	Name        string
+	Default     string
+	Description string
+}
+
+// Values holds the settings a backend was configured with, by option name.
+type Values map[string]string
+
+// NewValues returns the values of options, taking them from set or falling back to each option's default.
+func NewValues(options []Option, set map[string]string) Values {
+	values := Values{}
+	for _, option := range options {
+		value := set[option.Name]
+		if value == "" {
+			value = option.Default
+		}
+		values[option.Name] = value
+	}
+
+	return values
+}
+
+// String returns the value of a setting, or "" if it was not set.
+func (v Values) String(name string) string {
+	return v[name]
+}
+
+// Float returns the value of a setting as a number.
+func (v Values) Float(name string) (float64, error) {
+	value, err := strconv.ParseFloat(v[name], 64)
+	if err != nil {
+		return 0, errors.New("-" + name + " must be a number, not '" + v[name] + "'")
+	}
+
+	return value, nil
+}
+
+// Unsupported returns the names of the settings in set that are not one of options, in sorted order.
+func Unsupported(options []Option, set map[string]string) []string {
+	supported := map[string]bool{}
+	for _, option := range options {
+		supported[option.Name] = true
+	}
+
+	var unsupported []string
+	for name, value := range set {
+		if value != "" && !supported[name] {
+			unsupported = append(unsupported, name)
+		}
+	}
+	sort.Strings(unsupported)
+
+	return unsupported
+}
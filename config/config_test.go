@@ -0,0 +1,58 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+var testOptions = []Option{
+	{Name: "path", Default: "/tmp", Description: "where to put things"},
+	{Name: "threshold", Default: "90", Description: "how full things may get"},
+	{Name: "user", Description: "who to be"},
+}
+
+func TestNewValues(t *testing.T) {
+	t.Parallel()
+
+	values := NewValues(testOptions, map[string]string{"threshold": "75", "ignored": "yes"})
+
+	expected := Values{"path": "/tmp", "threshold": "75", "user": ""}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("NewValues() = %v; want %v", values, expected)
+	}
+
+	if values.String("path") != "/tmp" {
+		t.Errorf("String(path) = %v; want /tmp", values.String("path"))
+	}
+
+	threshold, err := values.Float("threshold")
+	if err != nil || threshold != 75 {
+		t.Errorf("Float(threshold) = %v, %v; want 75", threshold, err)
+	}
+
+	_, err = values.Float("user")
+	if err == nil {
+		t.Error("Float(user) should fail as user is not a number")
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		set      map[string]string
+		expected []string
+	}{
+		{map[string]string{}, nil},
+		{map[string]string{"path": "/mnt", "user": "admin"}, nil},
+		{map[string]string{"pool": "", "path": "/mnt"}, nil},
+		{map[string]string{"pool": "rbd", "vg": "vg0", "path": "/mnt"}, []string{"pool", "vg"}},
+	}
+
+	for _, test := range tests {
+		actual := Unsupported(testOptions, test.set)
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("Unsupported(%v) = %v; want %v", test.set, actual, test.expected)
+		}
+	}
+}
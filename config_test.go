@@ -0,0 +1,282 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
+)
+
+func writeConfigFile(t *testing.T, contents string) string {
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-config")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+
+	configFilePath := path.Join(tempDir, "config.json")
+	err = ioutil.WriteFile(configFilePath, []byte(contents), 0644)
+	if err != nil {
+		t.Fatal("Unable to write config file! ", err)
+	}
+
+	return configFilePath
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	configFilePath := writeConfigFile(t, `{
+		"default-backend": "fast",
+		"backends": {
+			"fast": {"sc": "lvm", "sc-vg": "nvme", "sc-thinpool": "pool", "sc-data-threshold": 75},
+			"bulk": {"sc": "glusterfs"}
+		}
+	}`)
+	defer os.RemoveAll(path.Dir(configFilePath))
+
+	backends, defaultBackend, err := loadConfigFile(configFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if defaultBackend != "fast" {
+		t.Error("Expected the default backend to be fast, got ", defaultBackend)
+	}
+
+	if len(backends) != 2 {
+		t.Fatal("Expected 2 backends, got ", len(backends))
+	}
+
+	fast := backends["fast"]
+	if fast["sc"] != "lvm" || fast["sc-vg"] != "nvme" || fast["sc-thinpool"] != "pool" {
+		t.Error("The fast backend was not configured from the file, got ", fast)
+	}
+
+	if fast["sc-data-threshold"] != "75" {
+		t.Error("Numbers should be read as the equivalent flag value 75, got ", fast["sc-data-threshold"])
+	}
+}
+
+func TestGetBackends(t *testing.T) {
+	configFilePath := writeConfigFile(t, `{"backends": {"fast": {"sc": "lvm", "sc-vg": "nvme", "sc-thinpool": "pool", "sc-data-threshold": 75}}}`)
+	defer os.RemoveAll(path.Dir(configFilePath))
+
+	backends, _, err := getBackends(configFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fast, ok := backends["fast"].(drivers.LVMStorageController)
+	if !ok {
+		t.Fatal("The fast backend was not an drivers.LVMStorageController")
+	}
+
+	if fast.DataThreshold != 75 || fast.MetadataThreshold != 90 {
+		t.Error("The fast backend's thresholds should be 75 and the default 90, got ", fast.DataThreshold, fast.MetadataThreshold)
+	}
+
+	var tests = []struct {
+		name     string
+		contents string
+	}{
+		{"unknown storage controller", `{"backends": {"a": {"sc": "floppy"}}}`},
+		{"invalid setting", `{"backends": {"a": {"sc": "lvm", "sc-data-threshold": "lots"}}}`},
+		{"unsupported setting", `{"backends": {"a": {"sc": "on-disk", "sc-pool": "volumes"}}}`},
+		{"shared scpath", `{"backends": {"a": {"sc": "tmpfs", "scpath": "/mnt/scratch"}, "b": {"sc": "on-disk", "scpath": "/mnt/scratch/"}}}`},
+		{"default scpath", `{"backends": {"a": {"sc": "lvm", "sc-vg": "a"}, "b": {"sc": "lvm", "sc-vg": "b"}}}`},
+	}
+
+	for _, test := range tests {
+		configFilePath := writeConfigFile(t, test.contents)
+		_, _, err := getBackends(configFilePath)
+		if err == nil {
+			t.Error(test.name, " should not create a backend")
+		}
+		os.RemoveAll(path.Dir(configFilePath))
+	}
+}
+
+func TestLoadConfigFile_bad(t *testing.T) {
+	var tests = []struct {
+		name     string
+		contents string
+	}{
+		{"invalid json", `{"backends": `},
+		{"no backends", `{"backends": {}}`},
+		{"missing default", `{"backends": {"a": {"sc": "glusterfs"}, "b": {"sc": "glusterfs"}}}`},
+		{"unknown default", `{"default-backend": "c", "backends": {"a": {"sc": "glusterfs"}}}`},
+		{"invalid backend", `{"backends": {"a": {"sc": ["lvm"]}}}`},
+	}
+
+	for _, test := range tests {
+		configFilePath := writeConfigFile(t, test.contents)
+		_, _, err := loadConfigFile(configFilePath)
+		if err == nil {
+			t.Error(test.name, " should not be a valid config file")
+		}
+		os.RemoveAll(path.Dir(configFilePath))
+	}
+
+	_, _, err := loadConfigFile("does/not/exist.json")
+	if err == nil {
+		t.Error("A missing config file should not load")
+	}
+}
+
+func TestConfigure_backends(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	configFilePath := writeConfigFile(t, `{
+		"default-backend": "disk",
+		"backends": {
+			"disk": {"sc": "on-disk", "scpath": "`+path.Join(tempDir, "disk")+`"},
+			"other": {"sc": "on-disk", "scpath": "`+path.Join(tempDir, "other")+`"}
+		}
+	}`)
+	defer os.RemoveAll(path.Dir(configFilePath))
+
+	flag.Set("db", "in-memory")
+	flag.Set("config", configFilePath)
+	flag.Parse()
+	defer flag.Set("config", "")
+
+	driver, _, err := configure()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(driver.Backends) != 2 || driver.DefaultBackend != "disk" {
+		t.Fatal("The driver was not configured with the backends from the config file")
+	}
+
+	// Volumes are routed to the backend they were created on.
+	var tests = []struct {
+		name     string
+		backend  string
+		expected string
+	}{
+		{"defaultvol", "", path.Join(tempDir, "disk", "defaultvol")},
+		{"othervol", "other", path.Join(tempDir, "other", "othervol")},
+	}
+
+	for _, test := range tests {
+		response := driver.Create(volume.Request{Name: test.name, Options: map[string]string{"backend": test.backend}})
+		if response.Err != "" {
+			t.Fatal(response.Err)
+		}
+
+		response = driver.Mount(volume.MountRequest{Name: test.name, ID: "1"})
+		if response.Err != "" {
+			t.Fatal(response.Err)
+		}
+
+		if response.Mountpoint != test.expected {
+			t.Error("Expected the volume to be mounted at ", test.expected, ", got ", response.Mountpoint)
+		}
+	}
+
+	response := driver.Create(volume.Request{Name: "volunknown", Options: map[string]string{"backend": "unknown"}})
+	if response.Err == "" {
+		t.Error("Creating a volume on an unknown backend should fail")
+	}
+}
+
+func TestLoadQuotasFile(t *testing.T) {
+	quotasFilePath := writeConfigFile(t, `{
+		"default": {"volumes": 20},
+		"tenants": {
+			"ml": {"volumes": 100, "size": "10T"},
+			"web": {"size": "512G"}
+		}
+	}`)
+	defer os.RemoveAll(path.Dir(quotasFilePath))
+
+	quotas, defaultQuota, err := loadQuotasFile(quotasFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if defaultQuota != (drivers.Quota{Volumes: 20}) {
+		t.Error("Unexpected default quota: ", defaultQuota)
+	}
+
+	if len(quotas) != 2 || quotas["ml"] != (drivers.Quota{Volumes: 100, Bytes: 10 << 40}) || quotas["web"] != (drivers.Quota{Bytes: 512 << 30}) {
+		t.Error("Unexpected quotas: ", quotas)
+	}
+
+	var tests = []struct {
+		name     string
+		contents string
+	}{
+		{"invalid json", `{"tenants": `},
+		{"invalid size", `{"tenants": {"ml": {"size": "lots"}}}`},
+		{"negative volumes", `{"default": {"volumes": -1}}`},
+	}
+
+	for _, test := range tests {
+		quotasFilePath := writeConfigFile(t, test.contents)
+		if _, _, err := loadQuotasFile(quotasFilePath); err == nil {
+			t.Error(test.name, " should not be a valid quotas file")
+		}
+		os.RemoveAll(path.Dir(quotasFilePath))
+	}
+
+	if _, err = getTenants("team", "-", ""); err == nil {
+		t.Error("Tenants should not be taken from a label and a name prefix at once")
+	}
+
+	tenants, err := getTenants("", "-", quotasFilePath)
+	if err != nil || tenants.Quota("ml").Volumes != 100 || tenants.Quota("other").Volumes != 20 {
+		t.Error("Expected the tenants to be limited by the quotas file, got ", tenants, err)
+	}
+}
+
+func TestGetNotifier(t *testing.T) {
+	outboxDir, err := ioutil.TempDir("", "docker-volume-rdma-outbox")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+	defer os.RemoveAll(outboxDir)
+
+	webhooksFilePath := writeConfigFile(t, `{
+		"outbox": "`+outboxDir+`",
+		"endpoints": [
+			{"url": "http://localhost:9000/hooks", "secret": "s3cret"},
+			{"url": "http://localhost:9001/hooks", "secret": "other", "events": ["volume.created", "volume.removed"]}
+		]
+	}`)
+	defer os.RemoveAll(path.Dir(webhooksFilePath))
+
+	notifier, err := getNotifier(webhooksFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if notifier.Outbox.Dir != outboxDir || len(notifier.Endpoints) != 2 || notifier.Endpoints[1].Secret != "other" || len(notifier.Endpoints[1].Events) != 2 {
+		t.Error("Unexpected notifier: ", notifier)
+	}
+
+	var tests = []struct {
+		name     string
+		contents string
+	}{
+		{"invalid json", `{"endpoints": `},
+		{"no endpoints", `{"outbox": "` + outboxDir + `"}`},
+		{"no secret", `{"outbox": "` + outboxDir + `", "endpoints": [{"url": "http://localhost:9000"}]}`},
+		{"unknown event", `{"outbox": "` + outboxDir + `", "endpoints": [{"url": "http://localhost:9000", "secret": "s3cret", "events": ["volume.resized"]}]}`},
+	}
+
+	for _, test := range tests {
+		webhooksFilePath := writeConfigFile(t, test.contents)
+		if _, err := getNotifier(webhooksFilePath); err == nil {
+			t.Error(test.name, " should not be a valid webhooks file")
+		}
+		os.RemoveAll(path.Dir(webhooksFilePath))
+	}
+}
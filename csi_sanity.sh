@@ -0,0 +1,28 @@
+#!/bin/bash -e
+# Runs the csi-sanity test suite against the CSI frontend backed by the on-disk
+# storage controller. Requires csi-sanity on the PATH and root, volumes are
+# bind mounted into the paths csi-sanity creates.
+#
+#   go get github.com/kubernetes-csi/csi-test/cmd/csi-sanity
+if ! command -v csi-sanity > /dev/null; then
+	echo 'csi-sanity is not installed' >&2
+	exit 2
+fi
+
+workdir=$(mktemp -d)
+trap 'kill $plugin 2> /dev/null; rm -rf "$workdir"' EXIT
+
+go build -o "$workdir/docker-volume-rdma" .
+"$workdir/docker-volume-rdma" -logtostderr=true \
+	-docker-plugin=false \
+	-csi-endpoint="unix://$workdir/csi.sock" \
+	-db=in-memory \
+	-sc=on-disk \
+	-scpath="$workdir/volumes" &
+plugin=$!
+
+csi-sanity \
+	--csi.endpoint="$workdir/csi.sock" \
+	--csi.mountdir="$workdir/target" \
+	--csi.stagingdir="$workdir/staging" \
+	"$@"
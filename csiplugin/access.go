@@ -0,0 +1,146 @@
+package csiplugin
+
+import (
+	"strings"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// singleNodeModes are the access modes of volumes mounted on one host at a time, every Storage Controller supports
+// them. The multi node modes need a Storage Controller whose volumes may be mounted on several hosts at once.
+var singleNodeModes = map[csi.VolumeCapability_AccessMode_Mode]bool{
+	csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER:        true,
+	csi.VolumeCapability_AccessMode_SINGLE_NODE_READER_ONLY:   true,
+	csi.VolumeCapability_AccessMode_SINGLE_NODE_SINGLE_WRITER: true,
+	csi.VolumeCapability_AccessMode_SINGLE_NODE_MULTI_WRITER:  true,
+}
+
+// singleWriterModes allow any number of readers but only one writer.
+var singleWriterModes = map[csi.VolumeCapability_AccessMode_Mode]bool{
+	csi.VolumeCapability_AccessMode_SINGLE_NODE_SINGLE_WRITER: true,
+	csi.VolumeCapability_AccessMode_MULTI_NODE_SINGLE_WRITER:  true,
+}
+
+// readOnlyModes are always published read only.
+var readOnlyModes = map[csi.VolumeCapability_AccessMode_Mode]bool{
+	csi.VolumeCapability_AccessMode_SINGLE_NODE_READER_ONLY: true,
+	csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY:  true,
+}
+
+// validateAccessTypes returns an InvalidArgument error unless every capability is for a mounted volume.
+func validateAccessTypes(capabilities []*csi.VolumeCapability) error {
+	if len(capabilities) == 0 {
+		return status.Error(codes.InvalidArgument, "volume capabilities are required")
+	}
+
+	for _, capability := range capabilities {
+		if capability.GetMount() == nil {
+			return status.Error(codes.InvalidArgument, "only mounted volumes are supported, not block volumes")
+		}
+	}
+
+	return nil
+}
+
+// validateCapabilities returns an InvalidArgument error if any of the capabilities is not supported by the Storage
+// Controller.
+func validateCapabilities(capabilities []*csi.VolumeCapability, storageController drivers.StorageController) error {
+	err := validateAccessTypes(capabilities)
+	if err != nil {
+		return err
+	}
+
+	fabric, ok := storageController.(drivers.FabricStorage)
+	multiHost := ok && fabric.MultiHost()
+
+	for _, capability := range capabilities {
+		mode := capability.GetAccessMode().GetMode()
+		if mode == csi.VolumeCapability_AccessMode_UNKNOWN || (!singleNodeModes[mode] && !multiHost) {
+			return status.Error(codes.InvalidArgument, "unsupported access mode "+mode.String())
+		}
+	}
+
+	return nil
+}
+
+// requester is a CSI mount request recorded in the VolumeDatabase. Docker records container IDs in the same place.
+type requester struct {
+	kind     string
+	node     string
+	readOnly bool
+	path     string
+}
+
+const (
+	stageRequesterKind   = "csi-stage"
+	publishRequesterKind = "csi-publish"
+)
+
+// stageRequester is the ID of the mount request for a volume staged at stagingPath on node.
+func stageRequester(node string, stagingPath string) string {
+	return stageRequesterKind + ":" + node + ":" + stagingPath
+}
+
+// publishRequester is the ID of the mount request for a volume published at targetPath on node.
+func publishRequester(node string, readOnly bool, targetPath string) string {
+	access := "rw"
+	if readOnly {
+		access = "ro"
+	}
+
+	return publishRequesterKind + ":" + node + ":" + access + ":" + targetPath
+}
+
+// parseRequester parses the ID of a CSI mount request, returning false for other IDs.
+func parseRequester(id string) (requester, bool) {
+	fields := strings.SplitN(id, ":", 3)
+	if len(fields) != 3 {
+		return requester{}, false
+	}
+
+	switch fields[0] {
+	case stageRequesterKind:
+		return requester{kind: fields[0], node: fields[1], path: fields[2]}, true
+
+	case publishRequesterKind:
+		access := strings.SplitN(fields[2], ":", 2)
+		if len(access) != 2 || (access[0] != "rw" && access[0] != "ro") {
+			return requester{}, false
+		}
+		return requester{kind: fields[0], node: fields[1], readOnly: access[0] == "ro", path: access[1]}, true
+
+	default:
+		return requester{}, false
+	}
+}
+
+// checkAccess returns a FailedPrecondition error if mounting a volume on this host with mode would break the access
+// mode: single node volumes may not be in use on another host, and single writer volumes may not have another
+// writer.
+func (s Server) checkAccess(volumeID string, mode csi.VolumeCapability_AccessMode_Mode, readOnly bool, targetPath string) error {
+	mounts, err := s.Driver.VolumeDatabase.Mounts(volumeID)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	for id := range mounts {
+		other, ok := parseRequester(id)
+		if !ok {
+			continue
+		}
+
+		if singleNodeModes[mode] && other.node != s.NodeID {
+			return status.Error(codes.FailedPrecondition, "volume "+volumeID+" is in use on node "+other.node)
+		}
+
+		if singleWriterModes[mode] && !readOnly && other.kind == publishRequesterKind && !other.readOnly &&
+			(other.node != s.NodeID || other.path != targetPath) {
+			return status.Error(codes.FailedPrecondition, "volume "+volumeID+" is already published for writing at "+other.node+":"+other.path)
+		}
+	}
+
+	return nil
+}
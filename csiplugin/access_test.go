@@ -0,0 +1,62 @@
+package csiplugin
+
+import (
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+)
+
+func TestParseRequester(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		id        string
+		requester requester
+		valid     bool
+	}{
+		{stageRequester("node1", "/var/lib/staging"), requester{kind: stageRequesterKind, node: "node1", path: "/var/lib/staging"}, true},
+		{publishRequester("node1", true, "/pods/a:b"), requester{kind: publishRequesterKind, node: "node1", readOnly: true, path: "/pods/a:b"}, true},
+		{publishRequester("node2", false, "/pods/c"), requester{kind: publishRequesterKind, node: "node2", path: "/pods/c"}, true},
+		{"b87d7442095999a92b65b3d9691e697b61713829cc0ffd1bb72e4ccd51aa4d6c", requester{}, false},
+		{"csi-publish:node1:rx:/pods/c", requester{}, false},
+	}
+
+	for _, test := range tests {
+		parsed, ok := parseRequester(test.id)
+		if ok != test.valid || parsed != test.requester {
+			t.Errorf("parseRequester(%q) = %v, %v; want %v, %v", test.id, parsed, ok, test.requester, test.valid)
+		}
+	}
+}
+
+func TestValidateCapabilities(t *testing.T) {
+	t.Parallel()
+
+	capability := func(mode csi.VolumeCapability_AccessMode_Mode) []*csi.VolumeCapability {
+		return []*csi.VolumeCapability{{
+			AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+			AccessMode: &csi.VolumeCapability_AccessMode{Mode: mode},
+		}}
+	}
+
+	var tests = []struct {
+		name              string
+		mode              csi.VolumeCapability_AccessMode_Mode
+		storageController drivers.StorageController
+		valid             bool
+	}{
+		{"single node on-disk", csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER, drivers.NewOnDiskStorageController("test/access"), true},
+		{"multi node on-disk", csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY, drivers.NewOnDiskStorageController("test/access"), false},
+		{"multi node rbd", csi.VolumeCapability_AccessMode_MULTI_NODE_SINGLE_WRITER, drivers.NewRBDStorageController("pool", "", "", "test/access"), false},
+		{"multi node glusterfs", csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER, drivers.NewGlusterStorageController(), true},
+		{"unknown", csi.VolumeCapability_AccessMode_UNKNOWN, drivers.NewGlusterStorageController(), false},
+	}
+
+	for _, test := range tests {
+		err := validateCapabilities(capability(test.mode), test.storageController)
+		if (err == nil) != test.valid {
+			t.Error(test.name, ": expected valid to be ", test.valid, ", got ", err)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package csiplugin
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CreateVolume creates a volume with the parameters as its options. The requested capacity is passed to the
+// Storage Controller as the size option. Volumes of Storage Controllers that are not fabric storage can only be
+// mounted on this host, so they are created here only if the accessibility requirements allow it.
+func (s Server) CreateVolume(ctx context.Context, request *csi.CreateVolumeRequest) (*csi.CreateVolumeResponse, error) {
+	if request.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "a volume name is required")
+	}
+
+	storageController, err := s.Driver.Backend(request.Parameters[drivers.BackendOption])
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	err = validateCapabilities(request.VolumeCapabilities, storageController)
+	if err != nil {
+		return nil, err
+	}
+
+	topology := s.volumeTopology(storageController)
+	if !accessible(request.AccessibilityRequirements.GetRequisite(), topology) {
+		return nil, status.Error(codes.ResourceExhausted, "volumes on this backend can only be created on node "+s.NodeID)
+	}
+
+	options := map[string]string{}
+	for name, value := range request.Parameters {
+		options[name] = value
+	}
+
+	if bytes := request.CapacityRange.GetRequiredBytes(); bytes > 0 {
+		options["size"] = sizeOption(bytes)
+	}
+
+	for _, capability := range request.VolumeCapabilities {
+		if fs := capability.GetMount().GetFsType(); fs != "" && options["fs"] == "" {
+			options["fs"] = fs
+		}
+	}
+
+	if _, ok := storageController.(drivers.FabricStorage); !ok {
+		options[NodeOption] = s.NodeID
+	}
+
+	capacity := sizeBytes(options["size"])
+	if limit := request.CapacityRange.GetLimitBytes(); limit > 0 && capacity > limit {
+		return nil, status.Error(codes.OutOfRange, "the requested capacity exceeds the limit of "+strconv.FormatInt(limit, 10)+" bytes")
+	}
+
+	exists, err := s.volumeExists(request.Name)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	// Creating a volume that already exists succeeds, as long as it was created with the same size.
+	if exists {
+		existing, err := s.Driver.VolumeDatabase.Options(request.Name)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+
+		if existing["size"] != options["size"] {
+			return nil, status.Error(codes.AlreadyExists, "volume "+request.Name+" already exists with size "+existing["size"])
+		}
+	} else {
+		err = responseError(s.Driver.Create(volume.Request{Name: request.Name, Options: options}).Err)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	}
+
+	return &csi.CreateVolumeResponse{
+		Volume: &csi.Volume{
+			VolumeId:           request.Name,
+			CapacityBytes:      capacity,
+			AccessibleTopology: topology,
+		}}, nil
+}
+
+// DeleteVolume deletes a volume, succeeding if the volume does not exist.
+func (s Server) DeleteVolume(ctx context.Context, request *csi.DeleteVolumeRequest) (*csi.DeleteVolumeResponse, error) {
+	if request.VolumeId == "" {
+		return nil, status.Error(codes.InvalidArgument, "a volume ID is required")
+	}
+
+	exists, err := s.volumeExists(request.VolumeId)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	if exists {
+		err = responseError(s.Driver.Remove(volume.Request{Name: request.VolumeId}).Err)
+		if err != nil {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+	}
+
+	return &csi.DeleteVolumeResponse{}, nil
+}
+
+// ValidateVolumeCapabilities confirms the capabilities if every one of them is supported.
+func (s Server) ValidateVolumeCapabilities(ctx context.Context, request *csi.ValidateVolumeCapabilitiesRequest) (*csi.ValidateVolumeCapabilitiesResponse, error) {
+	if request.VolumeId == "" {
+		return nil, status.Error(codes.InvalidArgument, "a volume ID is required")
+	}
+
+	if len(request.VolumeCapabilities) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "volume capabilities are required")
+	}
+
+	exists, err := s.volumeExists(request.VolumeId)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	} else if !exists {
+		return nil, status.Error(codes.NotFound, "volume "+request.VolumeId+" does not exist")
+	}
+
+	options, err := s.Driver.VolumeDatabase.Options(request.VolumeId)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	storageController, err := s.Driver.Backend(options[drivers.BackendOption])
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	err = validateCapabilities(request.VolumeCapabilities, storageController)
+	if err != nil {
+		return &csi.ValidateVolumeCapabilitiesResponse{Message: status.Convert(err).Message()}, nil
+	}
+
+	return &csi.ValidateVolumeCapabilitiesResponse{
+		Confirmed: &csi.ValidateVolumeCapabilitiesResponse_Confirmed{
+			VolumeContext:      request.VolumeContext,
+			VolumeCapabilities: request.VolumeCapabilities,
+			Parameters:         request.Parameters,
+		}}, nil
+}
+
+// ListVolumes lists the volumes in name order. The next token is the index of the next volume to list.
+func (s Server) ListVolumes(ctx context.Context, request *csi.ListVolumesRequest) (*csi.ListVolumesResponse, error) {
+	volumes, err := s.Driver.VolumeDatabase.List()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	sort.Slice(volumes, func(i, j int) bool { return volumes[i].Name < volumes[j].Name })
+
+	start := 0
+	if request.StartingToken != "" {
+		start, err = strconv.Atoi(request.StartingToken)
+		if err != nil || start < 0 || start > len(volumes) {
+			return nil, status.Error(codes.Aborted, "invalid starting token "+request.StartingToken)
+		}
+	}
+
+	end := len(volumes)
+	if request.MaxEntries > 0 && start+int(request.MaxEntries) < end {
+		end = start + int(request.MaxEntries)
+	}
+
+	response := &csi.ListVolumesResponse{}
+	for _, vol := range volumes[start:end] {
+		options, err := s.Driver.VolumeDatabase.Options(vol.Name)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+
+		response.Entries = append(response.Entries, &csi.ListVolumesResponse_Entry{
+			Volume: &csi.Volume{VolumeId: vol.Name, CapacityBytes: sizeBytes(options["size"])},
+		})
+	}
+
+	if end < len(volumes) {
+		response.NextToken = strconv.Itoa(end)
+	}
+
+	return response, nil
+}
+
+// GetCapacity returns the capacity left on a backend, chosen with the backend parameter, as reported by its Storage
+// Controller. Storage Controllers that cannot report their capacity, and topologies that are not this host, have none.
+func (s Server) GetCapacity(ctx context.Context, request *csi.GetCapacityRequest) (*csi.GetCapacityResponse, error) {
+	storageController, err := s.Driver.Backend(request.Parameters[drivers.BackendOption])
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if request.AccessibleTopology != nil && !accessible([]*csi.Topology{request.AccessibleTopology}, s.volumeTopology(storageController)) {
+		return &csi.GetCapacityResponse{}, nil
+	}
+
+	reporter, ok := storageController.(drivers.StorageCapacityReporter)
+	if !ok {
+		return &csi.GetCapacityResponse{}, nil
+	}
+
+	capacity, err := reporter.Capacity()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &csi.GetCapacityResponse{AvailableCapacity: capacity}, nil
+}
+
+// ControllerGetCapabilities reports that volumes can be created, deleted and listed, and capacity reported.
+func (s Server) ControllerGetCapabilities(ctx context.Context, request *csi.ControllerGetCapabilitiesRequest) (*csi.ControllerGetCapabilitiesResponse, error) {
+	response := &csi.ControllerGetCapabilitiesResponse{}
+	for _, capability := range []csi.ControllerServiceCapability_RPC_Type{
+		csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME,
+		csi.ControllerServiceCapability_RPC_LIST_VOLUMES,
+		csi.ControllerServiceCapability_RPC_GET_CAPACITY,
+	} {
+		response.Capabilities = append(response.Capabilities, &csi.ControllerServiceCapability{
+			Type: &csi.ControllerServiceCapability_Rpc{
+				Rpc: &csi.ControllerServiceCapability_RPC{Type: capability},
+			},
+		})
+	}
+
+	return response, nil
+}
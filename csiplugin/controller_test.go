@@ -0,0 +1,264 @@
+package csiplugin
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCreateVolume(t *testing.T) {
+	t.Parallel()
+	server, _, tempDir := newTestServer(t)
+	defer os.RemoveAll(tempDir)
+
+	request := &csi.CreateVolumeRequest{
+		Name:               "csivol1",
+		CapacityRange:      &csi.CapacityRange{RequiredBytes: 10 << 30},
+		VolumeCapabilities: []*csi.VolumeCapability{mountCapability},
+		Parameters:         map[string]string{"fs": "xfs"}}
+
+	response, err := server.CreateVolume(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Volume.VolumeId != "csivol1" || response.Volume.CapacityBytes != 10<<30 {
+		t.Error("Unexpected volume ", response.Volume)
+	}
+
+	options, err := server.Driver.VolumeDatabase.Options("csivol1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if options["size"] != "10240M" || options["fs"] != "xfs" {
+		t.Error("The capacity and parameters should be the volume's options, got ", options)
+	}
+
+	// Creating the same volume again succeeds.
+	_, err = server.CreateVolume(context.Background(), request)
+	if err != nil {
+		t.Error("Creating the same volume twice should succeed: ", err)
+	}
+
+	request.CapacityRange.RequiredBytes = 20 << 30
+	_, err = server.CreateVolume(context.Background(), request)
+	if status.Code(err) != codes.AlreadyExists {
+		t.Error("Creating the same volume with a different size should fail with AlreadyExists, got ", err)
+	}
+}
+
+func TestCreateVolume_invalid(t *testing.T) {
+	t.Parallel()
+	server, _, tempDir := newTestServer(t)
+	defer os.RemoveAll(tempDir)
+
+	block := &csi.VolumeCapability{
+		AccessType: &csi.VolumeCapability_Block{Block: &csi.VolumeCapability_BlockVolume{}},
+		AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER},
+	}
+
+	multiNode := &csi.VolumeCapability{
+		AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+		AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER},
+	}
+
+	var tests = []struct {
+		name    string
+		request *csi.CreateVolumeRequest
+		code    codes.Code
+	}{
+		{"no name", &csi.CreateVolumeRequest{VolumeCapabilities: []*csi.VolumeCapability{mountCapability}}, codes.InvalidArgument},
+		{"no capabilities", &csi.CreateVolumeRequest{Name: "csivol2"}, codes.InvalidArgument},
+		{"block", &csi.CreateVolumeRequest{Name: "csivol2", VolumeCapabilities: []*csi.VolumeCapability{block}}, codes.InvalidArgument},
+		{"multi node", &csi.CreateVolumeRequest{Name: "csivol2", VolumeCapabilities: []*csi.VolumeCapability{multiNode}}, codes.InvalidArgument},
+		{"over limit", &csi.CreateVolumeRequest{
+			Name:               "csivol2",
+			CapacityRange:      &csi.CapacityRange{RequiredBytes: 1<<20 + 1, LimitBytes: 1<<20 + 1},
+			VolumeCapabilities: []*csi.VolumeCapability{mountCapability}}, codes.OutOfRange},
+	}
+
+	for _, test := range tests {
+		_, err := server.CreateVolume(context.Background(), test.request)
+		if status.Code(err) != test.code {
+			t.Error(test.name, ": expected ", test.code, ", got ", err)
+		}
+	}
+}
+
+func TestDeleteVolume(t *testing.T) {
+	t.Parallel()
+	server, _, tempDir := newTestServer(t)
+	defer os.RemoveAll(tempDir)
+
+	_, err := server.CreateVolume(context.Background(), &csi.CreateVolumeRequest{Name: "csivol3", VolumeCapabilities: []*csi.VolumeCapability{mountCapability}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err = server.DeleteVolume(context.Background(), &csi.DeleteVolumeRequest{VolumeId: "csivol3"})
+		if err != nil {
+			t.Error("Deleting a volume should succeed, even if it was already deleted: ", err)
+		}
+	}
+
+	exists, err := server.volumeExists("csivol3")
+	if err != nil || exists {
+		t.Error("The volume should have been removed from the database ", exists, err)
+	}
+
+	_, err = server.DeleteVolume(context.Background(), &csi.DeleteVolumeRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Error("Deleting without a volume ID should fail with InvalidArgument, got ", err)
+	}
+}
+
+func TestListVolumes(t *testing.T) {
+	t.Parallel()
+	server, _, tempDir := newTestServer(t)
+	defer os.RemoveAll(tempDir)
+
+	for i := 0; i < 5; i++ {
+		_, err := server.CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+			Name:               "csivol" + strconv.Itoa(i),
+			CapacityRange:      &csi.CapacityRange{RequiredBytes: 1 << 20},
+			VolumeCapabilities: []*csi.VolumeCapability{mountCapability}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var listed []string
+	request := &csi.ListVolumesRequest{MaxEntries: 2}
+	for {
+		response, err := server.ListVolumes(context.Background(), request)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(response.Entries) > 2 {
+			t.Error("ListVolumes returned more than MaxEntries volumes")
+		}
+
+		for _, entry := range response.Entries {
+			listed = append(listed, entry.Volume.VolumeId)
+			if entry.Volume.CapacityBytes != 1<<20 {
+				t.Error("Expected the capacity of ", entry.Volume.VolumeId, " to be 1MiB, got ", entry.Volume.CapacityBytes)
+			}
+		}
+
+		if response.NextToken == "" {
+			break
+		}
+		request.StartingToken = response.NextToken
+	}
+
+	if len(listed) != 5 || listed[0] != "csivol0" || listed[4] != "csivol4" {
+		t.Error("Expected every volume to be listed in order, got ", listed)
+	}
+
+	_, err := server.ListVolumes(context.Background(), &csi.ListVolumesRequest{StartingToken: "lots"})
+	if status.Code(err) != codes.Aborted {
+		t.Error("An invalid starting token should fail with Aborted, got ", err)
+	}
+}
+
+func TestValidateVolumeCapabilities(t *testing.T) {
+	t.Parallel()
+	server, _, tempDir := newTestServer(t)
+	defer os.RemoveAll(tempDir)
+
+	_, err := server.CreateVolume(context.Background(), &csi.CreateVolumeRequest{Name: "csivol4", VolumeCapabilities: []*csi.VolumeCapability{mountCapability}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := server.ValidateVolumeCapabilities(context.Background(), &csi.ValidateVolumeCapabilitiesRequest{
+		VolumeId:           "csivol4",
+		VolumeCapabilities: []*csi.VolumeCapability{mountCapability}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Confirmed == nil {
+		t.Error("A mounted single node volume should be confirmed")
+	}
+
+	_, err = server.ValidateVolumeCapabilities(context.Background(), &csi.ValidateVolumeCapabilitiesRequest{
+		VolumeId:           "missing",
+		VolumeCapabilities: []*csi.VolumeCapability{mountCapability}})
+	if status.Code(err) != codes.NotFound {
+		t.Error("Validating a volume that does not exist should fail with NotFound, got ", err)
+	}
+}
+
+func TestCreateVolume_topology(t *testing.T) {
+	t.Parallel()
+	server, _, tempDir := newTestServer(t)
+	defer os.RemoveAll(tempDir)
+
+	response, err := server.CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+		Name:               "topologyvol",
+		VolumeCapabilities: []*csi.VolumeCapability{mountCapability},
+		AccessibilityRequirements: &csi.TopologyRequirement{
+			Requisite: []*csi.Topology{
+				{Segments: map[string]string{"rdma.test/host": "node2"}},
+				{Segments: map[string]string{"rdma.test/host": "node1"}},
+			},
+		}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// On-disk volumes can only be mounted on the host that created them.
+	topology := response.Volume.AccessibleTopology
+	if len(topology) != 1 || topology[0].Segments["rdma.test/host"] != "node1" {
+		t.Error("The volume should only be accessible from node1, got ", topology)
+	}
+
+	options, err := server.Driver.VolumeDatabase.Options("topologyvol")
+	if err != nil || options[NodeOption] != "node1" {
+		t.Error("The volume should record that it is stored on node1, got ", options, err)
+	}
+
+	_, err = server.CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+		Name:               "elsewherevol",
+		VolumeCapabilities: []*csi.VolumeCapability{mountCapability},
+		AccessibilityRequirements: &csi.TopologyRequirement{
+			Requisite: []*csi.Topology{{Segments: map[string]string{"rdma.test/host": "node2"}}},
+		}})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Error("A volume required on node2 cannot be created on node1, got ", err)
+	}
+}
+
+func TestGetCapacity(t *testing.T) {
+	t.Parallel()
+	server, _, tempDir := newTestServer(t)
+	defer os.RemoveAll(tempDir)
+
+	response, err := server.GetCapacity(context.Background(), &csi.GetCapacityRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.AvailableCapacity <= 0 {
+		t.Error("The on-disk storage controller should report its capacity, got ", response.AvailableCapacity)
+	}
+
+	response, err = server.GetCapacity(context.Background(), &csi.GetCapacityRequest{
+		AccessibleTopology: &csi.Topology{Segments: map[string]string{"rdma.test/host": "node2"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.AvailableCapacity != 0 {
+		t.Error("No capacity is available to node2, got ", response.AvailableCapacity)
+	}
+}
@@ -0,0 +1,37 @@
+package csiplugin
+
+import (
+	"context"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// GetPluginInfo returns the name and version of the plugin.
+func (s Server) GetPluginInfo(ctx context.Context, request *csi.GetPluginInfoRequest) (*csi.GetPluginInfoResponse, error) {
+	return &csi.GetPluginInfoResponse{Name: s.Name, VendorVersion: Version}, nil
+}
+
+// GetPluginCapabilities reports that the plugin provides the Controller service, and that volumes may only be
+// accessible from some hosts.
+func (s Server) GetPluginCapabilities(ctx context.Context, request *csi.GetPluginCapabilitiesRequest) (*csi.GetPluginCapabilitiesResponse, error) {
+	return &csi.GetPluginCapabilitiesResponse{
+		Capabilities: []*csi.PluginCapability{
+			{
+				Type: &csi.PluginCapability_Service_{
+					Service: &csi.PluginCapability_Service{Type: csi.PluginCapability_Service_CONTROLLER_SERVICE},
+				},
+			},
+			{
+				Type: &csi.PluginCapability_Service_{
+					Service: &csi.PluginCapability_Service{Type: csi.PluginCapability_Service_VOLUME_ACCESSIBILITY_CONSTRAINTS},
+				},
+			},
+		}}, nil
+}
+
+// Probe reports the plugin as ready while the Storage Controllers are healthy.
+func (s Server) Probe(ctx context.Context, request *csi.ProbeRequest) (*csi.ProbeResponse, error) {
+	health := s.Driver.Health()
+	return &csi.ProbeResponse{Ready: wrapperspb.Bool(health.Err == "")}, nil
+}
@@ -0,0 +1,47 @@
+package csiplugin
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+)
+
+func TestIdentity(t *testing.T) {
+	t.Parallel()
+	server, _, tempDir := newTestServer(t)
+	defer os.RemoveAll(tempDir)
+
+	info, err := server.GetPluginInfo(context.Background(), &csi.GetPluginInfoRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Name != "rdma.test" || info.VendorVersion != Version {
+		t.Error("Unexpected plugin info ", info)
+	}
+
+	capabilities, err := server.GetPluginCapabilities(context.Background(), &csi.GetPluginCapabilitiesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var services []csi.PluginCapability_Service_Type
+	for _, capability := range capabilities.Capabilities {
+		services = append(services, capability.GetService().GetType())
+	}
+
+	if len(services) != 2 || services[0] != csi.PluginCapability_Service_CONTROLLER_SERVICE || services[1] != csi.PluginCapability_Service_VOLUME_ACCESSIBILITY_CONSTRAINTS {
+		t.Error("The plugin should report the controller service and accessibility constraints, got ", services)
+	}
+
+	probe, err := server.Probe(context.Background(), &csi.ProbeRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !probe.Ready.GetValue() {
+		t.Error("The plugin should be ready while its storage controller is healthy")
+	}
+}
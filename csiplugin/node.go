@@ -0,0 +1,243 @@
+package csiplugin
+
+import (
+	"context"
+	"os"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/golang/glog"
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// isMounted reports whether something is mounted at mountpoint.
+func (s Server) isMounted(mountpoint string) bool {
+	_, err := s.Runner.Run("mountpoint", "-q", mountpoint)
+	return err == nil
+}
+
+// bindMount mounts source at target, creating target if needed.
+func (s Server) bindMount(source string, target string, readOnly bool) error {
+	err := os.MkdirAll(target, 0750)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"--bind"}
+	if readOnly {
+		args = append(args, "-o", "ro")
+	}
+
+	_, err = s.Runner.Run("mount", append(args, source, target)...)
+	return err
+}
+
+// NodeStageVolume mounts a volume on the host with its Storage Controller and bind mounts it at the staging path. The
+// staging path is recorded as a mount request, so the volume stays mounted on the host until it is unstaged.
+func (s Server) NodeStageVolume(ctx context.Context, request *csi.NodeStageVolumeRequest) (*csi.NodeStageVolumeResponse, error) {
+	if request.VolumeId == "" || request.StagingTargetPath == "" {
+		return nil, status.Error(codes.InvalidArgument, "a volume ID and staging target path are required")
+	}
+
+	if request.VolumeCapability == nil {
+		return nil, status.Error(codes.InvalidArgument, "a volume capability is required")
+	}
+
+	err := validateAccessTypes([]*csi.VolumeCapability{request.VolumeCapability})
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := s.volumeExists(request.VolumeId)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	} else if !exists {
+		return nil, status.Error(codes.NotFound, "volume "+request.VolumeId+" does not exist")
+	}
+
+	// The volume is already staged.
+	if s.isMounted(request.StagingTargetPath) {
+		return &csi.NodeStageVolumeResponse{}, nil
+	}
+
+	options, err := s.Driver.VolumeDatabase.Options(request.VolumeId)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	if node := options[NodeOption]; node != "" && node != s.NodeID {
+		return nil, status.Error(codes.FailedPrecondition, "volume "+request.VolumeId+" is stored on node "+node)
+	}
+
+	err = s.checkAccess(request.VolumeId, request.VolumeCapability.GetAccessMode().GetMode(), false, "")
+	if err != nil {
+		return nil, err
+	}
+
+	requester := stageRequester(s.NodeID, request.StagingTargetPath)
+	response := s.Driver.Mount(volume.MountRequest{Name: request.VolumeId, ID: requester})
+	if response.Err != "" {
+		return nil, status.Error(codes.Internal, response.Err)
+	}
+
+	err = s.bindMount(response.Mountpoint, request.StagingTargetPath, false)
+	if err != nil {
+		if unmount := s.Driver.Unmount(volume.UnmountRequest{Name: request.VolumeId, ID: requester}); unmount.Err != "" {
+			glog.Error(unmount.Err)
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &csi.NodeStageVolumeResponse{}, nil
+}
+
+// NodeUnstageVolume unmounts the staging path, unmounting the volume from the host once nothing else requires it.
+func (s Server) NodeUnstageVolume(ctx context.Context, request *csi.NodeUnstageVolumeRequest) (*csi.NodeUnstageVolumeResponse, error) {
+	if request.VolumeId == "" || request.StagingTargetPath == "" {
+		return nil, status.Error(codes.InvalidArgument, "a volume ID and staging target path are required")
+	}
+
+	if s.isMounted(request.StagingTargetPath) {
+		if _, err := s.Runner.Run("umount", request.StagingTargetPath); err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	}
+
+	// Only release the mount request if the volume is still staged, so that unstaging twice succeeds.
+	exists, err := s.volumeExists(request.VolumeId)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	} else if !exists {
+		return &csi.NodeUnstageVolumeResponse{}, nil
+	}
+
+	mounts, err := s.Driver.VolumeDatabase.Mounts(request.VolumeId)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	requester := stageRequester(s.NodeID, request.StagingTargetPath)
+	if mounts[requester] > 0 {
+		response := s.Driver.Unmount(volume.UnmountRequest{Name: request.VolumeId, ID: requester})
+		if response.Err != "" {
+			return nil, status.Error(codes.Internal, response.Err)
+		}
+	}
+
+	return &csi.NodeUnstageVolumeResponse{}, nil
+}
+
+// NodePublishVolume bind mounts a staged volume at the target path, read only for the reader only access modes. Each
+// publication is recorded as a mount request so that the access mode can be enforced across hosts.
+func (s Server) NodePublishVolume(ctx context.Context, request *csi.NodePublishVolumeRequest) (*csi.NodePublishVolumeResponse, error) {
+	if request.VolumeId == "" || request.TargetPath == "" {
+		return nil, status.Error(codes.InvalidArgument, "a volume ID and target path are required")
+	}
+
+	if request.StagingTargetPath == "" {
+		return nil, status.Error(codes.InvalidArgument, "a staging target path is required, volumes are staged before they are published")
+	}
+
+	if request.VolumeCapability == nil {
+		return nil, status.Error(codes.InvalidArgument, "a volume capability is required")
+	}
+
+	err := validateAccessTypes([]*csi.VolumeCapability{request.VolumeCapability})
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := s.volumeExists(request.VolumeId)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	} else if !exists {
+		return nil, status.Error(codes.NotFound, "volume "+request.VolumeId+" does not exist")
+	}
+
+	// The volume is already published.
+	if s.isMounted(request.TargetPath) {
+		return &csi.NodePublishVolumeResponse{}, nil
+	}
+
+	mode := request.VolumeCapability.GetAccessMode().GetMode()
+	readOnly := request.Readonly || readOnlyModes[mode]
+	err = s.checkAccess(request.VolumeId, mode, readOnly, request.TargetPath)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.bindMount(request.StagingTargetPath, request.TargetPath, readOnly)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	err = s.Driver.VolumeDatabase.Mount(request.VolumeId, publishRequester(s.NodeID, readOnly, request.TargetPath), request.TargetPath)
+	if err != nil {
+		if _, unmountErr := s.Runner.Run("umount", request.TargetPath); unmountErr != nil {
+			glog.Error(unmountErr)
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &csi.NodePublishVolumeResponse{}, nil
+}
+
+// NodeUnpublishVolume unmounts the target path, removes it, and releases its mount request.
+func (s Server) NodeUnpublishVolume(ctx context.Context, request *csi.NodeUnpublishVolumeRequest) (*csi.NodeUnpublishVolumeResponse, error) {
+	if request.VolumeId == "" || request.TargetPath == "" {
+		return nil, status.Error(codes.InvalidArgument, "a volume ID and target path are required")
+	}
+
+	if s.isMounted(request.TargetPath) {
+		if _, err := s.Runner.Run("umount", request.TargetPath); err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	}
+
+	err := os.Remove(request.TargetPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	exists, err := s.volumeExists(request.VolumeId)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	} else if !exists {
+		return &csi.NodeUnpublishVolumeResponse{}, nil
+	}
+
+	mounts, err := s.Driver.VolumeDatabase.Mounts(request.VolumeId)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	for _, readOnly := range []bool{false, true} {
+		requester := publishRequester(s.NodeID, readOnly, request.TargetPath)
+		if mounts[requester] > 0 {
+			err = s.Driver.VolumeDatabase.Unmount(request.VolumeId, requester)
+			if err != nil {
+				return nil, status.Error(codes.Internal, err.Error())
+			}
+		}
+	}
+
+	return &csi.NodeUnpublishVolumeResponse{}, nil
+}
+
+// NodeGetCapabilities reports that volumes are staged before they are published.
+func (s Server) NodeGetCapabilities(ctx context.Context, request *csi.NodeGetCapabilitiesRequest) (*csi.NodeGetCapabilitiesResponse, error) {
+	return &csi.NodeGetCapabilitiesResponse{
+		Capabilities: []*csi.NodeServiceCapability{
+			{
+				Type: &csi.NodeServiceCapability_Rpc{
+					Rpc: &csi.NodeServiceCapability_RPC{Type: csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME},
+				},
+			},
+		}}, nil
+}
+
+// NodeGetInfo returns the ID of this host and its topology: the host and the fabric segment it is attached to.
+func (s Server) NodeGetInfo(ctx context.Context, request *csi.NodeGetInfoRequest) (*csi.NodeGetInfoResponse, error) {
+	return &csi.NodeGetInfoResponse{NodeId: s.NodeID, AccessibleTopology: s.nodeTopology()}, nil
+}
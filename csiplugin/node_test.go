@@ -0,0 +1,208 @@
+package csiplugin
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNodeLifecycle(t *testing.T) {
+	t.Parallel()
+	server, fake, tempDir := newTestServer(t)
+	defer os.RemoveAll(tempDir)
+
+	_, err := server.CreateVolume(context.Background(), &csi.CreateVolumeRequest{Name: "nodevol", VolumeCapabilities: []*csi.VolumeCapability{mountCapability}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	staging := path.Join(tempDir, "staging")
+	target := path.Join(tempDir, "target")
+
+	// Staging and publishing twice succeeds without mounting twice.
+	for i := 0; i < 2; i++ {
+		_, err = server.NodeStageVolume(context.Background(), &csi.NodeStageVolumeRequest{
+			VolumeId:          "nodevol",
+			StagingTargetPath: staging,
+			VolumeCapability:  mountCapability})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = server.NodePublishVolume(context.Background(), &csi.NodePublishVolumeRequest{
+			VolumeId:          "nodevol",
+			StagingTargetPath: staging,
+			TargetPath:        target,
+			VolumeCapability:  mountCapability,
+			Readonly:          true})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mounts, err := server.Driver.VolumeDatabase.Mounts("nodevol")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(mounts) != 2 || mounts[stageRequester("node1", staging)] != 1 || mounts[publishRequester("node1", true, target)] != 1 {
+		t.Error("The staging and target paths should be the volume's mount requesters, got ", mounts)
+	}
+
+	expected := []string{
+		"mount --bind " + path.Join(tempDir, "volumes", "nodevol") + " " + staging,
+		"mount --bind -o ro " + staging + " " + target,
+	}
+	for _, command := range expected {
+		if !containsString(fake.commands, command) {
+			t.Error("Expected the command ", command, " to be run. Ran: ", fake.commands)
+		}
+	}
+
+	// Unpublishing and unstaging twice succeeds.
+	for i := 0; i < 2; i++ {
+		_, err = server.NodeUnpublishVolume(context.Background(), &csi.NodeUnpublishVolumeRequest{VolumeId: "nodevol", TargetPath: target})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = server.NodeUnstageVolume(context.Background(), &csi.NodeUnstageVolumeRequest{VolumeId: "nodevol", StagingTargetPath: staging})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if fake.mounted[staging] || fake.mounted[target] {
+		t.Error("The staging and target paths should be unmounted, got ", fake.mounted)
+	}
+
+	if _, err = os.Stat(target); !os.IsNotExist(err) {
+		t.Error("The target path should be removed once it is unpublished")
+	}
+
+	mounts, err = server.Driver.VolumeDatabase.Mounts("nodevol")
+	if err != nil || len(mounts) != 0 {
+		t.Error("The volume should no longer be mounted, got ", mounts, err)
+	}
+}
+
+func TestNode_invalid(t *testing.T) {
+	t.Parallel()
+	server, _, tempDir := newTestServer(t)
+	defer os.RemoveAll(tempDir)
+
+	_, err := server.NodeStageVolume(context.Background(), &csi.NodeStageVolumeRequest{
+		VolumeId:          "missing",
+		StagingTargetPath: path.Join(tempDir, "staging"),
+		VolumeCapability:  mountCapability})
+	if status.Code(err) != codes.NotFound {
+		t.Error("Staging a volume that does not exist should fail with NotFound, got ", err)
+	}
+
+	_, err = server.NodeStageVolume(context.Background(), &csi.NodeStageVolumeRequest{VolumeId: "missing", VolumeCapability: mountCapability})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Error("Staging without a staging path should fail with InvalidArgument, got ", err)
+	}
+
+	_, err = server.NodePublishVolume(context.Background(), &csi.NodePublishVolumeRequest{VolumeId: "missing", TargetPath: path.Join(tempDir, "target")})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Error("Publishing without a staging path should fail with InvalidArgument, got ", err)
+	}
+
+	_, err = server.NodeUnpublishVolume(context.Background(), &csi.NodeUnpublishVolumeRequest{VolumeId: "missing"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Error("Unpublishing without a target path should fail with InvalidArgument, got ", err)
+	}
+}
+
+func TestNodeAccessModes(t *testing.T) {
+	t.Parallel()
+	server, _, tempDir := newTestServer(t)
+	defer os.RemoveAll(tempDir)
+
+	_, err := server.CreateVolume(context.Background(), &csi.CreateVolumeRequest{Name: "accessvol", VolumeCapabilities: []*csi.VolumeCapability{mountCapability}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	singleWriter := &csi.VolumeCapability{
+		AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+		AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_SINGLE_WRITER},
+	}
+
+	staging := path.Join(tempDir, "staging")
+	_, err = server.NodeStageVolume(context.Background(), &csi.NodeStageVolumeRequest{VolumeId: "accessvol", StagingTargetPath: staging, VolumeCapability: singleWriter})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publish := func(target string, readOnly bool) error {
+		_, err := server.NodePublishVolume(context.Background(), &csi.NodePublishVolumeRequest{
+			VolumeId:          "accessvol",
+			StagingTargetPath: staging,
+			TargetPath:        path.Join(tempDir, target),
+			VolumeCapability:  singleWriter,
+			Readonly:          readOnly})
+		return err
+	}
+
+	if err = publish("writer1", false); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = publish("writer2", false); status.Code(err) != codes.FailedPrecondition {
+		t.Error("A single writer volume should not be published for writing twice, got ", err)
+	}
+
+	if err = publish("reader1", true); err != nil {
+		t.Error("A single writer volume may be published for reading alongside its writer: ", err)
+	}
+
+	// Once the writer is unpublished, another may take its place.
+	_, err = server.NodeUnpublishVolume(context.Background(), &csi.NodeUnpublishVolumeRequest{VolumeId: "accessvol", TargetPath: path.Join(tempDir, "writer1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = publish("writer2", false); err != nil {
+		t.Error("The volume should be published for writing once the previous writer is gone: ", err)
+	}
+
+	// A host sharing the database may not stage a single node volume that is in use here.
+	other := server
+	other.NodeID = "node2"
+	_, err = other.NodeStageVolume(context.Background(), &csi.NodeStageVolumeRequest{VolumeId: "accessvol", StagingTargetPath: path.Join(tempDir, "other"), VolumeCapability: singleWriter})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Error("A volume stored on node1 should not be staged on node2, got ", err)
+	}
+}
+
+func TestNodeGetInfo(t *testing.T) {
+	t.Parallel()
+	server, _, tempDir := newTestServer(t)
+	defer os.RemoveAll(tempDir)
+
+	info, err := server.NodeGetInfo(context.Background(), &csi.NodeGetInfoRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	segments := info.AccessibleTopology.GetSegments()
+	if info.NodeId != "node1" || segments["rdma.test/host"] != "node1" || segments["rdma.test/fabric"] != "fabric1" {
+		t.Error("Expected node1 on fabric1, got ", info)
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
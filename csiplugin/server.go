@@ -0,0 +1,132 @@
+// Package csiplugin serves the RDMAVolumeDriver over the Container Storage Interface, so that volumes can be used by
+// Kubernetes and Swarm cluster volumes as well as by the Docker volume plugin protocol.
+package csiplugin
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/golang/glog"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers/external"
+	"google.golang.org/grpc"
+)
+
+// Version of the CSI plugin reported by GetPluginInfo.
+const Version = "0.1.0"
+
+// Server implements the CSI Identity, Controller and Node services on top of an RDMAVolumeDriver. Volume IDs are
+// the names of the volumes in the VolumeDatabase.
+type Server struct {
+	csi.UnimplementedIdentityServer
+	csi.UnimplementedControllerServer
+	csi.UnimplementedNodeServer
+
+	// Name of the plugin reported to the container orchestrator, e.g. rdma.docker-volume.io.
+	Name string
+
+	// NodeID identifies this host to the container orchestrator.
+	NodeID string
+
+	// Fabric names the RDMA fabric segment this host is attached to, volumes on fabric storage can be mounted by
+	// any host on the same segment.
+	Fabric string
+
+	Driver drivers.RDMAVolumeDriver
+
+	// Runner runs the commands that bind mount volumes into the paths the container orchestrator asks for.
+	Runner drivers.CommandRunner
+}
+
+// NewServer creates a new Server for driver, using the host name as the node ID when nodeID is empty. The fabric
+// segment is optional.
+func NewServer(name string, nodeID string, fabric string, driver drivers.RDMAVolumeDriver) (Server, error) {
+	if nodeID == "" {
+		var err error
+		nodeID, err = os.Hostname()
+		if err != nil {
+			return Server{}, err
+		}
+	}
+
+	glog.Info("CSI plugin: ", name, " Node: ", nodeID, " Fabric: ", fabric)
+
+	return Server{
+		Name:   name,
+		NodeID: nodeID,
+		Fabric: fabric,
+		Driver: driver,
+		Runner: drivers.ExecCommandRunner{}}, nil
+}
+
+// Serve listens on endpoint, e.g. unix:///run/csi/csi.sock, and serves the CSI services until the listener fails.
+func Serve(endpoint string, server Server) error {
+	listener, err := external.Listen(endpoint)
+	if err != nil {
+		return err
+	}
+
+	grpcServer := grpc.NewServer()
+	csi.RegisterIdentityServer(grpcServer, server)
+	csi.RegisterControllerServer(grpcServer, server)
+	csi.RegisterNodeServer(grpcServer, server)
+
+	glog.Info("Serving CSI on ", endpoint)
+	return grpcServer.Serve(listener)
+}
+
+// volumeExists reports whether a volume is in the VolumeDatabase.
+func (s Server) volumeExists(volumeID string) (bool, error) {
+	volumes, err := s.Driver.VolumeDatabase.List()
+	if err != nil {
+		return false, err
+	}
+
+	for _, volume := range volumes {
+		if volume.Name == volumeID {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// responseError converts the Err of a volume.Response to an error.
+func responseError(errString string) error {
+	if errString == "" {
+		return nil
+	}
+
+	return errors.New(errString)
+}
+
+// sizePattern matches the sizes accepted by the Storage Controllers' size option, e.g. 512M, 10G or 1.5T.
+var sizePattern = regexp.MustCompile(`^([0-9]+(\.[0-9]+)?)([kKmMgGtT]?)$`)
+
+// sizeOption returns the size option for a volume of at least bytes, rounded up to the nearest mebibyte as every
+// Storage Controller accepts sizes in M.
+func sizeOption(bytes int64) string {
+	const mebibyte = 1 << 20
+	return fmt.Sprint((bytes+mebibyte-1)/mebibyte) + "M"
+}
+
+// sizeBytes returns the number of bytes in a size option, or 0 if it is not a size that can be converted.
+func sizeBytes(size string) int64 {
+	match := sizePattern.FindStringSubmatch(size)
+	if match == nil {
+		return 0
+	}
+
+	value, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0
+	}
+
+	units := map[string]float64{"": 1, "k": 1 << 10, "m": 1 << 20, "g": 1 << 30, "t": 1 << 40}
+	return int64(value * units[strings.ToLower(match[3])])
+}
@@ -0,0 +1,153 @@
+package csiplugin
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/mellanox-senior-design/docker-volume-rdma/db"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// fakeMounts pretends to be the mount tools of a host.
+type fakeMounts struct {
+	mounted  map[string]bool
+	commands []string
+}
+
+func (f *fakeMounts) run(name string, args ...string) (string, error) {
+	f.commands = append(f.commands, strings.TrimSpace(name+" "+strings.Join(args, " ")))
+	last := args[len(args)-1]
+
+	switch name {
+	case "mountpoint":
+		if !f.mounted[last] {
+			return "", errors.New(last + " is not a mountpoint")
+		}
+	case "mount":
+		f.mounted[last] = true
+	case "umount":
+		delete(f.mounted, last)
+	}
+
+	return "", nil
+}
+
+// newTestServer creates a Server backed by the on-disk storage controller and an in-memory database in a new
+// temporary directory.
+func newTestServer(t *testing.T) (Server, *fakeMounts, string) {
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-csi")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+
+	driver := drivers.NewRDMAVolumeDriver(drivers.NewOnDiskStorageController(path.Join(tempDir, "volumes")), db.NewInMemoryVolumeDatabase())
+	err = driver.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := NewServer("rdma.test", "node1", "fabric1", driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &fakeMounts{mounted: map[string]bool{}}
+	server.Runner = drivers.CommandRunnerFunc(fake.run)
+
+	return server, fake, tempDir
+}
+
+var mountCapability = &csi.VolumeCapability{
+	AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+	AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER},
+}
+
+func TestServe(t *testing.T) {
+	t.Parallel()
+	server, _, tempDir := newTestServer(t)
+	defer os.RemoveAll(tempDir)
+
+	endpoint := "unix://" + path.Join(tempDir, "csi.sock")
+	go Serve(endpoint, server)
+
+	conn, err := grpc.NewClient(endpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	info, err := csi.NewIdentityClient(conn).GetPluginInfo(context.Background(), &csi.GetPluginInfoRequest{}, grpc.WaitForReady(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Name != "rdma.test" {
+		t.Error("Expected the plugin name rdma.test, got ", info.Name)
+	}
+
+	created, err := csi.NewControllerClient(conn).CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+		Name:               "csivol",
+		VolumeCapabilities: []*csi.VolumeCapability{mountCapability}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if created.Volume.VolumeId != "csivol" {
+		t.Error("Expected the volume ID to be its name, got ", created.Volume.VolumeId)
+	}
+
+	node, err := csi.NewNodeClient(conn).NodeGetInfo(context.Background(), &csi.NodeGetInfoRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if node.NodeId != "node1" {
+		t.Error("Expected the node ID node1, got ", node.NodeId)
+	}
+}
+
+func TestSizes(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		bytes int64
+		size  string
+	}{
+		{1, "1M"},
+		{1 << 20, "1M"},
+		{1<<20 + 1, "2M"},
+		{10 << 30, "10240M"},
+	}
+
+	for _, test := range tests {
+		if size := sizeOption(test.bytes); size != test.size {
+			t.Errorf("sizeOption(%d) = %s; want %s", test.bytes, size, test.size)
+		}
+	}
+
+	var sizes = []struct {
+		size  string
+		bytes int64
+	}{
+		{"10G", 10 << 30},
+		{"512m", 512 << 20},
+		{"1.5T", 3 << 39},
+		{"4096", 4096},
+		{"25%", 0},
+		{"", 0},
+	}
+
+	for _, test := range sizes {
+		if bytes := sizeBytes(test.size); bytes != test.bytes {
+			t.Errorf("sizeBytes(%s) = %d; want %d", test.size, bytes, test.bytes)
+		}
+	}
+}
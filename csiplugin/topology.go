@@ -0,0 +1,73 @@
+package csiplugin
+
+import (
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+)
+
+// NodeOption is the volume option recording the host a volume was created on, for Storage Controllers whose volumes
+// can only be mounted on that host.
+const NodeOption = "csi-node"
+
+// hostKey is the topology segment naming the host.
+func (s Server) hostKey() string {
+	return s.Name + "/host"
+}
+
+// fabricKey is the topology segment naming the RDMA fabric segment the host is attached to.
+func (s Server) fabricKey() string {
+	return s.Name + "/fabric"
+}
+
+// nodeTopology returns the topology of this host.
+func (s Server) nodeTopology() *csi.Topology {
+	segments := map[string]string{s.hostKey(): s.NodeID}
+	if s.Fabric != "" {
+		segments[s.fabricKey()] = s.Fabric
+	}
+
+	return &csi.Topology{Segments: segments}
+}
+
+// volumeTopology returns where the volumes of a Storage Controller can be mounted: anywhere on the fabric segment for
+// fabric storage, otherwise only on this host.
+func (s Server) volumeTopology(storageController drivers.StorageController) []*csi.Topology {
+	if _, ok := storageController.(drivers.FabricStorage); !ok {
+		return []*csi.Topology{s.nodeTopology()}
+	}
+
+	if s.Fabric == "" {
+		return nil
+	}
+
+	return []*csi.Topology{{Segments: map[string]string{s.fabricKey(): s.Fabric}}}
+}
+
+// compatible reports whether a topology satisfies a requirement, every segment they share must match.
+func compatible(requirement *csi.Topology, topology *csi.Topology) bool {
+	for key, value := range topology.GetSegments() {
+		if required, exists := requirement.GetSegments()[key]; exists && required != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// accessible reports whether volumes with the given topology satisfy at least one of the requisite topologies.
+// Volumes without a topology are accessible everywhere.
+func accessible(requisite []*csi.Topology, topology []*csi.Topology) bool {
+	if len(requisite) == 0 || len(topology) == 0 {
+		return true
+	}
+
+	for _, requirement := range requisite {
+		for _, segment := range topology {
+			if compatible(requirement, segment) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
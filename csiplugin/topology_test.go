@@ -0,0 +1,54 @@
+package csiplugin
+
+import (
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+)
+
+func TestVolumeTopology(t *testing.T) {
+	t.Parallel()
+	server := Server{Name: "rdma.test", NodeID: "node1", Fabric: "fabric1"}
+
+	local := server.volumeTopology(drivers.NewOnDiskStorageController("test/topology"))
+	if len(local) != 1 || local[0].Segments["rdma.test/host"] != "node1" {
+		t.Error("Local volumes should only be accessible from this host, got ", local)
+	}
+
+	fabric := server.volumeTopology(drivers.NewGlusterStorageController())
+	if len(fabric) != 1 || fabric[0].Segments["rdma.test/fabric"] != "fabric1" || fabric[0].Segments["rdma.test/host"] != "" {
+		t.Error("Fabric volumes should be accessible from the whole fabric segment, got ", fabric)
+	}
+
+	server.Fabric = ""
+	if anywhere := server.volumeTopology(drivers.NewGlusterStorageController()); anywhere != nil {
+		t.Error("Fabric volumes should be accessible anywhere without a fabric segment, got ", anywhere)
+	}
+}
+
+func TestAccessible(t *testing.T) {
+	t.Parallel()
+
+	node1 := []*csi.Topology{{Segments: map[string]string{"host": "node1", "fabric": "fabric1"}}}
+
+	var tests = []struct {
+		name       string
+		requisite  []*csi.Topology
+		topology   []*csi.Topology
+		accessible bool
+	}{
+		{"no requirements", nil, node1, true},
+		{"no topology", []*csi.Topology{{Segments: map[string]string{"host": "node2"}}}, nil, true},
+		{"same host", []*csi.Topology{{Segments: map[string]string{"host": "node1"}}}, node1, true},
+		{"same fabric", []*csi.Topology{{Segments: map[string]string{"fabric": "fabric1"}}}, node1, true},
+		{"other host", []*csi.Topology{{Segments: map[string]string{"host": "node2", "fabric": "fabric1"}}}, node1, false},
+		{"other fabric", []*csi.Topology{{Segments: map[string]string{"fabric": "fabric2"}}}, node1, false},
+	}
+
+	for _, test := range tests {
+		if accessible(test.requisite, test.topology) != test.accessible {
+			t.Error(test.name, ": expected accessible to be ", test.accessible)
+		}
+	}
+}
@@ -1,6 +1,6 @@
 package db
 
-import "github.com/docker/go-plugins-helpers/volume"
+import "github.com/mellanox-senior-design/docker-volume-rdma/volume"
 
 // VolumeDatabase interface describes a connection to a database that is capable of keeping track of volumes and mounts
 type VolumeDatabase interface {
@@ -21,6 +21,18 @@ type VolumeDatabase interface {
 	// Get info about a particular volume.
 	Get(volumeName string) (*volume.Volume, error)
 
+	// Options returns the options a particular volume was created with.
+	Options(volumeName string) (map[string]string, error)
+
+	// SetOption changes, or adds, one of the options of a particular volume.
+	SetOption(volumeName string, name string, value string) error
+
+	// Labels returns the labels Docker recorded for a particular volume.
+	Labels(volumeName string) (map[string]string, error)
+
+	// SetLabels replaces all of the labels of a particular volume.
+	SetLabels(volumeName string, labels map[string]string) error
+
 	// Get the path of a particular volume.
 	Path(volumeName string) (string, error)
 
@@ -32,4 +44,7 @@ type VolumeDatabase interface {
 
 	// Unmount a particular volume.
 	Unmount(volumeName string, id string) error
+
+	// Mounts returns the IDs requesting a particular volume, and the number of outstanding requests for each ID.
+	Mounts(volumeName string) (map[string]int, error)
 }
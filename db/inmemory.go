@@ -3,14 +3,24 @@ package db
 import (
 	"errors"
 
-	"github.com/docker/go-plugins-helpers/volume"
 	"github.com/golang/glog"
+	"github.com/mellanox-senior-design/docker-volume-rdma/config"
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
 )
 
+func init() {
+	Register("in-memory", VolumeDatabaseFactory{
+		New: func(values config.Values) (VolumeDatabase, error) {
+			return NewInMemoryVolumeDatabase(), nil
+		}})
+}
+
 // InMemoryVolumeDatabase defines a volume database that is completely in memory (and ephimeral)
 type InMemoryVolumeDatabase struct {
 	volumes map[string]*volume.Volume
 	mounts  map[string]map[string]int
+	options map[string]map[string]string
+	labels  map[string]map[string]string
 }
 
 // NewInMemoryVolumeDatabase creates a new InMemoryVolumeDatabase, inilizing all of its properties.
@@ -35,7 +45,9 @@ func NewInMemoryVolumeDatabase() InMemoryVolumeDatabase {
 
 	volumes := map[string]*volume.Volume{}
 	mounts := map[string]map[string]int{}
-	return InMemoryVolumeDatabase{volumes: volumes, mounts: mounts}
+	options := map[string]map[string]string{}
+	labels := map[string]map[string]string{}
+	return InMemoryVolumeDatabase{volumes: volumes, mounts: mounts, options: options, labels: labels}
 }
 
 // Connect is a NOP, though required by VolumeDatabase interface
@@ -68,6 +80,11 @@ func (i InMemoryVolumeDatabase) Create(volumeName string, options map[string]str
 		Mountpoint: "",
 		Status:     nil}
 
+	i.options[volumeName] = map[string]string{}
+	for name, value := range options {
+		i.options[volumeName][name] = value
+	}
+
 	return nil
 }
 
@@ -92,6 +109,62 @@ func (i InMemoryVolumeDatabase) Get(volumeName string) (*volume.Volume, error) {
 	return vol, nil
 }
 
+// Options the specified volume was created with, returning an error if one occured.
+func (i InMemoryVolumeDatabase) Options(volumeName string) (map[string]string, error) {
+	_, err := i.Get(volumeName)
+	if err != nil {
+		return nil, err
+	}
+
+	options := map[string]string{}
+	for name, value := range i.options[volumeName] {
+		options[name] = value
+	}
+
+	return options, nil
+}
+
+// SetOption changes one of the options of the specified volume, returning an error if one occured.
+func (i InMemoryVolumeDatabase) SetOption(volumeName string, name string, value string) error {
+	_, err := i.Get(volumeName)
+	if err != nil {
+		return err
+	}
+
+	i.options[volumeName][name] = value
+	return nil
+}
+
+// Labels of the specified volume, returning an error if one occured.
+func (i InMemoryVolumeDatabase) Labels(volumeName string) (map[string]string, error) {
+	_, err := i.Get(volumeName)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := map[string]string{}
+	for name, value := range i.labels[volumeName] {
+		labels[name] = value
+	}
+
+	return labels, nil
+}
+
+// SetLabels replaces the labels of the specified volume, returning an error if one occured.
+func (i InMemoryVolumeDatabase) SetLabels(volumeName string, labels map[string]string) error {
+	_, err := i.Get(volumeName)
+	if err != nil {
+		return err
+	}
+
+	i.labels[volumeName] = map[string]string{}
+	for name, value := range labels {
+		i.labels[volumeName][name] = value
+	}
+
+	return nil
+}
+
 // Path of the specified volume, returning an error if one occured.
 func (i InMemoryVolumeDatabase) Path(volumeName string) (string, error) {
 	vol, err := i.Get(volumeName)
@@ -124,6 +197,8 @@ func (i InMemoryVolumeDatabase) Remove(volumeName string) error {
 
 	delete(i.volumes, volumeName)
 	delete(i.mounts, volumeName)
+	delete(i.options, volumeName)
+	delete(i.labels, volumeName)
 	return nil
 }
 
@@ -183,3 +258,20 @@ func (i InMemoryVolumeDatabase) Unmount(volumeName string, id string) error {
 	glog.Info(volumeName, " and id ", id, " is now has ", i.mounts[volumeName][id], " connections")
 	return nil
 }
+
+// Mounts returns the ids that are still referencing the specified volume, returning an error if one occured.
+func (i InMemoryVolumeDatabase) Mounts(volumeName string) (map[string]int, error) {
+	_, err := i.Get(volumeName)
+	if err != nil {
+		return nil, err
+	}
+
+	mounts := map[string]int{}
+	for id, count := range i.mounts[volumeName] {
+		if count > 0 {
+			mounts[id] = count
+		}
+	}
+
+	return mounts, nil
+}
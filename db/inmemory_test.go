@@ -326,3 +326,127 @@ func TestInMemUnmount(t *testing.T) {
 		t.Error("Should encounter an error as volume id not mounted")
 	}
 }
+
+func TestInMemMounts(t *testing.T) {
+	t.Parallel()
+	im := NewInMemoryVolumeDatabase()
+
+	_, err := im.Mounts("Non-existing-Vol")
+	if err == nil {
+		t.Error("Should not be able to list the mounts of a volume that does not exist")
+	}
+
+	err = im.Create("MusicFiles", nil)
+	if err != nil {
+		t.Fatal("Error obtained while attempting to create volume", err)
+	}
+
+	mounts, err := im.Mounts("MusicFiles")
+	assert.Nil(t, err)
+	assert.Empty(t, mounts)
+
+	assert.Nil(t, im.Mount("MusicFiles", "42", "/mnt/sure"))
+	assert.Nil(t, im.Mount("MusicFiles", "42", "/mnt/sure"))
+	assert.Nil(t, im.Mount("MusicFiles", "43", "/mnt/sure"))
+
+	mounts, err = im.Mounts("MusicFiles")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"42": 2, "43": 1}, mounts)
+
+	assert.Nil(t, im.Unmount("MusicFiles", "43"))
+
+	mounts, err = im.Mounts("MusicFiles")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"42": 2}, mounts)
+}
+
+func TestInMemOptions(t *testing.T) {
+	t.Parallel()
+	im := NewInMemoryVolumeDatabase()
+
+	_, err := im.Options("Non-existing-Vol")
+	if err == nil {
+		t.Error("Should not be able to get the options of a volume that does not exist")
+	}
+
+	options := map[string]string{"backend": "fast", "size": "10G"}
+	err = im.Create("MusicFiles", options)
+	if err != nil {
+		t.Fatal("Error obtained while attempting to create volume", err)
+	}
+
+	// Changing the caller's map should not change the saved options.
+	options["size"] = "1G"
+
+	saved, err := im.Options("MusicFiles")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"backend": "fast", "size": "10G"}, saved)
+
+	assert.Nil(t, im.Remove("MusicFiles"))
+	assert.Nil(t, im.Create("MusicFiles", nil))
+
+	saved, err = im.Options("MusicFiles")
+	assert.Nil(t, err)
+	assert.Empty(t, saved)
+}
+
+func TestInMemSetOption(t *testing.T) {
+	t.Parallel()
+	im := NewInMemoryVolumeDatabase()
+
+	err := im.SetOption("Non-existing-Vol", "backend", "fast")
+	if err == nil {
+		t.Error("Should not be able to set an option of a volume that does not exist")
+	}
+
+	assert.Nil(t, im.Create("MusicFiles", map[string]string{"backend": "slow", "size": "10G"}))
+	assert.Nil(t, im.SetOption("MusicFiles", "backend", "fast"))
+	assert.Nil(t, im.SetOption("MusicFiles", "fs", "xfs"))
+
+	saved, err := im.Options("MusicFiles")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"backend": "fast", "size": "10G", "fs": "xfs"}, saved)
+}
+
+func TestInMemLabels(t *testing.T) {
+	t.Parallel()
+	im := NewInMemoryVolumeDatabase()
+
+	_, err := im.Labels("Non-existing-Vol")
+	if err == nil {
+		t.Error("Should not be able to get the labels of a volume that does not exist")
+	}
+
+	if err = im.SetLabels("Non-existing-Vol", map[string]string{"team": "ml"}); err == nil {
+		t.Error("Should not be able to set the labels of a volume that does not exist")
+	}
+
+	assert.Nil(t, im.Create("MusicFiles", nil))
+
+	labels, err := im.Labels("MusicFiles")
+	assert.Nil(t, err)
+	assert.Empty(t, labels)
+
+	set := map[string]string{"team": "ml", "env": "prod"}
+	assert.Nil(t, im.SetLabels("MusicFiles", set))
+
+	// Changing the caller's map should not change the saved labels.
+	set["env"] = "dev"
+
+	labels, err = im.Labels("MusicFiles")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"team": "ml", "env": "prod"}, labels)
+
+	// Labels are replaced, not merged.
+	assert.Nil(t, im.SetLabels("MusicFiles", map[string]string{"team": "web"}))
+	labels, err = im.Labels("MusicFiles")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"team": "web"}, labels)
+
+	assert.Nil(t, im.Remove("MusicFiles"))
+	assert.Nil(t, im.Create("MusicFiles", nil))
+
+	labels, err = im.Labels("MusicFiles")
+	assert.Nil(t, err)
+	assert.Empty(t, labels)
+}
@@ -7,8 +7,23 @@ import (
 	// Allows connecting to mysql
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/golang/glog"
+	"github.com/mellanox-senior-design/docker-volume-rdma/config"
 )
 
+func init() {
+	Register("mysql", VolumeDatabaseFactory{
+		Options: []config.Option{
+			{Name: "dbhost", Description: "set the database host (default is '' or localhost:3306)"},
+			{Name: "dbuser", Description: "set the database username (default is root)"},
+			{Name: "dbpass", Description: "set the database password (optional)"},
+			{Name: "dbschema", Description: "set the database schema (required)"},
+		},
+		Lenient: true,
+		New: func(values config.Values) (VolumeDatabase, error) {
+			return NewMySQLVolumeDatabase(values.String("dbhost"), values.String("dbuser"), values.String("dbpass"), values.String("dbschema"))
+		}})
+}
+
 // NewMySQLVolumeDatabase creates a new SQLVolumeDatabase, connectiong to a mysql host.
 func NewMySQLVolumeDatabase(host string, username string, password string, schema string) (SQLVolumeDatabase, error) {
 	var sqlVolumeDatabase SQLVolumeDatabase
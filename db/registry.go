@@ -0,0 +1,59 @@
+package db
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/config"
+)
+
+// VolumeDatabaseFactory describes a kind of Volume Database: the settings it accepts and how to create one.
+type VolumeDatabaseFactory struct {
+	// Options are the settings the Volume Database accepts, their names are also the command line flags.
+	Options []config.Option
+
+	// Lenient Volume Databases only warn about unsupported settings, rather than refusing to start.
+	Lenient bool
+
+	// New creates a Volume Database configured with values.
+	New func(values config.Values) (VolumeDatabase, error)
+}
+
+var registryMutex sync.RWMutex
+var registry = map[string]VolumeDatabaseFactory{}
+
+// Register makes a kind of Volume Database available by name, e.g. for use with -db=name.
+// Register panics if it is called twice with the same name.
+func Register(name string, factory VolumeDatabaseFactory) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	if _, exists := registry[name]; exists {
+		panic("db: Register called twice for volume database " + name)
+	}
+
+	registry[name] = factory
+}
+
+// Lookup returns the factory of the Volume Database registered as name.
+func Lookup(name string) (VolumeDatabaseFactory, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	factory, exists := registry[name]
+	return factory, exists
+}
+
+// Registered returns the names of all registered Volume Databases in sorted order.
+func Registered() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,32 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistered(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{"in-memory", "mysql", "sqlite"}, Registered())
+
+	factory, exists := Lookup("in-memory")
+	assert.True(t, exists)
+
+	database, err := factory.New(config.NewValues(factory.Options, map[string]string{}))
+	assert.Nil(t, err)
+	assert.IsType(t, InMemoryVolumeDatabase{}, database)
+
+	_, exists = Lookup("postgres")
+	assert.False(t, exists)
+}
+
+func TestRegisterTwice(t *testing.T) {
+	t.Parallel()
+
+	assert.Panics(t, func() {
+		Register("sqlite", VolumeDatabaseFactory{})
+	})
+}
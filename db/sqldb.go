@@ -4,8 +4,8 @@ import (
 	"database/sql"
 	"errors"
 
-	"github.com/docker/go-plugins-helpers/volume"
 	"github.com/golang/glog"
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
 )
 
 var sqlDB *sql.DB
@@ -34,6 +34,19 @@ type VolumeDatabaseQueries struct {
 	mountsUpdateCountByVolumeIDAndRequesterSQL  string
 	mountsDeleteByVolumeIDSQL                   string
 	mountsDeleteByVolumeIDAndRequesterSQL       string
+
+	// Volume options SQL statements
+	optionsCreateTableSQL             string
+	optionsInsertSQL                  string
+	optionsGetByVolumeNameListSQL     string
+	optionsDeleteByVolumeIDSQL        string
+	optionsDeleteByVolumeIDAndNameSQL string
+
+	// Volume labels SQL statements
+	labelsCreateTableSQL         string
+	labelsInsertSQL              string
+	labelsGetByVolumeNameListSQL string
+	labelsDeleteByVolumeIDSQL    string
 }
 
 // DefaultSQLQueries stores the default SQL functions for sqldbs to use.
@@ -61,6 +74,27 @@ var DefaultSQLQueries = VolumeDatabaseQueries{
 	mountsUpdateCountByVolumeIDAndRequesterSQL:  "UPDATE mounts SET count=? WHERE volume_id = ? and requester_id = ?;",
 	mountsDeleteByVolumeIDSQL:                   "DELETE FROM mounts WHERE volume_id = ?;",
 	mountsDeleteByVolumeIDAndRequesterSQL:       "DELETE FROM mounts WHERE volume_id = ? AND requester_id = ?;",
+
+	// Volume options SQL statements
+	optionsCreateTableSQL: `CREATE TABLE IF NOT EXISTS volume_options (
+        volume_id INTEGER NOT NULL,
+        name VARCHAR(256) NOT NULL,
+        value TEXT
+    );`,
+	optionsInsertSQL:                  "INSERT INTO volume_options(volume_id, name, value) VALUES (?, ?, ?);",
+	optionsGetByVolumeNameListSQL:     "SELECT volume_options.name, volume_options.value FROM volume_options JOIN volumes ON volumes.id = volume_options.volume_id WHERE volumes.name = ?;",
+	optionsDeleteByVolumeIDSQL:        "DELETE FROM volume_options WHERE volume_id = ?;",
+	optionsDeleteByVolumeIDAndNameSQL: "DELETE FROM volume_options WHERE volume_id = ? AND name = ?;",
+
+	// Volume labels SQL statements
+	labelsCreateTableSQL: `CREATE TABLE IF NOT EXISTS volume_labels (
+        volume_id INTEGER NOT NULL,
+        name VARCHAR(256) NOT NULL,
+        value TEXT
+    );`,
+	labelsInsertSQL:              "INSERT INTO volume_labels(volume_id, name, value) VALUES (?, ?, ?);",
+	labelsGetByVolumeNameListSQL: "SELECT volume_labels.name, volume_labels.value FROM volume_labels JOIN volumes ON volumes.id = volume_labels.volume_id WHERE volumes.name = ?;",
+	labelsDeleteByVolumeIDSQL:    "DELETE FROM volume_labels WHERE volume_id = ?;",
 }
 
 // NewSQLVolumeDatabase creates a new SQLVolumeDatabase, saving the database at dbPath.
@@ -107,6 +141,22 @@ func (s SQLVolumeDatabase) Connect() error {
 		return err
 	}
 
+	// Create the volume options table, this will hold the options each volume was created with
+	glog.Info(s.DBQueries.optionsCreateTableSQL)
+	_, err = sqlDB.Exec(s.DBQueries.optionsCreateTableSQL)
+	if err != nil {
+		glog.Error(err, ": ", s.DBQueries.optionsCreateTableSQL)
+		return err
+	}
+
+	// Create the volume labels table, this will hold the labels Docker recorded for each volume
+	glog.Info(s.DBQueries.labelsCreateTableSQL)
+	_, err = sqlDB.Exec(s.DBQueries.labelsCreateTableSQL)
+	if err != nil {
+		glog.Error(err, ": ", s.DBQueries.labelsCreateTableSQL)
+		return err
+	}
+
 	glog.Info("Connected to db.")
 	return nil
 }
@@ -156,12 +206,36 @@ func (s SQLVolumeDatabase) Create(volumeName string, options map[string]string)
 	defer preparedStatement.Close()
 
 	// Actually make the insert
-	_, err = preparedStatement.Exec(volumeName)
+	result, err := preparedStatement.Exec(volumeName)
 	if err != nil {
 		transaction.Rollback()
 		return err
 	}
 
+	// Save the options the volume was created with
+	if len(options) > 0 {
+		id, err := result.LastInsertId()
+		if err != nil {
+			transaction.Rollback()
+			return err
+		}
+
+		optionsPreparedStatement, err := transaction.Prepare(s.DBQueries.optionsInsertSQL)
+		if err != nil {
+			transaction.Rollback()
+			return err
+		}
+		defer optionsPreparedStatement.Close()
+
+		for name, value := range options {
+			_, err = optionsPreparedStatement.Exec(id, name, value)
+			if err != nil {
+				transaction.Rollback()
+				return err
+			}
+		}
+	}
+
 	// Commit the change
 	return transaction.Commit()
 }
@@ -329,6 +403,18 @@ func (s SQLVolumeDatabase) Remove(volumeName string) error {
 	}
 	defer mountsPreparedStatement.Close()
 
+	optionsPreparedStatement, err := transaction.Prepare(s.DBQueries.optionsDeleteByVolumeIDSQL)
+	if err != nil {
+		return err
+	}
+	defer optionsPreparedStatement.Close()
+
+	labelsPreparedStatement, err := transaction.Prepare(s.DBQueries.labelsDeleteByVolumeIDSQL)
+	if err != nil {
+		return err
+	}
+	defer labelsPreparedStatement.Close()
+
 	volumesPreparedStatement, err := transaction.Prepare(s.DBQueries.volumesDeleteByIDSQL)
 	if err != nil {
 		return err
@@ -342,6 +428,18 @@ func (s SQLVolumeDatabase) Remove(volumeName string) error {
 		return err
 	}
 
+	_, err = optionsPreparedStatement.Exec(id)
+	if err != nil {
+		transaction.Rollback()
+		return err
+	}
+
+	_, err = labelsPreparedStatement.Exec(id)
+	if err != nil {
+		transaction.Rollback()
+		return err
+	}
+
 	_, err = volumesPreparedStatement.Exec(id)
 	if err != nil {
 		transaction.Rollback()
@@ -488,6 +586,205 @@ func (s SQLVolumeDatabase) Unmount(volumeName string, id string) error {
 	return transaction.Commit()
 }
 
+// Mounts returns the IDs requesting the volume to be mounted and number of requests outstanding for each id.
+func (s SQLVolumeDatabase) Mounts(volumeName string) (map[string]int, error) {
+	if err := s.VerifyOrCrash(); err != nil {
+		return nil, err
+	}
+
+	mounts, _, err := s.listMounts(volumeName)
+	return mounts, err
+}
+
+// Options returns the options a volume was created with.
+func (s SQLVolumeDatabase) Options(volumeName string) (map[string]string, error) {
+	// Ensure that the volume exists, so that a missing volume is not mistaken for one without options.
+	_, err := s.getVolumeIDByName(volumeName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Prepare the query
+	preparedStatement, err := sqlDB.Prepare(s.DBQueries.optionsGetByVolumeNameListSQL)
+	if err != nil {
+		return nil, err
+	}
+	defer preparedStatement.Close()
+
+	// Query the database about the options
+	rows, err := preparedStatement.Query(volumeName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Itterate over all of the rows creating the map of options.
+	options := map[string]string{}
+	for rows.Next() {
+		var name string
+		var valueNS sql.NullString
+		err = rows.Scan(&name, &valueNS)
+		if err != nil {
+			return nil, err
+		}
+
+		options[name] = valueNS.String
+	}
+
+	// Check to see if there was an error durring interation
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return options, nil
+}
+
+// SetOption replaces one of the options of a volume in a single transaction, so that readers see either the old
+// value or the new one.
+func (s SQLVolumeDatabase) SetOption(volumeName string, name string, value string) error {
+	if err := s.VerifyOrCrash(); err != nil {
+		return err
+	}
+
+	id, err := s.getVolumeIDByName(volumeName)
+	if err != nil {
+		return err
+	}
+
+	// Begin transaction to the database
+	transaction, err := sqlDB.Begin()
+	if err != nil {
+		return err
+	}
+
+	// Prepare the queries
+	deletePreparedStatement, err := transaction.Prepare(s.DBQueries.optionsDeleteByVolumeIDAndNameSQL)
+	if err != nil {
+		transaction.Rollback()
+		return err
+	}
+	defer deletePreparedStatement.Close()
+
+	insertPreparedStatement, err := transaction.Prepare(s.DBQueries.optionsInsertSQL)
+	if err != nil {
+		transaction.Rollback()
+		return err
+	}
+	defer insertPreparedStatement.Close()
+
+	// Replace the option
+	_, err = deletePreparedStatement.Exec(id, name)
+	if err != nil {
+		transaction.Rollback()
+		return err
+	}
+
+	_, err = insertPreparedStatement.Exec(id, name, value)
+	if err != nil {
+		transaction.Rollback()
+		return err
+	}
+
+	// Commit the change
+	return transaction.Commit()
+}
+
+// Labels returns the labels Docker recorded for a volume.
+func (s SQLVolumeDatabase) Labels(volumeName string) (map[string]string, error) {
+	// Ensure that the volume exists, so that a missing volume is not mistaken for one without labels.
+	_, err := s.getVolumeIDByName(volumeName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Prepare the query
+	preparedStatement, err := sqlDB.Prepare(s.DBQueries.labelsGetByVolumeNameListSQL)
+	if err != nil {
+		return nil, err
+	}
+	defer preparedStatement.Close()
+
+	// Query the database about the labels
+	rows, err := preparedStatement.Query(volumeName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Itterate over all of the rows creating the map of labels.
+	labels := map[string]string{}
+	for rows.Next() {
+		var name string
+		var valueNS sql.NullString
+		err = rows.Scan(&name, &valueNS)
+		if err != nil {
+			return nil, err
+		}
+
+		labels[name] = valueNS.String
+	}
+
+	// Check to see if there was an error durring interation
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return labels, nil
+}
+
+// SetLabels replaces all of the labels of a volume in a single transaction.
+func (s SQLVolumeDatabase) SetLabels(volumeName string, labels map[string]string) error {
+	if err := s.VerifyOrCrash(); err != nil {
+		return err
+	}
+
+	id, err := s.getVolumeIDByName(volumeName)
+	if err != nil {
+		return err
+	}
+
+	// Begin transaction to the database
+	transaction, err := sqlDB.Begin()
+	if err != nil {
+		return err
+	}
+
+	// Prepare the queries
+	deletePreparedStatement, err := transaction.Prepare(s.DBQueries.labelsDeleteByVolumeIDSQL)
+	if err != nil {
+		transaction.Rollback()
+		return err
+	}
+	defer deletePreparedStatement.Close()
+
+	insertPreparedStatement, err := transaction.Prepare(s.DBQueries.labelsInsertSQL)
+	if err != nil {
+		transaction.Rollback()
+		return err
+	}
+	defer insertPreparedStatement.Close()
+
+	// Replace the labels
+	_, err = deletePreparedStatement.Exec(id)
+	if err != nil {
+		transaction.Rollback()
+		return err
+	}
+
+	for name, value := range labels {
+		_, err = insertPreparedStatement.Exec(id, name, value)
+		if err != nil {
+			transaction.Rollback()
+			return err
+		}
+	}
+
+	// Commit the change
+	return transaction.Commit()
+}
+
 // listMounts returns of all the IDs requesting the volume to be mounted and number of requests outstanding for that id.
 func (s SQLVolumeDatabase) listMounts(volumeName string) (map[string]int, int, error) {
 
@@ -569,6 +866,19 @@ func (d VolumeDatabaseQueries) merge(defaults VolumeDatabaseQueries) VolumeDatab
 		mountsUpdateCountByVolumeIDAndRequesterSQL:  update(d.mountsUpdateCountByVolumeIDAndRequesterSQL, defaults.mountsUpdateCountByVolumeIDAndRequesterSQL),
 		mountsDeleteByVolumeIDSQL:                   update(d.mountsDeleteByVolumeIDSQL, defaults.mountsDeleteByVolumeIDSQL),
 		mountsDeleteByVolumeIDAndRequesterSQL:       update(d.mountsDeleteByVolumeIDAndRequesterSQL, defaults.mountsDeleteByVolumeIDAndRequesterSQL),
+
+		// Volume options SQL statements
+		optionsCreateTableSQL:             update(d.optionsCreateTableSQL, defaults.optionsCreateTableSQL),
+		optionsInsertSQL:                  update(d.optionsInsertSQL, defaults.optionsInsertSQL),
+		optionsGetByVolumeNameListSQL:     update(d.optionsGetByVolumeNameListSQL, defaults.optionsGetByVolumeNameListSQL),
+		optionsDeleteByVolumeIDSQL:        update(d.optionsDeleteByVolumeIDSQL, defaults.optionsDeleteByVolumeIDSQL),
+		optionsDeleteByVolumeIDAndNameSQL: update(d.optionsDeleteByVolumeIDAndNameSQL, defaults.optionsDeleteByVolumeIDAndNameSQL),
+
+		// Volume labels SQL statements
+		labelsCreateTableSQL:         update(d.labelsCreateTableSQL, defaults.labelsCreateTableSQL),
+		labelsInsertSQL:              update(d.labelsInsertSQL, defaults.labelsInsertSQL),
+		labelsGetByVolumeNameListSQL: update(d.labelsGetByVolumeNameListSQL, defaults.labelsGetByVolumeNameListSQL),
+		labelsDeleteByVolumeIDSQL:    update(d.labelsDeleteByVolumeIDSQL, defaults.labelsDeleteByVolumeIDSQL),
 	}
 
 }
@@ -46,6 +46,19 @@ func TestNewSQLVolumeDatabase_modifications(t *testing.T) {
 		mountsUpdateCountByVolumeIDAndRequesterSQL:  "j",
 		mountsDeleteByVolumeIDSQL:                   "k",
 		mountsDeleteByVolumeIDAndRequesterSQL:       "l",
+
+		// Volume options SQL statements
+		optionsCreateTableSQL:             "m",
+		optionsInsertSQL:                  "n",
+		optionsGetByVolumeNameListSQL:     "o",
+		optionsDeleteByVolumeIDSQL:        "p",
+		optionsDeleteByVolumeIDAndNameSQL: "q",
+
+		// Volume labels SQL statements
+		labelsCreateTableSQL:         "r",
+		labelsInsertSQL:              "s",
+		labelsGetByVolumeNameListSQL: "t",
+		labelsDeleteByVolumeIDSQL:    "u",
 	}
 
 	foo := NewSQLVolumeDatabase("type", "datasource", queries)
@@ -121,6 +134,29 @@ func TestValidateOrCrash(t *testing.T) {
 		{"Unmount", func() error {
 			return volumeDatabase.Unmount("volumeName", "id")
 		}},
+
+		{"Mounts", func() error {
+			_, err := volumeDatabase.Mounts("volumeName")
+			return err
+		}},
+
+		{"Options", func() error {
+			_, err := volumeDatabase.Options("volumeName")
+			return err
+		}},
+
+		{"SetOption", func() error {
+			return volumeDatabase.SetOption("volumeName", "backend", "fast")
+		}},
+
+		{"Labels", func() error {
+			_, err := volumeDatabase.Labels("volumeName")
+			return err
+		}},
+
+		{"SetLabels", func() error {
+			return volumeDatabase.SetLabels("volumeName", map[string]string{"team": "ml"})
+		}},
 	}
 	for _, test := range tests {
 		if err := test.f(); err == nil {
@@ -151,6 +187,43 @@ func TestCreate_trivial(t *testing.T) {
 	}
 }
 
+func TestCreate_options(t *testing.T) {
+	createSQL := `[INSERT INTO volumes(name) VALUES (?);]`
+	optionsSQL := `[INSERT INTO volume_options(volume_id, name, value) VALUES (?, ?, ?);]`
+
+	db, mock, volumeDatabase := createMockVolumeDatabase(t)
+	defer db.Close()
+
+	// Configure Mock
+	mock.ExpectBegin()
+	prepared := mock.ExpectPrepare(createSQL)
+	prepared.ExpectExec().WithArgs("volume_name").WillReturnResult(sqlmock.NewResult(7, 1))
+	optionsPrepared := mock.ExpectPrepare(optionsSQL)
+	optionsPrepared.ExpectExec().WithArgs(7, "backend", "fast").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if err := volumeDatabase.Create("volume_name", map[string]string{"backend": "fast"}); err != nil {
+		t.Errorf("error was not expected while creating volume: %s", err)
+	}
+
+	// Configure Mock
+	mock.ExpectBegin()
+	prepared = mock.ExpectPrepare(createSQL)
+	prepared.ExpectExec().WithArgs("volume_name").WillReturnResult(sqlmock.NewResult(8, 1))
+	optionsPrepared = mock.ExpectPrepare(optionsSQL)
+	optionsPrepared.ExpectExec().WithArgs(8, "backend", "fast").WillReturnError(errors.New("ExampleError"))
+	mock.ExpectRollback()
+
+	if err := volumeDatabase.Create("volume_name", map[string]string{"backend": "fast"}); err == nil {
+		t.Error("error was expected while saving the volume options")
+	}
+
+	// we make sure that all expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
 func TestCreate_badNoName(t *testing.T) {
 	t.Parallel()
 
@@ -539,10 +612,14 @@ func TestSQLRemove(t *testing.T) {
 	handleListMounts(mock, false, false, "", "", rRows)
 	handleGetVolumeByName(mock, false, false, "", "", rRows)
 
+	optQuery := `[DELETE FROM volume_options WHERE volume_id = ?;]`
+	labelQuery := `[DELETE FROM volume_labels WHERE volume_id = ?;]`
 	volQuery := `[DELETE FROM volumes WHERE id = ?;]`
 
 	mock.ExpectBegin()
 	mountPrep = mock.ExpectPrepare(mountQuery)
+	optPrep := mock.ExpectPrepare(optQuery)
+	labelPrep := mock.ExpectPrepare(labelQuery)
 	volPrep := mock.ExpectPrepare(volQuery).WillReturnError(errors.New("volprep err"))
 
 	err = volDB.Remove("aventura_vol")
@@ -558,6 +635,8 @@ func TestSQLRemove(t *testing.T) {
 
 	mock.ExpectBegin()
 	mountPrep = mock.ExpectPrepare(mountQuery)
+	optPrep = mock.ExpectPrepare(optQuery)
+	labelPrep = mock.ExpectPrepare(labelQuery)
 	volPrep = mock.ExpectPrepare(volQuery)
 	mountPrep.ExpectExec().WithArgs(42).WillReturnError(errors.New("mnt delete err"))
 	mock.ExpectRollback()
@@ -575,8 +654,32 @@ func TestSQLRemove(t *testing.T) {
 
 	mock.ExpectBegin()
 	mountPrep = mock.ExpectPrepare(mountQuery)
+	optPrep = mock.ExpectPrepare(optQuery)
+	labelPrep = mock.ExpectPrepare(labelQuery)
 	volPrep = mock.ExpectPrepare(volQuery)
 	mountPrep.ExpectExec().WithArgs(42).WillReturnResult(sqlmock.NewResult(1, 1))
+	optPrep.ExpectExec().WithArgs(42).WillReturnError(errors.New("opt delete err"))
+	mock.ExpectRollback()
+
+	err = volDB.Remove("aventura_vol")
+
+	if err == nil {
+		t.Error("we should have gotten an error from database")
+	} else if err.Error() != "opt delete err" {
+		t.Error("did not receive the expected error, instead : ", err)
+	}
+
+	handleListMounts(mock, false, false, "", "", rRows)
+	handleGetVolumeByName(mock, false, false, "", "", rRows)
+
+	mock.ExpectBegin()
+	mountPrep = mock.ExpectPrepare(mountQuery)
+	optPrep = mock.ExpectPrepare(optQuery)
+	labelPrep = mock.ExpectPrepare(labelQuery)
+	volPrep = mock.ExpectPrepare(volQuery)
+	mountPrep.ExpectExec().WithArgs(42).WillReturnResult(sqlmock.NewResult(1, 1))
+	optPrep.ExpectExec().WithArgs(42).WillReturnResult(sqlmock.NewResult(1, 1))
+	labelPrep.ExpectExec().WithArgs(42).WillReturnResult(sqlmock.NewResult(1, 1))
 	volPrep.ExpectExec().WithArgs(42).WillReturnError(errors.New("vol delete err"))
 	mock.ExpectRollback()
 
@@ -593,8 +696,12 @@ func TestSQLRemove(t *testing.T) {
 
 	mock.ExpectBegin()
 	mountPrep = mock.ExpectPrepare(mountQuery)
+	optPrep = mock.ExpectPrepare(optQuery)
+	labelPrep = mock.ExpectPrepare(labelQuery)
 	volPrep = mock.ExpectPrepare(volQuery)
 	mountPrep.ExpectExec().WithArgs(42).WillReturnResult(sqlmock.NewResult(1, 1))
+	optPrep.ExpectExec().WithArgs(42).WillReturnResult(sqlmock.NewResult(1, 1))
+	labelPrep.ExpectExec().WithArgs(42).WillReturnResult(sqlmock.NewResult(1, 1))
 	volPrep.ExpectExec().WithArgs(42).WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
@@ -756,3 +863,197 @@ func TestSQLUnmount(t *testing.T) {
 		t.Errorf("there were unfulfilled expections: %s", err)
 	}
 }
+
+func TestSQLMounts(t *testing.T) {
+	db, mock, volDB := createMockVolumeDatabase(t)
+	defer db.Close()
+
+	rRows := []responseRows{
+		{id: "42", name: "aventura_vol", mountpoint: "/etc/mnt/", requester: "42", count: 2},
+	}
+
+	handleListMounts(mock, false, false, "", "", rRows)
+
+	mounts, err := volDB.Mounts("aventura_vol")
+	if err != nil {
+		t.Error("error encountered while listing mounts: ", err)
+	}
+
+	if len(mounts) != 1 || mounts["42"] != 2 {
+		t.Error("expected requester 42 to have 2 mounts, instead got: ", mounts)
+	}
+
+	rRows = []responseRows{
+		{id: "9", name: "music_vol"},
+	}
+
+	handleListMounts(mock, false, true, "", "list error", rRows)
+
+	_, err = volDB.Mounts("music_vol")
+	if err == nil {
+		t.Error("we should get an error when the mounts cannot be listed")
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func TestSQLOptions(t *testing.T) {
+	db, mock, volDB := createMockVolumeDatabase(t)
+	defer db.Close()
+
+	query := `[SELECT volume_options.name, volume_options.value FROM volume_options JOIN volumes ON volumes.id = volume_options.volume_id WHERE volumes.name = ?;]`
+
+	rRows := []responseRows{
+		{id: "42", name: "aventura_vol"},
+	}
+
+	handleGetVolumeByName(mock, false, false, "", "", rRows)
+	prepare := mock.ExpectPrepare(query)
+	prepare.ExpectQuery().WithArgs("aventura_vol").WillReturnRows(sqlmock.NewRows([]string{"name", "value"}).
+		AddRow("backend", "fast").
+		AddRow("size", "10G"))
+
+	options, err := volDB.Options("aventura_vol")
+	if err != nil {
+		t.Error("error encountered while getting options: ", err)
+	}
+
+	if len(options) != 2 || options["backend"] != "fast" || options["size"] != "10G" {
+		t.Error("unexpected options: ", options)
+	}
+
+	handleGetVolumeByName(mock, false, false, "", "", nil)
+
+	_, err = volDB.Options("missing_vol")
+	if err == nil {
+		t.Error("we should get an error when getting the options of a volume that does not exist")
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func TestSQLSetOption(t *testing.T) {
+	db, mock, volDB := createMockVolumeDatabase(t)
+	defer db.Close()
+
+	rRows := []responseRows{
+		{id: "42", name: "aventura_vol"},
+	}
+
+	handleGetVolumeByName(mock, false, false, "", "", rRows)
+	mock.ExpectBegin()
+	deletePrepare := mock.ExpectPrepare(`[DELETE FROM volume_options WHERE volume_id = ? AND name = ?;]`)
+	insertPrepare := mock.ExpectPrepare(`[INSERT INTO volume_options(volume_id, name, value) VALUES (?, ?, ?);]`)
+	deletePrepare.ExpectExec().WithArgs(42, "backend").WillReturnResult(sqlmock.NewResult(0, 1))
+	insertPrepare.ExpectExec().WithArgs(42, "backend", "fast").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := volDB.SetOption("aventura_vol", "backend", "fast"); err != nil {
+		t.Error("error encountered while setting an option: ", err)
+	}
+
+	handleGetVolumeByName(mock, false, false, "", "", rRows)
+	mock.ExpectBegin()
+	deletePrepare = mock.ExpectPrepare(`[DELETE FROM volume_options WHERE volume_id = ? AND name = ?;]`)
+	mock.ExpectPrepare(`[INSERT INTO volume_options(volume_id, name, value) VALUES (?, ?, ?);]`)
+	deletePrepare.ExpectExec().WillReturnError(errors.New("delete error"))
+	mock.ExpectRollback()
+
+	if err := volDB.SetOption("aventura_vol", "backend", "fast"); err == nil {
+		t.Error("we should get an error when the old option cannot be deleted")
+	}
+
+	handleGetVolumeByName(mock, false, false, "", "", nil)
+
+	if err := volDB.SetOption("missing_vol", "backend", "fast"); err == nil {
+		t.Error("we should get an error when setting an option of a volume that does not exist")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func TestSQLLabels(t *testing.T) {
+	db, mock, volDB := createMockVolumeDatabase(t)
+	defer db.Close()
+
+	query := `[SELECT volume_labels.name, volume_labels.value FROM volume_labels JOIN volumes ON volumes.id = volume_labels.volume_id WHERE volumes.name = ?;]`
+
+	rRows := []responseRows{
+		{id: "42", name: "aventura_vol"},
+	}
+
+	handleGetVolumeByName(mock, false, false, "", "", rRows)
+	prepare := mock.ExpectPrepare(query)
+	prepare.ExpectQuery().WithArgs("aventura_vol").WillReturnRows(sqlmock.NewRows([]string{"name", "value"}).
+		AddRow("team", "ml").
+		AddRow("env", "prod"))
+
+	labels, err := volDB.Labels("aventura_vol")
+	if err != nil {
+		t.Error("error encountered while getting labels: ", err)
+	}
+
+	if len(labels) != 2 || labels["team"] != "ml" || labels["env"] != "prod" {
+		t.Error("unexpected labels: ", labels)
+	}
+
+	handleGetVolumeByName(mock, false, false, "", "", nil)
+
+	_, err = volDB.Labels("missing_vol")
+	if err == nil {
+		t.Error("we should get an error when getting the labels of a volume that does not exist")
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func TestSQLSetLabels(t *testing.T) {
+	db, mock, volDB := createMockVolumeDatabase(t)
+	defer db.Close()
+
+	rRows := []responseRows{
+		{id: "42", name: "aventura_vol"},
+	}
+
+	handleGetVolumeByName(mock, false, false, "", "", rRows)
+	mock.ExpectBegin()
+	deletePrepare := mock.ExpectPrepare(`[DELETE FROM volume_labels WHERE volume_id = ?;]`)
+	insertPrepare := mock.ExpectPrepare(`[INSERT INTO volume_labels(volume_id, name, value) VALUES (?, ?, ?);]`)
+	deletePrepare.ExpectExec().WithArgs(42).WillReturnResult(sqlmock.NewResult(0, 2))
+	insertPrepare.ExpectExec().WithArgs(42, "team", "ml").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := volDB.SetLabels("aventura_vol", map[string]string{"team": "ml"}); err != nil {
+		t.Error("error encountered while setting labels: ", err)
+	}
+
+	handleGetVolumeByName(mock, false, false, "", "", rRows)
+	mock.ExpectBegin()
+	deletePrepare = mock.ExpectPrepare(`[DELETE FROM volume_labels WHERE volume_id = ?;]`)
+	insertPrepare = mock.ExpectPrepare(`[INSERT INTO volume_labels(volume_id, name, value) VALUES (?, ?, ?);]`)
+	deletePrepare.ExpectExec().WithArgs(42).WillReturnResult(sqlmock.NewResult(0, 1))
+	insertPrepare.ExpectExec().WithArgs(42, "team", "ml").WillReturnError(errors.New("insert error"))
+	mock.ExpectRollback()
+
+	if err := volDB.SetLabels("aventura_vol", map[string]string{"team": "ml"}); err == nil {
+		t.Error("we should get an error when a label cannot be inserted")
+	}
+
+	handleGetVolumeByName(mock, false, false, "", "", nil)
+
+	if err := volDB.SetLabels("missing_vol", map[string]string{"team": "ml"}); err == nil {
+		t.Error("we should get an error when setting the labels of a volume that does not exist")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
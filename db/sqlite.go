@@ -7,8 +7,20 @@ import (
 	"github.com/golang/glog"
 	// Starts sqlite db in the background
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/mellanox-senior-design/docker-volume-rdma/config"
 )
 
+func init() {
+	Register("sqlite", VolumeDatabaseFactory{
+		Options: []config.Option{
+			{Name: "dbpath", Description: "set the database storage path"},
+		},
+		Lenient: true,
+		New: func(values config.Values) (VolumeDatabase, error) {
+			return NewSQLiteVolumeDatabase(values.String("dbpath")), nil
+		}})
+}
+
 // SQLiteSQLOverrides defines a list of qurries that need to differ from the defaults in order for sqlite to function correctly.
 var SQLiteSQLOverrides = VolumeDatabaseQueries{
 	volumesCreateTableSQL: `CREATE TABLE IF NOT EXISTS volumes (
@@ -22,6 +34,12 @@ var SQLiteSQLOverrides = VolumeDatabaseQueries{
         requester_id TEXT NOT NULL,
         count INTEGER NOT NULL
     );`,
+
+	optionsCreateTableSQL: `CREATE TABLE IF NOT EXISTS volume_options (
+        volume_id INTEGER NOT NULL,
+        name TEXT NOT NULL,
+        value TEXT
+    );`,
 }
 
 // NewSQLiteVolumeDatabase creates a new SQLVolumeDatabase, saving the database at dbPath.
@@ -0,0 +1,34 @@
+package drivers
+
+import (
+	"os"
+	"time"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/audit"
+)
+
+// Auditor records the volume operations that the driver handles, it is implemented by audit.Log.
+type Auditor interface {
+	Record(entry audit.Entry)
+}
+
+// recordOperation audits an operation on a volume that started at start and failed with errString, if it failed. The
+// Docker daemon does not say who asked for a volume to be created or removed, so only mounts have a requester, the ID
+// of the mount request.
+func (r RDMAVolumeDriver) recordOperation(operation string, volumeName string, id string, start time.Time, errString string) {
+	if r.Audit == nil {
+		return
+	}
+
+	host, _ := os.Hostname()
+	r.Audit.Record(audit.Entry{
+		Time:     start.UTC(),
+		API:      "plugin",
+		Action:   operation,
+		Volume:   volumeName,
+		Subject:  id,
+		Host:     host,
+		Allowed:  true,
+		Err:      errString,
+		Duration: time.Since(start)})
+}
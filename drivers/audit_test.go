@@ -0,0 +1,63 @@
+package drivers
+
+import (
+	"testing"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/audit"
+	"github.com/mellanox-senior-design/docker-volume-rdma/db"
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
+)
+
+// recordingAuditor remembers the entries it is given.
+type recordingAuditor struct {
+	entries []audit.Entry
+}
+
+func (r *recordingAuditor) Record(entry audit.Entry) {
+	r.entries = append(r.entries, entry)
+}
+
+func TestRecordOperation(t *testing.T) {
+	t.Parallel()
+	auditor := &recordingAuditor{}
+	rdmaVolDriver := NewRDMAVolumeDriver(newFakeTmpfsStorageController(newFakeMounts()), db.NewInMemoryVolumeDatabase())
+	rdmaVolDriver.Audit = auditor
+
+	rdmaVolDriver.Create(volume.Request{Name: "vol1", Options: map[string]string{"size": "1m"}})
+	rdmaVolDriver.Mount(volume.MountRequest{Name: "vol1", ID: "c1"})
+	rdmaVolDriver.Mount(volume.MountRequest{Name: "missing", ID: "c2"})
+	rdmaVolDriver.Unmount(volume.UnmountRequest{Name: "vol1", ID: "c1"})
+	rdmaVolDriver.Remove(volume.Request{Name: "vol1"})
+	rdmaVolDriver.List(volume.Request{})
+
+	expected := []struct {
+		action  string
+		volume  string
+		subject string
+		outcome string
+	}{
+		{"create", "vol1", "", "succeeded"},
+		{"mount", "vol1", "c1", "succeeded"},
+		{"mount", "missing", "c2", "failed"},
+		{"unmount", "vol1", "c1", "succeeded"},
+		{"remove", "vol1", "", "succeeded"},
+	}
+
+	if len(auditor.entries) != len(expected) {
+		t.Fatal("Expected only creates, removes, mounts and unmounts to be audited, got ", auditor.entries)
+	}
+
+	for i, entry := range auditor.entries {
+		if entry.Action != expected[i].action || entry.Volume != expected[i].volume || entry.Subject != expected[i].subject || entry.Outcome() != expected[i].outcome {
+			t.Error("Expected ", expected[i], ", got ", entry)
+		}
+
+		if entry.API != "plugin" || entry.Time.IsZero() || entry.Duration < 0 {
+			t.Error("Unexpected entry: ", entry)
+		}
+	}
+
+	if auditor.entries[2].Err == "" {
+		t.Error("A failed operation should record its error")
+	}
+}
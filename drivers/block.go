@@ -0,0 +1,74 @@
+package drivers
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/mellanox-senior-design/docker-volume-rdma/keys"
+)
+
+// supportedFilesystems lists the filesystems that block device backed volumes may be formatted with.
+var supportedFilesystems = map[string]bool{"ext4": true, "xfs": true}
+
+// filesystemOption returns the filesystem requested by the fs option, defaulting to ext4.
+func filesystemOption(options map[string]string) (string, error) {
+	filesystem := options["fs"]
+	if filesystem == "" {
+		filesystem = "ext4"
+	}
+
+	if !supportedFilesystems[filesystem] {
+		return "", errors.New("unsupported filesystem: " + filesystem)
+	}
+
+	return filesystem, nil
+}
+
+// formatDevice creates a filesystem on a block device.
+func formatDevice(runner CommandRunner, filesystem string, device string) error {
+	_, err := runner.Run("mkfs."+filesystem, "-q", device)
+	return err
+}
+
+// isMounted returns true if something is mounted on mountpoint.
+func isMounted(runner CommandRunner, mountpoint string) bool {
+	_, err := runner.Run("mountpoint", "-q", mountpoint)
+	return err == nil
+}
+
+// growFilesystem grows the filesystem of a volume to fill its resized device, growing its LUKS container first if it
+// is encrypted. Both ext4 and xfs are grown while mounted, so a volume that is not mounted is mounted until its
+// filesystem has grown.
+func growFilesystem(runner CommandRunner, storageController StorageController, volumeName string, mountpoint string, filesystem string, provider keys.KeyProvider, luks *luksVolume) error {
+	if !isMounted(runner, mountpoint) {
+		if _, err := storageController.Mount(volumeName); err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := storageController.Unmount(volumeName); err != nil {
+				glog.Error("Unable to unmount ", volumeName, " after growing its filesystem: ", err)
+			}
+		}()
+	}
+
+	if luks != nil {
+		if err := resizeLUKS(runner, provider, volumeName, *luks); err != nil {
+			return err
+		}
+	}
+
+	if filesystem == "xfs" {
+		_, err := runner.Run("xfs_growfs", mountpoint)
+		return err
+	}
+
+	device, err := runner.Run("findmnt", "--noheadings", "--output", "SOURCE", mountpoint)
+	if err != nil {
+		return err
+	}
+
+	_, err = runner.Run("resize2fs", strings.TrimSpace(device))
+	return err
+}
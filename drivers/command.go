@@ -0,0 +1,69 @@
+package drivers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+// CommandRunner runs host commands (lvcreate, mount, etc.) on behalf of a Storage Controller.
+// Storage Controllers hold a CommandRunner so that tests may replace the host's tools.
+type CommandRunner interface {
+	// Run the command name with args, returning its combined output and error (nil if no error).
+	Run(name string, args ...string) (string, error)
+}
+
+// InputCommandRunner is implemented by CommandRunners that can pass input to a command on its standard input, keeping
+// secrets such as keys off the command line and out of the host's filesystem.
+type InputCommandRunner interface {
+	// RunWithInput runs the command name with args and input on its standard input, like Run.
+	RunWithInput(input []byte, name string, args ...string) (string, error)
+}
+
+// CommandRunnerFunc allows an ordinary function to be used as a CommandRunner.
+type CommandRunnerFunc func(name string, args ...string) (string, error)
+
+// Run calls f(name, args...).
+func (f CommandRunnerFunc) Run(name string, args ...string) (string, error) {
+	return f(name, args...)
+}
+
+// ExecCommandRunner runs commands on the host using os/exec.
+type ExecCommandRunner struct{}
+
+// Run a command on the host, including its output in the error if the command fails.
+func (e ExecCommandRunner) Run(name string, args ...string) (string, error) {
+	return e.RunWithInput(nil, name, args...)
+}
+
+// RunWithInput runs a command on the host with input on its standard input. The input is not logged.
+func (e ExecCommandRunner) RunWithInput(input []byte, name string, args ...string) (string, error) {
+	commandLine := strings.TrimSpace(name + " " + strings.Join(args, " "))
+	glog.Info("Running: ", commandLine)
+
+	command := exec.Command(name, args...)
+	if input != nil {
+		command.Stdin = bytes.NewReader(input)
+	}
+
+	output, err := command.CombinedOutput()
+	if err != nil {
+		return string(output), fmt.Errorf("%s failed: %v: %s", commandLine, err, strings.TrimSpace(string(output)))
+	}
+
+	return string(output), nil
+}
+
+// runWithInput runs a command with input on its standard input, failing if runner can not pass input.
+func runWithInput(runner CommandRunner, input []byte, name string, args ...string) (string, error) {
+	inputRunner, ok := runner.(InputCommandRunner)
+	if !ok {
+		return "", errors.New("unable to pass input to " + name)
+	}
+
+	return inputRunner.RunWithInput(input, name, args...)
+}
@@ -1,22 +1,56 @@
 package drivers
 
 import (
-	"github.com/docker/go-plugins-helpers/volume"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
 	"github.com/golang/glog"
 	"github.com/mellanox-senior-design/docker-volume-rdma/db"
+	"github.com/mellanox-senior-design/docker-volume-rdma/keys"
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
 )
 
 // RDMAVolumeDriver holds all the information pertaining to a RDMA Volume Driver.
 type RDMAVolumeDriver struct {
+	// StorageController is used for volumes that were not created on a named backend.
 	StorageController StorageController
 	VolumeDatabase    db.VolumeDatabase
+
+	// Backends are the named Storage Controllers that a volume may be created on with the backend option.
+	Backends       map[string]StorageController
+	DefaultBackend string
+
+	// Tenants, if enabled, limits the volumes of each team sharing the driver to the team's quota.
+	Tenants Tenants
+
+	// Audit, if set, records every create, remove, mount and unmount, whether it succeeded or not.
+	Audit Auditor
+
+	// Notifier, if set, is told when a volume is created, first mounted, last unmounted and removed.
+	Notifier Notifier
+
+	// mountLock is held while a volume is mounted or removed, so that a volume can not be mounted after the check
+	// that it is not mounted and before its storage is deleted.
+	mountLock *sync.Mutex
 }
 
+// BackendOption is the create option that selects which backend a volume is stored on.
+const BackendOption = "backend"
+
+// SizeOption is the create option that limits the size of a volume, on the Storage Controllers that limit it.
+const SizeOption = "size"
+
 // StorageController interface allowing Storage Controllers to create, mounte, remove, ect. volumes on a host.
 type StorageController interface {
 	Connect() error
 	Disconnect() error
 
+	// Create a volume with options, allocating any storage it needs up front.
+	Create(volumeName string, options map[string]string) error
+
 	// Mount a volume, returning Mountpoint and error (nil if no error).
 	Mount(volumeName string) (string, error)
 
@@ -27,9 +61,109 @@ type StorageController interface {
 	Delete(volumeName string) error
 }
 
+// StorageHealthReporter is implemented by Storage Controllers that can report the health of their backing storage.
+type StorageHealthReporter interface {
+	// Health returns details about the backing storage, and an error if the storage is unhealthy.
+	Health() (map[string]interface{}, error)
+}
+
+// StorageSnapshotter is implemented by Storage Controllers that can take point in time copies of a volume.
+type StorageSnapshotter interface {
+	// Snapshot copies a volume to a new volume, snapshotName, that can be mounted and deleted like any other.
+	Snapshot(volumeName string, snapshotName string) error
+}
+
+// StorageResizer is implemented by Storage Controllers whose volumes have a size limit that can be raised.
+type StorageResizer interface {
+	// Resize grows a volume, created with options, and its filesystem to size, given like the size option. The volume
+	// may be mounted while it grows. Shrinking a volume is refused.
+	Resize(volumeName string, size string, options map[string]string) error
+}
+
+// StorageSizer is implemented by Storage Controllers that count the size option in units other than megabytes.
+type StorageSizer interface {
+	// ProvisionedBytes returns the number of bytes a volume created with size, given like the size option, may hold.
+	ProvisionedBytes(size string) (int64, error)
+}
+
+// StorageEncrypter is implemented by Storage Controllers that can encrypt volumes created with the encrypted option.
+type StorageEncrypter interface {
+	// KeyProvider returns the provider of the keys of encrypted volumes, or nil if none is configured and volumes can
+	// not be encrypted.
+	KeyProvider() keys.KeyProvider
+}
+
+// StorageCapacityReporter is implemented by Storage Controllers that know how much storage is left for new volumes.
+type StorageCapacityReporter interface {
+	// Capacity returns the number of bytes available for new volumes.
+	Capacity() (int64, error)
+}
+
+// FabricStorage is implemented by Storage Controllers whose volumes can be mounted from any host on the RDMA fabric,
+// rather than only from the host that created them.
+type FabricStorage interface {
+	// MultiHost reports whether a volume may be mounted on several hosts at once, rather than one host at a time.
+	MultiHost() bool
+}
+
+// StorageLister is implemented by Storage Controllers that can enumerate the volumes they hold storage for, so that
+// storage left behind by volumes the volume database no longer knows about can be found. Only storage the controller
+// can tell is its own may be listed, as it may be collected.
+type StorageLister interface {
+	// List returns every volume that storage is held for, whether or not the volume database knows about it.
+	List() ([]StoredVolume, error)
+}
+
+// StorageQuarantiner is implemented by Storage Controllers that can set a volume's storage aside instead of deleting it.
+type StorageQuarantiner interface {
+	// Quarantine moves a volume's storage out of the way, keeping its data, so that it is no longer listed or mounted.
+	Quarantine(volumeName string) error
+}
+
+// StorageMountRoot is implemented by Storage Controllers that mount volumes beneath a folder on the host, their
+// scpath. Storage Controllers sharing the folder would mount their volumes over each other's.
+type StorageMountRoot interface {
+	// MountRoot returns the folder that volumes are mounted beneath.
+	MountRoot() string
+}
+
+// StoredVolume is a volume that a Storage Controller holds storage for.
+type StoredVolume struct {
+	Name string
+
+	// Bytes is how much storage the volume takes up, as far as the Storage Controller can tell.
+	Bytes int64
+
+	// Modified is when the volume's storage last changed, or when it was created if that is all that is known.
+	Modified time.Time
+}
+
+// HealthResponse describes the health of the RDMAVolumeDriver's backends.
+type HealthResponse struct {
+	StorageController map[string]interface{}            `json:",omitempty"`
+	Backends          map[string]map[string]interface{} `json:",omitempty"`
+	Err               string
+}
+
 // NewRDMAVolumeDriver constructs a new RDMAVolumeDriver.
 func NewRDMAVolumeDriver(storageController StorageController, volumeDatabase db.VolumeDatabase) RDMAVolumeDriver {
-	return RDMAVolumeDriver{storageController, volumeDatabase}
+	return RDMAVolumeDriver{StorageController: storageController, VolumeDatabase: volumeDatabase, mountLock: &sync.Mutex{}}
+}
+
+// NewMultiBackendRDMAVolumeDriver constructs a new RDMAVolumeDriver that stores volumes on several named backends.
+// Volumes created without the backend option are stored on defaultBackend.
+func NewMultiBackendRDMAVolumeDriver(backends map[string]StorageController, defaultBackend string, volumeDatabase db.VolumeDatabase) (RDMAVolumeDriver, error) {
+	storageController, exists := backends[defaultBackend]
+	if !exists {
+		return RDMAVolumeDriver{}, errors.New("default backend " + defaultBackend + " is not configured")
+	}
+
+	return RDMAVolumeDriver{
+		StorageController: storageController,
+		VolumeDatabase:    volumeDatabase,
+		Backends:          backends,
+		DefaultBackend:    defaultBackend,
+		mountLock:         &sync.Mutex{}}, nil
 }
 
 func (r RDMAVolumeDriver) validateOrCrash() {
@@ -46,9 +180,11 @@ func (r RDMAVolumeDriver) Connect() error {
 		return err
 	}
 
-	err = r.StorageController.Connect()
-	if err != nil {
-		return err
+	for _, storageController := range r.storageControllers() {
+		err = storageController.Connect()
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -62,14 +198,95 @@ func (r RDMAVolumeDriver) Disconnect() error {
 		return err
 	}
 
-	err = r.StorageController.Disconnect()
-	if err != nil {
-		return err
+	for _, storageController := range r.storageControllers() {
+		err = storageController.Disconnect()
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// storageControllers returns every configured Storage Controller.
+func (r RDMAVolumeDriver) storageControllers() []StorageController {
+	if len(r.Backends) == 0 {
+		return []StorageController{r.StorageController}
+	}
+
+	names := make([]string, 0, len(r.Backends))
+	for name := range r.Backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	storageControllers := make([]StorageController, 0, len(names))
+	for _, name := range names {
+		storageControllers = append(storageControllers, r.Backends[name])
+	}
+
+	return storageControllers
+}
+
+// createOptions resolves which backend a new volume will be stored on, recording it in the volume's options.
+func (r RDMAVolumeDriver) createOptions(options map[string]string) (StorageController, map[string]string, error) {
+	backend := options[BackendOption]
+	if len(r.Backends) == 0 {
+		if backend != "" {
+			return nil, nil, errors.New("backends are not configured, unable to use backend " + backend)
+		}
+		return r.StorageController, options, nil
+	}
+
+	if backend == "" {
+		backend = r.DefaultBackend
+	}
+
+	storageController, exists := r.Backends[backend]
+	if !exists {
+		return nil, nil, errors.New("unknown backend: " + backend)
+	}
+
+	resolved := map[string]string{}
+	for name, value := range options {
+		resolved[name] = value
+	}
+	resolved[BackendOption] = backend
+
+	return storageController, resolved, nil
+}
+
+// Backend returns the Storage Controller of the named backend, or of the default backend if name is empty.
+func (r RDMAVolumeDriver) Backend(name string) (StorageController, error) {
+	storageController, _, err := r.createOptions(map[string]string{BackendOption: name})
+	return storageController, err
+}
+
+// storageControllerFor returns the Storage Controller that a volume was created on.
+func (r RDMAVolumeDriver) storageControllerFor(volumeName string) (StorageController, error) {
+	if len(r.Backends) == 0 {
+		return r.StorageController, nil
+	}
+
+	options, err := r.VolumeDatabase.Options(volumeName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Volumes created before backends were configured are stored on the default backend.
+	backend := options[BackendOption]
+	if backend == "" {
+		backend = r.DefaultBackend
+	}
+
+	storageController, exists := r.Backends[backend]
+	if !exists {
+		return nil, errors.New("volume " + volumeName + " is stored on backend " + backend + " which is not configured")
+	}
+
+	return storageController, nil
+}
+
 // Create a new volume with name and options.
 // POST /VolumeDriver.Create
 // 		in: { "Name": "volume_name", "Opts": {} }
@@ -78,20 +295,54 @@ func (r RDMAVolumeDriver) Disconnect() error {
 // 		referTo: https://docs.docker.com/engine/extend/plugins_volume/
 func (r RDMAVolumeDriver) Create(request volume.Request) volume.Response {
 	glog.Info("Creating volume: " + request.Name)
+	start := time.Now()
 
 	// Ensure the r is properly configured
 	r.validateOrCrash()
 
-	// Pass the create request to the volume database.
-	err := r.VolumeDatabase.Create(request.Name, request.Options)
+	// Choose the backend the volume will be stored on.
+	storageController, options, err := r.createOptions(request.Options)
+	if err == nil {
+		options, err = encryptionOptions(storageController, request.Name, options)
+	}
+	if err == nil {
+		options, err = r.tenantOptions(request.Name, options)
+	}
+	if err == nil {
+		options, err = expiryOptions(options, time.Now())
+	}
+	if err == nil {
+		_, err = ParseIOLimits(options)
+	}
+
+	// Pass the create request to the volume database, if the volume fits in its tenant's quota.
+	if err == nil {
+		err = r.recordVolume(storageController, request.Name, options)
+	}
+
+	if err == nil {
+
+		// Pass the create request to the storage controller, forgetting the volume if no storage could be allocated.
+		err = storageController.Create(request.Name, options)
+		if err != nil {
+			if removeErr := r.VolumeDatabase.Remove(request.Name); removeErr != nil {
+				glog.Error("Error: " + removeErr.Error() + "! Encountered while forgetting volume: " + request.Name)
+			}
+		}
+	}
 
 	// If there was an error, log.
 	var errString string
 	if err != nil {
 		errString = err.Error()
 		glog.Error("Error: " + errString + "! Encountered while creating a volume: " + request.Name)
+	} else {
+		r.notify(VolumeCreated, request.Name)
 	}
 
+	// Record the operation, if operations are audited.
+	r.recordOperation("create", request.Name, "", start, errString)
+
 	// Construct and return a response using the docker library.
 	var response volume.Response
 	response.Err = errString
@@ -142,6 +393,30 @@ func (r RDMAVolumeDriver) Get(request volume.Request) volume.Response {
 	// Pass the get request to the volume database.
 	vol, err := r.VolumeDatabase.Get(request.Name)
 
+	// Report the recorded size, which may have been changed by Resize since the volume was created, and the labels
+	// Docker recorded for the volume.
+	if err == nil {
+		var options, labels map[string]string
+		options, err = r.VolumeDatabase.Options(request.Name)
+		if err == nil {
+			labels, err = r.VolumeDatabase.Labels(request.Name)
+		}
+
+		status := map[string]interface{}{}
+		if options[SizeOption] != "" {
+			status["Size"] = options[SizeOption]
+		}
+		if len(labels) > 0 {
+			status["Labels"] = labels
+		}
+
+		if err == nil && len(status) > 0 {
+			described := *vol
+			described.Status = status
+			vol = &described
+		}
+	}
+
 	// If there was an error, log.
 	var errString string
 	if err != nil {
@@ -164,12 +439,41 @@ func (r RDMAVolumeDriver) Get(request volume.Request) volume.Response {
 // 		referTo: https://docs.docker.com/engine/extend/plugins_volume/
 func (r RDMAVolumeDriver) Remove(request volume.Request) volume.Response {
 	glog.Info("Removing volume: " + request.Name)
+	start := time.Now()
 
 	// Ensure the r is properly confiured
 	r.validateOrCrash()
 
-	// Pass the remove request to the volume database.
-	err := r.StorageController.Delete(request.Name)
+	// Pass the remove request to the storage controller the volume is stored on, then the volume database. The key of
+	// an encrypted volume is only deleted once its storage is, and the volume is kept until both are gone.
+	r.mountLock.Lock()
+	defer r.mountLock.Unlock()
+
+	options, err := r.VolumeDatabase.Options(request.Name)
+
+	// A mounted volume is refused before its storage is touched, the volume database would only refuse it after.
+	var mounts map[string]int
+	if err == nil {
+		mounts, err = r.VolumeDatabase.Mounts(request.Name)
+	}
+
+	if err == nil && len(mounts) > 0 {
+		err = errors.New("volume " + request.Name + " can not be removed as it has " + strconv.Itoa(len(mounts)) + " active mount requests")
+	}
+
+	var storageController StorageController
+	if err == nil {
+		storageController, err = r.storageControllerFor(request.Name)
+	}
+
+	if err == nil {
+		err = storageController.Delete(request.Name)
+	}
+
+	if err == nil {
+		err = shredKey(storageController, request.Name, options)
+	}
+
 	if err == nil {
 		err = r.VolumeDatabase.Remove(request.Name)
 	}
@@ -179,8 +483,13 @@ func (r RDMAVolumeDriver) Remove(request volume.Request) volume.Response {
 	if err != nil {
 		errString = err.Error()
 		glog.Error("Error: " + errString + "! Encountered while removing a volume: " + request.Name)
+	} else {
+		r.notify(VolumeRemoved, request.Name)
 	}
 
+	// Record the operation, if operations are audited.
+	r.recordOperation("remove", request.Name, "", start, errString)
+
 	// Construct and return a response using the docker library.
 	var response volume.Response
 	response.Err = errString
@@ -234,18 +543,37 @@ func (r RDMAVolumeDriver) Path(request volume.Request) volume.Response {
 // 		referTo: https://docs.docker.com/engine/extend/plugins_volume/
 func (r RDMAVolumeDriver) Mount(request volume.MountRequest) volume.Response {
 	glog.Info("Mounting volume: " + request.Name)
+	start := time.Now()
 
 	// Ensure the r is properly confiured
 	r.validateOrCrash()
 
-	// Pass the mount request to the storage controller.
-	mountpoint, err := r.StorageController.Mount(request.Name)
+	// Pass the mount request to the storage controller the volume is stored on.
+	r.mountLock.Lock()
+	defer r.mountLock.Unlock()
+
+	var mountpoint string
+	storageController, err := r.storageControllerFor(request.Name)
+	if err == nil {
+		mountpoint, err = storageController.Mount(request.Name)
+	}
+
+	// The volume is first mounted if no requests to mount it were outstanding.
+	var mounts map[string]int
+	if err == nil {
+		mounts, err = r.VolumeDatabase.Mounts(request.Name)
+	}
+
 	if err == nil {
 
 		// Pass the mount request to the volume database.
 		err = r.VolumeDatabase.Mount(request.Name, request.ID, mountpoint)
 	}
 
+	if err == nil && len(mounts) == 0 {
+		r.notify(VolumeMounted, request.Name)
+	}
+
 	// If there was an error, log.
 	var errString string
 	if err != nil {
@@ -253,6 +581,9 @@ func (r RDMAVolumeDriver) Mount(request volume.MountRequest) volume.Response {
 		glog.Error("Error: " + errString + "! Encountered while mounting volume: " + request.Name + " and ID: " + request.ID)
 	}
 
+	// Record the operation, if operations are audited.
+	r.recordOperation("mount", request.Name, request.ID, start, errString)
+
 	// Construct and return a response using the docker library.
 	var response volume.Response
 	response.Mountpoint = mountpoint
@@ -273,14 +604,28 @@ func (r RDMAVolumeDriver) Mount(request volume.MountRequest) volume.Response {
 func (r RDMAVolumeDriver) Unmount(request volume.UnmountRequest) volume.Response {
 	var errString string
 	glog.Info("Unmounting volume: " + request.Name + " and ID: " + request.ID)
+	start := time.Now()
 
 	r.validateOrCrash()
 
 	// Pass the unmount request to the volume database.
 	err := r.VolumeDatabase.Unmount(request.Name, request.ID)
 	if err == nil {
-		// Pass the unmount request to the storage controller.
-		err = r.StorageController.Unmount(request.Name)
+		var mounts map[string]int
+		mounts, err = r.VolumeDatabase.Mounts(request.Name)
+
+		// Pass the unmount request to the storage controller, once the last mount request has been released.
+		if err == nil && len(mounts) == 0 {
+			var storageController StorageController
+			storageController, err = r.storageControllerFor(request.Name)
+			if err == nil {
+				err = storageController.Unmount(request.Name)
+			}
+			if err == nil {
+				r.notify(VolumeUnmounted, request.Name)
+				r.restartIdleExpiry(request.Name)
+			}
+		}
 	}
 
 	// If there was an error, log.
@@ -289,12 +634,70 @@ func (r RDMAVolumeDriver) Unmount(request volume.UnmountRequest) volume.Response
 		glog.Error("Error: " + errString + " Encountered while unmounting volume: " + request.Name + " and ID: " + request.ID)
 	}
 
+	// Record the operation, if operations are audited.
+	r.recordOperation("unmount", request.Name, request.ID, start, errString)
+
 	// Construct and return a response using the docker library.
 	var response volume.Response
 	response.Err = errString
 	return response
 }
 
+// Release drops every outstanding mount request of id on a particular volume, as if each had been unmounted, for
+// requesters that will never unmount the volume themselves. The volume is unmounted from the host once no requests
+// remain.
+func (r RDMAVolumeDriver) Release(volumeName string, id string) error {
+	glog.Info("Releasing volume: " + volumeName + " from ID: " + id)
+
+	r.validateOrCrash()
+
+	mounts, err := r.VolumeDatabase.Mounts(volumeName)
+	if err != nil {
+		return err
+	}
+
+	count, exists := mounts[id]
+	if !exists {
+		return errors.New("volume " + volumeName + " has no mount requests from " + id)
+	}
+
+	for i := 0; i < count; i++ {
+		response := r.Unmount(volume.UnmountRequest{Name: volumeName, ID: id})
+		if response.Err != "" {
+			return errors.New(response.Err)
+		}
+	}
+
+	return nil
+}
+
+// Resize grows a particular volume to size, recording the new size option once its storage has grown. Only volumes
+// stored by a Storage Controller that implements StorageResizer can be resized.
+func (r RDMAVolumeDriver) Resize(volumeName string, size string) error {
+	glog.Info("Resizing volume: " + volumeName + " to " + size)
+
+	r.validateOrCrash()
+
+	storageController, err := r.storageControllerFor(volumeName)
+	if err != nil {
+		return err
+	}
+
+	resizer, ok := storageController.(StorageResizer)
+	if !ok {
+		return errors.New("the storage controller of volume " + volumeName + " does not support resizing")
+	}
+
+	options, err := r.VolumeDatabase.Options(volumeName)
+	if err != nil {
+		return err
+	}
+
+	return r.resizeVolume(storageController, volumeName, size, options, func() error {
+		return resizer.Resize(volumeName, size, options)
+	})
+}
+
 // Capabilities that our plugin supports.
 // POST /VolumeDriver.Capabilities
 // 		in: {}
@@ -317,3 +720,44 @@ func (r RDMAVolumeDriver) Capabilities(request volume.Request) volume.Response {
 	response.Capabilities = volume.Capability{Scope: "local"}
 	return response
 }
+
+// Health reports on the health of the storage controllers, if they are able to report it.
+func (r RDMAVolumeDriver) Health() HealthResponse {
+	var response HealthResponse
+
+	if len(r.Backends) == 0 {
+		response.StorageController, response.Err = storageControllerHealth(r.StorageController)
+		return response
+	}
+
+	response.Backends = map[string]map[string]interface{}{}
+	for name, storageController := range r.Backends {
+		health, errString := storageControllerHealth(storageController)
+		if health != nil {
+			response.Backends[name] = health
+		}
+
+		if errString != "" {
+			response.Err = "backend " + name + ": " + errString
+		}
+	}
+
+	return response
+}
+
+// storageControllerHealth reports on the health of a storage controller, if it is able to report it.
+func storageControllerHealth(storageController StorageController) (map[string]interface{}, string) {
+	reporter, ok := storageController.(StorageHealthReporter)
+	if !ok {
+		return nil, ""
+	}
+
+	var errString string
+	health, err := reporter.Health()
+	if err != nil {
+		errString = err.Error()
+		glog.Error("Error: " + errString + "! Encountered while checking the health of the storage controller")
+	}
+
+	return health, errString
+}
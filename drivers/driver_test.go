@@ -4,8 +4,8 @@ import (
 	"os"
 	"testing"
 
-	"github.com/docker/go-plugins-helpers/volume"
 	"github.com/mellanox-senior-design/docker-volume-rdma/db"
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
 )
 
 func tearDown() {
@@ -84,6 +84,20 @@ func TestCreate(t *testing.T) {
 		t.Fatal("We should receive an error because a volume cannot be created twice")
 	}
 
+	response = rdmaVolDriver.Create(volume.Request{Name: "secret", Options: map[string]string{EncryptedOption: "true"}})
+	if len(response.Err) == 0 {
+		t.Fatal("We should receive an error because the on-disk storage controller cannot encrypt volumes")
+	}
+
+	if _, err = db.Get("secret"); err == nil {
+		t.Fatal("A volume that could not be encrypted should not be recorded")
+	}
+
+	response = rdmaVolDriver.Create(volume.Request{Name: "throttled", Options: map[string]string{ReadIOPSOption: "many"}})
+	if len(response.Err) == 0 {
+		t.Fatal("We should receive an error because the I/O limit is invalid")
+	}
+
 }
 
 func TestList(t *testing.T) {
@@ -165,6 +179,20 @@ func TestGet(t *testing.T) {
 		t.Error(response.Err)
 	}
 
+	if response.Volume.Status != nil {
+		t.Error("A volume without a size or labels should have no status, got ", response.Volume.Status)
+	}
+
+	if err := db.SetLabels("testGet", map[string]string{"team": "ml"}); err != nil {
+		t.Fatal(err)
+	}
+
+	response = rdmaVolDriver.Get(req)
+	labels, _ := response.Volume.Status["Labels"].(map[string]string)
+	if labels["team"] != "ml" {
+		t.Error("Expected the volume's labels in its status, got ", response.Volume.Status)
+	}
+
 	secondReq := volume.Request{Name: "notCreated"}
 	secondRes := rdmaVolDriver.Get(secondReq)
 	if len(secondRes.Err) == 0 {
@@ -214,6 +242,55 @@ func TestRemove(t *testing.T) {
 
 }
 
+func TestRemoveEncrypted(t *testing.T) {
+	t.Parallel()
+	provider, cleanUp := newTestKeyProvider(t)
+	defer cleanUp()
+
+	db := db.NewInMemoryVolumeDatabase()
+	fake := newFakeLVM()
+	sc := newFakeLVMStorageController(fake)
+	sc.Runner = &inputRecorder{CommandRunnerFunc: fake.run}
+	sc.Keys = provider
+
+	rdmaVolDriver := NewRDMAVolumeDriver(sc, db)
+
+	response := rdmaVolDriver.Create(volume.Request{Name: "shredded", Options: map[string]string{EncryptedOption: "true"}})
+	if len(response.Err) != 0 {
+		t.Fatal(response.Err)
+	}
+
+	options, err := db.Options("shredded")
+	if err != nil || options[KeyIDOption] != "shredded" {
+		t.Fatal("The volume's key ID should be recorded with it, got ", options, err)
+	}
+
+	mountResponse := rdmaVolDriver.Mount(volume.MountRequest{Name: "shredded", ID: "container"})
+	if len(mountResponse.Err) != 0 {
+		t.Fatal(mountResponse.Err)
+	}
+
+	if _, err = provider.GetKey("shredded"); err != nil {
+		t.Fatal("The volume's key should be created when it is first mounted: ", err)
+	}
+
+	rdmaVolDriver.Unmount(volume.UnmountRequest{Name: "shredded", ID: "container"})
+
+	response = rdmaVolDriver.Remove(volume.Request{Name: "shredded"})
+	if len(response.Err) != 0 {
+		t.Fatal(response.Err)
+	}
+
+	if _, err = provider.GetKey("shredded"); err == nil {
+		t.Error("The volume's key should be deleted with it")
+	}
+
+	response = rdmaVolDriver.Create(volume.Request{Name: "chosen", Options: map[string]string{EncryptedOption: "true", KeyIDOption: "shredded"}})
+	if len(response.Err) == 0 {
+		t.Error("Volumes should not be able to choose their key")
+	}
+}
+
 func TestPath(t *testing.T) {
 	t.Parallel()
 	db := db.NewInMemoryVolumeDatabase()
@@ -354,3 +431,277 @@ func TestCapabilities(t *testing.T) {
 	}
 
 }
+
+func TestCreateStorageControllerFailure(t *testing.T) {
+	t.Parallel()
+	db := db.NewInMemoryVolumeDatabase()
+	fake := newFakeLVM()
+	fake.dataPercent = 99
+	sc := newFakeLVMStorageController(fake)
+
+	rdmaVolDriver := NewRDMAVolumeDriver(sc, db)
+	response := rdmaVolDriver.Create(volume.Request{Name: "full"})
+
+	if len(response.Err) == 0 {
+		t.Error("Create should fail when the storage controller refuses the volume")
+	}
+
+	_, err := db.Get("full")
+	if err == nil {
+		t.Error("The volume should have been removed from the database when the storage controller failed")
+	}
+}
+
+func TestHealth(t *testing.T) {
+	t.Parallel()
+	db := db.NewInMemoryVolumeDatabase()
+	fake := newFakeLVM()
+	sc := newFakeLVMStorageController(fake)
+
+	rdmaVolDriver := NewRDMAVolumeDriver(sc, db)
+	response := rdmaVolDriver.Health()
+	if len(response.Err) != 0 {
+		t.Error(response.Err)
+	}
+
+	if response.StorageController["ThinPool"] != "vg/pool" {
+		t.Error("Health did not include the thin pool usage, got ", response.StorageController)
+	}
+
+	fake.metadataPercent = 99
+	response = rdmaVolDriver.Health()
+	if len(response.Err) == 0 {
+		t.Error("Health should report an error once the thin pool is full")
+	}
+
+	rdmaVolDriver = NewRDMAVolumeDriver(NewOnDiskStorageController("tests/docker/mounts/"), db)
+	response = rdmaVolDriver.Health()
+	if len(response.Err) != 0 || response.StorageController != nil {
+		t.Error("The on-disk storage controller does not report health, got ", response)
+	}
+}
+
+func TestUnmountLastRequester(t *testing.T) {
+	t.Parallel()
+	db := db.NewInMemoryVolumeDatabase()
+	fake := newFakeMounts()
+	sc := newFakeTmpfsStorageController(fake)
+
+	rdmaVolDriver := NewRDMAVolumeDriver(sc, db)
+
+	response := rdmaVolDriver.Create(volume.Request{Name: "scratch", Options: map[string]string{"persist": "false"}})
+	if len(response.Err) != 0 {
+		t.Fatal(response.Err)
+	}
+
+	for _, id := range []string{"1", "2"} {
+		response = rdmaVolDriver.Mount(volume.MountRequest{Name: "scratch", ID: id})
+		if len(response.Err) != 0 {
+			t.Fatal(response.Err)
+		}
+	}
+
+	mountpoint := response.Mountpoint
+
+	response = rdmaVolDriver.Unmount(volume.UnmountRequest{Name: "scratch", ID: "1"})
+	if len(response.Err) != 0 {
+		t.Fatal(response.Err)
+	}
+
+	if !fake.mounted[mountpoint] {
+		t.Error("The volume should stay mounted while another requester is using it")
+	}
+
+	response = rdmaVolDriver.Unmount(volume.UnmountRequest{Name: "scratch", ID: "2"})
+	if len(response.Err) != 0 {
+		t.Fatal(response.Err)
+	}
+
+	if fake.mounted[mountpoint] {
+		t.Error("The volume should be unmounted once the last requester has unmounted it")
+	}
+
+	response = rdmaVolDriver.Remove(volume.Request{Name: "scratch"})
+	if len(response.Err) != 0 {
+		t.Error(response.Err)
+	}
+}
+
+func TestRelease(t *testing.T) {
+	t.Parallel()
+	db := db.NewInMemoryVolumeDatabase()
+	fake := newFakeMounts()
+	sc := newFakeTmpfsStorageController(fake)
+
+	rdmaVolDriver := NewRDMAVolumeDriver(sc, db)
+
+	response := rdmaVolDriver.Create(volume.Request{Name: "stale", Options: map[string]string{"persist": "false"}})
+	if len(response.Err) != 0 {
+		t.Fatal(response.Err)
+	}
+
+	for _, id := range []string{"1", "1", "2"} {
+		response = rdmaVolDriver.Mount(volume.MountRequest{Name: "stale", ID: id})
+		if len(response.Err) != 0 {
+			t.Fatal(response.Err)
+		}
+	}
+
+	mountpoint := response.Mountpoint
+
+	err := rdmaVolDriver.Release("stale", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mounts, err := db.Mounts("stale")
+	if err != nil || len(mounts) != 1 || mounts["2"] != 1 {
+		t.Error("Every request from 1 should be released, got ", mounts, err)
+	}
+
+	if !fake.mounted[mountpoint] {
+		t.Error("The volume should stay mounted while 2 is using it")
+	}
+
+	if err = rdmaVolDriver.Release("stale", "1"); err == nil {
+		t.Error("Releasing an ID without requests should fail")
+	}
+
+	err = rdmaVolDriver.Release("stale", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fake.mounted[mountpoint] {
+		t.Error("The volume should be unmounted once every request has been released")
+	}
+}
+
+func TestResize(t *testing.T) {
+	t.Parallel()
+	db := db.NewInMemoryVolumeDatabase()
+	fake := newFakeMounts()
+	sc := newFakeTmpfsStorageController(fake)
+
+	rdmaVolDriver := NewRDMAVolumeDriver(sc, db)
+
+	response := rdmaVolDriver.Create(volume.Request{Name: "growing", Options: map[string]string{"size": "1g"}})
+	if len(response.Err) != 0 {
+		t.Fatal(response.Err)
+	}
+
+	err := rdmaVolDriver.Resize("growing", "2g")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response = rdmaVolDriver.Get(volume.Request{Name: "growing"})
+	if response.Volume == nil || response.Volume.Status["Size"] != "2g" {
+		t.Error("Get should report the new size, got ", response.Volume)
+	}
+
+	if err = rdmaVolDriver.Resize("growing", "1g"); err == nil {
+		t.Error("Shrinking a volume should fail")
+	}
+
+	options, err := db.Options("growing")
+	if err != nil || options["size"] != "2g" {
+		t.Error("The size option should only change when the volume grows, got ", options, err)
+	}
+
+	if err = rdmaVolDriver.Resize("missing", "2g"); err == nil {
+		t.Error("Resizing a volume that does not exist should fail")
+	}
+
+	// The on-disk storage controller does not limit the size of its volumes.
+	onDisk := NewRDMAVolumeDriver(NewOnDiskStorageController("tests/docker/mounts/"), db)
+	if err = onDisk.Resize("growing", "3g"); err == nil {
+		t.Error("Resizing should fail on storage controllers that do not support it")
+	}
+}
+
+func TestMultiBackend(t *testing.T) {
+	t.Parallel()
+	db := db.NewInMemoryVolumeDatabase()
+	fast := newFakeLVM()
+	backends := map[string]StorageController{
+		"fast": newFakeLVMStorageController(fast),
+		"bulk": NewOnDiskStorageController("tests/docker/bulk/"),
+	}
+
+	_, err := NewMultiBackendRDMAVolumeDriver(backends, "missing", db)
+	if err == nil {
+		t.Error("The default backend must be one of the backends")
+	}
+
+	rdmaVolDriver, err := NewMultiBackendRDMAVolumeDriver(backends, "bulk", db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response := rdmaVolDriver.Create(volume.Request{Name: "fastvol", Options: map[string]string{"backend": "fast"}})
+	if len(response.Err) != 0 {
+		t.Fatal(response.Err)
+	}
+
+	response = rdmaVolDriver.Create(volume.Request{Name: "bulkvol"})
+	if len(response.Err) != 0 {
+		t.Fatal(response.Err)
+	}
+
+	response = rdmaVolDriver.Create(volume.Request{Name: "unknownvol", Options: map[string]string{"backend": "unknown"}})
+	if len(response.Err) == 0 {
+		t.Error("Volumes cannot be created on a backend that is not configured")
+	}
+
+	// The chosen backend is saved with the volume, even when it was the default.
+	options, err := db.Options("bulkvol")
+	if err != nil || options["backend"] != "bulk" {
+		t.Error("The default backend was not saved with the volume, got ", options, err)
+	}
+
+	if !fast.volumes["fastvol"] || fast.volumes["bulkvol"] {
+		t.Error("Only fastvol should have been created on the fast backend")
+	}
+
+	response = rdmaVolDriver.Mount(volume.MountRequest{Name: "fastvol", ID: "1"})
+	if response.Mountpoint != "test/lvm/fastvol" {
+		t.Error("fastvol should be mounted by the fast backend, got ", response.Mountpoint, response.Err)
+	}
+
+	response = rdmaVolDriver.Mount(volume.MountRequest{Name: "bulkvol", ID: "1"})
+	if response.Mountpoint != "tests/docker/bulk/bulkvol" {
+		t.Error("bulkvol should be mounted by the bulk backend, got ", response.Mountpoint, response.Err)
+	}
+
+	response = rdmaVolDriver.Unmount(volume.UnmountRequest{Name: "fastvol", ID: "1"})
+	if len(response.Err) != 0 || fast.mounted["test/lvm/fastvol"] {
+		t.Error("fastvol should be unmounted by the fast backend ", response.Err)
+	}
+
+	response = rdmaVolDriver.Remove(volume.Request{Name: "fastvol"})
+	if len(response.Err) != 0 || fast.volumes["fastvol"] {
+		t.Error("fastvol should be deleted by the fast backend ", response.Err)
+	}
+
+	health := rdmaVolDriver.Health()
+	if len(health.Err) != 0 || health.Backends["fast"]["ThinPool"] != "vg/pool" {
+		t.Error("Health should report on each backend, got ", health)
+	}
+
+	storageController, err := rdmaVolDriver.Backend("")
+	if err != nil || storageController != backends["bulk"] {
+		t.Error("The default backend should be bulk, got ", storageController, err)
+	}
+
+	_, err = rdmaVolDriver.Backend("unknown")
+	if err == nil {
+		t.Error("Backend should fail for a backend that is not configured")
+	}
+
+	singleBackendDriver := NewRDMAVolumeDriver(NewOnDiskStorageController("tests/docker/mounts/"), db)
+	response = singleBackendDriver.Create(volume.Request{Name: "fastvol", Options: map[string]string{"backend": "fast"}})
+	if len(response.Err) == 0 {
+		t.Error("The backend option cannot be used when backends are not configured")
+	}
+}
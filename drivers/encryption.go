@@ -0,0 +1,275 @@
+package drivers
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/mellanox-senior-design/docker-volume-rdma/config"
+	"github.com/mellanox-senior-design/docker-volume-rdma/keys"
+)
+
+// EncryptedOption is the create option that encrypts a volume with dm-crypt, in a LUKS2 container, on the Storage
+// Controllers of block devices.
+const EncryptedOption = "encrypted"
+
+// KeyIDOption records, with an encrypted volume in the volume database, the ID of its key in the key provider. It is
+// set by the driver, so that a volume can never be given the key of another.
+const KeyIDOption = "key-id"
+
+// luksVolume describes an encrypted volume. It is recorded with the volume's storage, rather than in the volume
+// database, so that the Storage Controller can open the volume whenever it is mounted.
+type luksVolume struct {
+	// Filesystem is created inside the LUKS container when the volume is first mounted.
+	Filesystem string
+
+	// KeyID names the volume's key in the key provider.
+	KeyID string
+}
+
+func (l luksVolume) String() string {
+	return l.Filesystem + ":" + l.KeyID
+}
+
+// parseLUKSVolume parses the description of an encrypted volume recorded with its storage.
+func parseLUKSVolume(recorded string) (*luksVolume, error) {
+	fields := strings.SplitN(recorded, ":", 2)
+	if len(fields) != 2 || !supportedFilesystems[fields[0]] || fields[1] == "" {
+		return nil, errors.New("unable to parse the encryption of a volume: " + recorded)
+	}
+
+	return &luksVolume{Filesystem: fields[0], KeyID: fields[1]}, nil
+}
+
+// newKeyProvider returns the key provider configured by -sc-key-dir or -sc-vault-addr, or nil if neither is.
+func newKeyProvider(values config.Values) (keys.KeyProvider, error) {
+	dir := values.String("sc-key-dir")
+	address := values.String("sc-vault-addr")
+
+	switch {
+	case dir != "" && address != "":
+		return nil, errors.New("keys can be kept in -sc-key-dir or -sc-vault-addr, not both")
+	case dir != "":
+		return keys.NewDirectoryProvider(dir), nil
+	case address == "":
+		return nil, nil
+	}
+
+	tokenFile := values.String("sc-vault-token-file")
+	if tokenFile == "" {
+		return nil, errors.New("-sc-vault-addr requires -sc-vault-token-file")
+	}
+
+	contents, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return keys.NewVaultProvider(address, strings.TrimSpace(string(contents)), values.String("sc-vault-mount"))
+}
+
+// requestsEncryption returns whether the encrypted option asks for a volume to be encrypted.
+func requestsEncryption(options map[string]string) (bool, error) {
+	value := options[EncryptedOption]
+	if value == "" {
+		return false, nil
+	}
+
+	encrypted, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errors.New("invalid encrypted: " + value)
+	}
+
+	return encrypted, nil
+}
+
+// encryptedOption returns how a new volume will be encrypted, or nil if the encrypted option does not ask for it. The
+// volume's key is the one recorded by the driver, or is named after the volume.
+func encryptedOption(provider keys.KeyProvider, volumeName string, options map[string]string) (*luksVolume, error) {
+	encrypted, err := requestsEncryption(options)
+	if err != nil || !encrypted {
+		return nil, err
+	}
+
+	if provider == nil {
+		return nil, errors.New("unable to encrypt volume " + volumeName + ", no key provider is configured")
+	}
+
+	filesystem, err := filesystemOption(options)
+	if err != nil {
+		return nil, err
+	}
+
+	keyID := options[KeyIDOption]
+	if keyID == "" {
+		keyID = volumeName
+	}
+
+	return &luksVolume{Filesystem: filesystem, KeyID: keyID}, nil
+}
+
+// cryptName is the name of the device mapper device that an encrypted volume is opened as.
+func cryptName(volumeName string) string {
+	return "docker-volume-rdma-" + volumeName
+}
+
+// openLUKS opens the LUKS container on device, returning the device of its contents. The first time a volume is
+// opened its key is created, the container is formatted and a filesystem is created inside it.
+func openLUKS(runner CommandRunner, provider keys.KeyProvider, volumeName string, luks luksVolume, device string) (string, error) {
+	if provider == nil {
+		return "", errors.New("volume " + volumeName + " is encrypted but no key provider is configured")
+	}
+
+	opened := path.Join("/dev/mapper", cryptName(volumeName))
+	if _, err := runner.Run("cryptsetup", "status", cryptName(volumeName)); err == nil {
+		return opened, nil
+	}
+
+	_, err := runner.Run("cryptsetup", "isLuks", device)
+	formatted := err == nil
+
+	key, err := provider.GetKey(luks.KeyID)
+	if !formatted {
+		// The key already exists if an earlier first mount failed after creating it.
+		if err != nil {
+			key, err = provider.CreateKey(luks.KeyID)
+		}
+
+		if err == nil {
+			glog.Info("Formatting ", device, " as a LUKS2 container for ", volumeName)
+			_, err = runWithInput(runner, key, "cryptsetup", "luksFormat", "--type", "luks2", "--batch-mode", "--key-file", "-", device)
+		}
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	_, err = runWithInput(runner, key, "cryptsetup", "open", "--type", "luks2", "--key-file", "-", device, cryptName(volumeName))
+	if err != nil {
+		return "", err
+	}
+
+	if formatted {
+		return opened, nil
+	}
+
+	// Wipe a container without a filesystem, so that the next mount starts over rather than mounting it as it is.
+	err = formatDevice(runner, luks.Filesystem, opened)
+	if err != nil {
+		if closeErr := closeLUKS(runner, volumeName); closeErr != nil {
+			glog.Error(closeErr)
+		} else if _, wipeErr := runner.Run("wipefs", "--all", device); wipeErr != nil {
+			glog.Error(wipeErr)
+		}
+		return "", err
+	}
+
+	return opened, nil
+}
+
+// closeLUKS closes the LUKS container of a volume, if it is open.
+func closeLUKS(runner CommandRunner, volumeName string) error {
+	if _, err := runner.Run("cryptsetup", "status", cryptName(volumeName)); err != nil {
+		return nil
+	}
+
+	_, err := runner.Run("cryptsetup", "close", cryptName(volumeName))
+	return err
+}
+
+// resizeLUKS grows an open LUKS container to fill its resized device.
+func resizeLUKS(runner CommandRunner, provider keys.KeyProvider, volumeName string, luks luksVolume) error {
+	if provider == nil {
+		return errors.New("volume " + volumeName + " is encrypted but no key provider is configured")
+	}
+
+	key, err := provider.GetKey(luks.KeyID)
+	if err != nil {
+		return err
+	}
+
+	_, err = runWithInput(runner, key, "cryptsetup", "resize", "--key-file", "-", cryptName(volumeName))
+	return err
+}
+
+// encryptionOptions returns the options of a new volume, recording the ID of its key if the options ask for it to be
+// encrypted. An error is returned if storageController is not able to encrypt it.
+func encryptionOptions(storageController StorageController, volumeName string, options map[string]string) (map[string]string, error) {
+	if _, set := options[KeyIDOption]; set {
+		return nil, errors.New("the " + KeyIDOption + " option is set by the driver and can not be chosen")
+	}
+
+	encrypted, err := requestsEncryption(options)
+	if err != nil || !encrypted {
+		return options, err
+	}
+
+	if keyProvider(storageController) == nil {
+		return nil, errors.New("the storage controller is unable to encrypt volumes, only lvm and rbd can once a key provider is configured")
+	}
+
+	resolved := map[string]string{}
+	for name, value := range options {
+		resolved[name] = value
+	}
+	resolved[KeyIDOption] = volumeName
+
+	return resolved, nil
+}
+
+// shredKey deletes the key of a volume whose storage has been deleted, so that nothing left on the storage can ever be
+// decrypted.
+func shredKey(storageController StorageController, volumeName string, options map[string]string) error {
+	keyID := options[KeyIDOption]
+	if keyID == "" {
+		return nil
+	}
+
+	provider := keyProvider(storageController)
+	if provider == nil {
+		return errors.New("unable to delete the key of volume " + volumeName + ", no key provider is configured")
+	}
+
+	glog.Info("Deleting key ", keyID, " of ", volumeName)
+	return provider.DeleteKey(keyID)
+}
+
+// SharesKey returns an error unless target can encrypt volumes with the key called keyID that source encrypts a
+// volume with, so that the volume can be copied from one to the other and still be decrypted.
+func SharesKey(source StorageController, target StorageController, keyID string) error {
+	sourceProvider, targetProvider := keyProvider(source), keyProvider(target)
+	if sourceProvider == nil || targetProvider == nil {
+		return errors.New("unable to encrypt with key " + keyID + ", no key provider is configured")
+	}
+
+	sourceKey, err := sourceProvider.GetKey(keyID)
+	if err != nil {
+		return err
+	}
+
+	targetKey, err := targetProvider.GetKey(keyID)
+	if err != nil {
+		return errors.New("unable to find key " + keyID + ": " + err.Error())
+	}
+
+	if !bytes.Equal(sourceKey, targetKey) {
+		return errors.New("key " + keyID + " differs between the key providers")
+	}
+
+	return nil
+}
+
+// keyProvider returns the key provider of storageController, or nil if it can not encrypt volumes.
+func keyProvider(storageController StorageController) keys.KeyProvider {
+	encrypter, ok := storageController.(StorageEncrypter)
+	if !ok {
+		return nil
+	}
+
+	return encrypter.KeyProvider()
+}
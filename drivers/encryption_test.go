@@ -0,0 +1,309 @@
+package drivers
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/config"
+	"github.com/mellanox-senior-design/docker-volume-rdma/keys"
+)
+
+// fakeCrypt pretends to be cryptsetup, remembering which devices hold a LUKS container and which containers are open.
+type fakeCrypt struct {
+	formatted map[string]bool
+	opened    map[string]bool
+}
+
+func newFakeCrypt() *fakeCrypt {
+	return &fakeCrypt{formatted: map[string]bool{}, opened: map[string]bool{}}
+}
+
+func (f *fakeCrypt) run(args ...string) (string, error) {
+	last := args[len(args)-1]
+
+	switch args[0] {
+	case "status":
+		if !f.opened[last] {
+			return "", errors.New(last + " is inactive")
+		}
+	case "isLuks":
+		if !f.formatted[last] {
+			return "", errors.New(last + " is not a LUKS device")
+		}
+	case "luksFormat":
+		f.formatted[last] = true
+	case "open":
+		f.opened[last] = true
+	case "close":
+		delete(f.opened, last)
+	}
+
+	return "", nil
+}
+
+// inputRecorder is a CommandRunner that records the input passed to its commands.
+type inputRecorder struct {
+	CommandRunnerFunc
+	inputs [][]byte
+}
+
+func (i *inputRecorder) RunWithInput(input []byte, name string, args ...string) (string, error) {
+	i.inputs = append(i.inputs, input)
+	return i.Run(name, args...)
+}
+
+// newTestKeyProvider creates a key provider in a new temporary directory, returning a function that removes it.
+func newTestKeyProvider(t *testing.T) (keys.DirectoryProvider, func()) {
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-keys")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+
+	return keys.NewDirectoryProvider(filepath.Join(tempDir, "keys")), func() { os.RemoveAll(tempDir) }
+}
+
+func TestParseLUKSVolume(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		recorded string
+		expected *luksVolume
+	}{
+		{"ext4:vol1", &luksVolume{Filesystem: "ext4", KeyID: "vol1"}},
+		{"xfs:vol:2", &luksVolume{Filesystem: "xfs", KeyID: "vol:2"}},
+		{"ext4:", nil},
+		{"btrfs:vol1", nil},
+		{"vol1", nil},
+		{"", nil},
+	}
+
+	for _, test := range tests {
+		luks, err := parseLUKSVolume(test.recorded)
+		if test.expected == nil {
+			if err == nil {
+				t.Error("Parsing ", test.recorded, " should fail, got ", luks)
+			}
+			continue
+		}
+
+		if err != nil || *luks != *test.expected {
+			t.Error("Expected ", test.recorded, " to parse as ", test.expected, ", got ", luks, err)
+		}
+
+		if luks.String() != test.recorded {
+			t.Error("Expected ", luks, " to be recorded as ", test.recorded, ", got ", luks.String())
+		}
+	}
+}
+
+func TestEncryptedOption(t *testing.T) {
+	t.Parallel()
+	provider, cleanUp := newTestKeyProvider(t)
+	defer cleanUp()
+
+	luks, err := encryptedOption(provider, "vol1", map[string]string{})
+	if err != nil || luks != nil {
+		t.Error("Volumes should not be encrypted unless asked to be, got ", luks, err)
+	}
+
+	luks, err = encryptedOption(provider, "vol1", map[string]string{EncryptedOption: "false"})
+	if err != nil || luks != nil {
+		t.Error("Volumes should not be encrypted with encrypted=false, got ", luks, err)
+	}
+
+	luks, err = encryptedOption(provider, "vol1", map[string]string{EncryptedOption: "true", "fs": "xfs"})
+	if err != nil || luks == nil || *luks != (luksVolume{Filesystem: "xfs", KeyID: "vol1"}) {
+		t.Error("Expected an xfs volume encrypted with the key vol1, got ", luks, err)
+	}
+
+	if _, err = encryptedOption(provider, "vol1", map[string]string{EncryptedOption: "maybe"}); err == nil {
+		t.Error("An invalid encrypted option should fail")
+	}
+
+	if _, err = encryptedOption(provider, "vol1", map[string]string{EncryptedOption: "true", "fs": "btrfs"}); err == nil {
+		t.Error("An unsupported filesystem should fail")
+	}
+
+	if _, err = encryptedOption(nil, "vol1", map[string]string{EncryptedOption: "true"}); err == nil {
+		t.Error("Encrypting a volume without a key provider should fail")
+	}
+}
+
+func TestOpenLUKS_formatFailure(t *testing.T) {
+	t.Parallel()
+	provider, cleanUp := newTestKeyProvider(t)
+	defer cleanUp()
+
+	crypt := newFakeCrypt()
+	var commands []string
+	runner := &inputRecorder{CommandRunnerFunc: func(name string, args ...string) (string, error) {
+		commands = append(commands, name)
+		if name == "mkfs.ext4" {
+			return "", errors.New("mkfs.ext4 failed")
+		}
+		if name == "cryptsetup" {
+			return crypt.run(args...)
+		}
+		return "", nil
+	}}
+
+	luks := luksVolume{Filesystem: "ext4", KeyID: "vol1"}
+	if _, err := openLUKS(runner, provider, "vol1", luks, "/dev/vg/vol1"); err == nil {
+		t.Fatal("Opening a container that could not be formatted should fail")
+	}
+
+	if len(crypt.opened) != 0 || !containsString(commands, "wipefs") {
+		t.Error("The container should be closed and wiped, so that the next mount formats it again. Ran: ", commands)
+	}
+
+	// The key is kept, and reused, so that a retry does not need to create it.
+	key, err := provider.GetKey("vol1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	crypt.formatted = map[string]bool{}
+	runner.CommandRunnerFunc = func(name string, args ...string) (string, error) {
+		if name == "cryptsetup" {
+			return crypt.run(args...)
+		}
+		return "", nil
+	}
+
+	device, err := openLUKS(runner, provider, "vol1", luks, "/dev/vg/vol1")
+	if err != nil || device != "/dev/mapper/docker-volume-rdma-vol1" {
+		t.Fatal("Expected the container to be opened on retry, got ", device, err)
+	}
+
+	if string(runner.inputs[len(runner.inputs)-1]) != string(key) {
+		t.Error("The retry should open the container with the existing key")
+	}
+}
+
+func TestOpenLUKS_noInput(t *testing.T) {
+	t.Parallel()
+	provider, cleanUp := newTestKeyProvider(t)
+	defer cleanUp()
+
+	crypt := newFakeCrypt()
+	runner := CommandRunnerFunc(func(name string, args ...string) (string, error) {
+		return crypt.run(args...)
+	})
+
+	if _, err := openLUKS(runner, provider, "vol1", luksVolume{Filesystem: "ext4", KeyID: "vol1"}, "/dev/vg/vol1"); err == nil {
+		t.Error("Keys should never be passed to cryptsetup by a runner that can not pass them on its standard input")
+	}
+
+	if _, err := openLUKS(runner, nil, "vol1", luksVolume{Filesystem: "ext4", KeyID: "vol1"}, "/dev/vg/vol1"); err == nil {
+		t.Error("Opening an encrypted volume without a key provider should fail")
+	}
+}
+
+func TestEncryptionOptions(t *testing.T) {
+	t.Parallel()
+	provider, cleanUp := newTestKeyProvider(t)
+	defer cleanUp()
+
+	encrypted := map[string]string{EncryptedOption: "true"}
+	lvm := newFakeLVMStorageController(newFakeLVM())
+
+	if _, err := encryptionOptions(lvm, "vol1", encrypted); err == nil {
+		t.Error("Encrypting a volume should fail when no key provider is configured")
+	}
+
+	lvm.Keys = provider
+	options, err := encryptionOptions(lvm, "vol1", encrypted)
+	if err != nil || options[KeyIDOption] != "vol1" || options[EncryptedOption] != "true" {
+		t.Error("Expected the volume's key ID to be recorded, got ", options, err)
+	}
+
+	if _, set := encrypted[KeyIDOption]; set {
+		t.Error("The requested options should not be changed")
+	}
+
+	if _, err = encryptionOptions(lvm, "vol1", map[string]string{EncryptedOption: "true", KeyIDOption: "vol2"}); err == nil {
+		t.Error("Choosing the key of another volume should fail")
+	}
+
+	onDisk := NewOnDiskStorageController("tests/docker/mounts/")
+	if _, err = encryptionOptions(onDisk, "vol1", encrypted); err == nil {
+		t.Error("Encrypting a volume should fail on storage controllers that can not encrypt")
+	}
+
+	options, err = encryptionOptions(onDisk, "vol1", map[string]string{"size": "1G"})
+	if err != nil || len(options) != 1 || options[KeyIDOption] != "" {
+		t.Error("Volumes that are not encrypted should not have a key, got ", options, err)
+	}
+}
+
+func TestShredKey(t *testing.T) {
+	t.Parallel()
+	provider, cleanUp := newTestKeyProvider(t)
+	defer cleanUp()
+
+	lvm := newFakeLVMStorageController(newFakeLVM())
+	lvm.Keys = provider
+
+	if _, err := provider.CreateKey("vol1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := shredKey(lvm, "vol1", map[string]string{KeyIDOption: "vol1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := provider.GetKey("vol1"); err == nil {
+		t.Error("The volume's key should be deleted")
+	}
+
+	if err := shredKey(NewOnDiskStorageController("tests/docker/mounts/"), "vol2", map[string]string{}); err != nil {
+		t.Error("Volumes without a key should have nothing to delete, got ", err)
+	}
+
+	lvm.Keys = nil
+	if err := shredKey(lvm, "vol1", map[string]string{KeyIDOption: "vol1"}); err == nil {
+		t.Error("A key that can not be deleted should fail, so that the volume can be removed again later")
+	}
+}
+
+func TestNewKeyProvider(t *testing.T) {
+	t.Parallel()
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-keys")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	tokenFile := filepath.Join(tempDir, "token")
+	if err = ioutil.WriteFile(tokenFile, []byte("s.test\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		set      map[string]string
+		expected keys.KeyProvider
+		fails    bool
+	}{
+		{map[string]string{}, nil, false},
+		{map[string]string{"sc-key-dir": tempDir}, keys.NewDirectoryProvider(tempDir), false},
+		{map[string]string{"sc-key-dir": tempDir, "sc-vault-addr": "http://vault:8200"}, nil, true},
+		{map[string]string{"sc-vault-addr": "http://vault:8200"}, nil, true},
+		{map[string]string{"sc-vault-addr": "http://vault:8200", "sc-vault-token-file": filepath.Join(tempDir, "missing")}, nil, true},
+		{map[string]string{"sc-vault-addr": "vault:8200", "sc-vault-token-file": tokenFile}, nil, true},
+	}
+
+	for _, test := range tests {
+		provider, err := newKeyProvider(config.NewValues(keyProviderOptions, test.set))
+		if test.fails != (err != nil) || (!test.fails && provider != test.expected) {
+			t.Error("Expected ", test.set, " to configure ", test.expected, ", got ", provider, err)
+		}
+	}
+
+	provider, err := newKeyProvider(config.NewValues(keyProviderOptions, map[string]string{"sc-vault-addr": "https://vault:8200", "sc-vault-token-file": tokenFile, "sc-vault-mount": "volumes"}))
+	vault, ok := provider.(keys.VaultProvider)
+	if err != nil || !ok || vault.Token != "s.test" || vault.Mount != "volumes" {
+		t.Error("Expected a vault provider using the token in the token file, got ", provider, err)
+	}
+}
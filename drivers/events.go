@@ -0,0 +1,28 @@
+package drivers
+
+import "github.com/golang/glog"
+
+// The lifecycle events of a volume that are sent to the driver's Notifier.
+const (
+	VolumeCreated   = "volume.created"
+	VolumeMounted   = "volume.mounted"
+	VolumeUnmounted = "volume.unmounted"
+	VolumeRemoved   = "volume.removed"
+)
+
+// Notifier is told about the lifecycle of volumes, it is implemented by webhook.Notifier.
+type Notifier interface {
+	Notify(eventType string, volumeName string) error
+}
+
+// notify tells the Notifier, if one is set, about an event. The operation has already happened by then, so an event
+// that can not be queued is logged rather than failing it.
+func (r RDMAVolumeDriver) notify(eventType string, volumeName string) {
+	if r.Notifier == nil {
+		return
+	}
+
+	if err := r.Notifier.Notify(eventType, volumeName); err != nil {
+		glog.Error("Unable to queue the ", eventType, " event of ", volumeName, ": ", err)
+	}
+}
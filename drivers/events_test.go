@@ -0,0 +1,58 @@
+package drivers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/db"
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
+)
+
+// recordingNotifier remembers the events it is told about, failing if fail is set.
+type recordingNotifier struct {
+	events []string
+	fail   bool
+}
+
+func (r *recordingNotifier) Notify(eventType string, volumeName string) error {
+	if r.fail {
+		return errors.New("the outbox is full")
+	}
+
+	r.events = append(r.events, eventType+" "+volumeName)
+	return nil
+}
+
+func TestNotify(t *testing.T) {
+	t.Parallel()
+	notifier := &recordingNotifier{}
+	rdmaVolDriver := NewRDMAVolumeDriver(newFakeTmpfsStorageController(newFakeMounts()), db.NewInMemoryVolumeDatabase())
+	rdmaVolDriver.Notifier = notifier
+
+	rdmaVolDriver.Create(volume.Request{Name: "vol1", Options: map[string]string{"size": "1m"}})
+	rdmaVolDriver.Create(volume.Request{Name: "vol1", Options: map[string]string{"size": "1m"}})
+	rdmaVolDriver.Mount(volume.MountRequest{Name: "vol1", ID: "c1"})
+	rdmaVolDriver.Mount(volume.MountRequest{Name: "vol1", ID: "c2"})
+	rdmaVolDriver.Mount(volume.MountRequest{Name: "missing", ID: "c3"})
+	rdmaVolDriver.Unmount(volume.UnmountRequest{Name: "vol1", ID: "c1"})
+	rdmaVolDriver.Unmount(volume.UnmountRequest{Name: "vol1", ID: "c2"})
+	rdmaVolDriver.Remove(volume.Request{Name: "vol1"})
+	rdmaVolDriver.Remove(volume.Request{Name: "vol1"})
+
+	expected := []string{"volume.created vol1", "volume.mounted vol1", "volume.unmounted vol1", "volume.removed vol1"}
+	if len(notifier.events) != len(expected) {
+		t.Fatal("Expected ", expected, ", got ", notifier.events)
+	}
+
+	for i := range expected {
+		if notifier.events[i] != expected[i] {
+			t.Error("Expected ", expected[i], ", got ", notifier.events[i])
+		}
+	}
+
+	// Events that can not be queued do not fail the operation, which has already happened.
+	notifier.fail = true
+	if response := rdmaVolDriver.Create(volume.Request{Name: "vol2", Options: map[string]string{"size": "1m"}}); response.Err != "" {
+		t.Error("Expected the volume to be created, got ", response.Err)
+	}
+}
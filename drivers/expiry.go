@@ -0,0 +1,127 @@
+package drivers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// The create options that let a volume expire, as durations such as 72h. A volume with a ttl expires that long after
+// it was created, and a volume with expire-after-unmount expires once it has not been mounted for that long, counting
+// from when it was created until it is first mounted. Expired volumes are removed by the janitor once nothing has
+// them mounted.
+const (
+	TTLOption                = "ttl"
+	ExpireAfterUnmountOption = "expire-after-unmount"
+)
+
+// The options that hold when a volume expires, in RFC 3339. ExpiresAtOption is fixed when the volume is created, and
+// IdleExpiresAtOption is pushed back each time the volume's last mount request is released.
+const (
+	ExpiresAtOption     = "expires-at"
+	IdleExpiresAtOption = "idle-expires-at"
+)
+
+// expiryOptions records when a new volume expires in a copy of its options, if it was given a ttl or
+// expire-after-unmount. The expiry times themselves may not be chosen.
+func expiryOptions(options map[string]string, now time.Time) (map[string]string, error) {
+	for _, option := range []string{ExpiresAtOption, IdleExpiresAtOption} {
+		if _, set := options[option]; set {
+			return nil, errors.New("the " + option + " option is set by the driver and can not be chosen, please use " + TTLOption + " or " + ExpireAfterUnmountOption)
+		}
+	}
+
+	ttl, err := parseExpiryOption(options, TTLOption)
+	if err != nil {
+		return nil, err
+	}
+
+	idle, err := parseExpiryOption(options, ExpireAfterUnmountOption)
+	if err != nil {
+		return nil, err
+	}
+
+	if ttl == 0 && idle == 0 {
+		return options, nil
+	}
+
+	resolved := map[string]string{}
+	for name, value := range options {
+		resolved[name] = value
+	}
+
+	if ttl != 0 {
+		resolved[ExpiresAtOption] = formatExpiry(now.Add(ttl))
+	}
+
+	if idle != 0 {
+		resolved[IdleExpiresAtOption] = formatExpiry(now.Add(idle))
+	}
+
+	return resolved, nil
+}
+
+// parseExpiryOption parses one of the expiry options, returning 0 if it is not set.
+func parseExpiryOption(options map[string]string, option string) (time.Duration, error) {
+	value := options[option]
+	if value == "" {
+		return 0, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return 0, errors.New("invalid " + option + ": " + value + ", expected a positive duration such as 72h")
+	}
+
+	return duration, nil
+}
+
+// Expiry returns when a volume with options expires, the earlier of its expiry times, and false if it never does.
+func Expiry(options map[string]string) (time.Time, bool, error) {
+	var expiry time.Time
+	for _, option := range []string{ExpiresAtOption, IdleExpiresAtOption} {
+		value := options[option]
+		if value == "" {
+			continue
+		}
+
+		at, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, false, errors.New("invalid " + option + ": " + value)
+		}
+
+		if expiry.IsZero() || at.Before(expiry) {
+			expiry = at
+		}
+	}
+
+	return expiry, !expiry.IsZero(), nil
+}
+
+// restartIdleExpiry pushes back when a volume with expire-after-unmount expires, now that it is no longer mounted. The
+// volume has been unmounted by then, so a failure is logged, leaving the volume to expire at its previous time.
+func (r RDMAVolumeDriver) restartIdleExpiry(volumeName string) {
+	options, err := r.VolumeDatabase.Options(volumeName)
+	if err == nil && options[ExpireAfterUnmountOption] == "" {
+		return
+	}
+
+	var idle time.Duration
+	if err == nil {
+		idle, err = parseExpiryOption(options, ExpireAfterUnmountOption)
+	}
+
+	if err == nil {
+		err = r.VolumeDatabase.SetOption(volumeName, IdleExpiresAtOption, formatExpiry(time.Now().Add(idle)))
+	}
+
+	if err != nil {
+		glog.Error("Unable to record when ", volumeName, " expires: ", err)
+	}
+}
+
+// formatExpiry formats an expiry time for the volume database.
+func formatExpiry(at time.Time) string {
+	return at.UTC().Format(time.RFC3339)
+}
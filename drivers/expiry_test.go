@@ -0,0 +1,73 @@
+package drivers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/db"
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
+)
+
+func TestExpiryOptions(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2026, 10, 19, 9, 30, 0, 0, time.UTC)
+
+	options, err := expiryOptions(map[string]string{TTLOption: "72h", ExpireAfterUnmountOption: "30m", "size": "1G"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if options[ExpiresAtOption] != "2026-10-22T09:30:00Z" || options[IdleExpiresAtOption] != "2026-10-19T10:00:00Z" || options["size"] != "1G" {
+		t.Error("Unexpected options: ", options)
+	}
+
+	expiry, expires, err := Expiry(options)
+	if err != nil || !expires || !expiry.Equal(now.Add(30*time.Minute)) {
+		t.Error("Expected the volume to expire at the earlier time, got ", expiry, expires, err)
+	}
+
+	if _, expires, err = Expiry(map[string]string{"size": "1G"}); expires || err != nil {
+		t.Error("A volume without an expiry should never expire, got ", expires, err)
+	}
+
+	if _, _, err = Expiry(map[string]string{ExpiresAtOption: "tomorrow"}); err == nil {
+		t.Error("An invalid expiry time should fail")
+	}
+
+	for _, option := range []string{ExpiresAtOption, IdleExpiresAtOption} {
+		if _, err = expiryOptions(map[string]string{option: "2099-01-01T00:00:00Z"}, now); err == nil {
+			t.Error("The ", option, " option should not be chosen")
+		}
+	}
+
+	for _, value := range []string{"3 days", "-1h", "0s"} {
+		if _, err = expiryOptions(map[string]string{TTLOption: value}, now); err == nil {
+			t.Error("A ttl of ", value, " should be invalid")
+		}
+	}
+}
+
+func TestRestartIdleExpiry(t *testing.T) {
+	t.Parallel()
+	rdmaVolDriver := NewRDMAVolumeDriver(newFakeTmpfsStorageController(newFakeMounts()), db.NewInMemoryVolumeDatabase())
+
+	if response := rdmaVolDriver.Create(volume.Request{Name: "vol1", Options: map[string]string{"size": "1m", ExpireAfterUnmountOption: "1h"}}); response.Err != "" {
+		t.Fatal(response.Err)
+	}
+
+	if response := rdmaVolDriver.Create(volume.Request{Name: "vol2", Options: map[string]string{"size": "1m", TTLOption: "bad"}}); response.Err == "" {
+		t.Error("A volume with an invalid ttl should not be created")
+	}
+
+	options, _ := rdmaVolDriver.VolumeDatabase.Options("vol1")
+	created := options[IdleExpiresAtOption]
+
+	rdmaVolDriver.Mount(volume.MountRequest{Name: "vol1", ID: "c1"})
+	rdmaVolDriver.VolumeDatabase.SetOption("vol1", IdleExpiresAtOption, "2000-01-01T00:00:00Z")
+	rdmaVolDriver.Unmount(volume.UnmountRequest{Name: "vol1", ID: "c1"})
+
+	options, _ = rdmaVolDriver.VolumeDatabase.Options("vol1")
+	if created == "" || options[IdleExpiresAtOption] < created {
+		t.Error("Unmounting the volume should push back its expiry, got ", options[IdleExpiresAtOption], " after ", created)
+	}
+}
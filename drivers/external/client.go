@@ -0,0 +1,185 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/mellanox-senior-design/docker-volume-rdma/config"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func init() {
+	drivers.Register("external", drivers.StorageControllerFactory{
+		Options: []config.Option{
+			{Name: "sc-endpoint", Description: "set the endpoint of an out of process storage controller, e.g. unix:///run/docker-volume-rdma/controller.sock"},
+		},
+		New: func(values config.Values) (drivers.StorageController, error) {
+			return NewClient(values.String("sc-endpoint"))
+		}})
+}
+
+// DefaultTimeout is how long a call to the remote Storage Controller may take, mounting remote storage can be slow.
+const DefaultTimeout = 5 * time.Minute
+
+// Client is a Storage Controller that forwards every call to a StorageController gRPC server.
+type Client struct {
+	Endpoint string
+	Timeout  time.Duration
+
+	conn   *grpc.ClientConn
+	client StorageControllerClient
+}
+
+// NewClient creates a new Client for the server listening on endpoint, e.g. unix:///run/controller.sock or
+// tcp://localhost:9000. The server is not contacted until Connect is called.
+func NewClient(endpoint string) (Client, error) {
+	network, address, err := parseEndpoint(endpoint)
+	if err != nil {
+		return Client{}, err
+	}
+
+	target := address
+	if network == "unix" {
+		target = "unix://" + address
+	}
+
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return Client{}, err
+	}
+
+	glog.Info("External storage controller: ", endpoint)
+
+	return Client{
+		Endpoint: endpoint,
+		Timeout:  DefaultTimeout,
+		conn:     conn,
+		client:   NewStorageControllerClient(conn)}, nil
+}
+
+// Connect to the remote Storage Controller.
+func (c Client) Connect() error {
+	ctx, cancel := c.context()
+	defer cancel()
+
+	_, err := c.client.Connect(ctx, &ConnectRequest{})
+	return c.remoteError(err)
+}
+
+// Disconnect from the remote Storage Controller, closing the connection to it.
+func (c Client) Disconnect() error {
+	ctx, cancel := c.context()
+	defer cancel()
+
+	_, err := c.client.Disconnect(ctx, &DisconnectRequest{})
+
+	closeErr := c.conn.Close()
+	if err == nil {
+		return closeErr
+	}
+
+	return c.remoteError(err)
+}
+
+// Create a volume with options.
+func (c Client) Create(volumeName string, options map[string]string) error {
+	ctx, cancel := c.context()
+	defer cancel()
+
+	_, err := c.client.Create(ctx, &CreateRequest{VolumeName: volumeName, Options: options})
+	return c.remoteError(err)
+}
+
+// Mount a particular volume, returning where the remote Storage Controller mounted it.
+func (c Client) Mount(volumeName string) (string, error) {
+	ctx, cancel := c.context()
+	defer cancel()
+
+	response, err := c.client.Mount(ctx, &MountRequest{VolumeName: volumeName})
+	if err != nil {
+		return "", c.remoteError(err)
+	}
+
+	return response.Mountpoint, nil
+}
+
+// Unmount a particular volume.
+func (c Client) Unmount(volumeName string) error {
+	ctx, cancel := c.context()
+	defer cancel()
+
+	_, err := c.client.Unmount(ctx, &UnmountRequest{VolumeName: volumeName})
+	return c.remoteError(err)
+}
+
+// Delete a particular volume.
+func (c Client) Delete(volumeName string) error {
+	ctx, cancel := c.context()
+	defer cancel()
+
+	_, err := c.client.Delete(ctx, &DeleteRequest{VolumeName: volumeName})
+	return c.remoteError(err)
+}
+
+// Health returns the health reported by the remote Storage Controller, or nothing if it does not report its health.
+func (c Client) Health() (map[string]interface{}, error) {
+	ctx, cancel := c.context()
+	defer cancel()
+
+	response, err := c.client.Health(ctx, &HealthRequest{})
+	if status.Code(err) == codes.Unimplemented {
+		return nil, nil
+	} else if err != nil {
+		return nil, c.remoteError(err)
+	}
+
+	var details map[string]interface{}
+	if response.Details != nil {
+		details = response.Details.AsMap()
+	}
+
+	if response.Error != "" {
+		return details, errors.New(response.Error)
+	}
+
+	return details, nil
+}
+
+// Snapshot copies a volume to snapshotName, if the remote Storage Controller supports snapshots.
+func (c Client) Snapshot(volumeName string, snapshotName string) error {
+	ctx, cancel := c.context()
+	defer cancel()
+
+	_, err := c.client.Snapshot(ctx, &SnapshotRequest{VolumeName: volumeName, SnapshotName: snapshotName})
+	if status.Code(err) == codes.Unimplemented {
+		return errors.New("the storage controller at " + c.Endpoint + " does not support snapshots")
+	}
+
+	return c.remoteError(err)
+}
+
+func (c Client) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), c.Timeout)
+}
+
+// remoteError returns the error reported by the remote Storage Controller without the gRPC status details, so
+// that e.g. "already unmounted" reads the same as it would from a local Storage Controller.
+func (c Client) remoteError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	remote := status.Convert(err)
+	switch remote.Code() {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return errors.New("unable to reach the storage controller at " + c.Endpoint + ": " + remote.Message())
+	default:
+		return errors.New(remote.Message())
+	}
+}
@@ -0,0 +1,216 @@
+package external
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/config"
+	"github.com/mellanox-senior-design/docker-volume-rdma/db"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
+	"google.golang.org/grpc"
+)
+
+// healthyStorageController is an on-disk Storage Controller that also reports its health and takes snapshots.
+type healthyStorageController struct {
+	drivers.OnDiskStorageController
+	snapshots map[string]string
+}
+
+func (h healthyStorageController) Health() (map[string]interface{}, error) {
+	return map[string]interface{}{"DataPercent": 95.5}, errors.New("thin pool is nearly full")
+}
+
+func (h healthyStorageController) Snapshot(volumeName string, snapshotName string) error {
+	h.snapshots[snapshotName] = volumeName
+	return nil
+}
+
+// startServer serves storageController on a unix socket in a new temporary directory, returning the endpoint and
+// the directory.
+func startServer(t *testing.T, storageController func(string) drivers.StorageController) (string, string) {
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-external")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+
+	endpoint := "unix://" + path.Join(tempDir, "controller.sock")
+	listener, err := Listen(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := grpc.NewServer()
+	RegisterStorageControllerServer(server, NewServer(storageController(path.Join(tempDir, "volumes"))))
+	go server.Serve(listener)
+
+	return endpoint, tempDir
+}
+
+func onDisk(mountPath string) drivers.StorageController {
+	return drivers.NewOnDiskStorageController(mountPath)
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+	endpoint, tempDir := startServer(t, onDisk)
+	defer os.RemoveAll(tempDir)
+
+	client, err := NewClient(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = client.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = client.Create("externalvol", map[string]string{"size": "1G"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mountpoint, err := client.Mount("externalvol")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mountpoint != path.Join(tempDir, "volumes", "externalvol") {
+		t.Error("The volume was not mounted by the server's on-disk storage controller, got ", mountpoint)
+	}
+
+	if _, err = os.Stat(mountpoint); err != nil {
+		t.Error("The server should have created the mountpoint: ", err)
+	}
+
+	err = client.Unmount("externalvol")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Errors from the server's storage controller are passed on unchanged.
+	err = client.Unmount("externalvol")
+	if err == nil || err.Error() != "already unmounted" {
+		t.Error("Expected the error already unmounted, got ", err)
+	}
+
+	err = client.Delete("externalvol")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The on-disk storage controller neither reports its health nor takes snapshots.
+	health, err := client.Health()
+	if health != nil || err != nil {
+		t.Error("Health should report nothing when the server does not implement it, got ", health, err)
+	}
+
+	err = client.Snapshot("externalvol", "externalsnap")
+	if err == nil {
+		t.Error("Snapshot should fail when the server does not implement it")
+	}
+
+	err = client.Disconnect()
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestClientOptionalCalls(t *testing.T) {
+	t.Parallel()
+	snapshots := map[string]string{}
+	endpoint, tempDir := startServer(t, func(mountPath string) drivers.StorageController {
+		return healthyStorageController{drivers.NewOnDiskStorageController(mountPath), snapshots}
+	})
+	defer os.RemoveAll(tempDir)
+
+	client, err := NewClient(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect()
+
+	health, err := client.Health()
+	if err == nil || err.Error() != "thin pool is nearly full" {
+		t.Error("Expected the server's health error, got ", err)
+	}
+
+	if health["DataPercent"] != 95.5 {
+		t.Error("Expected the server's health details, got ", health)
+	}
+
+	err = client.Snapshot("externalvol", "externalsnap")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if snapshots["externalsnap"] != "externalvol" {
+		t.Error("The server did not snapshot externalvol to externalsnap, got ", snapshots)
+	}
+}
+
+func TestClientUnreachable(t *testing.T) {
+	t.Parallel()
+
+	client, err := NewClient("unix:///does/not/exist.sock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect()
+
+	err = client.Connect()
+	if err == nil {
+		t.Error("Connecting to a server that is not running should fail")
+	}
+}
+
+func TestRDMAVolumeDriver(t *testing.T) {
+	t.Parallel()
+	endpoint, tempDir := startServer(t, onDisk)
+	defer os.RemoveAll(tempDir)
+
+	factory, exists := drivers.Lookup("external")
+	if !exists {
+		t.Fatal("The external storage controller is not registered")
+	}
+
+	storageController, err := factory.New(config.NewValues(factory.Options, map[string]string{"sc-endpoint": endpoint}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	driver := drivers.NewRDMAVolumeDriver(storageController, db.NewInMemoryVolumeDatabase())
+	err = driver.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer driver.Disconnect()
+
+	response := driver.Create(volume.Request{Name: "drivervol"})
+	if response.Err != "" {
+		t.Fatal(response.Err)
+	}
+
+	response = driver.Mount(volume.MountRequest{Name: "drivervol", ID: "1"})
+	if response.Err != "" {
+		t.Fatal(response.Err)
+	}
+
+	if response.Mountpoint != path.Join(tempDir, "volumes", "drivervol") {
+		t.Error("The volume was not mounted by the external storage controller, got ", response.Mountpoint)
+	}
+
+	response = driver.Unmount(volume.UnmountRequest{Name: "drivervol", ID: "1"})
+	if response.Err != "" {
+		t.Fatal(response.Err)
+	}
+
+	response = driver.Remove(volume.Request{Name: "drivervol"})
+	if response.Err != "" {
+		t.Fatal(response.Err)
+	}
+}
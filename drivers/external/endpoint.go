@@ -0,0 +1,53 @@
+// Package external runs Storage Controllers outside of the docker-volume-rdma process, talking to them over the
+// StorageController gRPC service defined in storagecontroller.proto.
+package external
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"os"
+)
+
+// parseEndpoint splits an endpoint, e.g. unix:///run/controller.sock or tcp://localhost:9000, into the network and
+// address to listen on or dial.
+func parseEndpoint(endpoint string) (string, string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", "", errors.New("invalid endpoint " + endpoint + ": " + err.Error())
+	}
+
+	switch u.Scheme {
+	case "unix":
+		if u.Path == "" {
+			return "", "", errors.New("invalid endpoint " + endpoint + ", the socket path is missing")
+		}
+		return "unix", u.Path, nil
+
+	case "tcp":
+		if u.Host == "" {
+			return "", "", errors.New("invalid endpoint " + endpoint + ", the host is missing")
+		}
+		return "tcp", u.Host, nil
+
+	default:
+		return "", "", errors.New("unsupported endpoint " + endpoint + ", please use unix:// or tcp://")
+	}
+}
+
+// Listen listens on an endpoint, removing a unix socket left behind by a previous server.
+func Listen(endpoint string) (net.Listener, error) {
+	network, address, err := parseEndpoint(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	if network == "unix" {
+		err = os.Remove(address)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	return net.Listen(network, address)
+}
@@ -0,0 +1,34 @@
+package external
+
+import "testing"
+
+func TestParseEndpoint(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		endpoint string
+		network  string
+		address  string
+		valid    bool
+	}{
+		{"unix:///run/controller.sock", "unix", "/run/controller.sock", true},
+		{"tcp://localhost:9000", "tcp", "localhost:9000", true},
+		{"unix://", "", "", false},
+		{"tcp://", "", "", false},
+		{"http://localhost:9000", "", "", false},
+		{"/run/controller.sock", "", "", false},
+		{"", "", "", false},
+	}
+
+	for _, test := range tests {
+		network, address, err := parseEndpoint(test.endpoint)
+		if (err == nil) != test.valid {
+			t.Errorf("parseEndpoint(%q) error = %v; want valid %v", test.endpoint, err, test.valid)
+			continue
+		}
+
+		if network != test.network || address != test.address {
+			t.Errorf("parseEndpoint(%q) = %q, %q; want %q, %q", test.endpoint, network, address, test.network, test.address)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package external
+
+import (
+	"context"
+
+	"github.com/golang/glog"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Server serves a Storage Controller over gRPC so that it can be used with -sc=external. Health and Snapshot are
+// only served if the Storage Controller implements them.
+type Server struct {
+	UnimplementedStorageControllerServer
+	StorageController drivers.StorageController
+}
+
+// NewServer creates a new Server for storageController.
+func NewServer(storageController drivers.StorageController) Server {
+	return Server{StorageController: storageController}
+}
+
+// Serve listens on endpoint and serves storageController until the listener fails.
+func Serve(endpoint string, storageController drivers.StorageController) error {
+	listener, err := Listen(endpoint)
+	if err != nil {
+		return err
+	}
+
+	server := grpc.NewServer()
+	RegisterStorageControllerServer(server, NewServer(storageController))
+
+	glog.Info("Serving storage controller on ", endpoint)
+	return server.Serve(listener)
+}
+
+// Connect the Storage Controller.
+func (s Server) Connect(ctx context.Context, request *ConnectRequest) (*ConnectResponse, error) {
+	return &ConnectResponse{}, s.StorageController.Connect()
+}
+
+// Disconnect the Storage Controller.
+func (s Server) Disconnect(ctx context.Context, request *DisconnectRequest) (*DisconnectResponse, error) {
+	return &DisconnectResponse{}, s.StorageController.Disconnect()
+}
+
+// Create a volume.
+func (s Server) Create(ctx context.Context, request *CreateRequest) (*CreateResponse, error) {
+	return &CreateResponse{}, s.StorageController.Create(request.VolumeName, request.Options)
+}
+
+// Mount a volume.
+func (s Server) Mount(ctx context.Context, request *MountRequest) (*MountResponse, error) {
+	mountpoint, err := s.StorageController.Mount(request.VolumeName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MountResponse{Mountpoint: mountpoint}, nil
+}
+
+// Unmount a volume.
+func (s Server) Unmount(ctx context.Context, request *UnmountRequest) (*UnmountResponse, error) {
+	return &UnmountResponse{}, s.StorageController.Unmount(request.VolumeName)
+}
+
+// Delete a volume.
+func (s Server) Delete(ctx context.Context, request *DeleteRequest) (*DeleteResponse, error) {
+	return &DeleteResponse{}, s.StorageController.Delete(request.VolumeName)
+}
+
+// Health reports the health of the Storage Controller, if it is a drivers.StorageHealthReporter.
+func (s Server) Health(ctx context.Context, request *HealthRequest) (*HealthResponse, error) {
+	reporter, ok := s.StorageController.(drivers.StorageHealthReporter)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "the storage controller does not report its health")
+	}
+
+	response := &HealthResponse{}
+	details, err := reporter.Health()
+	if err != nil {
+		response.Error = err.Error()
+	}
+
+	if details != nil {
+		response.Details, err = structpb.NewStruct(details)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return response, nil
+}
+
+// Snapshot a volume, if the Storage Controller is a drivers.StorageSnapshotter.
+func (s Server) Snapshot(ctx context.Context, request *SnapshotRequest) (*SnapshotResponse, error) {
+	snapshotter, ok := s.StorageController.(drivers.StorageSnapshotter)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "the storage controller does not support snapshots")
+	}
+
+	return &SnapshotResponse{}, snapshotter.Snapshot(request.VolumeName, request.SnapshotName)
+}
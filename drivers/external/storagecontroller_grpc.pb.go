@@ -0,0 +1,394 @@
+// Regenerate storagecontroller.pb.go and storagecontroller_grpc.pb.go from the root of the repository with:
+//
+//   protoc --go_out=. --go_opt=paths=source_relative \
+//          --go-grpc_out=. --go-grpc_opt=paths=source_relative \
+//          drivers/external/storagecontroller.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: drivers/external/storagecontroller.proto
+
+package external
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	StorageController_Connect_FullMethodName    = "/dockervolumerdma.storagecontroller.v1.StorageController/Connect"
+	StorageController_Disconnect_FullMethodName = "/dockervolumerdma.storagecontroller.v1.StorageController/Disconnect"
+	StorageController_Create_FullMethodName     = "/dockervolumerdma.storagecontroller.v1.StorageController/Create"
+	StorageController_Mount_FullMethodName      = "/dockervolumerdma.storagecontroller.v1.StorageController/Mount"
+	StorageController_Unmount_FullMethodName    = "/dockervolumerdma.storagecontroller.v1.StorageController/Unmount"
+	StorageController_Delete_FullMethodName     = "/dockervolumerdma.storagecontroller.v1.StorageController/Delete"
+	StorageController_Health_FullMethodName     = "/dockervolumerdma.storagecontroller.v1.StorageController/Health"
+	StorageController_Snapshot_FullMethodName   = "/dockervolumerdma.storagecontroller.v1.StorageController/Snapshot"
+)
+
+// StorageControllerClient is the client API for StorageController service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type StorageControllerClient interface {
+	// Connect is called once before any volumes are created or mounted.
+	Connect(ctx context.Context, in *ConnectRequest, opts ...grpc.CallOption) (*ConnectResponse, error)
+	// Disconnect is called once when docker-volume-rdma is shutting down.
+	Disconnect(ctx context.Context, in *DisconnectRequest, opts ...grpc.CallOption) (*DisconnectResponse, error)
+	// Create a volume with options, allocating any storage it needs up front.
+	Create(ctx context.Context, in *CreateRequest, opts ...grpc.CallOption) (*CreateResponse, error)
+	// Mount a volume on the host, returning where it was mounted.
+	Mount(ctx context.Context, in *MountRequest, opts ...grpc.CallOption) (*MountResponse, error)
+	// Unmount a volume from the host, keeping its data.
+	Unmount(ctx context.Context, in *UnmountRequest, opts ...grpc.CallOption) (*UnmountResponse, error)
+	// Delete a volume and its data.
+	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
+	// Health reports details about the backing storage. Optional, servers that do not implement it return
+	// UNIMPLEMENTED.
+	Health(ctx context.Context, in *HealthRequest, opts ...grpc.CallOption) (*HealthResponse, error)
+	// Snapshot copies a volume to a new volume that can be mounted and deleted like any other. Optional, servers that
+	// do not implement it return UNIMPLEMENTED.
+	Snapshot(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (*SnapshotResponse, error)
+}
+
+type storageControllerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewStorageControllerClient(cc grpc.ClientConnInterface) StorageControllerClient {
+	return &storageControllerClient{cc}
+}
+
+func (c *storageControllerClient) Connect(ctx context.Context, in *ConnectRequest, opts ...grpc.CallOption) (*ConnectResponse, error) {
+	out := new(ConnectResponse)
+	err := c.cc.Invoke(ctx, StorageController_Connect_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageControllerClient) Disconnect(ctx context.Context, in *DisconnectRequest, opts ...grpc.CallOption) (*DisconnectResponse, error) {
+	out := new(DisconnectResponse)
+	err := c.cc.Invoke(ctx, StorageController_Disconnect_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageControllerClient) Create(ctx context.Context, in *CreateRequest, opts ...grpc.CallOption) (*CreateResponse, error) {
+	out := new(CreateResponse)
+	err := c.cc.Invoke(ctx, StorageController_Create_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageControllerClient) Mount(ctx context.Context, in *MountRequest, opts ...grpc.CallOption) (*MountResponse, error) {
+	out := new(MountResponse)
+	err := c.cc.Invoke(ctx, StorageController_Mount_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageControllerClient) Unmount(ctx context.Context, in *UnmountRequest, opts ...grpc.CallOption) (*UnmountResponse, error) {
+	out := new(UnmountResponse)
+	err := c.cc.Invoke(ctx, StorageController_Unmount_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageControllerClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error) {
+	out := new(DeleteResponse)
+	err := c.cc.Invoke(ctx, StorageController_Delete_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageControllerClient) Health(ctx context.Context, in *HealthRequest, opts ...grpc.CallOption) (*HealthResponse, error) {
+	out := new(HealthResponse)
+	err := c.cc.Invoke(ctx, StorageController_Health_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageControllerClient) Snapshot(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (*SnapshotResponse, error) {
+	out := new(SnapshotResponse)
+	err := c.cc.Invoke(ctx, StorageController_Snapshot_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// StorageControllerServer is the server API for StorageController service.
+// All implementations must embed UnimplementedStorageControllerServer
+// for forward compatibility
+type StorageControllerServer interface {
+	// Connect is called once before any volumes are created or mounted.
+	Connect(context.Context, *ConnectRequest) (*ConnectResponse, error)
+	// Disconnect is called once when docker-volume-rdma is shutting down.
+	Disconnect(context.Context, *DisconnectRequest) (*DisconnectResponse, error)
+	// Create a volume with options, allocating any storage it needs up front.
+	Create(context.Context, *CreateRequest) (*CreateResponse, error)
+	// Mount a volume on the host, returning where it was mounted.
+	Mount(context.Context, *MountRequest) (*MountResponse, error)
+	// Unmount a volume from the host, keeping its data.
+	Unmount(context.Context, *UnmountRequest) (*UnmountResponse, error)
+	// Delete a volume and its data.
+	Delete(context.Context, *DeleteRequest) (*DeleteResponse, error)
+	// Health reports details about the backing storage. Optional, servers that do not implement it return
+	// UNIMPLEMENTED.
+	Health(context.Context, *HealthRequest) (*HealthResponse, error)
+	// Snapshot copies a volume to a new volume that can be mounted and deleted like any other. Optional, servers that
+	// do not implement it return UNIMPLEMENTED.
+	Snapshot(context.Context, *SnapshotRequest) (*SnapshotResponse, error)
+	mustEmbedUnimplementedStorageControllerServer()
+}
+
+// UnimplementedStorageControllerServer must be embedded to have forward compatible implementations.
+type UnimplementedStorageControllerServer struct {
+}
+
+func (UnimplementedStorageControllerServer) Connect(context.Context, *ConnectRequest) (*ConnectResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Connect not implemented")
+}
+func (UnimplementedStorageControllerServer) Disconnect(context.Context, *DisconnectRequest) (*DisconnectResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Disconnect not implemented")
+}
+func (UnimplementedStorageControllerServer) Create(context.Context, *CreateRequest) (*CreateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Create not implemented")
+}
+func (UnimplementedStorageControllerServer) Mount(context.Context, *MountRequest) (*MountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Mount not implemented")
+}
+func (UnimplementedStorageControllerServer) Unmount(context.Context, *UnmountRequest) (*UnmountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Unmount not implemented")
+}
+func (UnimplementedStorageControllerServer) Delete(context.Context, *DeleteRequest) (*DeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedStorageControllerServer) Health(context.Context, *HealthRequest) (*HealthResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Health not implemented")
+}
+func (UnimplementedStorageControllerServer) Snapshot(context.Context, *SnapshotRequest) (*SnapshotResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Snapshot not implemented")
+}
+func (UnimplementedStorageControllerServer) mustEmbedUnimplementedStorageControllerServer() {}
+
+// UnsafeStorageControllerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to StorageControllerServer will
+// result in compilation errors.
+type UnsafeStorageControllerServer interface {
+	mustEmbedUnimplementedStorageControllerServer()
+}
+
+func RegisterStorageControllerServer(s grpc.ServiceRegistrar, srv StorageControllerServer) {
+	s.RegisterService(&StorageController_ServiceDesc, srv)
+}
+
+func _StorageController_Connect_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConnectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageControllerServer).Connect(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StorageController_Connect_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageControllerServer).Connect(ctx, req.(*ConnectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageController_Disconnect_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DisconnectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageControllerServer).Disconnect(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StorageController_Disconnect_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageControllerServer).Disconnect(ctx, req.(*DisconnectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageController_Create_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageControllerServer).Create(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StorageController_Create_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageControllerServer).Create(ctx, req.(*CreateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageController_Mount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageControllerServer).Mount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StorageController_Mount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageControllerServer).Mount(ctx, req.(*MountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageController_Unmount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnmountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageControllerServer).Unmount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StorageController_Unmount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageControllerServer).Unmount(ctx, req.(*UnmountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageController_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageControllerServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StorageController_Delete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageControllerServer).Delete(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageController_Health_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HealthRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageControllerServer).Health(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StorageController_Health_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageControllerServer).Health(ctx, req.(*HealthRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageController_Snapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SnapshotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageControllerServer).Snapshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StorageController_Snapshot_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageControllerServer).Snapshot(ctx, req.(*SnapshotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// StorageController_ServiceDesc is the grpc.ServiceDesc for StorageController service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var StorageController_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "dockervolumerdma.storagecontroller.v1.StorageController",
+	HandlerType: (*StorageControllerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Connect",
+			Handler:    _StorageController_Connect_Handler,
+		},
+		{
+			MethodName: "Disconnect",
+			Handler:    _StorageController_Disconnect_Handler,
+		},
+		{
+			MethodName: "Create",
+			Handler:    _StorageController_Create_Handler,
+		},
+		{
+			MethodName: "Mount",
+			Handler:    _StorageController_Mount_Handler,
+		},
+		{
+			MethodName: "Unmount",
+			Handler:    _StorageController_Unmount_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _StorageController_Delete_Handler,
+		},
+		{
+			MethodName: "Health",
+			Handler:    _StorageController_Health_Handler,
+		},
+		{
+			MethodName: "Snapshot",
+			Handler:    _StorageController_Snapshot_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "drivers/external/storagecontroller.proto",
+}
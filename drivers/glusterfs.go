@@ -1,5 +1,15 @@
 package drivers
 
+import "github.com/mellanox-senior-design/docker-volume-rdma/config"
+
+func init() {
+	Register("glusterfs", StorageControllerFactory{
+		Lenient: true,
+		New: func(values config.Values) (StorageController, error) {
+			return NewGlusterStorageController(), nil
+		}})
+}
+
 // GlusterStorageController connects to the local gluster client and facilitates volume mounts
 type GlusterStorageController struct {
 }
@@ -19,6 +29,11 @@ func (g GlusterStorageController) Disconnect() error {
 	return nil
 }
 
+// Create a volume by name
+func (g GlusterStorageController) Create(volumeName string, options map[string]string) error {
+	return nil
+}
+
 // Mount a volume by name
 func (g GlusterStorageController) Mount(volumeName string) (string, error) {
 	return "", nil
@@ -33,3 +48,8 @@ func (g GlusterStorageController) Unmount(volumeName string) error {
 func (g GlusterStorageController) Delete(volumeName string) error {
 	return nil
 }
+
+// MultiHost reports that gluster volumes may be mounted on several hosts at once.
+func (g GlusterStorageController) MultiHost() bool {
+	return true
+}
@@ -0,0 +1,65 @@
+package drivers
+
+import (
+	"errors"
+	"strconv"
+)
+
+// The create options that limit the I/O of the containers using a volume, applied with the io.max file of each
+// container's cgroup. Rates are per second, and byte rates accept the suffixes of the size option, e.g. 100M.
+const (
+	ReadIOPSOption  = "iops-read"
+	WriteIOPSOption = "iops-write"
+	ReadBPSOption   = "bps-read"
+	WriteBPSOption  = "bps-write"
+)
+
+// IOLimits are the I/O limits of a volume. A limit of 0 leaves that kind of I/O unlimited.
+type IOLimits struct {
+	ReadIOPS  int64
+	WriteIOPS int64
+	ReadBPS   int64
+	WriteBPS  int64
+}
+
+// ParseIOLimits returns the I/O limits requested by a volume's options.
+func ParseIOLimits(options map[string]string) (IOLimits, error) {
+	var limits IOLimits
+	var err error
+
+	for _, limit := range []struct {
+		option string
+		value  *int64
+		parse  func(string) (int64, error)
+	}{
+		{ReadIOPSOption, &limits.ReadIOPS, parseIOPS},
+		{WriteIOPSOption, &limits.WriteIOPS, parseIOPS},
+		{ReadBPSOption, &limits.ReadBPS, parseBPS},
+		{WriteBPSOption, &limits.WriteBPS, parseBPS},
+	} {
+		value := options[limit.option]
+		if value == "" {
+			continue
+		}
+
+		*limit.value, err = limit.parse(value)
+		if err != nil || *limit.value <= 0 {
+			return IOLimits{}, errors.New("invalid " + limit.option + ": " + value + ", expected a positive rate")
+		}
+	}
+
+	return limits, nil
+}
+
+// IsZero returns true if no I/O is limited.
+func (l IOLimits) IsZero() bool {
+	return l == IOLimits{}
+}
+
+func parseIOPS(value string) (int64, error) {
+	return strconv.ParseInt(value, 10, 64)
+}
+
+func parseBPS(value string) (int64, error) {
+	return parseSize(value, 1)
+}
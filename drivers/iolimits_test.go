@@ -0,0 +1,32 @@
+package drivers
+
+import "testing"
+
+func TestParseIOLimits(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		options  map[string]string
+		expected IOLimits
+		fails    bool
+	}{
+		{map[string]string{}, IOLimits{}, false},
+		{map[string]string{"size": "10G"}, IOLimits{}, false},
+		{map[string]string{ReadIOPSOption: "1000", WriteIOPSOption: "500"}, IOLimits{ReadIOPS: 1000, WriteIOPS: 500}, false},
+		{map[string]string{ReadBPSOption: "100M", WriteBPSOption: "1048576"}, IOLimits{ReadBPS: 100 << 20, WriteBPS: 1 << 20}, false},
+		{map[string]string{ReadIOPSOption: "1.5"}, IOLimits{}, true},
+		{map[string]string{WriteIOPSOption: "0"}, IOLimits{}, true},
+		{map[string]string{ReadBPSOption: "-1M"}, IOLimits{}, true},
+		{map[string]string{WriteBPSOption: "fast"}, IOLimits{}, true},
+	}
+
+	for _, test := range tests {
+		limits, err := ParseIOLimits(test.options)
+		if test.fails != (err != nil) || limits != test.expected {
+			t.Error("Expected ", test.options, " to give ", test.expected, ", got ", limits, err)
+		}
+
+		if !test.fails && limits.IsZero() != (test.expected == IOLimits{}) {
+			t.Error("Expected ", limits, " to be zero only if nothing is limited")
+		}
+	}
+}
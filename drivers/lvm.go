@@ -0,0 +1,518 @@
+package drivers
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/mellanox-senior-design/docker-volume-rdma/config"
+	"github.com/mellanox-senior-design/docker-volume-rdma/keys"
+)
+
+func init() {
+	Register("lvm", StorageControllerFactory{
+		Options: append([]config.Option{
+			mountPathOption,
+			{Name: "sc-vg", Description: "set the LVM volume group containing the thin pool"},
+			{Name: "sc-thinpool", Description: "set the LVM thin pool that volumes are created in"},
+			{Name: "sc-data-threshold", Default: "90", Description: "refuse creates once the thin pool data usage reaches this percent"},
+			{Name: "sc-metadata-threshold", Default: "90", Description: "refuse creates once the thin pool metadata usage reaches this percent"},
+		}, keyProviderOptions...),
+		New: func(values config.Values) (StorageController, error) {
+			dataThreshold, err := values.Float("sc-data-threshold")
+			if err != nil {
+				return nil, err
+			}
+
+			metadataThreshold, err := values.Float("sc-metadata-threshold")
+			if err != nil {
+				return nil, err
+			}
+
+			sc := NewLVMStorageController(values.String("sc-vg"), values.String("sc-thinpool"), values.String("scpath"), dataThreshold, metadataThreshold)
+			keyProvider, err := newKeyProvider(values)
+			sc.Keys = keyProvider
+			return sc, err
+		}})
+}
+
+// lvmVolumeTag is added to every logical volume created by the LVMStorageController so that they can be told apart
+// from other thin volumes that share the pool.
+const lvmVolumeTag = "docker-volume-rdma"
+
+// lvmLUKSTagPrefix starts the tag that records how an encrypted thin volume is encrypted.
+const lvmLUKSTagPrefix = lvmVolumeTag + "-luks="
+
+// lvmDefaultSize is the virtual size of a thin volume created without a size option.
+const lvmDefaultSize = "10G"
+
+// lvmSizePattern matches the sizes accepted by lvcreate, e.g. 512M, 10G, 1.5T.
+var lvmSizePattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?[bBsSkKmMgGtTpPeE]?$`)
+
+// LVMStorageController creates a thin logical volume in a thin pool for every volume, mounting it on the host.
+type LVMStorageController struct {
+	VolumeGroup       string
+	ThinPool          string
+	MountPath         string
+	DataThreshold     float64
+	MetadataThreshold float64
+	Runner            CommandRunner
+
+	// Keys supplies the keys of encrypted volumes, volumes can not be encrypted without it.
+	Keys keys.KeyProvider
+}
+
+// NewLVMStorageController creates a new LVMStorageController. Creates are refused once the thin pool's data or
+// metadata usage (in percent) reaches dataThreshold or metadataThreshold.
+func NewLVMStorageController(volumeGroup string, thinPool string, mountPath string, dataThreshold float64, metadataThreshold float64) LVMStorageController {
+	if mountPath == "" {
+		mountPath = "/etc/docker/mounts/"
+	}
+
+	glog.Info("Thin pool: ", volumeGroup, "/", thinPool, " Mount path: ", mountPath)
+
+	return LVMStorageController{
+		VolumeGroup:       volumeGroup,
+		ThinPool:          thinPool,
+		MountPath:         mountPath,
+		DataThreshold:     dataThreshold,
+		MetadataThreshold: metadataThreshold,
+		Runner:            ExecCommandRunner{}}
+}
+
+// MountRoot returns the folder that volumes are mounted beneath.
+func (l LVMStorageController) MountRoot() string {
+	return l.MountPath
+}
+
+// Connect ensures that the thin pool exists.
+func (l LVMStorageController) Connect() error {
+	if l.VolumeGroup == "" || l.ThinPool == "" {
+		return errors.New("a volume group and thin pool must be specified with -sc-vg and -sc-thinpool")
+	}
+
+	attributes, err := l.Runner.Run("lvs", "--noheadings", "-o", "lv_attr", l.poolPath())
+	if err != nil {
+		return err
+	}
+
+	// The first lv_attr character of a thin pool is 't'.
+	if !strings.HasPrefix(strings.TrimSpace(attributes), "t") {
+		return errors.New(l.poolPath() + " is not a thin pool")
+	}
+
+	glog.Info("Connected to thin pool ", l.poolPath())
+	return nil
+}
+
+// Disconnect is a NOOP
+func (l LVMStorageController) Disconnect() error {
+	glog.Info("Disconnect function called, no action taken.")
+	return nil
+}
+
+// Create a thin volume sized by the size option (default 10G) and format it with the fs option (default ext4). Volumes
+// created with encrypted=true are formatted, inside a LUKS container, when they are first mounted.
+func (l LVMStorageController) Create(volumeName string, options map[string]string) error {
+	size := options["size"]
+	if size == "" {
+		size = lvmDefaultSize
+	}
+
+	if !lvmSizePattern.MatchString(size) {
+		return errors.New("invalid size: " + size)
+	}
+
+	filesystem, err := filesystemOption(options)
+	if err != nil {
+		return err
+	}
+
+	luks, err := encryptedOption(l.Keys, volumeName, options)
+	if err != nil {
+		return err
+	}
+
+	// Refuse to over commit a pool that is nearly full.
+	dataPercent, metadataPercent, err := l.poolUsage()
+	if err != nil {
+		return err
+	}
+
+	if dataPercent >= l.DataThreshold {
+		return fmt.Errorf("thin pool %s data usage %.2f%% has reached the %.2f%% threshold", l.poolPath(), dataPercent, l.DataThreshold)
+	}
+
+	if metadataPercent >= l.MetadataThreshold {
+		return fmt.Errorf("thin pool %s metadata usage %.2f%% has reached the %.2f%% threshold", l.poolPath(), metadataPercent, l.MetadataThreshold)
+	}
+
+	args := []string{"--thin", "--virtualsize", size, "--name", volumeName, "--addtag", lvmVolumeTag}
+	if luks != nil {
+		args = append(args, "--addtag", lvmLUKSTagPrefix+luks.String())
+	}
+
+	_, err = l.Runner.Run("lvcreate", append(args, l.poolPath())...)
+	if err != nil || luks != nil {
+		return err
+	}
+
+	err = formatDevice(l.Runner, filesystem, l.devicePath(volumeName))
+	if err != nil {
+		if _, removeErr := l.Runner.Run("lvremove", "--force", l.volumePath(volumeName)); removeErr != nil {
+			glog.Error(removeErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Mount a particular volume, activating and mounting its thin volume, opening its LUKS container first if it is
+// encrypted.
+func (l LVMStorageController) Mount(volumeName string) (string, error) {
+	mountpoint := path.Join(l.MountPath, volumeName)
+
+	if isMounted(l.Runner, mountpoint) {
+		return mountpoint, nil
+	}
+
+	err := os.MkdirAll(mountpoint, 0755)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = l.Runner.Run("lvchange", "--activate", "y", l.volumePath(volumeName))
+	if err != nil {
+		return "", err
+	}
+
+	luks, err := l.luks(volumeName)
+	if err != nil {
+		return "", err
+	}
+
+	device := l.devicePath(volumeName)
+	if luks != nil {
+		device, err = openLUKS(l.Runner, l.Keys, volumeName, *luks, device)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	_, err = l.Runner.Run("mount", device, mountpoint)
+	if err != nil {
+		if closeErr := closeLUKS(l.Runner, volumeName); closeErr != nil {
+			glog.Error(closeErr)
+		}
+		return "", err
+	}
+
+	return mountpoint, nil
+}
+
+// Unmount a particular volume
+func (l LVMStorageController) Unmount(volumeName string) error {
+	mountpoint := path.Join(l.MountPath, volumeName)
+
+	if !isMounted(l.Runner, mountpoint) {
+		return errors.New("already unmounted")
+	}
+
+	_, err := l.Runner.Run("umount", mountpoint)
+	if err != nil {
+		return err
+	}
+
+	return closeLUKS(l.Runner, volumeName)
+}
+
+// Delete a particular volume, removing its thin volume. Logical volumes without the volume tag were not created by the
+// LVMStorageController, so they are refused rather than removed, e.g. when a volume is named after another LV.
+func (l LVMStorageController) Delete(volumeName string) error {
+	mountpoint := path.Join(l.MountPath, volumeName)
+
+	// If there is no thin volume there is nothing to delete, but it may still be mounted or open. Any other failure,
+	// such as a volume group that is not active, must not be taken for a deleted volume, or the thin volume would be
+	// forgotten while it still holds blocks.
+	output, err := l.Runner.Run("lvs", "--noheadings", "-o", "lv_tags", l.volumePath(volumeName))
+	exists := err == nil
+	if err != nil && !lvmNotFound(err) {
+		return err
+	}
+
+	if exists && !containsTag(strings.Split(strings.TrimSpace(output), ","), lvmVolumeTag) {
+		return errors.New("logical volume " + l.volumePath(volumeName) + " was not created by docker-volume-rdma, it lacks the " + lvmVolumeTag + " tag and will not be removed")
+	}
+
+	if isMounted(l.Runner, mountpoint) {
+		if _, err := l.Runner.Run("umount", mountpoint); err != nil {
+			return err
+		}
+	}
+
+	if err := closeLUKS(l.Runner, volumeName); err != nil {
+		return err
+	}
+
+	if !exists {
+		return nil
+	}
+
+	_, err = l.Runner.Run("lvremove", "--force", l.volumePath(volumeName))
+	if err != nil {
+		return err
+	}
+
+	os.Remove(mountpoint)
+	return nil
+}
+
+// lvmNotFound returns true if err is lvs reporting that the logical volume does not exist.
+func lvmNotFound(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "failed to find logical volume")
+}
+
+// lvmTimeLayout is the layout of the lv_time field reported by lvs.
+const lvmTimeLayout = "2006-01-02 15:04:05 -0700"
+
+// List the thin volumes created by the LVMStorageController, counting the blocks each has written to the thin pool.
+// The pool is shared, so thin volumes without the volume tag are left out.
+func (l LVMStorageController) List() ([]StoredVolume, error) {
+	output, err := l.Runner.Run("lvs", "--noheadings", "--nosuffix", "--units", "b", "--separator", "|", "-o", "lv_name,lv_size,data_percent,lv_time,lv_tags,pool_lv", l.VolumeGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	volumes := []StoredVolume{}
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		fields := strings.Split(strings.TrimSpace(line), "|")
+		if len(fields) != 6 {
+			return nil, errors.New("unable to parse logical volume: " + line)
+		}
+
+		if fields[5] != l.ThinPool || !containsTag(strings.Split(fields[4], ","), lvmVolumeTag) {
+			continue
+		}
+
+		size, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return nil, err
+		}
+
+		// Volumes that have never been activated report no data usage.
+		var dataPercent float64
+		if fields[2] != "" {
+			dataPercent, err = strconv.ParseFloat(fields[2], 64)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		created, err := time.Parse(lvmTimeLayout, fields[3])
+		if err != nil {
+			return nil, err
+		}
+
+		volumes = append(volumes, StoredVolume{Name: fields[0], Bytes: int64(size * dataPercent / 100), Modified: created})
+	}
+
+	return volumes, nil
+}
+
+// containsTag reports whether tags holds tag.
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Snapshot creates a thin snapshot of a volume, which shares the volume's blocks in the thin pool until either is
+// written to. Thin snapshots are skipped on activation by default, so the flag is cleared to let it be mounted. The
+// snapshot of an encrypted volume is opened with the volume's key.
+func (l LVMStorageController) Snapshot(volumeName string, snapshotName string) error {
+	if _, err := l.Runner.Run("lvs", l.volumePath(volumeName)); err != nil {
+		return errors.New("volume " + volumeName + " does not exist")
+	}
+
+	luks, err := l.luks(volumeName)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"--snapshot", "--setactivationskip", "n", "--name", snapshotName, "--addtag", lvmVolumeTag}
+	if luks != nil {
+		args = append(args, "--addtag", lvmLUKSTagPrefix+luks.String())
+	}
+
+	_, err = l.Runner.Run("lvcreate", append(args, l.volumePath(volumeName))...)
+	return err
+}
+
+// Resize grows a thin volume's virtual size and its filesystem. Thin volumes only take space in the pool as they are
+// written to, so the pool's thresholds are not checked.
+func (l LVMStorageController) Resize(volumeName string, size string, options map[string]string) error {
+	if !lvmSizePattern.MatchString(size) {
+		return errors.New("invalid size: " + size)
+	}
+
+	// lvextend counts sizes without a suffix in megabytes.
+	requested, err := parseSize(size, 1<<20)
+	if err != nil {
+		return err
+	}
+
+	filesystem, err := filesystemOption(options)
+	if err != nil {
+		return err
+	}
+
+	output, err := l.Runner.Run("lvs", "--noheadings", "--nosuffix", "--units", "b", "-o", "lv_size", l.volumePath(volumeName))
+	if err != nil {
+		return errors.New("volume " + volumeName + " does not exist")
+	}
+
+	current, err := strconv.ParseFloat(strings.TrimSpace(output), 64)
+	if err != nil {
+		return errors.New("unable to parse the size of " + volumeName + ": " + output)
+	}
+
+	if err = checkGrowth(volumeName, int64(current), requested); err != nil || int64(current) == requested {
+		return err
+	}
+
+	luks, err := l.luks(volumeName)
+	if err != nil {
+		return err
+	}
+
+	_, err = l.Runner.Run("lvextend", "--size", size, l.volumePath(volumeName))
+	if err != nil {
+		return err
+	}
+
+	return growFilesystem(l.Runner, l, volumeName, path.Join(l.MountPath, volumeName), filesystem, l.Keys, luks)
+}
+
+// KeyProvider returns the provider of the keys of encrypted volumes, or nil if volumes can not be encrypted.
+func (l LVMStorageController) KeyProvider() keys.KeyProvider {
+	return l.Keys
+}
+
+// Health reports the thin pool's data and metadata usage, returning an error if either has reached its threshold.
+func (l LVMStorageController) Health() (map[string]interface{}, error) {
+	dataPercent, metadataPercent, err := l.poolUsage()
+	if err != nil {
+		return nil, err
+	}
+
+	health := map[string]interface{}{
+		"ThinPool":          l.poolPath(),
+		"DataPercent":       dataPercent,
+		"MetadataPercent":   metadataPercent,
+		"DataThreshold":     l.DataThreshold,
+		"MetadataThreshold": l.MetadataThreshold,
+	}
+
+	if dataPercent >= l.DataThreshold || metadataPercent >= l.MetadataThreshold {
+		return health, errors.New("thin pool " + l.poolPath() + " has reached its usage threshold")
+	}
+
+	return health, nil
+}
+
+// Capacity returns the number of bytes that may be written to the thin pool before it reaches its data threshold.
+func (l LVMStorageController) Capacity() (int64, error) {
+	output, err := l.Runner.Run("lvs", "--noheadings", "--nosuffix", "--units", "b", "--separator", ",", "-o", "lv_size,data_percent", l.poolPath())
+	if err != nil {
+		return 0, err
+	}
+
+	fields := strings.Split(strings.TrimSpace(output), ",")
+	if len(fields) != 2 {
+		return 0, errors.New("unable to parse thin pool size: " + output)
+	}
+
+	size, err := strconv.ParseFloat(strings.TrimSpace(fields[0]), 64)
+	if err != nil {
+		return 0, err
+	}
+
+	dataPercent, err := strconv.ParseFloat(strings.TrimSpace(fields[1]), 64)
+	if err != nil {
+		return 0, err
+	}
+
+	if dataPercent >= l.DataThreshold {
+		return 0, nil
+	}
+
+	return int64(size * (l.DataThreshold - dataPercent) / 100), nil
+}
+
+// poolUsage returns the data and metadata usage of the thin pool in percent.
+func (l LVMStorageController) poolUsage() (float64, float64, error) {
+	output, err := l.Runner.Run("lvs", "--noheadings", "--nosuffix", "--separator", ",", "-o", "data_percent,metadata_percent", l.poolPath())
+	if err != nil {
+		return 0, 0, err
+	}
+
+	fields := strings.Split(strings.TrimSpace(output), ",")
+	if len(fields) != 2 {
+		return 0, 0, errors.New("unable to parse thin pool usage: " + output)
+	}
+
+	dataPercent, err := strconv.ParseFloat(strings.TrimSpace(fields[0]), 64)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	metadataPercent, err := strconv.ParseFloat(strings.TrimSpace(fields[1]), 64)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return dataPercent, metadataPercent, nil
+}
+
+// luks returns how a thin volume is encrypted, from its tags, or nil if it is not encrypted.
+func (l LVMStorageController) luks(volumeName string) (*luksVolume, error) {
+	output, err := l.Runner.Run("lvs", "--noheadings", "-o", "lv_tags", l.volumePath(volumeName))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tag := range strings.Split(strings.TrimSpace(output), ",") {
+		if strings.HasPrefix(tag, lvmLUKSTagPrefix) {
+			return parseLUKSVolume(strings.TrimPrefix(tag, lvmLUKSTagPrefix))
+		}
+	}
+
+	return nil, nil
+}
+
+func (l LVMStorageController) poolPath() string {
+	return l.VolumeGroup + "/" + l.ThinPool
+}
+
+func (l LVMStorageController) volumePath(volumeName string) string {
+	return l.VolumeGroup + "/" + volumeName
+}
+
+func (l LVMStorageController) devicePath(volumeName string) string {
+	return path.Join("/dev", l.VolumeGroup, volumeName)
+}
@@ -0,0 +1,576 @@
+package drivers
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeLVM pretends to be the lvm2 and mount tools of a host with the thin pool vg/pool.
+type fakeLVM struct {
+	size            float64
+	dataPercent     float64
+	metadataPercent float64
+	volumes         map[string]bool
+	sizes           map[string]int64
+	tags            map[string][]string
+	mounted         map[string]bool
+	crypt           *fakeCrypt
+	commands        []string
+}
+
+func newFakeLVM() *fakeLVM {
+	return &fakeLVM{volumes: map[string]bool{}, sizes: map[string]int64{}, tags: map[string][]string{}, mounted: map[string]bool{}, crypt: newFakeCrypt()}
+}
+
+func (f *fakeLVM) run(name string, args ...string) (string, error) {
+	f.commands = append(f.commands, strings.TrimSpace(name+" "+strings.Join(args, " ")))
+	last := args[len(args)-1]
+
+	switch name {
+	case "lvs":
+		if last == "vg/pool" {
+			if strings.Contains(strings.Join(args, " "), "lv_attr") {
+				return "  twi-aotz--\n", nil
+			}
+			if strings.Contains(strings.Join(args, " "), "lv_size") {
+				return fmt.Sprintf("  %.0f,%.2f\n", f.size, f.dataPercent), nil
+			}
+			return fmt.Sprintf("  %.2f,%.2f\n", f.dataPercent, f.metadataPercent), nil
+		}
+		if f.volumes[strings.TrimPrefix(last, "vg/")] {
+			if strings.Contains(strings.Join(args, " "), "lv_size") {
+				return fmt.Sprintf("  %d\n", f.sizes[strings.TrimPrefix(last, "vg/")]), nil
+			}
+			if strings.Contains(strings.Join(args, " "), "lv_tags") {
+				return "  " + strings.Join(f.tags[strings.TrimPrefix(last, "vg/")], ",") + "\n", nil
+			}
+			return "", nil
+		}
+		return "", errors.New("failed to find logical volume " + last)
+	case "lvcreate":
+		var size int64
+		var volumeName string
+		var tags []string
+		for i, arg := range args {
+			switch arg {
+			case "--virtualsize":
+				size, _ = parseSize(args[i+1], 1<<20)
+			case "--name":
+				volumeName = args[i+1]
+			case "--addtag":
+				tags = append(tags, args[i+1])
+			}
+		}
+		f.volumes[volumeName] = true
+		f.sizes[volumeName] = size
+		f.tags[volumeName] = tags
+	case "cryptsetup":
+		return f.crypt.run(args...)
+	case "lvextend":
+		f.sizes[strings.TrimPrefix(last, "vg/")], _ = parseSize(args[1], 1<<20)
+	case "findmnt":
+		return "/dev/mapper/vg-" + strings.TrimPrefix(last, "test/lvm/") + "\n", nil
+	case "lvremove":
+		delete(f.volumes, strings.TrimPrefix(last, "vg/"))
+	case "mountpoint":
+		if !f.mounted[last] {
+			return "", errors.New(last + " is not a mountpoint")
+		}
+	case "mount":
+		f.mounted[last] = true
+	case "umount":
+		delete(f.mounted, last)
+	}
+
+	return "", nil
+}
+
+func newFakeLVMStorageController(fake *fakeLVM) LVMStorageController {
+	sc := NewLVMStorageController("vg", "pool", "test/lvm", 80, 80)
+	sc.Runner = CommandRunnerFunc(fake.run)
+	return sc
+}
+
+func TestLVMConnect(t *testing.T) {
+	t.Parallel()
+	sc := newFakeLVMStorageController(newFakeLVM())
+
+	err := sc.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sc.ThinPool = ""
+	err = sc.Connect()
+	if err == nil {
+		t.Error("Connect should fail when no thin pool is configured")
+	}
+}
+
+func TestLVMCreateMountUnmountDelete(t *testing.T) {
+	t.Parallel()
+	fake := newFakeLVM()
+	sc := newFakeLVMStorageController(fake)
+
+	err := sc.Create("lvmvol1", map[string]string{"size": "1G"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !fake.volumes["lvmvol1"] {
+		t.Fatal("Create did not create a thin volume")
+	}
+
+	expected := []string{
+		"lvcreate --thin --virtualsize 1G --name lvmvol1 --addtag docker-volume-rdma vg/pool",
+		"mkfs.ext4 -q /dev/vg/lvmvol1",
+	}
+	for _, command := range expected {
+		if !containsString(fake.commands, command) {
+			t.Error("Expected the command ", command, " to be run. Ran: ", fake.commands)
+		}
+	}
+
+	mountpoint, err := sc.Mount("lvmvol1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mountpoint != "test/lvm/lvmvol1" || !fake.mounted[mountpoint] {
+		t.Error("Volume was not mounted at test/lvm/lvmvol1, got ", mountpoint)
+	}
+
+	err = sc.Unmount("lvmvol1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sc.Unmount("lvmvol1")
+	if err == nil {
+		t.Error("Should have received an error for unmounting a volume twice")
+	}
+
+	_, err = sc.Mount("lvmvol1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sc.Delete("lvmvol1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fake.volumes["lvmvol1"] || fake.mounted["test/lvm/lvmvol1"] {
+		t.Error("Delete did not unmount and remove the thin volume")
+	}
+
+	err = sc.Delete("lvmvol1")
+	if err != nil {
+		t.Error("Deleting a volume that does not exist should not fail: ", err)
+	}
+}
+
+func TestLVMDeleteUnavailable(t *testing.T) {
+	t.Parallel()
+	fake := newFakeLVM()
+	sc := newFakeLVMStorageController(fake)
+
+	err := sc.Create("lvmvol1", map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sc.Runner = CommandRunnerFunc(func(name string, args ...string) (string, error) {
+		if name == "lvs" {
+			return "", errors.New("lvs vg/lvmvol1 failed: exit status 5: WARNING: Failed to connect to lvmetad")
+		}
+		return fake.run(name, args...)
+	})
+
+	if err = sc.Delete("lvmvol1"); err == nil {
+		t.Error("Delete should fail when lvs can not tell whether the volume exists")
+	}
+
+	if !fake.volumes["lvmvol1"] {
+		t.Error("The thin volume should not be removed")
+	}
+}
+
+func TestLVMDeleteUntagged(t *testing.T) {
+	t.Parallel()
+	fake := newFakeLVM()
+	sc := newFakeLVMStorageController(fake)
+
+	// A logical volume of the volume group that was not created by the plugin, e.g. the host's root filesystem.
+	fake.volumes["root"] = true
+	fake.tags["root"] = []string{"system"}
+
+	if err := sc.Delete("root"); err == nil {
+		t.Error("Delete should refuse a logical volume without the volume tag")
+	}
+
+	if !fake.volumes["root"] || containsString(fake.commands, "lvremove --force vg/root") {
+		t.Error("The untagged logical volume should not be removed, ran: ", fake.commands)
+	}
+}
+
+func TestLVMCreateInvalidOptions(t *testing.T) {
+	t.Parallel()
+	fake := newFakeLVM()
+	sc := newFakeLVMStorageController(fake)
+
+	var tests = []map[string]string{
+		{"size": "lots"},
+		{"size": "10G; rm -rf /"},
+		{"fs": "ntfs"},
+	}
+
+	for _, options := range tests {
+		err := sc.Create("lvmvol2", options)
+		if err == nil {
+			t.Error("Create should fail for options ", options)
+		}
+	}
+
+	if len(fake.volumes) != 0 {
+		t.Error("No thin volumes should have been created")
+	}
+}
+
+func TestLVMThresholds(t *testing.T) {
+	t.Parallel()
+	fake := newFakeLVM()
+	sc := newFakeLVMStorageController(fake)
+
+	fake.dataPercent = 85
+	err := sc.Create("lvmvol3", nil)
+	if err == nil {
+		t.Error("Create should be refused when the data threshold has been reached")
+	}
+
+	health, err := sc.Health()
+	if err == nil {
+		t.Error("Health should report an error when the data threshold has been reached")
+	}
+
+	if health["DataPercent"] != 85.0 {
+		t.Error("Health did not report the data usage, got ", health)
+	}
+
+	fake.dataPercent = 10
+	fake.metadataPercent = 95
+	err = sc.Create("lvmvol3", nil)
+	if err == nil {
+		t.Error("Create should be refused when the metadata threshold has been reached")
+	}
+
+	fake.metadataPercent = 10
+	err = sc.Create("lvmvol3", nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, err = sc.Health()
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// TestLVMLoopDevice exercises a real thin pool. It requires root, and a thin pool that may be written to, e.g.
+//
+//	truncate -s 1G /tmp/lvm.img && losetup /dev/loop0 /tmp/lvm.img
+//	vgcreate rdmatest /dev/loop0 && lvcreate --type thin-pool -L 900M -n pool rdmatest
+//	LVM_TEST_VG=rdmatest LVM_TEST_THINPOOL=pool go test ./drivers -run TestLVMLoopDevice
+func TestLVMLoopDevice(t *testing.T) {
+	volumeGroup := os.Getenv("LVM_TEST_VG")
+	thinPool := os.Getenv("LVM_TEST_THINPOOL")
+	if volumeGroup == "" || thinPool == "" {
+		t.Skip("LVM_TEST_VG and LVM_TEST_THINPOOL are required to test against a real thin pool.")
+	}
+
+	sc := NewLVMStorageController(volumeGroup, thinPool, "test/lvmloop", 95, 95)
+	err := sc.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sc.Create("lvmloopvol", map[string]string{"size": "100M"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Delete("lvmloopvol")
+
+	mountpoint, err := sc.Mount("lvmloopvol")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ioutil.WriteFile(mountpoint+"/hello", []byte("world"), 0644)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = sc.Unmount("lvmloopvol")
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func TestLVMCapacity(t *testing.T) {
+	t.Parallel()
+	fake := newFakeLVM()
+	fake.size = 100 << 30
+	fake.dataPercent = 30
+	sc := newFakeLVMStorageController(fake)
+
+	// The thin pool may be filled to its 80% threshold.
+	capacity, err := sc.Capacity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if capacity != 50<<30 {
+		t.Error("Expected 50GiB to be available below the threshold, got ", capacity)
+	}
+
+	fake.dataPercent = 85
+	capacity, err = sc.Capacity()
+	if err != nil || capacity != 0 {
+		t.Error("Nothing should be available once the threshold is reached, got ", capacity, err)
+	}
+}
+
+func TestLVMSnapshot(t *testing.T) {
+	t.Parallel()
+	fake := newFakeLVM()
+	fake.volumes["data"] = true
+	sc := newFakeLVMStorageController(fake)
+
+	err := sc.Snapshot("data", "data-backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "lvcreate --snapshot --setactivationskip n --name data-backup --addtag docker-volume-rdma vg/data"
+	if fake.commands[len(fake.commands)-1] != expected || !fake.volumes["data-backup"] {
+		t.Error("Expected a thin snapshot, got ", fake.commands)
+	}
+
+	if err = sc.Snapshot("missing", "missing-backup"); err == nil {
+		t.Error("A missing volume should not be snapshotted")
+	}
+}
+
+func TestLVMResize(t *testing.T) {
+	t.Parallel()
+	fake := newFakeLVM()
+	sc := newFakeLVMStorageController(fake)
+
+	if err := sc.Create("lvmvol5", map[string]string{"size": "1G"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// An unmounted volume is mounted while its filesystem grows.
+	if err := sc.Resize("lvmvol5", "2G", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"lvextend --size 2G vg/lvmvol5",
+		"mount /dev/vg/lvmvol5 test/lvm/lvmvol5",
+		"resize2fs /dev/mapper/vg-lvmvol5",
+		"umount test/lvm/lvmvol5",
+	}
+	for _, command := range expected {
+		if !containsString(fake.commands, command) {
+			t.Error("Expected the command ", command, " to be run. Ran: ", fake.commands)
+		}
+	}
+
+	if fake.sizes["lvmvol5"] != 2<<30 || fake.mounted["test/lvm/lvmvol5"] {
+		t.Error("The volume should have grown and been unmounted again, got ", fake.sizes["lvmvol5"], fake.mounted)
+	}
+
+	// A mounted xfs volume is grown where it is mounted.
+	if _, err := sc.Mount("lvmvol5"); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.commands = nil
+	if err := sc.Resize("lvmvol5", "3072M", map[string]string{"fs": "xfs"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !containsString(fake.commands, "xfs_growfs test/lvm/lvmvol5") || containsString(fake.commands, "umount test/lvm/lvmvol5") {
+		t.Error("Expected xfs to be grown while it stays mounted. Ran: ", fake.commands)
+	}
+
+	var tests = []struct {
+		name string
+		size string
+	}{
+		{"shrink", "1G"},
+		{"invalid", "big"},
+	}
+
+	for _, test := range tests {
+		if err := sc.Resize("lvmvol5", test.size, nil); err == nil {
+			t.Error(test.name, " should fail")
+		}
+	}
+
+	if err := sc.Resize("missing", "5G", nil); err == nil {
+		t.Error("Resizing a volume that does not exist should fail")
+	}
+
+	if fake.sizes["lvmvol5"] != 3<<30 {
+		t.Error("Failed resizes should not change the volume, got ", fake.sizes["lvmvol5"])
+	}
+}
+
+func TestLVMEncrypted(t *testing.T) {
+	t.Parallel()
+	provider, cleanUp := newTestKeyProvider(t)
+	defer cleanUp()
+
+	fake := newFakeLVM()
+	sc := newFakeLVMStorageController(fake)
+	runner := &inputRecorder{CommandRunnerFunc: fake.run}
+	sc.Runner = runner
+	sc.Keys = provider
+
+	err := sc.Create("secret", map[string]string{"size": "1G", EncryptedOption: "true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !containsString(fake.commands, "lvcreate --thin --virtualsize 1G --name secret --addtag docker-volume-rdma --addtag docker-volume-rdma-luks=ext4:secret vg/pool") {
+		t.Error("The thin volume should be tagged with its encryption. Ran: ", fake.commands)
+	}
+
+	if containsString(fake.commands, "mkfs.ext4 -q /dev/vg/secret") {
+		t.Error("An encrypted volume should not be formatted until it is first mounted")
+	}
+
+	mountpoint, err := sc.Mount("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"cryptsetup luksFormat --type luks2 --batch-mode --key-file - /dev/vg/secret",
+		"cryptsetup open --type luks2 --key-file - /dev/vg/secret docker-volume-rdma-secret",
+		"mkfs.ext4 -q /dev/mapper/docker-volume-rdma-secret",
+		"mount /dev/mapper/docker-volume-rdma-secret test/lvm/secret",
+	}
+	for _, command := range expected {
+		if !containsString(fake.commands, command) {
+			t.Error("Expected the command ", command, " to be run. Ran: ", fake.commands)
+		}
+	}
+
+	key, err := provider.GetKey("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, input := range runner.inputs {
+		if string(input) != string(key) {
+			t.Error("The volume's key should be passed to cryptsetup on its standard input")
+		}
+	}
+
+	if err = sc.Unmount("secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	if fake.crypt.opened["docker-volume-rdma-secret"] || fake.mounted[mountpoint] {
+		t.Error("The LUKS container should be closed once the volume is unmounted")
+	}
+
+	// Mounting again opens the existing container rather than formatting it again.
+	fake.commands = nil
+	if _, err = sc.Mount("secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	if containsString(fake.commands, "cryptsetup luksFormat --type luks2 --batch-mode --key-file - /dev/vg/secret") {
+		t.Error("An encrypted volume should only be formatted once. Ran: ", fake.commands)
+	}
+
+	if err = sc.Snapshot("secret", "secret-snap"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !containsString(fake.tags["secret-snap"], "docker-volume-rdma-luks=ext4:secret") {
+		t.Error("A snapshot should be opened with the key of its volume, got tags ", fake.tags["secret-snap"])
+	}
+
+	if err = sc.Delete("secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.crypt.opened) != 0 {
+		t.Error("Deleting a volume should close its LUKS container")
+	}
+
+	sc.Keys = nil
+	if err = sc.Create("plain", map[string]string{EncryptedOption: "true"}); err == nil {
+		t.Error("Encrypting a volume without a key provider should fail")
+	}
+}
+
+func TestLVMList(t *testing.T) {
+	t.Parallel()
+	sc := NewLVMStorageController("vg", "pool", "test/lvm", 80, 80)
+	sc.Runner = CommandRunnerFunc(func(name string, args ...string) (string, error) {
+		return "  pool|107374182400|30.00|2026-01-01 09:00:00 +0000||\n" +
+			"  ci-build|10737418240|25.00|2026-10-19 09:30:00 +0200|docker-volume-rdma|pool\n" +
+			"  secret|10737418240|10.00|2026-10-19 10:00:00 +0000|docker-volume-rdma,docker-volume-rdma-luks=aes-xts-plain64|pool\n" +
+			"  fresh|10737418240||2026-10-19 11:00:00 +0000|docker-volume-rdma|pool\n" +
+			"  other|10737418240|50.00|2026-10-19 09:30:00 +0000||pool\n" +
+			"  elsewhere|10737418240|50.00|2026-10-19 09:30:00 +0000|docker-volume-rdma|pool2\n", nil
+	})
+
+	volumes, err := sc.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []StoredVolume{
+		{Name: "ci-build", Bytes: 10737418240 / 4, Modified: time.Date(2026, 10, 19, 7, 30, 0, 0, time.UTC)},
+		{Name: "secret", Bytes: 1073741824, Modified: time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)},
+		{Name: "fresh", Modified: time.Date(2026, 10, 19, 11, 0, 0, 0, time.UTC)},
+	}
+
+	if len(volumes) != len(expected) {
+		t.Fatal("Expected only the tagged volumes of the pool, got ", volumes)
+	}
+
+	for i := range expected {
+		if volumes[i].Name != expected[i].Name || volumes[i].Bytes != expected[i].Bytes || !volumes[i].Modified.Equal(expected[i].Modified) {
+			t.Error("Expected ", expected[i], ", got ", volumes[i])
+		}
+	}
+
+	sc.Runner = CommandRunnerFunc(func(name string, args ...string) (string, error) {
+		return "  ci-build|lots|25.00|2026-10-19 09:30:00 +0000|docker-volume-rdma|pool\n", nil
+	})
+	if _, err = sc.List(); err == nil {
+		t.Error("Unparsable output should fail")
+	}
+}
@@ -2,12 +2,28 @@ package drivers
 
 import (
 	"errors"
+	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/golang/glog"
+	"github.com/mellanox-senior-design/docker-volume-rdma/config"
 )
 
+func init() {
+	Register("on-disk", StorageControllerFactory{
+		Options: []config.Option{mountPathOption},
+		New: func(values config.Values) (StorageController, error) {
+			return NewOnDiskStorageController(values.String("scpath")), nil
+		}})
+}
+
 // OnDiskStorageController is a way of testing the StorageController backend to ensure that all of the logic is correct.
 type OnDiskStorageController struct {
 	FSPath string
@@ -51,6 +67,11 @@ func NewOnDiskStorageController(path string) OnDiskStorageController {
 	return OnDiskStorageController{path}
 }
 
+// MountRoot returns the folder that volumes are mounted beneath.
+func (d OnDiskStorageController) MountRoot() string {
+	return d.FSPath
+}
+
 // Connect is a NOOP
 func (d OnDiskStorageController) Connect() error {
 	glog.Info("Connect function called, no action taken.")
@@ -63,6 +84,22 @@ func (d OnDiskStorageController) Disconnect() error {
 	return nil
 }
 
+// Create is a NOOP, the volume's folder is created when it is first mounted.
+func (d OnDiskStorageController) Create(volumeName string, options map[string]string) error {
+	return nil
+}
+
+// Capacity returns the number of bytes available on the filesystem holding the volumes.
+func (d OnDiskStorageController) Capacity() (int64, error) {
+	var stat syscall.Statfs_t
+	err := syscall.Statfs(d.FSPath, &stat)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
+
 // Mount a particular volume
 func (d OnDiskStorageController) Mount(volumeName string) (string, error) {
 	pathMounted := path.Join(d.FSPath, volumeName)
@@ -110,21 +147,108 @@ func (d OnDiskStorageController) Unmount(volumeName string) error {
 	return errors.New("already unmounted")
 }
 
-// Delete a particular volume
+// onDiskQuarantineDir is the folder, within FSPath, that quarantined volumes are moved to. Docker volume names can not
+// start with a dot, so it is never mistaken for a volume.
+const onDiskQuarantineDir = ".quarantine"
+
+// Delete a particular volume, removing its folders whether it is mounted or not. A volume that was never mounted has no
+// folder, so there may be nothing to remove.
 func (d OnDiskStorageController) Delete(volumeName string) error {
-	pathMounted := path.Join(d.FSPath, volumeName)
-	pathUnmounted := path.Join(path.Dir(pathMounted), path.Base(pathMounted)+".unmounted")
+	for _, folder := range []string{volumeName + ".unmounted", volumeName} {
+		err := os.RemoveAll(path.Join(d.FSPath, folder))
+		if err != nil {
+			return err
+		}
+	}
 
-	// If there is an unmounted volume, return it.
-	_, err := os.Open(pathUnmounted)
-	if err == nil {
-		return os.RemoveAll(pathUnmounted)
+	return nil
+}
+
+// List the volumes that have a folder, mounted or not. Volumes are only given a folder when they are first mounted.
+func (d OnDiskStorageController) List() ([]StoredVolume, error) {
+	entries, err := ioutil.ReadDir(d.FSPath)
+	if err != nil {
+		return nil, err
 	}
 
-	_, err = os.Open(pathMounted)
-	if err == nil {
-		return os.RemoveAll(pathMounted)
+	// A volume whose rename was interrupted may have both folders, both are counted.
+	volumes := map[string]*StoredVolume{}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		name := strings.TrimSuffix(entry.Name(), ".unmounted")
+		bytes, modified, err := folderUsage(path.Join(d.FSPath, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		stored, exists := volumes[name]
+		if !exists {
+			stored = &StoredVolume{Name: name}
+			volumes[name] = stored
+		}
+
+		stored.Bytes += bytes
+		if modified.After(stored.Modified) {
+			stored.Modified = modified
+		}
+	}
+
+	names := make([]string, 0, len(volumes))
+	for name := range volumes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	list := make([]StoredVolume, 0, len(names))
+	for _, name := range names {
+		list = append(list, *volumes[name])
+	}
+
+	return list, nil
+}
+
+// Quarantine moves a volume's folders to the quarantine folder, named after the volume and the time they were moved,
+// where they are kept until removed by hand.
+func (d OnDiskStorageController) Quarantine(volumeName string) error {
+	quarantine := path.Join(d.FSPath, onDiskQuarantineDir)
+	err := os.MkdirAll(quarantine, 0700)
+	if err != nil {
+		return err
+	}
+
+	suffix := "." + strconv.FormatInt(time.Now().Unix(), 10)
+	for _, folder := range []string{volumeName, volumeName + ".unmounted"} {
+		err = os.Rename(path.Join(d.FSPath, folder), path.Join(quarantine, folder+suffix))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
 	}
 
 	return nil
 }
+
+// folderUsage returns the number of bytes in the files under a folder, and when the folder or any of them last changed.
+func folderUsage(folder string) (int64, time.Time, error) {
+	var bytes int64
+	var modified time.Time
+	err := filepath.Walk(folder, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.Mode().IsRegular() {
+			bytes += info.Size()
+		}
+
+		if info.ModTime().After(modified) {
+			modified = info.ModTime()
+		}
+
+		return nil
+	})
+
+	return bytes, modified, err
+}
@@ -1,6 +1,11 @@
 package drivers
 
-import "testing"
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
 
 func TestNewOnDiskStorageController(t *testing.T) {
 	t.Parallel()
@@ -143,3 +148,78 @@ func TestSCDelete(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestSCCapacity(t *testing.T) {
+	t.Parallel()
+	sc := NewOnDiskStorageController("test/controlla")
+
+	capacity, err := sc.Capacity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if capacity <= 0 {
+		t.Error("The filesystem holding the volumes should have space available, got ", capacity)
+	}
+}
+
+func TestSCListAndQuarantine(t *testing.T) {
+	t.Parallel()
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-ondisk")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	sc := NewOnDiskStorageController(tempDir)
+	for _, volumeName := range []string{"mounted", "unmounted", "both"} {
+		mountpoint, err := sc.Mount(volumeName)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err = ioutil.WriteFile(path.Join(mountpoint, "data"), make([]byte, 100), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sc.Unmount("unmounted")
+	sc.Unmount("both")
+	sc.Mount("both-") // never written to, sorts between the folders of both
+	os.MkdirAll(path.Join(tempDir, "both", "logs"), 0755)
+	ioutil.WriteFile(path.Join(tempDir, "both", "logs", "log"), make([]byte, 20), 0644)
+	ioutil.WriteFile(path.Join(tempDir, "stray-file"), nil, 0644)
+
+	volumes, err := sc.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []StoredVolume{{Name: "both", Bytes: 120}, {Name: "both-"}, {Name: "mounted", Bytes: 100}, {Name: "unmounted", Bytes: 100}}
+	if len(volumes) != len(expected) {
+		t.Fatal("Expected ", expected, ", got ", volumes)
+	}
+
+	for i := range expected {
+		if volumes[i].Name != expected[i].Name || volumes[i].Bytes != expected[i].Bytes || volumes[i].Modified.IsZero() {
+			t.Error("Expected ", expected[i], ", got ", volumes[i])
+		}
+	}
+
+	if err = sc.Quarantine("both"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = sc.Delete("unmounted"); err != nil {
+		t.Fatal(err)
+	}
+
+	if volumes, err = sc.List(); err != nil || len(volumes) != 2 || volumes[0].Name != "both-" || volumes[1].Name != "mounted" {
+		t.Error("Quarantined and deleted volumes should no longer be listed, got ", volumes, err)
+	}
+
+	quarantined, err := ioutil.ReadDir(path.Join(tempDir, onDiskQuarantineDir))
+	if err != nil || len(quarantined) != 2 {
+		t.Error("Expected both folders of the volume to be kept in quarantine, got ", quarantined, err)
+	}
+}
@@ -0,0 +1,397 @@
+package drivers
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/mellanox-senior-design/docker-volume-rdma/config"
+	"github.com/mellanox-senior-design/docker-volume-rdma/keys"
+)
+
+func init() {
+	Register("rbd", StorageControllerFactory{
+		Options: append([]config.Option{
+			mountPathOption,
+			{Name: "sc-pool", Description: "set the ceph pool that images are created in"},
+			{Name: "sc-ceph-user", Description: "set the ceph user used to access the pool (default is admin)"},
+			{Name: "sc-ceph-conf", Description: "set the ceph configuration file (default is /etc/ceph/ceph.conf)"},
+		}, keyProviderOptions...),
+		New: func(values config.Values) (StorageController, error) {
+			sc := NewRBDStorageController(values.String("sc-pool"), values.String("sc-ceph-user"), values.String("sc-ceph-conf"), values.String("scpath"))
+			keyProvider, err := newKeyProvider(values)
+			sc.Keys = keyProvider
+			return sc, err
+		}})
+}
+
+// rbdDefaultSize is the size of an image created without a size option.
+const rbdDefaultSize = "10G"
+
+// rbdImageFeatures are enabled on every image. exclusive-lock prevents two hosts from writing to an image at once.
+const rbdImageFeatures = "layering,exclusive-lock"
+
+// rbdLUKSMetadataKey is the image metadata that records how an encrypted image is encrypted.
+const rbdLUKSMetadataKey = "docker-volume-rdma.luks"
+
+// rbdSizePattern matches the sizes accepted by rbd create, e.g. 512M, 10G, 1T.
+var rbdSizePattern = regexp.MustCompile(`^[0-9]+[KMGT]?$`)
+
+// RBDStorageController creates a Ceph RBD image for every volume, mapping it with the kernel rbd module and mounting
+// it on the host.
+type RBDStorageController struct {
+	Pool       string
+	User       string
+	ConfigPath string
+	MountPath  string
+	Runner     CommandRunner
+
+	// Keys supplies the keys of encrypted volumes, volumes can not be encrypted without it.
+	Keys keys.KeyProvider
+}
+
+// NewRBDStorageController creates a new RBDStorageController. The ceph user and configuration file are optional.
+func NewRBDStorageController(pool string, user string, configPath string, mountPath string) RBDStorageController {
+	if mountPath == "" {
+		mountPath = "/etc/docker/mounts/"
+	}
+
+	glog.Info("RBD pool: ", pool, " Mount path: ", mountPath)
+
+	return RBDStorageController{
+		Pool:       pool,
+		User:       user,
+		ConfigPath: configPath,
+		MountPath:  mountPath,
+		Runner:     ExecCommandRunner{}}
+}
+
+// MountRoot returns the folder that volumes are mounted beneath.
+func (r RBDStorageController) MountRoot() string {
+	return r.MountPath
+}
+
+// Connect ensures that the pool can be reached.
+func (r RBDStorageController) Connect() error {
+	if r.Pool == "" {
+		return errors.New("a pool must be specified with -sc-pool")
+	}
+
+	_, err := r.rbd("ls", r.Pool)
+	if err != nil {
+		return err
+	}
+
+	glog.Info("Connected to RBD pool ", r.Pool)
+	return nil
+}
+
+// Disconnect is a NOOP
+func (r RBDStorageController) Disconnect() error {
+	glog.Info("Disconnect function called, no action taken.")
+	return nil
+}
+
+// Create an image sized by the size option (default 10G) and format it with the fs option (default ext4). Images
+// created with encrypted=true are formatted, inside a LUKS container, when they are first mounted.
+func (r RBDStorageController) Create(volumeName string, options map[string]string) error {
+	size := options["size"]
+	if size == "" {
+		size = rbdDefaultSize
+	}
+
+	if !rbdSizePattern.MatchString(size) {
+		return errors.New("invalid size: " + size)
+	}
+
+	filesystem, err := filesystemOption(options)
+	if err != nil {
+		return err
+	}
+
+	luks, err := encryptedOption(r.Keys, volumeName, options)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.rbd("create", "--size", size, "--image-feature", rbdImageFeatures, r.imageSpec(volumeName))
+	if err != nil {
+		return err
+	}
+
+	// Format the image, or record how it will be encrypted, removing it if that fails.
+	if luks != nil {
+		_, err = r.rbd("image-meta", "set", r.imageSpec(volumeName), rbdLUKSMetadataKey, luks.String())
+	} else {
+		err = r.format(volumeName, filesystem)
+	}
+
+	if err != nil {
+		if _, removeErr := r.rbd("rm", r.imageSpec(volumeName)); removeErr != nil {
+			glog.Error(removeErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Mount a particular volume, mapping its image exclusively to this host and mounting it. The LUKS container of an
+// encrypted image is opened once it is mapped.
+func (r RBDStorageController) Mount(volumeName string) (string, error) {
+	mountpoint := path.Join(r.MountPath, volumeName)
+
+	if isMounted(r.Runner, mountpoint) {
+		return mountpoint, nil
+	}
+
+	luks, err := r.luks(volumeName)
+	if err != nil {
+		return "", err
+	}
+
+	err = os.MkdirAll(mountpoint, 0755)
+	if err != nil {
+		return "", err
+	}
+
+	// --exclusive stops other hosts from taking the image's lock while it is mapped here.
+	device, err := r.rbd("map", "--exclusive", r.imageSpec(volumeName))
+	if err != nil {
+		return "", err
+	}
+
+	device = strings.TrimSpace(device)
+	if luks != nil {
+		device, err = openLUKS(r.Runner, r.Keys, volumeName, *luks, device)
+	}
+
+	if err == nil {
+		_, err = r.Runner.Run("mount", device, mountpoint)
+	}
+
+	if err != nil {
+		if closeErr := closeLUKS(r.Runner, volumeName); closeErr != nil {
+			glog.Error(closeErr)
+		}
+		if _, unmapErr := r.rbd("unmap", r.imageSpec(volumeName)); unmapErr != nil {
+			glog.Error(unmapErr)
+		}
+		return "", err
+	}
+
+	return mountpoint, nil
+}
+
+// Unmount a particular volume, releasing its image so that another host may map it. An image that is still mapped
+// though its filesystem is not mounted, as after a crash, is unmapped.
+func (r RBDStorageController) Unmount(volumeName string) error {
+	released, err := r.release(volumeName)
+	if err != nil {
+		return err
+	}
+
+	if !released {
+		return errors.New("already unmounted")
+	}
+
+	return nil
+}
+
+// release unmounts a volume, closes its LUKS container and unmaps its image, skipping whatever has already been
+// done. It returns false if there was nothing to release.
+func (r RBDStorageController) release(volumeName string) (bool, error) {
+	mountpoint := path.Join(r.MountPath, volumeName)
+
+	mounted := isMounted(r.Runner, mountpoint)
+	if mounted {
+		if _, err := r.Runner.Run("umount", mountpoint); err != nil {
+			return false, err
+		}
+	}
+
+	if err := closeLUKS(r.Runner, volumeName); err != nil {
+		return false, err
+	}
+
+	mapped, err := r.isMapped(volumeName)
+	if err != nil || !mapped {
+		return mounted, err
+	}
+
+	_, err = r.rbd("unmap", r.imageSpec(volumeName))
+	return true, err
+}
+
+// isMapped returns true if a volume's image is mapped on this host.
+func (r RBDStorageController) isMapped(volumeName string) (bool, error) {
+	output, err := r.rbd("showmapped", "--format", "json")
+	if err != nil {
+		return false, err
+	}
+
+	var mappings []struct {
+		Pool string `json:"pool"`
+		Name string `json:"name"`
+	}
+	if strings.TrimSpace(output) != "" {
+		if err = json.Unmarshal([]byte(output), &mappings); err != nil {
+			return false, errors.New("unable to parse the mapped images: " + err.Error())
+		}
+	}
+
+	for _, mapping := range mappings {
+		if mapping.Pool == r.Pool && mapping.Name == volumeName {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Delete a particular volume, removing its image.
+func (r RBDStorageController) Delete(volumeName string) error {
+	mountpoint := path.Join(r.MountPath, volumeName)
+
+	if _, err := r.release(volumeName); err != nil {
+		return err
+	}
+
+	// If there is no image there is nothing to delete. Other failures, such as a cluster that can not be reached, are
+	// returned, so that the volume is not forgotten while its image remains.
+	if _, err := r.rbd("info", r.imageSpec(volumeName)); err != nil {
+		if rbdNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	_, err := r.rbd("rm", r.imageSpec(volumeName))
+	if err != nil {
+		return err
+	}
+
+	os.Remove(mountpoint)
+	return nil
+}
+
+// Resize grows an image and its filesystem. A mapped image is resized by the kernel as soon as ceph has grown it.
+func (r RBDStorageController) Resize(volumeName string, size string, options map[string]string) error {
+	if !rbdSizePattern.MatchString(size) {
+		return errors.New("invalid size: " + size)
+	}
+
+	// rbd counts sizes without a suffix in megabytes.
+	requested, err := parseSize(size, 1<<20)
+	if err != nil {
+		return err
+	}
+
+	filesystem, err := filesystemOption(options)
+	if err != nil {
+		return err
+	}
+
+	output, err := r.rbd("info", "--format", "json", r.imageSpec(volumeName))
+	if err != nil {
+		return errors.New("volume " + volumeName + " does not exist")
+	}
+
+	var info struct {
+		Size int64 `json:"size"`
+	}
+	if err = json.Unmarshal([]byte(output), &info); err != nil {
+		return errors.New("unable to parse the size of " + volumeName + ": " + err.Error())
+	}
+
+	if err = checkGrowth(volumeName, info.Size, requested); err != nil || info.Size == requested {
+		return err
+	}
+
+	luks, err := r.luks(volumeName)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.rbd("resize", "--size", size, r.imageSpec(volumeName))
+	if err != nil {
+		return err
+	}
+
+	return growFilesystem(r.Runner, r, volumeName, path.Join(r.MountPath, volumeName), filesystem, r.Keys, luks)
+}
+
+// MultiHost reports that an image may only be mapped on one host at a time, as images are mapped exclusively.
+func (r RBDStorageController) MultiHost() bool {
+	return false
+}
+
+// KeyProvider returns the provider of the keys of encrypted volumes, or nil if volumes can not be encrypted.
+func (r RBDStorageController) KeyProvider() keys.KeyProvider {
+	return r.Keys
+}
+
+// luks returns how an image is encrypted, from its metadata, or nil if it is not encrypted.
+func (r RBDStorageController) luks(volumeName string) (*luksVolume, error) {
+	output, err := r.rbd("image-meta", "list", "--format", "json", r.imageSpec(volumeName))
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := map[string]string{}
+	if strings.TrimSpace(output) != "" {
+		if err = json.Unmarshal([]byte(output), &metadata); err != nil {
+			return nil, errors.New("unable to parse the metadata of " + volumeName + ": " + err.Error())
+		}
+	}
+
+	recorded, ok := metadata[rbdLUKSMetadataKey]
+	if !ok {
+		return nil, nil
+	}
+
+	return parseLUKSVolume(recorded)
+}
+
+// format maps the image just long enough to create a filesystem on it.
+func (r RBDStorageController) format(volumeName string, filesystem string) error {
+	device, err := r.rbd("map", r.imageSpec(volumeName))
+	if err != nil {
+		return err
+	}
+
+	err = formatDevice(r.Runner, filesystem, strings.TrimSpace(device))
+
+	_, unmapErr := r.rbd("unmap", r.imageSpec(volumeName))
+	if err == nil {
+		err = unmapErr
+	}
+
+	return err
+}
+
+// rbdNotFound returns true if err is rbd reporting that an image does not exist.
+func rbdNotFound(err error) bool {
+	return strings.Contains(err.Error(), "No such file or directory")
+}
+
+// rbd runs the rbd command as the configured ceph user.
+func (r RBDStorageController) rbd(args ...string) (string, error) {
+	if r.User != "" {
+		args = append(args, "--id", r.User)
+	}
+
+	if r.ConfigPath != "" {
+		args = append(args, "--conf", r.ConfigPath)
+	}
+
+	return r.Runner.Run("rbd", args...)
+}
+
+func (r RBDStorageController) imageSpec(volumeName string) string {
+	return r.Pool + "/" + volumeName
+}
@@ -0,0 +1,431 @@
+package drivers
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeRBD pretends to be the rbd and mount tools of a host connected to a ceph cluster with the pool rbd.
+type fakeRBD struct {
+	images   map[string]bool
+	sizes    map[string]int64
+	mapped   map[string]string
+	metadata map[string]map[string]string
+	mounted  map[string]bool
+	crypt    *fakeCrypt
+	commands []string
+}
+
+func newFakeRBD() *fakeRBD {
+	return &fakeRBD{images: map[string]bool{}, sizes: map[string]int64{}, mapped: map[string]string{}, metadata: map[string]map[string]string{}, mounted: map[string]bool{}, crypt: newFakeCrypt()}
+}
+
+func (f *fakeRBD) run(name string, args ...string) (string, error) {
+	f.commands = append(f.commands, strings.TrimSpace(name+" "+strings.Join(args, " ")))
+	last := args[len(args)-1]
+
+	switch name {
+	case "rbd":
+		var image string
+		for _, arg := range args {
+			if strings.HasPrefix(arg, "rbd/") {
+				image = strings.TrimPrefix(arg, "rbd/")
+			}
+		}
+
+		switch args[0] {
+		case "create":
+			f.images[image] = true
+			f.sizes[image], _ = parseSize(args[2], 1<<20)
+		case "info":
+			if !f.images[image] {
+				return "", errors.New("rbd: error opening image " + image + ": (2) No such file or directory")
+			}
+			return `{"name": "` + image + `", "size": ` + strconv.FormatInt(f.sizes[image], 10) + `}`, nil
+		case "resize":
+			f.sizes[image], _ = parseSize(args[2], 1<<20)
+		case "image-meta":
+			if !f.images[image] {
+				return "", errors.New("rbd: error opening image " + image)
+			}
+			if args[1] == "set" {
+				if f.metadata[image] == nil {
+					f.metadata[image] = map[string]string{}
+				}
+				f.metadata[image][args[3]] = args[4]
+				return "", nil
+			}
+			if len(f.metadata[image]) == 0 {
+				return "", nil
+			}
+			output, err := json.Marshal(f.metadata[image])
+			return string(output), err
+		case "rm":
+			delete(f.images, image)
+			delete(f.metadata, image)
+		case "map":
+			if _, mapped := f.mapped[image]; mapped {
+				return "", errors.New("rbd: image " + image + " is already mapped")
+			}
+			f.mapped[image] = "/dev/rbd" + strconv.Itoa(len(f.mapped))
+			return f.mapped[image] + "\n", nil
+		case "showmapped":
+			var mappings []map[string]string
+			for mappedImage, device := range f.mapped {
+				mappings = append(mappings, map[string]string{"pool": "rbd", "name": mappedImage, "device": device})
+			}
+			output, err := json.Marshal(mappings)
+			return string(output), err
+		case "unmap":
+			if _, mapped := f.mapped[image]; !mapped {
+				return "", errors.New("rbd: " + image + " is not mapped")
+			}
+			delete(f.mapped, image)
+		}
+	case "mountpoint":
+		if !f.mounted[last] {
+			return "", errors.New(last + " is not a mountpoint")
+		}
+	case "findmnt":
+		return "/dev/rbd0\n", nil
+	case "cryptsetup":
+		return f.crypt.run(args...)
+	case "mount":
+		f.mounted[last] = true
+	case "umount":
+		delete(f.mounted, last)
+	}
+
+	return "", nil
+}
+
+func newFakeRBDStorageController(fake *fakeRBD) RBDStorageController {
+	sc := NewRBDStorageController("rbd", "admin", "", "test/rbd")
+	sc.Runner = CommandRunnerFunc(fake.run)
+	return sc
+}
+
+func TestRBDConnect(t *testing.T) {
+	t.Parallel()
+	fake := newFakeRBD()
+	sc := newFakeRBDStorageController(fake)
+
+	err := sc.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !containsString(fake.commands, "rbd ls rbd --id admin") {
+		t.Error("Connect should list the pool as the configured user. Ran: ", fake.commands)
+	}
+
+	sc.Pool = ""
+	err = sc.Connect()
+	if err == nil {
+		t.Error("Connect should fail when no pool is configured")
+	}
+}
+
+func TestRBDCreateMountUnmountDelete(t *testing.T) {
+	t.Parallel()
+	fake := newFakeRBD()
+	sc := newFakeRBDStorageController(fake)
+
+	err := sc.Create("rbdvol1", map[string]string{"size": "1G", "fs": "xfs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"rbd create --size 1G --image-feature layering,exclusive-lock rbd/rbdvol1 --id admin",
+		"mkfs.xfs -q /dev/rbd0",
+		"rbd unmap rbd/rbdvol1 --id admin",
+	}
+	for _, command := range expected {
+		if !containsString(fake.commands, command) {
+			t.Error("Expected the command ", command, " to be run. Ran: ", fake.commands)
+		}
+	}
+
+	if len(fake.mapped) != 0 {
+		t.Error("The image should be unmapped once it has been formatted")
+	}
+
+	mountpoint, err := sc.Mount("rbdvol1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mountpoint != "test/rbd/rbdvol1" || !fake.mounted[mountpoint] {
+		t.Error("Volume was not mounted at test/rbd/rbdvol1, got ", mountpoint)
+	}
+
+	if !containsString(fake.commands, "rbd map --exclusive rbd/rbdvol1 --id admin") {
+		t.Error("The image should be mapped exclusively. Ran: ", fake.commands)
+	}
+
+	// Mounting again should reuse the existing mount rather than mapping the image twice.
+	_, err = sc.Mount("rbdvol1")
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = sc.Unmount("rbdvol1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.mapped) != 0 || len(fake.mounted) != 0 {
+		t.Error("Unmount should unmount and unmap the image")
+	}
+
+	err = sc.Unmount("rbdvol1")
+	if err == nil {
+		t.Error("Should have received an error for unmounting a volume twice")
+	}
+
+	_, err = sc.Mount("rbdvol1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sc.Delete("rbdvol1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fake.images["rbdvol1"] || len(fake.mapped) != 0 {
+		t.Error("Delete did not unmap and remove the image")
+	}
+
+	err = sc.Delete("rbdvol1")
+	if err != nil {
+		t.Error("Deleting a volume that does not exist should not fail: ", err)
+	}
+}
+
+func TestRBDUnmountMapped(t *testing.T) {
+	t.Parallel()
+	fake := newFakeRBD()
+	sc := newFakeRBDStorageController(fake)
+
+	err := sc.Create("rbdvol1", map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = sc.Mount("rbdvol1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A crash leaves the image mapped once the host has dropped its mounts.
+	fake.mounted = map[string]bool{}
+
+	err = sc.Unmount("rbdvol1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.mapped) != 0 {
+		t.Error("Unmount should unmap an image that is still mapped")
+	}
+
+	if err = sc.Unmount("rbdvol1"); err == nil {
+		t.Error("Should have received an error for unmounting a volume that is not mapped")
+	}
+}
+
+func TestRBDDeleteUnavailable(t *testing.T) {
+	t.Parallel()
+	fake := newFakeRBD()
+	sc := newFakeRBDStorageController(fake)
+
+	err := sc.Create("rbdvol1", map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sc.Runner = CommandRunnerFunc(func(name string, args ...string) (string, error) {
+		if name == "rbd" && args[0] == "info" {
+			return "", errors.New("rbd: error opening image rbdvol1: (110) Connection timed out")
+		}
+		return fake.run(name, args...)
+	})
+
+	if err = sc.Delete("rbdvol1"); err == nil {
+		t.Error("Delete should fail when the cluster can not be reached")
+	}
+
+	if !fake.images["rbdvol1"] {
+		t.Error("The image should not be removed")
+	}
+}
+
+func TestRBDCreateInvalidOptions(t *testing.T) {
+	t.Parallel()
+	fake := newFakeRBD()
+	sc := newFakeRBDStorageController(fake)
+
+	var tests = []map[string]string{
+		{"size": "1.5G"},
+		{"size": "--help"},
+		{"fs": "btrfs"},
+	}
+
+	for _, options := range tests {
+		err := sc.Create("rbdvol2", options)
+		if err == nil {
+			t.Error("Create should fail for options ", options)
+		}
+	}
+
+	if len(fake.images) != 0 {
+		t.Error("No images should have been created")
+	}
+}
+
+// TestRBDCluster exercises a real ceph cluster, such as a developer cluster started with vstart.sh. It requires root
+// and the rbd kernel module, e.g.
+//
+//	../src/vstart.sh -n -d && export CEPH_CONF=$PWD/ceph.conf
+//	ceph osd pool create rbdtest && rbd pool init rbdtest
+//	RBD_TEST_POOL=rbdtest go test ./drivers -run TestRBDCluster
+func TestRBDCluster(t *testing.T) {
+	pool := os.Getenv("RBD_TEST_POOL")
+	if pool == "" || os.Getenv("CEPH_CONF") == "" {
+		t.Skip("RBD_TEST_POOL and CEPH_CONF are required to test against a ceph cluster.")
+	}
+
+	if _, err := exec.LookPath("rbd"); err != nil {
+		t.Skip("rbd is required to test against a ceph cluster.")
+	}
+
+	sc := NewRBDStorageController(pool, "", os.Getenv("CEPH_CONF"), "test/rbdcluster")
+	err := sc.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sc.Create("rbdclustervol", map[string]string{"size": "100M"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Delete("rbdclustervol")
+
+	mountpoint, err := sc.Mount("rbdclustervol")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ioutil.WriteFile(mountpoint+"/hello", []byte("world"), 0644)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = sc.Unmount("rbdclustervol")
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRBDResize(t *testing.T) {
+	t.Parallel()
+	fake := newFakeRBD()
+	sc := newFakeRBDStorageController(fake)
+
+	if err := sc.Create("rbdvol5", map[string]string{"size": "1G"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := sc.Mount("rbdvol5"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sc.Resize("rbdvol5", "2G", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"rbd resize --size 2G rbd/rbdvol5 --id admin",
+		"resize2fs /dev/rbd0",
+	}
+	for _, command := range expected {
+		if !containsString(fake.commands, command) {
+			t.Error("Expected the command ", command, " to be run. Ran: ", fake.commands)
+		}
+	}
+
+	if fake.sizes["rbdvol5"] != 2<<30 || !fake.mounted["test/rbd/rbdvol5"] {
+		t.Error("The image should have grown and stayed mounted, got ", fake.sizes["rbdvol5"], fake.mounted)
+	}
+
+	if err := sc.Resize("rbdvol5", "1024", nil); err == nil {
+		t.Error("Shrinking an image should fail")
+	}
+
+	if err := sc.Resize("missing", "5G", nil); err == nil {
+		t.Error("Resizing an image that does not exist should fail")
+	}
+}
+
+func TestRBDEncrypted(t *testing.T) {
+	t.Parallel()
+	provider, cleanUp := newTestKeyProvider(t)
+	defer cleanUp()
+
+	fake := newFakeRBD()
+	sc := newFakeRBDStorageController(fake)
+	sc.Runner = &inputRecorder{CommandRunnerFunc: fake.run}
+	sc.Keys = provider
+
+	err := sc.Create("rbdsecret", map[string]string{"size": "1G", "fs": "xfs", EncryptedOption: "true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fake.metadata["rbdsecret"]["docker-volume-rdma.luks"] != "xfs:rbdsecret" {
+		t.Error("The image should record its encryption in its metadata, got ", fake.metadata["rbdsecret"])
+	}
+
+	if containsString(fake.commands, "mkfs.xfs -q /dev/rbd0") {
+		t.Error("An encrypted image should not be formatted until it is first mounted")
+	}
+
+	if _, err = sc.Mount("rbdsecret"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"cryptsetup luksFormat --type luks2 --batch-mode --key-file - /dev/rbd0",
+		"mkfs.xfs -q /dev/mapper/docker-volume-rdma-rbdsecret",
+		"mount /dev/mapper/docker-volume-rdma-rbdsecret test/rbd/rbdsecret",
+	}
+	for _, command := range expected {
+		if !containsString(fake.commands, command) {
+			t.Error("Expected the command ", command, " to be run. Ran: ", fake.commands)
+		}
+	}
+
+	if err = sc.Resize("rbdsecret", "2G", map[string]string{"fs": "xfs"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !containsString(fake.commands, "cryptsetup resize --key-file - docker-volume-rdma-rbdsecret") {
+		t.Error("The LUKS container should grow before its filesystem. Ran: ", fake.commands)
+	}
+
+	if err = sc.Unmount("rbdsecret"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.crypt.opened) != 0 || len(fake.mapped) != 0 {
+		t.Error("The LUKS container should be closed before the image is unmapped")
+	}
+}
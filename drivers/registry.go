@@ -0,0 +1,84 @@
+package drivers
+
+import (
+	"path"
+	"sort"
+	"sync"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/config"
+)
+
+// StorageControllerFactory describes a kind of Storage Controller: the settings it accepts and how to create one.
+type StorageControllerFactory struct {
+	// Options are the settings the Storage Controller accepts, their names are also the command line flags.
+	Options []config.Option
+
+	// Lenient Storage Controllers only warn about unsupported settings, rather than refusing to start.
+	Lenient bool
+
+	// New creates a Storage Controller configured with values.
+	New func(values config.Values) (StorageController, error)
+}
+
+// mountPathOption is the setting shared by Storage Controllers that mount volumes beneath a folder on the host.
+var mountPathOption = config.Option{
+	Name:        "scpath",
+	Description: "set the storage path used to know where to put the volumes on the host"}
+
+// MountRoot returns the folder that storageController mounts volumes beneath, or "" if it is not known.
+func MountRoot(storageController StorageController) string {
+	mounter, ok := storageController.(StorageMountRoot)
+	if !ok {
+		return ""
+	}
+
+	return path.Clean(mounter.MountRoot())
+}
+
+// keyProviderOptions are the settings shared by Storage Controllers that can encrypt volumes, choosing where the keys
+// of encrypted volumes are kept: in a local directory or in Vault's transit secrets engine.
+var keyProviderOptions = []config.Option{
+	{Name: "sc-key-dir", Description: "set the directory holding the keys of encrypted volumes"},
+	{Name: "sc-vault-addr", Description: "set the address of the vault holding the keys of encrypted volumes, e.g. https://vault:8200"},
+	{Name: "sc-vault-token-file", Description: "set the file holding the token used to access the vault"},
+	{Name: "sc-vault-mount", Description: "set the path the vault's transit secrets engine is mounted at (default is transit)"},
+}
+
+var registryMutex sync.RWMutex
+var registry = map[string]StorageControllerFactory{}
+
+// Register makes a kind of Storage Controller available by name, e.g. for use with -sc=name.
+// Register panics if it is called twice with the same name.
+func Register(name string, factory StorageControllerFactory) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	if _, exists := registry[name]; exists {
+		panic("drivers: Register called twice for storage controller " + name)
+	}
+
+	registry[name] = factory
+}
+
+// Lookup returns the factory of the Storage Controller registered as name.
+func Lookup(name string) (StorageControllerFactory, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	factory, exists := registry[name]
+	return factory, exists
+}
+
+// Registered returns the names of all registered Storage Controllers in sorted order.
+func Registered() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,48 @@
+package drivers
+
+import (
+	"testing"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/config"
+)
+
+func TestRegistered(t *testing.T) {
+	t.Parallel()
+
+	registered := Registered()
+	for _, name := range []string{"glusterfs", "lvm", "on-disk", "rbd", "tmpfs"} {
+		if !containsString(registered, name) {
+			t.Error("The ", name, " storage controller should be registered, got ", registered)
+		}
+	}
+
+	factory, exists := Lookup("on-disk")
+	if !exists {
+		t.Fatal("Lookup did not find the on-disk storage controller")
+	}
+
+	sc, err := factory.New(config.NewValues(factory.Options, map[string]string{"scpath": "test/registry"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sc.(OnDiskStorageController).FSPath != "test/registry" {
+		t.Error("The on-disk storage controller was not configured with -scpath")
+	}
+
+	if _, exists := Lookup("floppy"); exists {
+		t.Error("Lookup should not find a storage controller that was never registered")
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Registering a storage controller twice should panic")
+		}
+	}()
+
+	Register("on-disk", StorageControllerFactory{})
+}
@@ -0,0 +1,41 @@
+package drivers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// sizeUnits are the multipliers of the suffixes accepted by the size option. lvcreate, rbd and tmpfs all treat them as
+// powers of 1024, whatever their case, and lvcreate also accepts 512 byte sectors.
+var sizeUnits = map[string]int64{"b": 1, "s": 512, "k": 1 << 10, "m": 1 << 20, "g": 1 << 30, "t": 1 << 40, "p": 1 << 50, "e": 1 << 60}
+
+// parseSize returns the number of bytes in a size such as 512M or 1.5T. Sizes without a suffix are counted in units of
+// unit bytes.
+func parseSize(size string, unit int64) (int64, error) {
+	if size == "" {
+		return 0, errors.New("a size is required")
+	}
+
+	number := size
+	if suffix := strings.ToLower(size[len(size)-1:]); sizeUnits[suffix] != 0 {
+		number = size[:len(size)-1]
+		unit = sizeUnits[suffix]
+	}
+
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value < 0 {
+		return 0, errors.New("invalid size: " + size)
+	}
+
+	return int64(value * float64(unit)), nil
+}
+
+// checkGrowth returns an error if a volume of current bytes would have to shrink to be resized to requested bytes.
+func checkGrowth(volumeName string, current int64, requested int64) error {
+	if requested < current {
+		return errors.New("volume " + volumeName + " can not be shrunk from " + strconv.FormatInt(current, 10) + " to " + strconv.FormatInt(requested, 10) + " bytes, only grown")
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package drivers
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		size     string
+		unit     int64
+		expected int64
+		valid    bool
+	}{
+		{"10G", 1, 10 << 30, true},
+		{"10g", 1, 10 << 30, true},
+		{"1.5T", 1, 3 << 39, true},
+		{"512", 1 << 20, 512 << 20, true},
+		{"512", 1, 512, true},
+		{"8s", 1, 4096, true},
+		{"", 1, 0, false},
+		{"G", 1, 0, false},
+		{"25%", 1, 0, false},
+		{"-1G", 1, 0, false},
+	}
+
+	for _, test := range tests {
+		size, err := parseSize(test.size, test.unit)
+		if test.valid && (err != nil || size != test.expected) {
+			t.Error("Expected ", test.size, " to be ", test.expected, " bytes, got ", size, err)
+		} else if !test.valid && err == nil {
+			t.Error("Expected ", test.size, " to be invalid, got ", size)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package drivers
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
+)
+
+// TenantOption records the tenant that a volume belongs to. It is set when the volume is created, from the volume's
+// name or options, and may not be chosen otherwise.
+const TenantOption = "tenant"
+
+// Quota limits the volumes of a tenant. A limit of 0 leaves that resource unlimited.
+type Quota struct {
+	Volumes int
+	Bytes   int64
+}
+
+// NewQuota creates a Quota of volumes volumes and size bytes, given like the size option. Sizes without a suffix are
+// in megabytes.
+func NewQuota(volumes int, size string) (Quota, error) {
+	if volumes < 0 {
+		return Quota{}, errors.New("invalid volume quota: " + strconv.Itoa(volumes))
+	}
+
+	var bytes int64
+	if size != "" {
+		var err error
+		bytes, err = parseSize(size, 1<<20)
+		if err != nil {
+			return Quota{}, err
+		}
+	}
+
+	return Quota{Volumes: volumes, Bytes: bytes}, nil
+}
+
+// TenantUsage is what the volumes of a tenant use, and the quota they are limited to.
+type TenantUsage struct {
+	Tenant  string
+	Volumes int
+	Bytes   int64
+	Quota   Quota
+}
+
+// Tenants divides volumes between the teams that share a deployment, limiting each team's volumes to its quota. A
+// volume's tenant is either the part of its name before Separator, e.g. ml for ml-data with the separator -, or the
+// value of its Label option. Docker does not pass labels to volume plugins, so the label must be given as an option
+// as well, e.g. -o team=ml.
+type Tenants struct {
+	Label        string
+	Separator    string
+	Quotas       map[string]Quota
+	DefaultQuota Quota
+
+	// lock is held while a volume's quota is checked and the volume is recorded, so that concurrent requests can
+	// not both fit in the last of a quota.
+	lock *sync.Mutex
+}
+
+// NewTenants creates Tenants that take the tenant of each volume from label or, if label is empty, from the prefix
+// of its name before separator. Tenants without a quota in quotas are limited to defaultQuota.
+func NewTenants(label string, separator string, quotas map[string]Quota, defaultQuota Quota) (Tenants, error) {
+	if (label == "") == (separator == "") {
+		return Tenants{}, errors.New("tenants are taken from either a label or a name prefix, please choose one")
+	}
+
+	return Tenants{
+		Label:        label,
+		Separator:    separator,
+		Quotas:       quotas,
+		DefaultQuota: defaultQuota,
+		lock:         &sync.Mutex{}}, nil
+}
+
+// Enabled reports whether volumes are divided between tenants.
+func (t Tenants) Enabled() bool {
+	return t.Label != "" || t.Separator != ""
+}
+
+// Tenant returns the tenant of a volume, or an error if its name or options do not name one.
+func (t Tenants) Tenant(volumeName string, options map[string]string) (string, error) {
+	if t.Label != "" {
+		if options[t.Label] == "" {
+			return "", errors.New("volume " + volumeName + " has no tenant, please create it with the option " + t.Label)
+		}
+		return options[t.Label], nil
+	}
+
+	index := strings.Index(volumeName, t.Separator)
+	if index <= 0 {
+		return "", errors.New("volume " + volumeName + " has no tenant, please prefix its name with the tenant and " + t.Separator)
+	}
+
+	return volumeName[:index], nil
+}
+
+// Quota returns the quota of a tenant.
+func (t Tenants) Quota(tenant string) Quota {
+	if quota, exists := t.Quotas[tenant]; exists {
+		return quota
+	}
+
+	return t.DefaultQuota
+}
+
+// tenantOptions records the tenant of a new volume in a copy of its options.
+func (r RDMAVolumeDriver) tenantOptions(volumeName string, options map[string]string) (map[string]string, error) {
+	if !r.Tenants.Enabled() {
+		return options, nil
+	}
+
+	tenant, err := r.Tenants.Tenant(volumeName, options)
+	if err != nil {
+		return nil, err
+	}
+
+	if options[TenantOption] != "" && options[TenantOption] != tenant {
+		return nil, errors.New("volume " + volumeName + " belongs to tenant " + tenant + ", not " + options[TenantOption])
+	}
+
+	resolved := map[string]string{}
+	for name, value := range options {
+		resolved[name] = value
+	}
+	resolved[TenantOption] = tenant
+
+	return resolved, nil
+}
+
+// recordVolume records a new volume, stored by storageController, in the volume database once it is known to fit in
+// its tenant's quota.
+func (r RDMAVolumeDriver) recordVolume(storageController StorageController, volumeName string, options map[string]string) error {
+	if !r.Tenants.Enabled() {
+		return r.VolumeDatabase.Create(volumeName, options)
+	}
+
+	r.Tenants.lock.Lock()
+	defer r.Tenants.lock.Unlock()
+
+	// Volumes without a size would not count towards a quota of bytes.
+	tenant := options[TenantOption]
+	if r.Tenants.Quota(tenant).Bytes > 0 && options[SizeOption] == "" {
+		return errors.New("tenant " + tenant + " has a quota of bytes, please create volume " + volumeName + " with a size")
+	}
+
+	bytes, err := provisionedBytes(storageController, options[SizeOption])
+	if err != nil {
+		return err
+	}
+
+	err = r.checkQuota(tenant, 1, bytes)
+	if err != nil {
+		return err
+	}
+
+	return r.VolumeDatabase.Create(volumeName, options)
+}
+
+// resizeVolume grows a volume's recorded size, once the growth is known to fit in its tenant's quota, calling grow to
+// resize its storage on storageController.
+func (r RDMAVolumeDriver) resizeVolume(storageController StorageController, volumeName string, size string, options map[string]string, grow func() error) error {
+	if !r.Tenants.Enabled() {
+		if err := grow(); err != nil {
+			return err
+		}
+		return r.VolumeDatabase.SetOption(volumeName, SizeOption, size)
+	}
+
+	r.Tenants.lock.Lock()
+	defer r.Tenants.lock.Unlock()
+
+	current, err := provisionedBytes(storageController, options[SizeOption])
+	if err != nil {
+		return err
+	}
+
+	requested, err := provisionedBytes(storageController, size)
+	if err != nil {
+		return err
+	}
+
+	tenant := options[TenantOption]
+	if tenant == "" {
+		tenant, err = r.Tenants.Tenant(volumeName, options)
+		if err != nil {
+			return err
+		}
+	}
+
+	if requested > current {
+		err = r.checkQuota(tenant, 0, requested-current)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = grow(); err != nil {
+		return err
+	}
+
+	return r.VolumeDatabase.SetOption(volumeName, SizeOption, size)
+}
+
+// checkQuota returns an error if a tenant's volumes can not grow by volumes volumes and bytes bytes.
+func (r RDMAVolumeDriver) checkQuota(tenant string, volumes int, bytes int64) error {
+	usage, err := r.tenantUsage()
+	if err != nil {
+		return err
+	}
+
+	used := usage[tenant]
+	quota := r.Tenants.Quota(tenant)
+	if quota.Volumes > 0 && used.Volumes+volumes > quota.Volumes {
+		return errors.New("tenant " + tenant + " has " + strconv.Itoa(used.Volumes) + " of its quota of " + strconv.Itoa(quota.Volumes) + " volumes")
+	}
+
+	if quota.Bytes > 0 && used.Bytes+bytes > quota.Bytes {
+		return errors.New("tenant " + tenant + " has " + strconv.FormatInt(quota.Bytes-used.Bytes, 10) + " bytes of its quota left, " + strconv.FormatInt(bytes, 10) + " were requested")
+	}
+
+	return nil
+}
+
+// TenantUsage returns what the volumes of every tenant use, sorted by tenant, including the tenants that have a quota
+// but no volumes. There are no tenants unless they are enabled.
+func (r RDMAVolumeDriver) TenantUsage() ([]TenantUsage, error) {
+	if !r.Tenants.Enabled() {
+		return []TenantUsage{}, nil
+	}
+
+	usage, err := r.tenantUsage()
+	if err != nil {
+		return nil, err
+	}
+
+	for tenant := range r.Tenants.Quotas {
+		if _, exists := usage[tenant]; !exists {
+			usage[tenant] = TenantUsage{Tenant: tenant}
+		}
+	}
+
+	tenants := make([]TenantUsage, 0, len(usage))
+	for tenant, used := range usage {
+		used.Quota = r.Tenants.Quota(tenant)
+		tenants = append(tenants, used)
+	}
+
+	sort.Slice(tenants, func(i, j int) bool { return tenants[i].Tenant < tenants[j].Tenant })
+	return tenants, nil
+}
+
+// tenantUsage adds up the volumes and bytes of each tenant. Volumes created before tenants were configured count
+// towards the tenant their name or options would give them, if any. Volumes whose size can not be counted, such as
+// those on a backend that is no longer configured, count towards their tenant's volumes but not its bytes.
+func (r RDMAVolumeDriver) tenantUsage() (map[string]TenantUsage, error) {
+	volumes, err := r.VolumeDatabase.List()
+	if err != nil {
+		return nil, err
+	}
+
+	usage := map[string]TenantUsage{}
+	for _, vol := range volumes {
+		options, err := r.VolumeDatabase.Options(vol.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		tenant := options[TenantOption]
+		if tenant == "" {
+			tenant, err = r.Tenants.Tenant(vol.Name, options)
+			if err != nil {
+				continue
+			}
+		}
+
+		var bytes int64
+		storageController, err := r.storageControllerFor(vol.Name)
+		if err == nil {
+			bytes, err = provisionedBytes(storageController, options[SizeOption])
+		}
+		if err != nil {
+			glog.Warning("Unable to count the size of volume ", vol.Name, " towards tenant ", tenant, ": ", err)
+		}
+
+		used := usage[tenant]
+		used.Tenant = tenant
+		used.Volumes++
+		used.Bytes += bytes
+		usage[tenant] = used
+	}
+
+	return usage, nil
+}
+
+// provisionedBytes returns the number of bytes a volume of size may hold on storageController, or 0 if it was not given
+// a size. Unless the Storage Controller is a StorageSizer, sizes without a suffix are in megabytes, as for lvm and rbd.
+func provisionedBytes(storageController StorageController, size string) (int64, error) {
+	if size == "" {
+		return 0, nil
+	}
+
+	if sizer, ok := storageController.(StorageSizer); ok {
+		return sizer.ProvisionedBytes(size)
+	}
+
+	return parseSize(size, 1<<20)
+}
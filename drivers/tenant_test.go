@@ -0,0 +1,202 @@
+package drivers
+
+import (
+	"testing"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/db"
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
+)
+
+func TestNewQuota(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		volumes  int
+		size     string
+		expected Quota
+		fails    bool
+	}{
+		{0, "", Quota{}, false},
+		{10, "1G", Quota{Volumes: 10, Bytes: 1 << 30}, false},
+		{0, "512", Quota{Bytes: 512 << 20}, false},
+		{-1, "", Quota{}, true},
+		{1, "lots", Quota{}, true},
+	}
+
+	for _, test := range tests {
+		quota, err := NewQuota(test.volumes, test.size)
+		if test.fails != (err != nil) || quota != test.expected {
+			t.Error("Expected ", test.volumes, " volumes and ", test.size, " to be ", test.expected, ", got ", quota, err)
+		}
+	}
+}
+
+func TestTenants_Tenant(t *testing.T) {
+	t.Parallel()
+	if _, err := NewTenants("team", "-", nil, Quota{}); err == nil {
+		t.Error("Tenants should not be taken from a label and a prefix at once")
+	}
+
+	if _, err := NewTenants("", "", nil, Quota{}); err == nil {
+		t.Error("Tenants should be taken from a label or a prefix")
+	}
+
+	byPrefix, _ := NewTenants("", "-", nil, Quota{})
+	byLabel, _ := NewTenants("team", "", nil, Quota{})
+	tests := []struct {
+		tenants  Tenants
+		name     string
+		options  map[string]string
+		expected string
+	}{
+		{byPrefix, "ml-data", nil, "ml"},
+		{byPrefix, "ml-data-2", map[string]string{"team": "web"}, "ml"},
+		{byPrefix, "data", nil, ""},
+		{byPrefix, "-data", nil, ""},
+		{byLabel, "data", map[string]string{"team": "ml"}, "ml"},
+		{byLabel, "ml-data", nil, ""},
+	}
+
+	for _, test := range tests {
+		tenant, err := test.tenants.Tenant(test.name, test.options)
+		if tenant != test.expected || (test.expected == "") != (err != nil) {
+			t.Error("Expected the tenant of ", test.name, " with ", test.options, " to be ", test.expected, ", got ", tenant, err)
+		}
+	}
+}
+
+func TestCreate_quota(t *testing.T) {
+	t.Parallel()
+	fake := newFakeMounts()
+	rdmaVolDriver := NewRDMAVolumeDriver(newFakeTmpfsStorageController(fake), db.NewInMemoryVolumeDatabase())
+
+	quotas := map[string]Quota{"ml": {Volumes: 2, Bytes: 3 << 30}}
+	tenants, err := NewTenants("", "-", quotas, Quota{Volumes: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rdmaVolDriver.Tenants = tenants
+
+	create := func(name string, options map[string]string) string {
+		return rdmaVolDriver.Create(volume.Request{Name: name, Options: options}).Err
+	}
+
+	if errString := create("ml-a", map[string]string{"size": "2g"}); errString != "" {
+		t.Fatal(errString)
+	}
+
+	options, _ := rdmaVolDriver.VolumeDatabase.Options("ml-a")
+	if options[TenantOption] != "ml" {
+		t.Error("The volume's tenant should be recorded, got ", options)
+	}
+
+	var tests = []struct {
+		name    string
+		options map[string]string
+	}{
+		{"ml-b", map[string]string{"size": "2g"}},
+		{"ml-c", map[string]string{}},
+		{"ml-d", map[string]string{"size": "1g", TenantOption: "web"}},
+		{"unprefixed", map[string]string{"size": "1g"}},
+	}
+
+	for _, test := range tests {
+		if errString := create(test.name, test.options); errString == "" {
+			t.Error("Creating ", test.name, " with ", test.options, " should fail")
+		}
+	}
+
+	if errString := create("ml-b", map[string]string{"size": "1g"}); errString != "" {
+		t.Fatal(errString)
+	}
+
+	if errString := create("ml-c", map[string]string{"size": "1m"}); errString == "" {
+		t.Error("Creating more volumes than the quota allows should fail")
+	}
+
+	// Tenants without a quota of their own get the default quota.
+	if errString := create("web-a", nil); errString != "" {
+		t.Fatal(errString)
+	}
+
+	if errString := create("web-b", nil); errString == "" {
+		t.Error("Creating more volumes than the default quota allows should fail")
+	}
+
+	if err = rdmaVolDriver.Resize("ml-b", "2g"); err == nil {
+		t.Error("Growing a volume beyond its tenant's quota should fail")
+	}
+
+	if err = rdmaVolDriver.Resize("ml-b", "1024m"); err != nil {
+		t.Error("Resizing within the quota should succeed, got ", err)
+	}
+
+	usage, err := rdmaVolDriver.TenantUsage()
+	if err != nil || len(usage) != 2 {
+		t.Fatal("Expected the usage of ml and web, got ", usage, err)
+	}
+
+	if usage[0] != (TenantUsage{Tenant: "ml", Volumes: 2, Bytes: 3 << 30, Quota: quotas["ml"]}) {
+		t.Error("Unexpected usage of ml: ", usage[0])
+	}
+
+	if usage[1] != (TenantUsage{Tenant: "web", Volumes: 1, Quota: Quota{Volumes: 1}}) {
+		t.Error("Unexpected usage of web: ", usage[1])
+	}
+
+	// Removing a volume frees its share of the quota.
+	if errString := rdmaVolDriver.Remove(volume.Request{Name: "ml-a"}).Err; errString != "" {
+		t.Fatal(errString)
+	}
+
+	if errString := create("ml-c", map[string]string{"size": "2g"}); errString != "" {
+		t.Error("A removed volume should not count towards its tenant's quota, got ", errString)
+	}
+}
+
+func TestTenantUsage_backendUnits(t *testing.T) {
+	t.Parallel()
+	sc := newFakeTmpfsStorageController(newFakeMounts())
+	sc.Memory = func() (int64, error) {
+		return 8 << 30, nil
+	}
+	rdmaVolDriver := NewRDMAVolumeDriver(sc, db.NewInMemoryVolumeDatabase())
+
+	tenants, err := NewTenants("", "-", nil, Quota{Bytes: 4 << 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rdmaVolDriver.Tenants = tenants
+
+	// tmpfs counts sizes without a suffix in bytes, and percentages of the host's memory.
+	for name, size := range map[string]string{"ml-bytes": "1048576", "ml-percent": "25%"} {
+		if errString := rdmaVolDriver.Create(volume.Request{Name: name, Options: map[string]string{"size": size}}).Err; errString != "" {
+			t.Fatal(errString)
+		}
+	}
+
+	if errString := rdmaVolDriver.Create(volume.Request{Name: "ml-big", Options: map[string]string{"size": "50%"}}).Err; errString == "" {
+		t.Error("A percentage of memory beyond the tenant's quota should fail")
+	}
+
+	// Volumes whose size can not be counted are still counted as volumes.
+	if err = rdmaVolDriver.VolumeDatabase.Create("ml-unsized", map[string]string{"size": "lots"}); err != nil {
+		t.Fatal(err)
+	}
+
+	usage, err := rdmaVolDriver.TenantUsage()
+	if err != nil || len(usage) != 1 {
+		t.Fatal("Expected the usage of ml, got ", usage, err)
+	}
+
+	if usage[0] != (TenantUsage{Tenant: "ml", Volumes: 3, Bytes: 2<<30 + 1<<20, Quota: Quota{Bytes: 4 << 30}}) {
+		t.Error("Unexpected usage of ml: ", usage[0])
+	}
+
+	if err = rdmaVolDriver.Resize("ml-bytes", "2097152"); err != nil {
+		t.Error("Resizing within the quota should succeed, got ", err)
+	}
+
+	if err = rdmaVolDriver.Resize("ml-bytes", "3g"); err == nil {
+		t.Error("Growing a volume beyond its tenant's quota should fail")
+	}
+}
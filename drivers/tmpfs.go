@@ -0,0 +1,353 @@
+package drivers
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/mellanox-senior-design/docker-volume-rdma/config"
+)
+
+func init() {
+	Register("tmpfs", StorageControllerFactory{
+		Options: []config.Option{mountPathOption},
+		New: func(values config.Values) (StorageController, error) {
+			return NewTmpfsStorageController(values.String("scpath")), nil
+		}})
+}
+
+// tmpfsDefaultSize is the size of a tmpfs created without a size option.
+const tmpfsDefaultSize = "1g"
+
+// tmpfsSizePattern matches the sizes accepted by tmpfs, e.g. 512m, 10g or 25%.
+var tmpfsSizePattern = regexp.MustCompile(`^[0-9]+[kKmMgG%]?$`)
+
+// tmpfsOptionsDir is the folder, within MountPath, that the options of volumes are saved in. Docker volume names can
+// not start with a dot, so it can not be taken for a volume's mountpoint, and keeping the options out of MountPath
+// stops a volume named x.options from overwriting the options of x.
+const tmpfsOptionsDir = ".options"
+
+// tmpfsHugePolicies lists the transparent huge page policies a tmpfs may be mounted with.
+var tmpfsHugePolicies = map[string]bool{"never": true, "always": true, "within_size": true, "advise": true}
+
+// TmpfsStorageController mounts a memory backed tmpfs for every volume, useful for scratch space.
+type TmpfsStorageController struct {
+	MountPath string
+	Runner    CommandRunner
+
+	// Memory returns the number of bytes of memory on the host, which sizes given as a percentage are relative to.
+	Memory func() (int64, error)
+}
+
+// tmpfsOptions are the options a tmpfs volume was created with, saved in the options folder.
+type tmpfsOptions struct {
+	Size    string
+	Huge    string
+	Persist bool
+}
+
+// NewTmpfsStorageController creates a new TmpfsStorageController
+func NewTmpfsStorageController(mountPath string) TmpfsStorageController {
+	if mountPath == "" {
+		mountPath = "/etc/docker/mounts/"
+	}
+
+	glog.Info("Mount path: ", mountPath)
+
+	return TmpfsStorageController{
+		MountPath: mountPath,
+		Runner:    ExecCommandRunner{},
+		Memory:    hostMemory}
+}
+
+// MountRoot returns the folder that volumes are mounted beneath.
+func (t TmpfsStorageController) MountRoot() string {
+	return t.MountPath
+}
+
+// Connect ensures that the mount path exists.
+func (t TmpfsStorageController) Connect() error {
+	return os.MkdirAll(t.MountPath, 0755)
+}
+
+// Disconnect is a NOOP
+func (t TmpfsStorageController) Disconnect() error {
+	glog.Info("Disconnect function called, no action taken.")
+	return nil
+}
+
+// Create a volume, saving the size (default 1g), huge and persist (default true) options for when it is mounted.
+func (t TmpfsStorageController) Create(volumeName string, options map[string]string) error {
+	tmpfs := tmpfsOptions{Size: options["size"], Huge: options["huge"], Persist: true}
+	if tmpfs.Size == "" {
+		tmpfs.Size = tmpfsDefaultSize
+	}
+
+	if !tmpfsSizePattern.MatchString(tmpfs.Size) {
+		return errors.New("invalid size: " + tmpfs.Size)
+	}
+
+	if tmpfs.Huge != "" && !tmpfsHugePolicies[tmpfs.Huge] {
+		return errors.New("invalid huge: " + tmpfs.Huge + ", please choose never, always, within_size or advise")
+	}
+
+	if persist, exists := options["persist"]; exists {
+		var err error
+		tmpfs.Persist, err = strconv.ParseBool(persist)
+		if err != nil {
+			return errors.New("invalid persist: " + persist)
+		}
+	}
+
+	err := os.MkdirAll(path.Join(t.MountPath, tmpfsOptionsDir), 0755)
+	if err != nil {
+		return err
+	}
+
+	return t.writeOptions(volumeName, tmpfs)
+}
+
+// Mount a particular volume, mounting a new tmpfs if one is not already mounted.
+func (t TmpfsStorageController) Mount(volumeName string) (string, error) {
+	mountpoint := path.Join(t.MountPath, volumeName)
+
+	if isMounted(t.Runner, mountpoint) {
+		return mountpoint, nil
+	}
+
+	tmpfs, err := t.readOptions(volumeName)
+	if err != nil {
+		return "", err
+	}
+
+	err = os.MkdirAll(mountpoint, 0755)
+	if err != nil {
+		return "", err
+	}
+
+	mountOptions := "size=" + tmpfs.Size + ",mode=0755"
+	if tmpfs.Huge != "" {
+		mountOptions += ",huge=" + tmpfs.Huge
+	}
+
+	_, err = t.Runner.Run("mount", "-t", "tmpfs", "-o", mountOptions, "tmpfs", mountpoint)
+	if err != nil {
+		return "", err
+	}
+
+	return mountpoint, nil
+}
+
+// Unmount a particular volume. Volumes created with persist=false are unmounted, discarding their data, volumes that
+// persist stay mounted until they are deleted.
+func (t TmpfsStorageController) Unmount(volumeName string) error {
+	mountpoint := path.Join(t.MountPath, volumeName)
+
+	if !isMounted(t.Runner, mountpoint) {
+		return errors.New("already unmounted")
+	}
+
+	tmpfs, err := t.readOptions(volumeName)
+	if err != nil {
+		return err
+	}
+
+	if tmpfs.Persist {
+		glog.Info("Keeping ", mountpoint, " mounted as it persists until deleted.")
+		return nil
+	}
+
+	_, err = t.Runner.Run("umount", mountpoint)
+	return err
+}
+
+// Resize raises the size limit of a volume, remounting its tmpfs with the new limit if it is mounted. Sizes given as a
+// percentage of memory can not be compared, so they are not resized.
+func (t TmpfsStorageController) Resize(volumeName string, size string, options map[string]string) error {
+	if !tmpfsSizePattern.MatchString(size) {
+		return errors.New("invalid size: " + size)
+	}
+
+	tmpfs, err := t.readOptions(volumeName)
+	if err != nil {
+		return err
+	}
+
+	if strings.HasSuffix(size, "%") || strings.HasSuffix(tmpfs.Size, "%") {
+		return errors.New("volume " + volumeName + " can not be resized from " + tmpfs.Size + " to " + size + ", sizes relative to memory are not supported")
+	}
+
+	current, err := parseSize(tmpfs.Size, 1)
+	if err != nil {
+		return err
+	}
+
+	requested, err := parseSize(size, 1)
+	if err != nil {
+		return err
+	}
+
+	if err = checkGrowth(volumeName, current, requested); err != nil {
+		return err
+	}
+
+	tmpfs.Size = size
+	err = t.writeOptions(volumeName, tmpfs)
+	if err != nil {
+		return err
+	}
+
+	mountpoint := path.Join(t.MountPath, volumeName)
+	if !isMounted(t.Runner, mountpoint) {
+		return nil
+	}
+
+	_, err = t.Runner.Run("mount", "-o", "remount,size="+size, mountpoint)
+	return err
+}
+
+// ProvisionedBytes returns the size limit of a tmpfs mounted with size. As tmpfs counts them, sizes without a suffix
+// are in bytes and percentages are of the host's memory.
+func (t TmpfsStorageController) ProvisionedBytes(size string) (int64, error) {
+	if !tmpfsSizePattern.MatchString(size) {
+		return 0, errors.New("invalid size: " + size)
+	}
+
+	if !strings.HasSuffix(size, "%") {
+		return parseSize(size, 1)
+	}
+
+	percent, err := strconv.ParseInt(strings.TrimSuffix(size, "%"), 10, 64)
+	if err != nil {
+		return 0, errors.New("invalid size: " + size)
+	}
+
+	memory, err := t.Memory()
+	if err != nil {
+		return 0, err
+	}
+
+	return memory * percent / 100, nil
+}
+
+// Delete a particular volume, unmounting its tmpfs and wiping its data.
+func (t TmpfsStorageController) Delete(volumeName string) error {
+	mountpoint := path.Join(t.MountPath, volumeName)
+
+	if isMounted(t.Runner, mountpoint) {
+		if _, err := t.Runner.Run("umount", mountpoint); err != nil {
+			return err
+		}
+	}
+
+	err := os.RemoveAll(mountpoint)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(t.optionsPath(volumeName))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// List the volumes that have been created, from their saved options. The data of a volume that is not mounted is
+// already gone, so only mounted volumes take up memory.
+func (t TmpfsStorageController) List() ([]StoredVolume, error) {
+	entries, err := ioutil.ReadDir(path.Join(t.MountPath, tmpfsOptionsDir))
+	if os.IsNotExist(err) {
+		return []StoredVolume{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	volumes := []StoredVolume{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		stored := StoredVolume{Name: entry.Name(), Modified: entry.ModTime()}
+		mountpoint := path.Join(t.MountPath, stored.Name)
+		if isMounted(t.Runner, mountpoint) {
+			var modified time.Time
+			stored.Bytes, modified, err = folderUsage(mountpoint)
+			if err != nil {
+				return nil, err
+			}
+
+			if modified.After(stored.Modified) {
+				stored.Modified = modified
+			}
+		}
+
+		volumes = append(volumes, stored)
+	}
+
+	sort.Slice(volumes, func(i, j int) bool { return volumes[i].Name < volumes[j].Name })
+	return volumes, nil
+}
+
+func (t TmpfsStorageController) readOptions(volumeName string) (tmpfsOptions, error) {
+	var tmpfs tmpfsOptions
+
+	contents, err := ioutil.ReadFile(t.optionsPath(volumeName))
+	if err != nil {
+		return tmpfs, err
+	}
+
+	err = json.Unmarshal(contents, &tmpfs)
+	return tmpfs, err
+}
+
+func (t TmpfsStorageController) writeOptions(volumeName string, tmpfs tmpfsOptions) error {
+	contents, err := json.Marshal(tmpfs)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(t.optionsPath(volumeName), contents, 0644)
+}
+
+func (t TmpfsStorageController) optionsPath(volumeName string) string {
+	return path.Join(t.MountPath, tmpfsOptionsDir, volumeName)
+}
+
+// hostMemory returns the number of bytes of memory on the host, from the MemTotal line of /proc/meminfo.
+func hostMemory() (int64, error) {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 3 && fields[0] == "MemTotal:" && fields[2] == "kB" {
+			kilobytes, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return 0, errors.New("unable to parse the host's memory: " + scanner.Text())
+			}
+			return kilobytes << 10, nil
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	return 0, errors.New("unable to find the host's memory in /proc/meminfo")
+}
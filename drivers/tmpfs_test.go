@@ -0,0 +1,284 @@
+package drivers
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+// fakeMounts pretends to be the mount tools of a host.
+type fakeMounts struct {
+	mounted  map[string]bool
+	commands []string
+}
+
+func newFakeMounts() *fakeMounts {
+	return &fakeMounts{mounted: map[string]bool{}}
+}
+
+func (f *fakeMounts) run(name string, args ...string) (string, error) {
+	f.commands = append(f.commands, strings.TrimSpace(name+" "+strings.Join(args, " ")))
+	last := args[len(args)-1]
+
+	switch name {
+	case "mountpoint":
+		if !f.mounted[last] {
+			return "", errors.New(last + " is not a mountpoint")
+		}
+	case "mount":
+		f.mounted[last] = true
+	case "umount":
+		delete(f.mounted, last)
+	}
+
+	return "", nil
+}
+
+func newFakeTmpfsStorageController(fake *fakeMounts) TmpfsStorageController {
+	sc := NewTmpfsStorageController("test/tmpfs")
+	sc.Runner = CommandRunnerFunc(fake.run)
+	return sc
+}
+
+func TestTmpfsPersistentVolume(t *testing.T) {
+	t.Parallel()
+	fake := newFakeMounts()
+	sc := newFakeTmpfsStorageController(fake)
+
+	err := sc.Create("tmpfsvol1", map[string]string{"size": "2g", "huge": "within_size"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mountpoint, err := sc.Mount("tmpfsvol1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mountpoint != "test/tmpfs/tmpfsvol1" {
+		t.Error("Volume was not mounted at test/tmpfs/tmpfsvol1, got ", mountpoint)
+	}
+
+	expected := "mount -t tmpfs -o size=2g,mode=0755,huge=within_size tmpfs test/tmpfs/tmpfsvol1"
+	if !containsString(fake.commands, expected) {
+		t.Error("Expected the command ", expected, " to be run. Ran: ", fake.commands)
+	}
+
+	err = sc.Unmount("tmpfsvol1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !fake.mounted[mountpoint] {
+		t.Error("A persistent tmpfs should stay mounted until it is deleted")
+	}
+
+	err = sc.Delete("tmpfsvol1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fake.mounted[mountpoint] {
+		t.Error("Delete should unmount the tmpfs")
+	}
+
+	if _, err = os.Stat(sc.optionsPath("tmpfsvol1")); !os.IsNotExist(err) {
+		t.Error("Delete should remove the volume's options")
+	}
+}
+
+func TestTmpfsScratchVolume(t *testing.T) {
+	t.Parallel()
+	fake := newFakeMounts()
+	sc := newFakeTmpfsStorageController(fake)
+
+	err := sc.Create("tmpfsvol2", map[string]string{"persist": "false"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mountpoint, err := sc.Mount("tmpfsvol2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "mount -t tmpfs -o size=1g,mode=0755 tmpfs test/tmpfs/tmpfsvol2"
+	if !containsString(fake.commands, expected) {
+		t.Error("Expected the command ", expected, " to be run. Ran: ", fake.commands)
+	}
+
+	err = sc.Unmount("tmpfsvol2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fake.mounted[mountpoint] {
+		t.Error("A tmpfs that does not persist should be unmounted, discarding its data")
+	}
+
+	err = sc.Unmount("tmpfsvol2")
+	if err == nil {
+		t.Error("Should have received an error for unmounting a volume twice")
+	}
+
+	err = sc.Delete("tmpfsvol2")
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = sc.Delete("tmpfsvol2")
+	if err != nil {
+		t.Error("Deleting a volume that does not exist should not fail: ", err)
+	}
+}
+
+func TestTmpfsCreateInvalidOptions(t *testing.T) {
+	t.Parallel()
+	sc := newFakeTmpfsStorageController(newFakeMounts())
+
+	var tests = []map[string]string{
+		{"size": "1.5g"},
+		{"size": "1g,uid=0"},
+		{"huge": "sometimes"},
+		{"persist": "maybe"},
+	}
+
+	for _, options := range tests {
+		err := sc.Create("tmpfsvol3", options)
+		if err == nil {
+			t.Error("Create should fail for options ", options)
+		}
+	}
+
+	_, err := sc.Mount("tmpfsvol3")
+	if err == nil {
+		t.Error("A volume that failed to be created should not be mounted")
+	}
+}
+
+func TestTmpfsResize(t *testing.T) {
+	t.Parallel()
+	fake := newFakeMounts()
+	sc := newFakeTmpfsStorageController(fake)
+
+	if err := sc.Create("tmpfsvol5", map[string]string{"size": "512m"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Volumes that are not mounted are mounted with the new size later.
+	if err := sc.Resize("tmpfsvol5", "1g", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := sc.Mount("tmpfsvol5"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !containsString(fake.commands, "mount -t tmpfs -o size=1g,mode=0755 tmpfs test/tmpfs/tmpfsvol5") {
+		t.Error("Expected the volume to be mounted with its new size. Ran: ", fake.commands)
+	}
+
+	if err := sc.Resize("tmpfsvol5", "2g", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if !containsString(fake.commands, "mount -o remount,size=2g test/tmpfs/tmpfsvol5") {
+		t.Error("Expected a mounted volume to be remounted with its new size. Ran: ", fake.commands)
+	}
+
+	var tests = []struct {
+		name string
+		size string
+	}{
+		{"shrink", "1g"},
+		{"percent", "50%"},
+		{"invalid", "2 g"},
+	}
+
+	for _, test := range tests {
+		if err := sc.Resize("tmpfsvol5", test.size, nil); err == nil {
+			t.Error(test.name, " should fail")
+		}
+	}
+
+	if err := sc.Resize("missing", "5g", nil); err == nil {
+		t.Error("Resizing a volume that does not exist should fail")
+	}
+}
+
+func TestTmpfsProvisionedBytes(t *testing.T) {
+	t.Parallel()
+	sc := newFakeTmpfsStorageController(newFakeMounts())
+	sc.Memory = func() (int64, error) {
+		return 8 << 30, nil
+	}
+
+	tests := []struct {
+		size     string
+		expected int64
+		fails    bool
+	}{
+		{"4096", 4096, false},
+		{"512m", 512 << 20, false},
+		{"2G", 2 << 30, false},
+		{"25%", 2 << 30, false},
+		{"2 g", 0, true},
+		{"1t", 0, true},
+	}
+
+	for _, test := range tests {
+		bytes, err := sc.ProvisionedBytes(test.size)
+		if test.fails != (err != nil) || bytes != test.expected {
+			t.Error("Expected ", test.size, " to be ", test.expected, " bytes, got ", bytes, err)
+		}
+	}
+}
+
+func TestTmpfsList(t *testing.T) {
+	t.Parallel()
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-tmpfs")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	fake := newFakeMounts()
+	sc := NewTmpfsStorageController(tempDir)
+	sc.Runner = CommandRunnerFunc(fake.run)
+
+	volumes, err := sc.List()
+	if err != nil || len(volumes) != 0 {
+		t.Fatal("Expected no volumes before any were created, got ", volumes, err)
+	}
+
+	for _, volumeName := range []string{"scratch-b", "scratch-a", "scratch", "scratch.options"} {
+		if err = sc.Create(volumeName, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mountpoint, err := sc.Mount("scratch-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = ioutil.WriteFile(path.Join(mountpoint, "data"), make([]byte, 64), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	volumes, err = sc.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(volumes) != 4 || volumes[0].Name != "scratch" || volumes[1].Name != "scratch-a" || volumes[2].Name != "scratch-b" || volumes[3].Name != "scratch.options" {
+		t.Fatal("Expected every created volume in order, got ", volumes)
+	}
+
+	if volumes[0].Bytes != 0 || volumes[1].Bytes != 64 || volumes[0].Modified.IsZero() {
+		t.Error("Only the mounted volume should take up memory, got ", volumes)
+	}
+}
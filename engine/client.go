@@ -0,0 +1,200 @@
+// Package engine is a minimal client for the parts of the Docker Engine API that docker-volume-rdma uses to check
+// which containers are still using its volumes.
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultHost is the socket the Docker daemon listens on by default.
+const DefaultHost = "unix:///var/run/docker.sock"
+
+// DefaultTimeout bounds each request to the Docker daemon.
+const DefaultTimeout = 30 * time.Second
+
+// Container is a container listed by the Docker daemon.
+type Container struct {
+	ID     string `json:"Id"`
+	Names  []string
+	State  string
+	Mounts []MountPoint
+}
+
+// MountPoint is a mount of a container. Driver and Name are only set for volumes.
+type MountPoint struct {
+	Type        string
+	Name        string
+	Driver      string
+	Destination string
+}
+
+// Volume is a volume known to the Docker daemon, with the labels it was created with.
+type Volume struct {
+	Name   string
+	Driver string
+	Labels map[string]string
+}
+
+// Client talks to the Docker daemon at Host.
+type Client struct {
+	Host   string
+	URL    string
+	Client *http.Client
+}
+
+// NewClient creates a Client for the Docker daemon at host, e.g. unix:///var/run/docker.sock or tcp://10.0.0.1:2375.
+func NewClient(host string) (Client, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return Client{}, errors.New("invalid docker host " + host + ": " + err.Error())
+	}
+
+	client := Client{Host: host, Client: &http.Client{Timeout: DefaultTimeout}}
+	switch {
+	case u.Scheme == "unix" && u.Path != "":
+		// The host name is ignored, every request is sent over the socket.
+		socket := u.Path
+		client.URL = "http://docker"
+		client.Client.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, network string, address string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socket)
+			}}
+	case u.Scheme == "tcp" && u.Host != "":
+		client.URL = "http://" + u.Host
+	default:
+		return Client{}, errors.New("unsupported docker host " + host + ", please use unix:// or tcp://")
+	}
+
+	return client, nil
+}
+
+// Containers returns the running containers.
+func (c Client) Containers() ([]Container, error) {
+	var containers []Container
+	err := c.get("/containers/json", &containers)
+	return containers, err
+}
+
+// ContainerMounts returns the mounts of a particular container, running or not.
+func (c Client) ContainerMounts(id string) ([]MountPoint, error) {
+	var container struct {
+		Mounts []MountPoint
+	}
+
+	err := c.get("/containers/"+url.PathEscape(id)+"/json", &container)
+	return container.Mounts, err
+}
+
+// ContainerPid returns the process ID of a particular container, failing if it is not running.
+func (c Client) ContainerPid(id string) (int, error) {
+	var container struct {
+		State struct {
+			Running bool
+			Pid     int
+		}
+	}
+
+	err := c.get("/containers/"+url.PathEscape(id)+"/json", &container)
+	if err != nil {
+		return 0, err
+	}
+
+	if !container.State.Running || container.State.Pid == 0 {
+		return 0, errors.New("container " + id + " is not running")
+	}
+
+	return container.State.Pid, nil
+}
+
+// Volumes returns the volumes of a particular volume driver, or every volume if driver is empty.
+func (c Client) Volumes(driver string) ([]Volume, error) {
+	path := "/volumes"
+	if driver != "" {
+		encoded, err := json.Marshal(map[string][]string{"driver": {driver}})
+		if err != nil {
+			return nil, err
+		}
+		path += "?filters=" + url.QueryEscape(string(encoded))
+	}
+
+	var volumes struct {
+		Volumes []Volume
+	}
+
+	err := c.get(path, &volumes)
+	return volumes.Volumes, err
+}
+
+// Volume returns a particular volume.
+func (c Client) Volume(name string) (Volume, error) {
+	var vol Volume
+	err := c.get("/volumes/"+url.PathEscape(name), &vol)
+	return vol, err
+}
+
+// Events streams the events matching filters, e.g. {"type": ["container"]}, as json until the stream is closed.
+func (c Client) Events(filters map[string][]string) (io.ReadCloser, error) {
+	path := "/events"
+	if len(filters) > 0 {
+		encoded, err := json.Marshal(filters)
+		if err != nil {
+			return nil, err
+		}
+		path += "?filters=" + url.QueryEscape(string(encoded))
+	}
+
+	// The stream stays open for as long as the daemon runs, so it must not time out.
+	streaming := *c.Client
+	streaming.Timeout = 0
+
+	response, err := streaming.Get(c.URL + path)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		defer response.Body.Close()
+		return nil, responseError("/events", response)
+	}
+
+	return response.Body, nil
+}
+
+// get decodes the json response to a GET request for path.
+func (c Client) get(path string, result interface{}) error {
+	response, err := c.Client.Get(c.URL + path)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return responseError(path, response)
+	}
+
+	return json.NewDecoder(response.Body).Decode(result)
+}
+
+// responseError describes a failed request, using the message returned by the Docker daemon if there is one.
+func responseError(path string, response *http.Response) error {
+	body, _ := ioutil.ReadAll(response.Body)
+
+	var failed struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &failed) == nil && failed.Message != "" {
+		return errors.New(path + " failed: " + failed.Message)
+	}
+
+	return errors.New(path + " failed: " + response.Status + " " + strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,215 @@
+package engine
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+)
+
+// newFakeEngine serves handler on a unix socket, as the Docker daemon does, returning the socket's docker host.
+func newFakeEngine(t *testing.T, handler http.Handler) (*httptest.Server, string) {
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-engine")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+
+	socket := path.Join(tempDir, "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewUnstartedServer(handler)
+	server.Listener = listener
+	server.Start()
+
+	return server, "unix://" + socket
+}
+
+func TestContainers(t *testing.T) {
+	t.Parallel()
+	server, host := newFakeEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/containers/json" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Write([]byte(`[{"Id": "abc", "Names": ["/db"], "State": "running",
+			"Mounts": [{"Type": "volume", "Name": "data", "Driver": "docker-volume-rdma", "Destination": "/var/lib/mysql"}]}]`))
+	}))
+	defer server.Close()
+	defer os.RemoveAll(path.Dir(host[len("unix://"):]))
+
+	client, err := NewClient(host)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	containers, err := client.Containers()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(containers) != 1 || containers[0].ID != "abc" || len(containers[0].Mounts) != 1 || containers[0].Mounts[0].Name != "data" {
+		t.Error("Expected the db container and its data volume, got ", containers)
+	}
+}
+
+func TestContainers_error(t *testing.T) {
+	t.Parallel()
+	server, host := newFakeEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"message": "daemon is shutting down"}`))
+	}))
+	defer server.Close()
+	defer os.RemoveAll(path.Dir(host[len("unix://"):]))
+
+	client, err := NewClient(host)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.Containers()
+	if err == nil || err.Error() != "/containers/json failed: daemon is shutting down" {
+		t.Error("Expected the daemon's error, got ", err)
+	}
+}
+
+func TestContainerPid(t *testing.T) {
+	t.Parallel()
+	server, host := newFakeEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/containers/abc/json":
+			w.Write([]byte(`{"Id": "abc", "State": {"Status": "running", "Running": true, "Pid": 4242}}`))
+		case "/containers/def/json":
+			w.Write([]byte(`{"Id": "def", "State": {"Status": "created", "Running": false, "Pid": 0}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "No such container"}`))
+		}
+	}))
+	defer server.Close()
+	defer os.RemoveAll(path.Dir(host[len("unix://"):]))
+
+	client, err := NewClient(host)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pid, err := client.ContainerPid("abc")
+	if err != nil || pid != 4242 {
+		t.Error("Expected the running container's pid, got ", pid, err)
+	}
+
+	if _, err = client.ContainerPid("def"); err == nil {
+		t.Error("A container that is not running has no pid")
+	}
+
+	if _, err = client.ContainerPid("missing"); err == nil {
+		t.Error("A container that does not exist has no pid")
+	}
+}
+
+func TestVolumes(t *testing.T) {
+	t.Parallel()
+	server, host := newFakeEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/volumes" && r.URL.Query().Get("filters") == `{"driver":["rdma"]}`:
+			w.Write([]byte(`{"Volumes": [{"Name": "data", "Driver": "rdma", "Labels": {"team": "ml"}}], "Warnings": null}`))
+		case r.URL.Path == "/volumes/data":
+			w.Write([]byte(`{"Name": "data", "Driver": "rdma", "Labels": {"team": "ml"}, "Scope": "local"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "get missing: no such volume"}`))
+		}
+	}))
+	defer server.Close()
+	defer os.RemoveAll(path.Dir(host[len("unix://"):]))
+
+	client, err := NewClient(host)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	volumes, err := client.Volumes("rdma")
+	if err != nil || len(volumes) != 1 || volumes[0].Name != "data" || volumes[0].Labels["team"] != "ml" {
+		t.Error("Expected the data volume and its labels, got ", volumes, err)
+	}
+
+	vol, err := client.Volume("data")
+	if err != nil || vol.Driver != "rdma" || vol.Labels["team"] != "ml" {
+		t.Error("Expected the data volume and its labels, got ", vol, err)
+	}
+
+	if _, err = client.Volume("missing"); err == nil {
+		t.Error("A volume that does not exist should fail")
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		host  string
+		url   string
+		valid bool
+	}{
+		{DefaultHost, "http://docker", true},
+		{"tcp://10.0.0.1:2375", "http://10.0.0.1:2375", true},
+		{"unix://", "", false},
+		{"npipe:////./pipe/docker_engine", "", false},
+		{"://", "", false},
+	}
+
+	for _, test := range tests {
+		client, err := NewClient(test.host)
+		if (err == nil) != test.valid || client.URL != test.url {
+			t.Error(test.host, ": expected ", test.url, ", got ", client.URL, err)
+		}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	t.Parallel()
+	server, host := newFakeEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/events":
+			w.Write([]byte(r.URL.Query().Get("filters")))
+		case "/containers/abc/json":
+			w.Write([]byte(`{"Id": "abc", "State": {"Running": false}, "Mounts": [{"Type": "volume", "Name": "data"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	defer os.RemoveAll(path.Dir(host[len("unix://"):]))
+
+	client, err := NewClient(host)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stream, err := client.Events(map[string][]string{"type": {"volume"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	filters, err := ioutil.ReadAll(stream)
+	if err != nil || string(filters) != `{"type":["volume"]}` {
+		t.Error("Expected the filters to be sent as json, got ", string(filters), err)
+	}
+
+	mounts, err := client.ContainerMounts("abc")
+	if err != nil || len(mounts) != 1 || mounts[0].Name != "data" {
+		t.Error("Expected the container's data volume, got ", mounts, err)
+	}
+
+	if _, err = client.ContainerMounts("missing"); err == nil {
+		t.Error("Inspecting a missing container should fail")
+	}
+}
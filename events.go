@@ -0,0 +1,82 @@
+package main
+
+import (
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/mellanox-senior-design/docker-volume-rdma/engine"
+	"github.com/mellanox-senior-design/docker-volume-rdma/events"
+	"github.com/mellanox-senior-design/docker-volume-rdma/reaper"
+	"github.com/mellanox-senior-design/docker-volume-rdma/throttle"
+)
+
+// dockerEvents are the events after which mount requests may be left behind, the start of containers whose I/O may
+// need to be limited, and the creation of volumes whose labels need to be recorded.
+var dockerEvents = map[string][]string{
+	"type":  {"container", "volume"},
+	"event": {"create", "start", "die", "destroy"},
+}
+
+// reconcileDelay gives Docker the chance to unmount a container's volumes itself, which it does just after reporting
+// that the container died, and is the time between the two checks that a request is stale.
+var reconcileDelay = 5 * time.Second
+
+// watchDockerEvents releases mount requests as soon as Docker reports that their container or volume has gone,
+// rather than waiting for the reaper's next sweeps, limits the I/O of containers as they start, and records the labels
+// of new volumes.
+func watchDockerEvents(containers engine.Client, stale reaper.Reaper, throttler throttle.Throttler) {
+	watcher := events.NewWatcher(containers, dockerEvents, func(event events.Event) {
+		handleDockerEvent(containers, stale, throttler, event)
+	})
+
+	go watcher.Watch(nil)
+}
+
+func handleDockerEvent(containers engine.Client, stale reaper.Reaper, throttler throttle.Throttler, event events.Event) {
+	var err error
+	switch {
+	case event.Type == "container" && event.Action == "start":
+		var mounts []engine.MountPoint
+		mounts, err = containers.ContainerMounts(event.Actor.ID)
+		if err == nil {
+			err = throttler.ApplyContainer(event.Actor.ID, stale.Track(event.Actor.ID, mounts))
+		}
+	case event.Type == "container" && event.Action == "die":
+		// The container is inspected now, as it may be destroyed before the delay is up. A container that was removed
+		// as soon as it stopped can no longer be inspected, so the volumes tracked when it started are used instead.
+		if mounts, inspectErr := containers.ContainerMounts(event.Actor.ID); inspectErr == nil {
+			stale.Track(event.Actor.ID, mounts)
+		}
+		reconcileLater(stale, stale.Tracked(event.Actor.ID))
+	case event.Type == "container" && event.Action == "destroy":
+		reconcileLater(stale, stale.Forget(event.Actor.ID))
+	case event.Type == "volume" && event.Action == "create" && stale.IsPlugin(event.Actor.Attributes["driver"]):
+		err = recordLabels(containers, stale.Driver.VolumeDatabase, event.Actor.ID)
+	case event.Type == "volume" && event.Action == "destroy" && stale.IsPlugin(event.Actor.Attributes["driver"]):
+		reconcileLater(stale, []string{event.Actor.ID})
+	}
+
+	if err != nil {
+		glog.Error("Unable to handle ", event.Type, " ", event.Action, " event for ", event.Actor.ID, ": ", err)
+	}
+}
+
+// reconcileLater reconciles volumes once reconcileDelay is up, and again after another reconcileDelay, as a request
+// is only released once it has been found stale twice.
+func reconcileLater(stale reaper.Reaper, volumes []string) {
+	for _, volumeName := range volumes {
+		volumeName := volumeName
+		time.AfterFunc(reconcileDelay, func() {
+			for check := 0; check < 2; check++ {
+				if check > 0 {
+					time.Sleep(reconcileDelay)
+				}
+
+				if _, err := stale.Reconcile(volumeName); err != nil {
+					glog.Error("Unable to reconcile ", volumeName, ": ", err)
+					return
+				}
+			}
+		})
+	}
+}
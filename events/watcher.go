@@ -0,0 +1,126 @@
+// Package events follows the event stream of the Docker daemon, reconnecting whenever the stream drops, so that
+// docker-volume-rdma can react as soon as containers and volumes go away.
+package events
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// Default delays between attempts to reconnect, doubling from DefaultMinBackoff up to DefaultMaxBackoff.
+const (
+	DefaultMinBackoff = time.Second
+	DefaultMaxBackoff = time.Minute
+)
+
+// Event is an event reported by the Docker daemon, e.g. a container that died.
+type Event struct {
+	Type   string
+	Action string
+	Actor  Actor
+	Time   int64 `json:"time"`
+}
+
+// Actor is the object an event happened to. ID is a container's ID or a volume's name.
+type Actor struct {
+	ID         string
+	Attributes map[string]string
+}
+
+// Source streams events matching filters, it is implemented by engine.Client.
+type Source interface {
+	Events(filters map[string][]string) (io.ReadCloser, error)
+}
+
+// Watcher passes every event matching Filters to Handle, in the order they are reported.
+type Watcher struct {
+	Source     Source
+	Filters    map[string][]string
+	Handle     func(Event)
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// NewWatcher creates a Watcher for the events of source matching filters, e.g. {"type": ["container"], "event":
+// ["die"]}.
+func NewWatcher(source Source, filters map[string][]string, handle func(Event)) Watcher {
+	return Watcher{
+		Source:     source,
+		Filters:    filters,
+		Handle:     handle,
+		MinBackoff: DefaultMinBackoff,
+		MaxBackoff: DefaultMaxBackoff}
+}
+
+// Watch follows the event stream until stop is closed, reconnecting with backoff whenever the stream cannot be opened
+// or drops. Events that happen while disconnected are missed.
+func (w Watcher) Watch(stop <-chan struct{}) {
+	backoff := w.MinBackoff
+	for {
+		received, err := w.follow(stop)
+
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		// A stream that delivered events was healthy, so reconnect quickly.
+		if received {
+			backoff = w.MinBackoff
+		}
+
+		glog.Warning("Docker event stream dropped, reconnecting in ", backoff, ": ", err)
+		select {
+		case <-stop:
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > w.MaxBackoff {
+			backoff = w.MaxBackoff
+		}
+	}
+}
+
+// follow handles the events of one connection to the stream until it drops or stop is closed, reporting whether any
+// events were received.
+func (w Watcher) follow(stop <-chan struct{}) (bool, error) {
+	stream, err := w.Source.Events(w.Filters)
+	if err != nil {
+		return false, err
+	}
+
+	// Closing the stream stops the decoder below.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stop:
+		case <-done:
+		}
+		stream.Close()
+	}()
+
+	glog.Info("Following the Docker event stream")
+
+	received := false
+	decoder := json.NewDecoder(stream)
+	for {
+		var event Event
+		err = decoder.Decode(&event)
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return received, err
+		}
+
+		received = true
+		w.Handle(event)
+	}
+}
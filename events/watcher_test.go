@@ -0,0 +1,156 @@
+package events
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/engine"
+)
+
+// fakeEngine serves /events on a unix socket. Each connection is answered by the next response, a status code with
+// events to stream, after which the connection is dropped. Once the responses run out the stream is held open.
+type fakeEngine struct {
+	responses []fakeResponse
+	filters   []string
+	lock      sync.Mutex
+}
+
+type fakeResponse struct {
+	status int
+	events string
+}
+
+func (f *fakeEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	f.filters = append(f.filters, r.URL.Query().Get("filters"))
+	if len(f.responses) == 0 {
+		f.lock.Unlock()
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+		return
+	}
+
+	response := f.responses[0]
+	f.responses = f.responses[1:]
+	f.lock.Unlock()
+
+	w.WriteHeader(response.status)
+	w.Write([]byte(response.events))
+}
+
+func newFakeEngine(t *testing.T, fake *fakeEngine) (engine.Client, func()) {
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-events")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+
+	listener, err := net.Listen("unix", path.Join(tempDir, "docker.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewUnstartedServer(fake)
+	server.Listener = listener
+	server.Start()
+
+	client, err := engine.NewClient("unix://" + path.Join(tempDir, "docker.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client, func() {
+		server.CloseClientConnections()
+		server.Close()
+		os.RemoveAll(tempDir)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+	fake := &fakeEngine{responses: []fakeResponse{
+		{http.StatusOK, `{"Type": "container", "Action": "die", "Actor": {"ID": "abc", "Attributes": {"exitCode": "137"}}, "time": 1}
+			{"Type": "volume", "Action": "destroy", "Actor": {"ID": "data", "Attributes": {"driver": "rdma"}}, "time": 2}`},
+		{http.StatusServiceUnavailable, `{"message": "daemon is restarting"}`},
+		{http.StatusOK, `{"Type": "container", "Action": "destroy", "Actor": {"ID": "abc"}, "time": 3}`},
+	}}
+	client, closeEngine := newFakeEngine(t, fake)
+	defer closeEngine()
+
+	handled := make(chan Event, 10)
+	watcher := NewWatcher(client, map[string][]string{"type": {"container", "volume"}}, func(event Event) {
+		handled <- event
+	})
+	watcher.MinBackoff = time.Millisecond
+	watcher.MaxBackoff = 10 * time.Millisecond
+
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		watcher.Watch(stop)
+		close(stopped)
+	}()
+
+	var events []Event
+	for len(events) < 3 {
+		select {
+		case event := <-handled:
+			events = append(events, event)
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected 3 events, got ", events)
+		}
+	}
+
+	if events[0].Action != "die" || events[0].Actor.Attributes["exitCode"] != "137" || events[1].Actor.ID != "data" || events[2].Time != 3 {
+		t.Error("The events were not handled in order, got ", events)
+	}
+
+	close(stop)
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("The watcher should stop while following the stream")
+	}
+
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+	if len(fake.filters) < 3 || fake.filters[0] != `{"type":["container","volume"]}` {
+		t.Error("Expected a connection per response with the filters, got ", fake.filters)
+	}
+}
+
+func TestWatch_stopWhileDisconnected(t *testing.T) {
+	t.Parallel()
+
+	// Nothing is listening on the socket.
+	client, err := engine.NewClient("unix:///docker-volume-rdma/does/not/exist.sock")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	watcher := NewWatcher(client, nil, func(event Event) {
+		t.Error("No events should be handled, got ", event)
+	})
+	watcher.MinBackoff = time.Hour
+
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		watcher.Watch(stop)
+		close(stopped)
+	}()
+
+	close(stop)
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("The watcher should stop while waiting to reconnect")
+	}
+}
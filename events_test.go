@@ -0,0 +1,99 @@
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/db"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"github.com/mellanox-senior-design/docker-volume-rdma/engine"
+	"github.com/mellanox-senior-design/docker-volume-rdma/events"
+	"github.com/mellanox-senior-design/docker-volume-rdma/reaper"
+	"github.com/mellanox-senior-design/docker-volume-rdma/throttle"
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
+)
+
+func TestHandleDockerEvent(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-events")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	container1 := strings.Repeat("1", 64)
+
+	// A Docker daemon with no running containers, that can still inspect container1.
+	listener, err := net.Listen("unix", path.Join(tempDir, "docker.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/containers/json":
+			w.Write([]byte(`[]`))
+		case "/containers/" + container1 + "/json":
+			w.Write([]byte(`{"Mounts": [{"Type": "volume", "Name": "first", "Driver": "rdma"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	containers, err := engine.NewClient("unix://" + path.Join(tempDir, "docker.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Docker mounts volumes with random IDs, not the IDs of the containers.
+	driver := drivers.NewRDMAVolumeDriver(drivers.NewOnDiskStorageController(path.Join(tempDir, "volumes")), db.NewInMemoryVolumeDatabase())
+	for _, name := range []string{"first", "second"} {
+		driver.Create(volume.Request{Name: name})
+	}
+	driver.Mount(volume.MountRequest{Name: "first", ID: strings.Repeat("a", 64)})
+	driver.Mount(volume.MountRequest{Name: "second", ID: strings.Repeat("b", 64)})
+
+	stale := reaper.NewReaper(driver, containers, "rdma")
+	throttler := throttle.NewThrottler(driver, containers, "rdma", path.Join(tempDir, "cgroup"))
+
+	defer func(delay time.Duration) { reconcileDelay = delay }(reconcileDelay)
+	reconcileDelay = 10 * time.Millisecond
+
+	released := func(volumeName string) bool {
+		for wait := 0; wait < 100; wait++ {
+			if mounts, _ := driver.VolumeDatabase.Mounts(volumeName); len(mounts) == 0 {
+				return true
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return false
+	}
+
+	// The container's volumes are found when it starts, as it can not be inspected once it is destroyed.
+	handleDockerEvent(containers, stale, throttler, events.Event{Type: "container", Action: "start", Actor: events.Actor{ID: container1}})
+	handleDockerEvent(containers, stale, throttler, events.Event{Type: "container", Action: "destroy", Actor: events.Actor{ID: container1}})
+	if !released("first") {
+		t.Error("The destroyed container's request should be released")
+	}
+
+	// Volumes of other plugins are ignored.
+	handleDockerEvent(containers, stale, throttler, events.Event{Type: "volume", Action: "destroy", Actor: events.Actor{ID: "second", Attributes: map[string]string{"driver": "local"}}})
+	time.Sleep(5 * reconcileDelay)
+	if mounts, _ := driver.VolumeDatabase.Mounts("second"); len(mounts) != 1 {
+		t.Error("A local volume's event should not release requests, got ", mounts)
+	}
+
+	handleDockerEvent(containers, stale, throttler, events.Event{Type: "volume", Action: "destroy", Actor: events.Actor{ID: "second", Attributes: map[string]string{"driver": "rdma"}}})
+	if !released("second") {
+		t.Error("The removed volume's requests should be released")
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/golang/glog"
+	"github.com/mellanox-senior-design/docker-volume-rdma/flexvolume"
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
+)
+
+// runFlexVolume answers a FlexVolume call from the kubelet, writing the result as json, and returns the exit code.
+func runFlexVolume(args []string) int {
+	result := callFlexVolume(args)
+
+	err := json.NewEncoder(os.Stdout).Encode(result)
+	if err != nil {
+		glog.Error(err)
+	}
+
+	glog.Flush()
+	if result.Status == flexvolume.StatusFailure {
+		return 1
+	}
+
+	return 0
+}
+
+// callFlexVolume mounts through the plugin daemon if -flexvolume-daemon is set, otherwise it uses the configured
+// database and storage controllers directly.
+func callFlexVolume(args []string) flexvolume.Result {
+	var volumes volume.Driver
+	if flexVolumeDaemon != "" {
+		volumes = flexvolume.NewClient(flexVolumeDaemon)
+	} else if len(args) > 0 && (args[0] == "mount" || args[0] == "unmount") {
+		driver, _, err := configure()
+		if err == nil {
+			err = driver.Connect()
+		}
+
+		if err != nil {
+			return flexvolume.Result{Status: flexvolume.StatusFailure, Message: fmt.Sprint("unable to configure the driver: ", err)}
+		}
+		defer driver.Disconnect()
+
+		volumes = *driver
+	}
+
+	return flexvolume.NewDriver(volumes, flexVolumeStatePath).Run(args)
+}
@@ -0,0 +1,96 @@
+package flexvolume
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
+)
+
+// DefaultTimeout bounds each request to the plugin daemon, mounts may need to create and format a volume.
+const DefaultTimeout = 5 * time.Minute
+
+// Client calls the Docker volume plugin protocol served by a running plugin daemon, so that volumes mounted in pods
+// share the daemon's mount requests and storage controllers.
+type Client struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewClient creates a Client for the plugin daemon at url, e.g. http://localhost:8080.
+func NewClient(url string) Client {
+	return Client{
+		URL:    strings.TrimSuffix(url, "/"),
+		Client: &http.Client{Timeout: DefaultTimeout}}
+}
+
+// call posts request to the endpoint, e.g. /VolumeDriver.Mount, and decodes the daemon's response. Errors are
+// returned in the response, as they are from a local driver.
+func (c Client) call(endpoint string, request interface{}) volume.Response {
+	var response volume.Response
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		response.Err = err.Error()
+		return response
+	}
+
+	httpResponse, err := c.Client.Post(c.URL+endpoint, "application/vnd.docker.plugins.v1.2+json", bytes.NewReader(body))
+	if err != nil {
+		response.Err = err.Error()
+		return response
+	}
+	defer httpResponse.Body.Close()
+
+	err = json.NewDecoder(httpResponse.Body).Decode(&response)
+	if err != nil {
+		response.Err = "unable to decode the response to " + endpoint + ": " + err.Error()
+	} else if response.Err == "" && httpResponse.StatusCode != http.StatusOK {
+		response.Err = endpoint + " failed: " + httpResponse.Status
+	}
+
+	return response
+}
+
+// Create a volume.
+func (c Client) Create(request volume.Request) volume.Response {
+	return c.call("/VolumeDriver.Create", request)
+}
+
+// List all volumes.
+func (c Client) List(request volume.Request) volume.Response {
+	return c.call("/VolumeDriver.List", request)
+}
+
+// Get info relating to a particular volume.
+func (c Client) Get(request volume.Request) volume.Response {
+	return c.call("/VolumeDriver.Get", request)
+}
+
+// Remove a particular volume.
+func (c Client) Remove(request volume.Request) volume.Response {
+	return c.call("/VolumeDriver.Remove", request)
+}
+
+// Path of a particular volume on the host.
+func (c Client) Path(request volume.Request) volume.Response {
+	return c.call("/VolumeDriver.Path", request)
+}
+
+// Mount a particular volume for the requester ID.
+func (c Client) Mount(request volume.MountRequest) volume.Response {
+	return c.call("/VolumeDriver.Mount", request)
+}
+
+// Unmount a particular volume for the requester ID.
+func (c Client) Unmount(request volume.UnmountRequest) volume.Response {
+	return c.call("/VolumeDriver.Unmount", request)
+}
+
+// Capabilities of the plugin daemon.
+func (c Client) Capabilities(request volume.Request) volume.Response {
+	return c.call("/VolumeDriver.Capabilities", request)
+}
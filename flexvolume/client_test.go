@@ -0,0 +1,52 @@
+package flexvolume
+
+import (
+	"net"
+	"os"
+	"testing"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
+)
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+	driver, volumes, _, tempDir := newTestDriver(t)
+	defer os.RemoveAll(tempDir)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	go volume.NewHandler(volumes).Serve(listener)
+
+	client := NewClient("http://" + listener.Addr().String() + "/")
+	if response := client.Create(volume.Request{Name: "remote"}); response.Err != "" {
+		t.Fatal(response.Err)
+	}
+
+	response := client.Mount(volume.MountRequest{Name: "remote", ID: "pod-3"})
+	if response.Err != "" || response.Mountpoint == "" {
+		t.Fatal("The daemon should mount the volume, got ", response)
+	}
+
+	mounts, err := volumes.VolumeDatabase.Mounts("remote")
+	if err != nil || mounts["pod-3"] != 1 {
+		t.Error("The daemon should record the pod's mount request, got ", mounts, err)
+	}
+
+	if response = client.Get(volume.Request{Name: "missing"}); response.Err == "" {
+		t.Error("The daemon's errors should be returned")
+	}
+
+	// The driver can be used through the daemon.
+	driver.Volumes = client
+	if result := driver.Run([]string{"unmount", "/never/mounted"}); result.Status != StatusSuccess {
+		t.Error(result.Message)
+	}
+
+	if response = NewClient("http://127.0.0.1:1").Capabilities(volume.Request{}); response.Err == "" {
+		t.Error("An unreachable daemon should return an error")
+	}
+}
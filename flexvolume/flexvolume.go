@@ -0,0 +1,268 @@
+// Package flexvolume implements the Kubernetes FlexVolume call-out protocol, mounting volumes managed by
+// docker-volume-rdma into pods. The kubelet runs the driver as
+//
+//	<driver> init
+//	<driver> mount <mount dir> <json options>
+//	<driver> unmount <mount dir>
+//
+// and reads a JSON Result from its output.
+package flexvolume
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
+)
+
+// Statuses reported to the kubelet.
+const (
+	StatusSuccess      = "Success"
+	StatusFailure      = "Failure"
+	StatusNotSupported = "Not supported"
+)
+
+// Options set by the kubelet in the json options of a mount call.
+const (
+	podUIDOption     = "kubernetes.io/pod.uid"
+	volumeNameOption = "kubernetes.io/pvOrVolumeName"
+	readWriteOption  = "kubernetes.io/readwrite"
+	fsTypeOption     = "kubernetes.io/fsType"
+	kubeletPrefix    = "kubernetes.io/"
+)
+
+// VolumeOption names the volume to mount in a pod's volume options, defaulting to the name of the volume in the pod
+// or of the persistent volume.
+const VolumeOption = "volumeName"
+
+// DefaultStatePath is where the volume mounted at each mount dir is remembered until it is unmounted.
+const DefaultStatePath = "/var/lib/docker-volume-rdma/flexvolume"
+
+// Result is written as json for the kubelet after every call.
+type Result struct {
+	Status       string        `json:"status"`
+	Message      string        `json:"message,omitempty"`
+	Capabilities *Capabilities `json:"capabilities,omitempty"`
+}
+
+// Capabilities are reported by init. Volumes are mounted on the node directly, so there is nothing to attach.
+type Capabilities struct {
+	Attach bool `json:"attach"`
+}
+
+// mountState records the volume and requester ID of a mount dir, as unmount is only told the mount dir.
+type mountState struct {
+	Volume   string
+	ID       string
+	ReadOnly bool
+}
+
+// Driver answers FlexVolume calls using a volume driver, either the plugin daemon or a local RDMAVolumeDriver.
+type Driver struct {
+	Volumes   volume.Driver
+	StatePath string
+	Runner    drivers.CommandRunner
+}
+
+// NewDriver creates a new Driver mounting volumes from volumes. The state path is optional.
+func NewDriver(volumes volume.Driver, statePath string) Driver {
+	if statePath == "" {
+		statePath = DefaultStatePath
+	}
+
+	return Driver{
+		Volumes:   volumes,
+		StatePath: statePath,
+		Runner:    drivers.ExecCommandRunner{}}
+}
+
+// Run answers the call in args, e.g. ["mount", "/var/lib/kubelet/pods/...", "{...}"].
+func (d Driver) Run(args []string) Result {
+	if len(args) == 0 {
+		return Result{Status: StatusFailure, Message: "no call was given, please call init, mount or unmount"}
+	}
+
+	var err error
+	switch {
+	case args[0] == "init" && len(args) == 1:
+		return Result{Status: StatusSuccess, Capabilities: &Capabilities{Attach: false}}
+	case args[0] == "mount" && len(args) == 3:
+		err = d.mount(args[1], args[2])
+	case args[0] == "unmount" && len(args) == 2:
+		err = d.unmount(args[1])
+	case args[0] == "init" || args[0] == "mount" || args[0] == "unmount":
+		err = errors.New("invalid arguments for " + args[0] + ": " + strings.Join(args[1:], " "))
+	default:
+		return Result{Status: StatusNotSupported, Message: args[0] + " is not supported"}
+	}
+
+	if err != nil {
+		glog.Error(err)
+		return Result{Status: StatusFailure, Message: err.Error()}
+	}
+
+	return Result{Status: StatusSuccess}
+}
+
+// mount the volume named in the json options at mountDir, creating the volume with the pod's options if it does not
+// exist. The pod's UID is the mount requester, so the volume stays mounted on the host while any pod uses it.
+func (d Driver) mount(mountDir string, jsonOptions string) error {
+	var options map[string]string
+	err := json.Unmarshal([]byte(jsonOptions), &options)
+	if err != nil {
+		return errors.New("unable to parse options: " + err.Error())
+	}
+
+	state := mountState{Volume: options[VolumeOption], ID: options[podUIDOption], ReadOnly: options[readWriteOption] == "ro"}
+	if state.Volume == "" {
+		state.Volume = options[volumeNameOption]
+	}
+
+	if state.Volume == "" || state.ID == "" {
+		return errors.New("a volume name and pod uid are required")
+	}
+
+	if d.isMounted(mountDir) {
+		return nil
+	}
+
+	if response := d.Volumes.Get(volume.Request{Name: state.Volume}); response.Err != "" {
+		glog.Info("Creating volume ", state.Volume, " for pod ", state.ID)
+		response = d.Volumes.Create(volume.Request{Name: state.Volume, Options: createOptions(options)})
+		if response.Err != "" {
+			return errors.New(response.Err)
+		}
+	}
+
+	response := d.Volumes.Mount(volume.MountRequest{Name: state.Volume, ID: state.ID})
+	if response.Err != "" {
+		return errors.New(response.Err)
+	}
+
+	err = d.writeState(mountDir, state)
+	if err == nil {
+		err = d.bindMount(response.Mountpoint, mountDir, state.ReadOnly)
+	}
+
+	// Release the mount request if the volume could not be mounted in the pod.
+	if err != nil {
+		if response := d.Volumes.Unmount(volume.UnmountRequest{Name: state.Volume, ID: state.ID}); response.Err != "" {
+			glog.Error(response.Err)
+		}
+		d.removeState(mountDir)
+		return err
+	}
+
+	return nil
+}
+
+// unmount the volume at mountDir, releasing the pod's mount request.
+func (d Driver) unmount(mountDir string) error {
+	state, err := d.readState(mountDir)
+	if os.IsNotExist(err) {
+		// Nothing was mounted here by this driver.
+		glog.Warning("No volume was mounted at ", mountDir)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if d.isMounted(mountDir) {
+		if _, err := d.Runner.Run("umount", mountDir); err != nil {
+			return err
+		}
+	}
+
+	response := d.Volumes.Unmount(volume.UnmountRequest{Name: state.Volume, ID: state.ID})
+	if response.Err != "" {
+		return errors.New(response.Err)
+	}
+
+	d.removeState(mountDir)
+	return nil
+}
+
+// createOptions returns the options a volume is created with, the pod's volume options without those the kubelet
+// adds, and the fsType as the fs option.
+func createOptions(options map[string]string) map[string]string {
+	created := map[string]string{}
+	for name, value := range options {
+		if !strings.HasPrefix(name, kubeletPrefix) && name != VolumeOption {
+			created[name] = value
+		}
+	}
+
+	if fsType := options[fsTypeOption]; fsType != "" && created["fs"] == "" {
+		created["fs"] = fsType
+	}
+
+	return created
+}
+
+// isMounted reports whether something is mounted at mountpoint.
+func (d Driver) isMounted(mountpoint string) bool {
+	_, err := d.Runner.Run("mountpoint", "-q", mountpoint)
+	return err == nil
+}
+
+// bindMount mounts source at target, creating target if needed.
+func (d Driver) bindMount(source string, target string, readOnly bool) error {
+	err := os.MkdirAll(target, 0750)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"--bind"}
+	if readOnly {
+		args = append(args, "-o", "ro")
+	}
+
+	_, err = d.Runner.Run("mount", append(args, source, target)...)
+	return err
+}
+
+// statePath returns the file the state of mountDir is kept in, named after the mount dir as the same pod volume
+// name may be used by many pods.
+func (d Driver) statePath(mountDir string) string {
+	name := strings.Replace(strings.Trim(path.Clean(mountDir), "/"), "/", "_", -1)
+	return path.Join(d.StatePath, name+".json")
+}
+
+func (d Driver) writeState(mountDir string, state mountState) error {
+	err := os.MkdirAll(d.StatePath, 0700)
+	if err != nil {
+		return err
+	}
+
+	contents, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(d.statePath(mountDir), contents, 0600)
+}
+
+func (d Driver) readState(mountDir string) (mountState, error) {
+	var state mountState
+
+	contents, err := ioutil.ReadFile(d.statePath(mountDir))
+	if err != nil {
+		return state, err
+	}
+
+	err = json.Unmarshal(contents, &state)
+	return state, err
+}
+
+func (d Driver) removeState(mountDir string) {
+	err := os.Remove(d.statePath(mountDir))
+	if err != nil && !os.IsNotExist(err) {
+		glog.Error(err)
+	}
+}
@@ -0,0 +1,182 @@
+package flexvolume
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/db"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+)
+
+// fakeMounts pretends to be the mount tools of a host.
+type fakeMounts struct {
+	mounted map[string]bool
+	fail    bool
+}
+
+func (f *fakeMounts) run(name string, args ...string) (string, error) {
+	last := args[len(args)-1]
+
+	switch name {
+	case "mountpoint":
+		if !f.mounted[last] {
+			return "", errors.New(last + " is not a mountpoint")
+		}
+	case "mount":
+		if f.fail {
+			return "", errors.New("mount failed")
+		}
+		f.mounted[last] = true
+	case "umount":
+		delete(f.mounted, last)
+	}
+
+	return "", nil
+}
+
+// newTestDriver creates a Driver mounting volumes from the on-disk storage controller and an in-memory database in a
+// new temporary directory.
+func newTestDriver(t *testing.T) (Driver, drivers.RDMAVolumeDriver, *fakeMounts, string) {
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-flexvolume")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+
+	volumes := drivers.NewRDMAVolumeDriver(drivers.NewOnDiskStorageController(path.Join(tempDir, "volumes")), db.NewInMemoryVolumeDatabase())
+	err = volumes.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &fakeMounts{mounted: map[string]bool{}}
+	driver := NewDriver(volumes, path.Join(tempDir, "state"))
+	driver.Runner = drivers.CommandRunnerFunc(fake.run)
+
+	return driver, volumes, fake, tempDir
+}
+
+func TestInit(t *testing.T) {
+	t.Parallel()
+	driver, _, _, tempDir := newTestDriver(t)
+	defer os.RemoveAll(tempDir)
+
+	result := driver.Run([]string{"init"})
+	if result.Status != StatusSuccess || result.Capabilities == nil || result.Capabilities.Attach {
+		t.Error("init should succeed without attach support, got ", result)
+	}
+}
+
+func TestRun_unsupported(t *testing.T) {
+	t.Parallel()
+	driver, _, _, tempDir := newTestDriver(t)
+	defer os.RemoveAll(tempDir)
+
+	var tests = []struct {
+		name   string
+		args   []string
+		status string
+	}{
+		{"no call", nil, StatusFailure},
+		{"attach", []string{"attach", "{}", "node1"}, StatusNotSupported},
+		{"getvolumename", []string{"getvolumename", "{}"}, StatusNotSupported},
+		{"mount without options", []string{"mount", "/mnt"}, StatusFailure},
+		{"unmount with options", []string{"unmount", "/mnt", "{}"}, StatusFailure},
+		{"invalid json", []string{"mount", "/mnt", "{"}, StatusFailure},
+		{"no pod uid", []string{"mount", "/mnt", `{"volumeName": "vol"}`}, StatusFailure},
+	}
+
+	for _, test := range tests {
+		if result := driver.Run(test.args); result.Status != test.status {
+			t.Error(test.name, ": expected ", test.status, ", got ", result)
+		}
+	}
+}
+
+func TestMountUnmount(t *testing.T) {
+	t.Parallel()
+	driver, volumes, fake, tempDir := newTestDriver(t)
+	defer os.RemoveAll(tempDir)
+
+	mountDir := path.Join(tempDir, "pods", "pod-1", "volumes", "mellanox~rdma", "data")
+	options := `{"volumeName": "shared", "kubernetes.io/pod.uid": "pod-1", "kubernetes.io/pvOrVolumeName": "data",
+		"kubernetes.io/readwrite": "ro", "kubernetes.io/fsType": "xfs"}`
+
+	result := driver.Run([]string{"mount", mountDir, options})
+	if result.Status != StatusSuccess {
+		t.Fatal(result.Message)
+	}
+
+	if !fake.mounted[mountDir] {
+		t.Error("The volume should be bind mounted at ", mountDir)
+	}
+
+	// The volume is created with the pod's options, without those added by the kubelet.
+	created, err := volumes.VolumeDatabase.Options("shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if created["fs"] != "xfs" || created[podUIDOption] != "" {
+		t.Error("The volume should be created with fs=xfs and no kubelet options, got ", created)
+	}
+
+	mounts, err := volumes.VolumeDatabase.Mounts("shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(mounts) != 1 || mounts["pod-1"] != 1 {
+		t.Error("The pod UID should be the volume's mount requester, got ", mounts)
+	}
+
+	// Mounting again is a NOOP.
+	if result = driver.Run([]string{"mount", mountDir, options}); result.Status != StatusSuccess {
+		t.Fatal(result.Message)
+	}
+
+	result = driver.Run([]string{"unmount", mountDir})
+	if result.Status != StatusSuccess {
+		t.Fatal(result.Message)
+	}
+
+	if fake.mounted[mountDir] {
+		t.Error("The volume should no longer be mounted at ", mountDir)
+	}
+
+	mounts, err = volumes.VolumeDatabase.Mounts("shared")
+	if err != nil || len(mounts) != 0 {
+		t.Error("The pod's mount request should be released, got ", mounts, err)
+	}
+
+	// Unmounting again is a NOOP.
+	if result = driver.Run([]string{"unmount", mountDir}); result.Status != StatusSuccess {
+		t.Error("Unmounting an unmounted dir should succeed, got ", result)
+	}
+}
+
+func TestMount_failed(t *testing.T) {
+	t.Parallel()
+	driver, volumes, fake, tempDir := newTestDriver(t)
+	defer os.RemoveAll(tempDir)
+
+	fake.fail = true
+	mountDir := path.Join(tempDir, "pods", "pod-2", "volumes", "mellanox~rdma", "scratch")
+	result := driver.Run([]string{"mount", mountDir, `{"kubernetes.io/pod.uid": "pod-2", "kubernetes.io/pvOrVolumeName": "scratch"}`})
+	if result.Status != StatusFailure || !strings.Contains(result.Message, "mount failed") {
+		t.Fatal("The mount should fail, got ", result)
+	}
+
+	// The volume is named after the pod's volume when volumeName is not set.
+	mounts, err := volumes.VolumeDatabase.Mounts("scratch")
+	if err != nil || len(mounts) != 0 {
+		t.Error("The failed mount's request should be released, got ", mounts, err)
+	}
+
+	if _, err := os.Stat(driver.statePath(mountDir)); !os.IsNotExist(err) {
+		t.Error("The failed mount's state should be removed, got ", err)
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/flexvolume"
+)
+
+func TestCallFlexVolume(t *testing.T) {
+	result := callFlexVolume([]string{"init"})
+	if result.Status != flexvolume.StatusSuccess {
+		t.Error("init should not need the driver to be configured, got ", result)
+	}
+
+	flexVolumeDaemon = "http://127.0.0.1:1"
+	defer func() { flexVolumeDaemon = "" }()
+
+	result = callFlexVolume([]string{"mount", "/never/mounted", `{"kubernetes.io/pod.uid": "pod-1", "volumeName": "vol"}`})
+	if result.Status != flexvolume.StatusFailure {
+		t.Error("Mounting through an unreachable daemon should fail, got ", result)
+	}
+}
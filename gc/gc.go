@@ -0,0 +1,206 @@
+// Package gc finds storage that no volume in the volume database owns, left behind by a crash or by volumes removed
+// from the database by hand, and quarantines or deletes it to reclaim the space.
+package gc
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+)
+
+// DefaultGrace is how long storage is left alone after it last changed, unless a collection sets its own grace period.
+// Volumes are briefly orphaned while they are being created, migrated or snapshotted for a backup.
+const DefaultGrace = 24 * time.Hour
+
+// The actions taken on an orphan.
+const (
+	Quarantined = "quarantined"
+	Deleted     = "deleted"
+	Kept        = "kept"
+)
+
+// Options of a collection.
+type Options struct {
+	// Grace is how long an orphan must have gone unchanged before it is collected.
+	Grace time.Duration
+
+	// Delete deletes orphans rather than quarantining them.
+	Delete bool
+
+	// DryRun only reports what would be collected.
+	DryRun bool
+}
+
+// Orphan is storage held for a volume that the volume database does not know about, or knows to be stored on another
+// backend.
+type Orphan struct {
+	Backend  string `json:",omitempty"`
+	Volume   string
+	Bytes    int64
+	Modified time.Time
+
+	// Action is what was done with the orphan, or would be done by a dry run, and Reason says why it was kept.
+	Action string
+	Reason string `json:",omitempty"`
+}
+
+// Report is the outcome of a collection. Reclaimed counts the bytes of the orphans that were deleted, and Quarantined
+// those that were set aside.
+type Report struct {
+	Orphans     []Orphan
+	Reclaimed   int64
+	Quarantined int64
+	DryRun      bool `json:",omitempty"`
+
+	// Unlisted names the backends whose storage controllers can not list their volumes, so were not collected.
+	Unlisted []string `json:",omitempty"`
+}
+
+// Collect quarantines, or deletes, the storage of driver's backends that no volume owns, once it has gone unchanged for
+// the grace period. Storage named after a volume the database knows is never collected, even on another backend than
+// the volume's, as the name may be the volume's own storage seen through a shared folder, and neither is storage named
+// after a volume with mount requests.
+func Collect(driver drivers.RDMAVolumeDriver, options Options, now time.Time) (Report, error) {
+	if options.Grace < 0 {
+		return Report{}, errors.New("the grace period can not be negative")
+	}
+
+	report := Report{Orphans: []Orphan{}, DryRun: options.DryRun}
+	backends := backendsOf(driver)
+
+	// Storage is listed before the database, as volumes are recorded in the database before their storage is created
+	// and forgotten after it is deleted, so that a volume being created is not mistaken for an orphan.
+	stored := map[string][]drivers.StoredVolume{}
+	for _, backend := range sortedNames(backends) {
+		lister, ok := backends[backend].(drivers.StorageLister)
+		if !ok {
+			report.Unlisted = append(report.Unlisted, backend)
+			continue
+		}
+
+		volumes, err := lister.List()
+		if err != nil {
+			return Report{}, errors.New(describe(backend) + ": " + err.Error())
+		}
+		stored[backend] = volumes
+	}
+
+	known, err := knownVolumes(driver)
+	if err != nil {
+		return Report{}, err
+	}
+
+	for _, backend := range sortedNames(backends) {
+		for _, vol := range stored[backend] {
+			if known[vol.Name] {
+				continue
+			}
+
+			if mounts, err := driver.VolumeDatabase.Mounts(vol.Name); err == nil && len(mounts) > 0 {
+				glog.Warning("Not collecting the storage of ", vol.Name, " on ", describe(backend), ", it has mount requests")
+				continue
+			}
+
+			orphan := Orphan{Backend: backend, Volume: vol.Name, Bytes: vol.Bytes, Modified: vol.Modified}
+			collect(backends[backend], &orphan, options, now)
+			switch orphan.Action {
+			case Deleted:
+				report.Reclaimed += orphan.Bytes
+			case Quarantined:
+				report.Quarantined += orphan.Bytes
+			}
+
+			report.Orphans = append(report.Orphans, orphan)
+		}
+	}
+
+	return report, nil
+}
+
+// collect quarantines or deletes an orphan, recording what was done.
+func collect(storageController drivers.StorageController, orphan *Orphan, options Options, now time.Time) {
+	orphan.Action = Kept
+	if now.Sub(orphan.Modified) < options.Grace {
+		orphan.Reason = "changed within the grace period"
+		return
+	}
+
+	quarantiner, canQuarantine := storageController.(drivers.StorageQuarantiner)
+	if !options.Delete && !canQuarantine {
+		orphan.Reason = "the storage controller can not quarantine volumes, they can only be deleted"
+		return
+	}
+
+	action := Deleted
+	if !options.Delete {
+		action = Quarantined
+	}
+
+	if options.DryRun {
+		glog.Info("Dry run: the storage of ", orphan.Volume, " on ", describe(orphan.Backend), " is orphaned and would be ", action)
+		orphan.Action = action
+		return
+	}
+
+	var err error
+	if options.Delete {
+		err = storageController.Delete(orphan.Volume)
+	} else {
+		err = quarantiner.Quarantine(orphan.Volume)
+	}
+
+	if err != nil {
+		glog.Error("Unable to collect the storage of ", orphan.Volume, " on ", describe(orphan.Backend), ": ", err)
+		orphan.Reason = err.Error()
+		return
+	}
+
+	glog.Warning("The storage of ", orphan.Volume, " on ", describe(orphan.Backend), " was orphaned and has been ", action, ", ", orphan.Bytes, " bytes")
+	orphan.Action = action
+}
+
+// backendsOf returns the storage controllers of driver by backend name. A driver without backends has one, named "".
+func backendsOf(driver drivers.RDMAVolumeDriver) map[string]drivers.StorageController {
+	if len(driver.Backends) == 0 {
+		return map[string]drivers.StorageController{"": driver.StorageController}
+	}
+
+	return driver.Backends
+}
+
+// knownVolumes returns the names of the volumes in the database.
+func knownVolumes(driver drivers.RDMAVolumeDriver) (map[string]bool, error) {
+	volumes, err := driver.VolumeDatabase.List()
+	if err != nil {
+		return nil, err
+	}
+
+	known := map[string]bool{}
+	for _, vol := range volumes {
+		known[vol.Name] = true
+	}
+
+	return known, nil
+}
+
+// describe names a backend for messages.
+func describe(backend string) string {
+	if backend == "" {
+		return "the storage controller"
+	}
+
+	return "backend " + backend
+}
+
+func sortedNames(backends map[string]drivers.StorageController) []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
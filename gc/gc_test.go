@@ -0,0 +1,210 @@
+package gc
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/db"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
+)
+
+// newTestDriver creates a driver with the on-disk backends fast and bulk, and a gluster backend that can not be listed.
+// Each on-disk backend holds a volume the database knows about and an orphan of 100 bytes. The volume moved was
+// migrated from bulk to fast, leaving its old storage behind, which is kept as its name is known.
+func newTestDriver(t *testing.T) (drivers.RDMAVolumeDriver, string) {
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-gc")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+
+	backends := map[string]drivers.StorageController{
+		"fast":    drivers.NewOnDiskStorageController(path.Join(tempDir, "fast")),
+		"bulk":    drivers.NewOnDiskStorageController(path.Join(tempDir, "bulk")),
+		"gluster": drivers.NewGlusterStorageController(),
+	}
+
+	driver, err := drivers.NewMultiBackendRDMAVolumeDriver(backends, "bulk", db.NewInMemoryVolumeDatabase())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, vol := range []struct {
+		name    string
+		backend string
+	}{
+		{"data", "bulk"},
+		{"moved", "fast"},
+	} {
+		if response := driver.Create(volume.Request{Name: vol.name, Options: map[string]string{drivers.BackendOption: vol.backend}}); response.Err != "" {
+			t.Fatal(response.Err)
+		}
+		driver.Mount(volume.MountRequest{Name: vol.name, ID: "c1"})
+		driver.Unmount(volume.UnmountRequest{Name: vol.name, ID: "c1"})
+	}
+
+	for _, orphan := range []struct {
+		backend string
+		folder  string
+	}{
+		{"bulk", "crashed.unmounted"},
+		{"bulk", "moved.unmounted"},
+		{"fast", "forgotten"},
+	} {
+		folder := path.Join(tempDir, orphan.backend, orphan.folder)
+		os.MkdirAll(folder, 0755)
+		if err = ioutil.WriteFile(path.Join(folder, "data"), make([]byte, 100), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return driver, tempDir
+}
+
+func TestCollect(t *testing.T) {
+	t.Parallel()
+	driver, tempDir := newTestDriver(t)
+	defer os.RemoveAll(tempDir)
+
+	// Nothing is collected within the grace period.
+	report, err := Collect(driver, Options{Grace: time.Hour}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Orphans) != 2 || report.Reclaimed != 0 || report.Quarantined != 0 || len(report.Unlisted) != 1 || report.Unlisted[0] != "gluster" {
+		t.Fatal("Expected two orphans to be kept and gluster to be unlisted, got ", report)
+	}
+
+	expected := []Orphan{
+		{Backend: "bulk", Volume: "crashed", Bytes: 100, Action: Kept},
+		{Backend: "fast", Volume: "forgotten", Bytes: 100, Action: Kept},
+	}
+	for i := range expected {
+		orphan := report.Orphans[i]
+		if orphan.Backend != expected[i].Backend || orphan.Volume != expected[i].Volume || orphan.Bytes != expected[i].Bytes || orphan.Action != expected[i].Action || orphan.Reason == "" {
+			t.Error("Expected ", expected[i], ", got ", orphan)
+		}
+	}
+
+	// Once the grace period has passed, orphans are quarantined by default.
+	report, err = Collect(driver, Options{Grace: time.Hour}, time.Now().Add(2*time.Hour))
+	if err != nil || report.Quarantined != 200 || report.Reclaimed != 0 {
+		t.Fatal("Expected every orphan to be quarantined, got ", report, err)
+	}
+
+	if _, err = os.Stat(path.Join(tempDir, "bulk", ".quarantine")); err != nil {
+		t.Error("Expected the orphans to be kept in quarantine, got ", err)
+	}
+
+	// The volumes the database knows about are untouched.
+	for _, volumeName := range []string{"data", "moved"} {
+		if response := driver.Mount(volume.MountRequest{Name: volumeName, ID: "c2"}); response.Err != "" {
+			t.Error(response.Err)
+		}
+	}
+
+	for _, folder := range []string{"fast/moved", "bulk/moved.unmounted"} {
+		if _, err = os.Stat(path.Join(tempDir, folder)); err != nil {
+			t.Error("The storage named after a known volume should be kept, got ", err)
+		}
+	}
+
+	report, err = Collect(driver, Options{Grace: time.Hour}, time.Now().Add(2*time.Hour))
+	if err != nil || len(report.Orphans) != 0 {
+		t.Error("Quarantined orphans should not be found again, got ", report, err)
+	}
+}
+
+func TestCollect_delete(t *testing.T) {
+	t.Parallel()
+	driver, tempDir := newTestDriver(t)
+	defer os.RemoveAll(tempDir)
+
+	later := time.Now().Add(DefaultGrace + time.Hour)
+	report, err := Collect(driver, Options{Grace: DefaultGrace, Delete: true, DryRun: true}, later)
+	if err != nil || !report.DryRun || report.Reclaimed != 200 || report.Orphans[0].Action != Deleted {
+		t.Fatal("Expected a dry run to report 200 bytes that would be reclaimed, got ", report, err)
+	}
+
+	if _, err = os.Stat(path.Join(tempDir, "bulk", "crashed.unmounted")); err != nil {
+		t.Fatal("A dry run should not delete anything, got ", err)
+	}
+
+	report, err = Collect(driver, Options{Grace: DefaultGrace, Delete: true}, later)
+	if err != nil || report.Reclaimed != 200 {
+		t.Fatal("Expected 200 bytes to be reclaimed, got ", report, err)
+	}
+
+	for _, folder := range []string{"bulk/crashed.unmounted", "fast/forgotten"} {
+		if _, err = os.Stat(path.Join(tempDir, folder)); !os.IsNotExist(err) {
+			t.Error("Expected ", folder, " to be deleted, got ", err)
+		}
+	}
+
+	if _, err = Collect(driver, Options{Grace: -time.Hour}, later); err == nil {
+		t.Error("A negative grace period should fail")
+	}
+}
+
+func TestCollect_singleBackend(t *testing.T) {
+	t.Parallel()
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-gc")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	driver := drivers.NewRDMAVolumeDriver(drivers.NewOnDiskStorageController(tempDir), db.NewInMemoryVolumeDatabase())
+	driver.Create(volume.Request{Name: "data"})
+	driver.Mount(volume.MountRequest{Name: "data", ID: "c1"})
+	os.MkdirAll(path.Join(tempDir, "orphan"), 0755)
+
+	report, err := Collect(driver, Options{Delete: true}, time.Now())
+	if err != nil || len(report.Orphans) != 1 || report.Orphans[0].Volume != "orphan" || report.Orphans[0].Backend != "" || report.Orphans[0].Action != Deleted {
+		t.Error("Expected only the orphan to be deleted, got ", report, err)
+	}
+
+	driver = drivers.NewRDMAVolumeDriver(drivers.NewGlusterStorageController(), db.NewInMemoryVolumeDatabase())
+	if report, err = Collect(driver, Options{}, time.Now()); err != nil || len(report.Unlisted) != 1 {
+		t.Error("Expected the storage controller to be reported as unlisted, got ", report, err)
+	}
+}
+
+func TestCollect_sharedFolder(t *testing.T) {
+	t.Parallel()
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-gc")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Both backends list the volumes of the other.
+	backends := map[string]drivers.StorageController{
+		"a": drivers.NewOnDiskStorageController(tempDir),
+		"b": drivers.NewOnDiskStorageController(tempDir),
+	}
+
+	driver, err := drivers.NewMultiBackendRDMAVolumeDriver(backends, "a", db.NewInMemoryVolumeDatabase())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	driver.Create(volume.Request{Name: "live"})
+	response := driver.Mount(volume.MountRequest{Name: "live", ID: "c1"})
+	if response.Err != "" {
+		t.Fatal(response.Err)
+	}
+
+	report, err := Collect(driver, Options{Delete: true}, time.Now().Add(2*DefaultGrace))
+	if err != nil || len(report.Orphans) != 0 {
+		t.Error("Expected the mounted volume not to be collected from either backend, got ", report, err)
+	}
+
+	if _, err = os.Stat(response.Mountpoint); err != nil {
+		t.Error("The mounted volume should be kept, got ", err)
+	}
+}
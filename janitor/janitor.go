@@ -0,0 +1,119 @@
+// Package janitor removes volumes that were created with a ttl or expire-after-unmount option once they have expired,
+// so that throwaway volumes, such as those of CI jobs, do not pile up.
+package janitor
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
+)
+
+// Expiration is a volume that had expired when it was swept.
+type Expiration struct {
+	Volume    string
+	ExpiredAt time.Time
+
+	// Removed is false for volumes that were only reported, by a dry run.
+	Removed bool
+}
+
+// Janitor removes the expired volumes of a driver, through the driver so that the removal is audited and notified like
+// any other. Volumes that are still mounted are left until a later sweep finds them unmounted. With DryRun set, expired
+// volumes are only logged.
+type Janitor struct {
+	Driver drivers.RDMAVolumeDriver
+	DryRun bool
+
+	// lock stops sweeps overlapping, it is shared by every copy of the Janitor.
+	lock *sync.Mutex
+}
+
+// NewJanitor creates a Janitor for the volumes of driver.
+func NewJanitor(driver drivers.RDMAVolumeDriver, dryRun bool) Janitor {
+	return Janitor{Driver: driver, DryRun: dryRun, lock: &sync.Mutex{}}
+}
+
+// Run sweeps every interval until stop is closed.
+func (j Janitor) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			if _, err := j.Sweep(now); err != nil {
+				glog.Error("Unable to remove expired volumes: ", err)
+			}
+		}
+	}
+}
+
+// Sweep removes the volumes that had expired by now and are not mounted, returning them. A volume that can not be
+// removed is logged and left for the next sweep, and the last such error is returned once every volume was tried.
+func (j Janitor) Sweep(now time.Time) ([]Expiration, error) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	volumes, err := j.Driver.VolumeDatabase.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var expired []Expiration
+	var lastErr error
+	for _, vol := range volumes {
+		options, err := j.Driver.VolumeDatabase.Options(vol.Name)
+		if err != nil {
+			glog.Error("Unable to read the options of ", vol.Name, ": ", err)
+			lastErr = err
+			continue
+		}
+
+		expiry, expires, err := drivers.Expiry(options)
+		if err != nil {
+			glog.Error("Unable to tell when ", vol.Name, " expires: ", err)
+			lastErr = err
+			continue
+		}
+
+		if !expires || now.Before(expiry) {
+			continue
+		}
+
+		// Mounts are checked last, right before the removal, to leave as little time as possible for a new one.
+		mounts, err := j.Driver.VolumeDatabase.Mounts(vol.Name)
+		if err != nil {
+			glog.Error("Unable to list the mounts of ", vol.Name, ": ", err)
+			lastErr = err
+			continue
+		}
+
+		if len(mounts) > 0 {
+			glog.Info("Volume ", vol.Name, " expired at ", expiry, " but is still mounted, removing it once it is unmounted.")
+			continue
+		}
+
+		if j.DryRun {
+			glog.Info("Dry run: volume ", vol.Name, " expired at ", expiry, " and would be removed.")
+			expired = append(expired, Expiration{Volume: vol.Name, ExpiredAt: expiry})
+			continue
+		}
+
+		response := j.Driver.Remove(volume.Request{Name: vol.Name})
+		if response.Err != "" {
+			lastErr = errors.New("unable to remove expired volume " + vol.Name + ": " + response.Err)
+			continue
+		}
+
+		glog.Warning("Removed volume ", vol.Name, " as it expired at ", expiry)
+		expired = append(expired, Expiration{Volume: vol.Name, ExpiredAt: expiry, Removed: true})
+	}
+
+	return expired, lastErr
+}
@@ -0,0 +1,202 @@
+package janitor
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/db"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
+)
+
+func newTestJanitor(t *testing.T, dryRun bool) (Janitor, string) {
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-janitor")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+
+	driver := drivers.NewRDMAVolumeDriver(drivers.NewOnDiskStorageController(path.Join(tempDir, "volumes")), db.NewInMemoryVolumeDatabase())
+	err = driver.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	volumes := []struct {
+		name    string
+		options map[string]string
+	}{
+		{"ci-build", map[string]string{drivers.TTLOption: "1h"}},
+		{"ci-mounted", map[string]string{drivers.TTLOption: "1h"}},
+		{"ci-cache", map[string]string{drivers.ExpireAfterUnmountOption: "24h"}},
+		{"data", nil},
+	}
+
+	for _, vol := range volumes {
+		if response := driver.Create(volume.Request{Name: vol.name, Options: vol.options}); response.Err != "" {
+			t.Fatal(response.Err)
+		}
+	}
+
+	if response := driver.Mount(volume.MountRequest{Name: "ci-mounted", ID: "job1"}); response.Err != "" {
+		t.Fatal(response.Err)
+	}
+
+	return NewJanitor(driver, dryRun), tempDir
+}
+
+// names returns the names of the volumes a janitor's driver knows about.
+func names(t *testing.T, janitor Janitor) map[string]bool {
+	volumes, err := janitor.Driver.VolumeDatabase.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := map[string]bool{}
+	for _, vol := range volumes {
+		names[vol.Name] = true
+	}
+
+	return names
+}
+
+func TestSweep(t *testing.T) {
+	t.Parallel()
+	janitor, tempDir := newTestJanitor(t, false)
+	defer os.RemoveAll(tempDir)
+
+	now := time.Now()
+	expired, err := janitor.Sweep(now)
+	if err != nil || len(expired) != 0 {
+		t.Fatal("Nothing should have expired yet, got ", expired, err)
+	}
+
+	// Once the ttl has passed, only the unmounted volume is removed.
+	expired, err = janitor.Sweep(now.Add(2 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(expired) != 1 || expired[0].Volume != "ci-build" || !expired[0].Removed {
+		t.Fatal("Expected ci-build to be removed, got ", expired)
+	}
+
+	remaining := names(t, janitor)
+	if remaining["ci-build"] || !remaining["ci-mounted"] || !remaining["ci-cache"] || !remaining["data"] {
+		t.Fatal("Unexpected volumes left: ", remaining)
+	}
+
+	if _, err = os.Stat(path.Join(tempDir, "volumes", "ci-build")); !os.IsNotExist(err) {
+		t.Error("The storage of the expired volume should be deleted, got ", err)
+	}
+
+	// The mounted volume is removed once it has been unmounted.
+	if response := janitor.Driver.Unmount(volume.UnmountRequest{Name: "ci-mounted", ID: "job1"}); response.Err != "" {
+		t.Fatal(response.Err)
+	}
+
+	expired, err = janitor.Sweep(now.Add(25 * time.Hour))
+	if err != nil || len(expired) != 2 {
+		t.Fatal("Expected ci-mounted and ci-cache to be removed, got ", expired, err)
+	}
+
+	if remaining = names(t, janitor); len(remaining) != 1 || !remaining["data"] {
+		t.Error("Volumes without an expiry should be kept, got ", remaining)
+	}
+}
+
+func TestSweep_dryRun(t *testing.T) {
+	t.Parallel()
+	janitor, tempDir := newTestJanitor(t, true)
+	defer os.RemoveAll(tempDir)
+
+	expired, err := janitor.Sweep(time.Now().Add(48 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(expired) != 2 || expired[0].Removed || expired[1].Removed {
+		t.Fatal("Expected the two unmounted volumes to be reported, got ", expired)
+	}
+
+	if remaining := names(t, janitor); len(remaining) != 4 {
+		t.Error("A dry run should not remove any volumes, got ", remaining)
+	}
+}
+
+func TestSweep_unmountRestartsExpiry(t *testing.T) {
+	t.Parallel()
+	janitor, tempDir := newTestJanitor(t, false)
+	defer os.RemoveAll(tempDir)
+
+	// A volume that is used again is kept for expire-after-unmount after its last unmount, not after it was created.
+	for _, id := range []string{"job1", "job2"} {
+		janitor.Driver.Mount(volume.MountRequest{Name: "ci-cache", ID: id})
+	}
+	janitor.Driver.Release("ci-cache", "job1")
+
+	expired, err := janitor.Sweep(time.Now().Add(25 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expiration := range expired {
+		if expiration.Volume == "ci-cache" {
+			t.Fatal("A mounted volume should not be removed, got ", expired)
+		}
+	}
+
+	janitor.Driver.Unmount(volume.UnmountRequest{Name: "ci-cache", ID: "job2"})
+	if expired, err = janitor.Sweep(time.Now().Add(23 * time.Hour)); err != nil || len(expired) != 0 {
+		t.Error("The volume should not expire until a day after its last unmount, got ", expired, err)
+	}
+
+	if expired, err = janitor.Sweep(time.Now().Add(25 * time.Hour)); err != nil || len(expired) != 1 || expired[0].Volume != "ci-cache" {
+		t.Error("Expected ci-cache to expire a day after its last unmount, got ", expired, err)
+	}
+}
+
+// lateMountDatabase mounts volume right after the first check of its mounts, as if a container had started then.
+type lateMountDatabase struct {
+	db.VolumeDatabase
+	volume  string
+	mounted *bool
+}
+
+func (l lateMountDatabase) Mounts(volumeName string) (map[string]int, error) {
+	mounts, err := l.VolumeDatabase.Mounts(volumeName)
+	if err == nil && volumeName == l.volume && !*l.mounted {
+		*l.mounted = true
+		err = l.VolumeDatabase.Mount(volumeName, "late", "")
+	}
+
+	return mounts, err
+}
+
+func TestSweep_mountedBeforeRemove(t *testing.T) {
+	t.Parallel()
+	janitor, tempDir := newTestJanitor(t, false)
+	defer os.RemoveAll(tempDir)
+
+	// The folder of an on disk volume is created when it is first mounted.
+	janitor.Driver.Mount(volume.MountRequest{Name: "ci-build", ID: "job1"})
+	janitor.Driver.Unmount(volume.UnmountRequest{Name: "ci-build", ID: "job1"})
+
+	mounted := false
+	janitor.Driver.VolumeDatabase = lateMountDatabase{janitor.Driver.VolumeDatabase, "ci-build", &mounted}
+
+	expired, err := janitor.Sweep(time.Now().Add(2 * time.Hour))
+	if err == nil || len(expired) != 0 {
+		t.Fatal("A volume mounted after the janitor checked it should not be removed, got ", expired, err)
+	}
+
+	if remaining := names(t, janitor); !remaining["ci-build"] {
+		t.Error("The mounted volume should be kept, got ", remaining)
+	}
+
+	if _, err = os.Stat(path.Join(tempDir, "volumes", "ci-build.unmounted")); err != nil {
+		t.Error("The storage of the mounted volume should be kept, got ", err)
+	}
+}
@@ -0,0 +1,190 @@
+// Package keys supplies the keys that encrypted volumes are opened with, keeping them out of the volume database.
+package keys
+
+import (
+	"crypto/rand"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// KeySize is the number of random bytes in a new key.
+const KeySize = 64
+
+// KeyProvider creates, looks up and destroys the keys of encrypted volumes by ID.
+type KeyProvider interface {
+	// CreateKey creates a new random key called keyID, failing if one already exists.
+	CreateKey(keyID string) ([]byte, error)
+
+	// GetKey returns the key called keyID.
+	GetKey(keyID string) ([]byte, error)
+
+	// DeleteKey destroys the key called keyID, so that whatever was encrypted with it can never be decrypted again.
+	// Deleting a key that does not exist succeeds.
+	DeleteKey(keyID string) error
+
+	// Rotate replaces the key called keyID with a new random key, returning it. Anything encrypted with the old key
+	// must be re-encrypted with the new one, so the old key should be fetched first.
+	Rotate(keyID string) ([]byte, error)
+}
+
+// DirectoryProvider keeps every key in a file, named after its ID, in a directory that only its owner may read.
+type DirectoryProvider struct {
+	Dir string
+}
+
+// NewDirectoryProvider creates a DirectoryProvider that keeps keys in dir, creating dir when the first key is.
+func NewDirectoryProvider(dir string) DirectoryProvider {
+	return DirectoryProvider{Dir: dir}
+}
+
+// CreateKey creates a new random key called keyID.
+func (d DirectoryProvider) CreateKey(keyID string) ([]byte, error) {
+	name, err := d.keyPath(keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(d.Dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := newKey()
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeKey(name, key)
+	if os.IsExist(err) {
+		return nil, errors.New("key " + keyID + " already exists")
+	}
+
+	return key, err
+}
+
+// GetKey returns the key called keyID.
+func (d DirectoryProvider) GetKey(keyID string) ([]byte, error) {
+	name, err := d.keyPath(keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ioutil.ReadFile(name)
+	if os.IsNotExist(err) {
+		return nil, errors.New("key " + keyID + " does not exist")
+	}
+
+	return key, err
+}
+
+// DeleteKey removes the file holding the key called keyID.
+func (d DirectoryProvider) DeleteKey(keyID string) error {
+	name, err := d.keyPath(keyID)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+// Rotate replaces the key called keyID with a new random key. The new key is written to a temporary file first and
+// renamed over the old one, so the key file always holds one key or the other.
+func (d DirectoryProvider) Rotate(keyID string) ([]byte, error) {
+	name, err := d.keyPath(keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = os.Stat(name); os.IsNotExist(err) {
+		return nil, errors.New("key " + keyID + " does not exist")
+	} else if err != nil {
+		return nil, err
+	}
+
+	key, err := newKey()
+	if err != nil {
+		return nil, err
+	}
+
+	// The temporary file is only readable by its owner, and starts with a dot so that it is never mistaken for a key.
+	file, err := ioutil.TempFile(d.Dir, ".rotate-")
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = file.Write(key)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Chmod(file.Name(), 0400)
+	}
+
+	if err == nil {
+		err = os.Rename(file.Name(), name)
+	}
+
+	if err != nil {
+		os.Remove(file.Name())
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// keyPath returns the file holding a key, refusing IDs that would name a file outside of the directory.
+func (d DirectoryProvider) keyPath(keyID string) (string, error) {
+	if err := checkKeyID(keyID); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(d.Dir, keyID), nil
+}
+
+// checkKeyID refuses key IDs that could name something other than a key, in a directory or a URL. Names starting with
+// a dot are kept for temporary files.
+func checkKeyID(keyID string) error {
+	if keyID == "" || strings.HasPrefix(keyID, ".") || strings.ContainsAny(keyID, `/\?#%`) {
+		return errors.New("invalid key id: " + keyID)
+	}
+
+	return nil
+}
+
+// newKey returns KeySize random bytes.
+func newKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// writeKey writes a key to a new file that only its owner may read, failing if the file already exists.
+func writeKey(name string, key []byte) error {
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0400)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(key)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(name)
+	}
+
+	return err
+}
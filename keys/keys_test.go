@@ -0,0 +1,128 @@
+package keys
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDirectoryProvider(t *testing.T) {
+	t.Parallel()
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-keys")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	provider := NewDirectoryProvider(filepath.Join(tempDir, "keys"))
+
+	if _, err = provider.GetKey("vol1"); err == nil {
+		t.Error("Getting a key that was never created should fail")
+	}
+
+	created, err := provider.CreateKey("vol1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(created) != KeySize {
+		t.Error("Expected a key of ", KeySize, " bytes, got ", len(created))
+	}
+
+	other, err := provider.CreateKey("vol2")
+	if err != nil || bytes.Equal(created, other) {
+		t.Error("Every key should be random, got ", other, err)
+	}
+
+	key, err := provider.GetKey("vol1")
+	if err != nil || !bytes.Equal(key, created) {
+		t.Error("Expected the created key, got ", key, err)
+	}
+
+	if _, err = provider.CreateKey("vol1"); err == nil {
+		t.Error("A key should not be created twice")
+	}
+
+	info, err := os.Stat(filepath.Join(tempDir, "keys"))
+	if err != nil || info.Mode().Perm() != 0700 {
+		t.Error("Only the owner should be able to list the keys, got ", info, err)
+	}
+
+	info, err = os.Stat(filepath.Join(tempDir, "keys", "vol1"))
+	if err != nil || info.Mode().Perm() != 0400 {
+		t.Error("Only the owner should be able to read a key, got ", info, err)
+	}
+}
+
+func TestDirectoryProvider_rotateDelete(t *testing.T) {
+	t.Parallel()
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-keys")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	provider := NewDirectoryProvider(tempDir)
+
+	if _, err = provider.Rotate("vol1"); err == nil {
+		t.Error("Rotating a key that was never created should fail")
+	}
+
+	created, err := provider.CreateKey("vol1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := provider.Rotate("vol1")
+	if err != nil || bytes.Equal(rotated, created) || len(rotated) != KeySize {
+		t.Fatal("Expected a new key, got ", rotated, err)
+	}
+
+	key, err := provider.GetKey("vol1")
+	if err != nil || !bytes.Equal(key, rotated) {
+		t.Error("Expected the rotated key, got ", key, err)
+	}
+
+	info, err := os.Stat(filepath.Join(tempDir, "vol1"))
+	if err != nil || info.Mode().Perm() != 0400 {
+		t.Error("Only the owner should be able to read a rotated key, got ", info, err)
+	}
+
+	files, err := ioutil.ReadDir(tempDir)
+	if err != nil || len(files) != 1 {
+		t.Error("Rotating a key should leave nothing else behind, got ", files, err)
+	}
+
+	if err = provider.DeleteKey("vol1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = provider.GetKey("vol1"); err == nil {
+		t.Error("A deleted key should be gone")
+	}
+
+	if err = provider.DeleteKey("vol1"); err != nil {
+		t.Error("Deleting a key twice should succeed, got ", err)
+	}
+}
+
+func TestDirectoryProvider_invalidID(t *testing.T) {
+	t.Parallel()
+	provider := NewDirectoryProvider(os.TempDir())
+
+	for _, keyID := range []string{"", ".", "..", "../passwd", "a/b", ".rotate-1", "a?b"} {
+		if _, err := provider.CreateKey(keyID); err == nil {
+			t.Error("Creating key ", keyID, " should fail")
+		}
+
+		if _, err := provider.GetKey(keyID); err == nil {
+			t.Error("Getting key ", keyID, " should fail")
+		}
+
+		if err := provider.DeleteKey(keyID); err == nil {
+			t.Error("Deleting key ", keyID, " should fail")
+		}
+	}
+}
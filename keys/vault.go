@@ -0,0 +1,202 @@
+package keys
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultVaultMount is the path the transit secrets engine is mounted at by default.
+const DefaultVaultMount = "transit"
+
+// DefaultVaultTimeout bounds each request to Vault.
+const DefaultVaultTimeout = 30 * time.Second
+
+// errVaultNotFound is returned for requests about keys that Vault does not have.
+var errVaultNotFound = errors.New("not found")
+
+// VaultProvider keeps every key in Vault's transit secrets engine, or anything with a compatible API, as an
+// exportable aes256-gcm96 transit key named after its ID. Keys never leave Vault other than to open a volume.
+type VaultProvider struct {
+	Address string
+	Token   string
+	Mount   string
+	Client  *http.Client
+}
+
+// NewVaultProvider creates a VaultProvider for the Vault at address, e.g. https://vault:8200, authenticating with
+// token. The transit secrets engine is expected at mount, or at DefaultVaultMount if mount is empty.
+func NewVaultProvider(address string, token string, mount string) (VaultProvider, error) {
+	u, err := url.Parse(address)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return VaultProvider{}, errors.New("invalid vault address " + address + ", please use http:// or https://")
+	}
+
+	if token == "" {
+		return VaultProvider{}, errors.New("a vault token is required")
+	}
+
+	if mount == "" {
+		mount = DefaultVaultMount
+	}
+
+	return VaultProvider{
+		Address: strings.TrimSuffix(address, "/"),
+		Token:   token,
+		Mount:   strings.Trim(mount, "/"),
+		Client:  &http.Client{Timeout: DefaultVaultTimeout}}, nil
+}
+
+// CreateKey creates a new transit key called keyID and exports it.
+func (v VaultProvider) CreateKey(keyID string) ([]byte, error) {
+	if err := checkKeyID(keyID); err != nil {
+		return nil, err
+	}
+
+	// Vault succeeds when asked to create a key that already exists, so check first.
+	err := v.do(http.MethodGet, "keys/"+url.PathEscape(keyID), nil, nil)
+	if err == nil {
+		return nil, errors.New("key " + keyID + " already exists")
+	} else if err != errVaultNotFound {
+		return nil, err
+	}
+
+	err = v.do(http.MethodPost, "keys/"+url.PathEscape(keyID), map[string]interface{}{"type": "aes256-gcm96", "exportable": true}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.GetKey(keyID)
+}
+
+// GetKey exports the latest version of the transit key called keyID.
+func (v VaultProvider) GetKey(keyID string) ([]byte, error) {
+	if err := checkKeyID(keyID); err != nil {
+		return nil, err
+	}
+
+	var exported struct {
+		Data struct {
+			Keys map[string]string `json:"keys"`
+		} `json:"data"`
+	}
+
+	err := v.do(http.MethodGet, "export/encryption-key/"+url.PathEscape(keyID)+"/latest", nil, &exported)
+	if err == errVaultNotFound {
+		return nil, errors.New("key " + keyID + " does not exist")
+	} else if err != nil {
+		return nil, err
+	}
+
+	if len(exported.Data.Keys) != 1 {
+		return nil, errors.New("vault returned " + strconv.Itoa(len(exported.Data.Keys)) + " versions of key " + keyID + ", expected the latest")
+	}
+
+	for _, encoded := range exported.Data.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, errors.New("unable to decode key " + keyID + ": " + err.Error())
+		}
+		return key, nil
+	}
+
+	return nil, nil
+}
+
+// DeleteKey allows the transit key called keyID to be deleted, then deletes every version of it.
+func (v VaultProvider) DeleteKey(keyID string) error {
+	if err := checkKeyID(keyID); err != nil {
+		return err
+	}
+
+	err := v.do(http.MethodPost, "keys/"+url.PathEscape(keyID)+"/config", map[string]interface{}{"deletion_allowed": true}, nil)
+	if err == errVaultNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	err = v.do(http.MethodDelete, "keys/"+url.PathEscape(keyID), nil, nil)
+	if err == errVaultNotFound {
+		return nil
+	}
+
+	return err
+}
+
+// Rotate adds a new version to the transit key called keyID, returning it. Vault keeps the earlier versions until the
+// key is deleted.
+func (v VaultProvider) Rotate(keyID string) ([]byte, error) {
+	if err := checkKeyID(keyID); err != nil {
+		return nil, err
+	}
+
+	err := v.do(http.MethodPost, "keys/"+url.PathEscape(keyID)+"/rotate", nil, nil)
+	if err == errVaultNotFound {
+		return nil, errors.New("key " + keyID + " does not exist")
+	} else if err != nil {
+		return nil, err
+	}
+
+	return v.GetKey(keyID)
+}
+
+// do sends a request to the transit secrets engine, encoding body and decoding the response into result as json.
+func (v VaultProvider) do(method string, path string, body interface{}, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	request, err := http.NewRequest(method, v.Address+"/v1/"+v.Mount+"/"+path, reader)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("X-Vault-Token", v.Token)
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := v.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	switch {
+	case response.StatusCode == http.StatusNotFound:
+		return errVaultNotFound
+	case response.StatusCode < 200 || response.StatusCode > 299:
+		return vaultError(method, path, response)
+	case result == nil || response.StatusCode == http.StatusNoContent:
+		return nil
+	}
+
+	return json.NewDecoder(response.Body).Decode(result)
+}
+
+// vaultError describes a failed request, using the errors returned by Vault if there are any.
+func vaultError(method string, path string, response *http.Response) error {
+	body, _ := ioutil.ReadAll(response.Body)
+
+	var failed struct {
+		Errors []string `json:"errors"`
+	}
+	if json.Unmarshal(body, &failed) == nil && len(failed.Errors) > 0 {
+		return errors.New("vault " + method + " " + path + " failed: " + strings.Join(failed.Errors, ", "))
+	}
+
+	return errors.New("vault " + method + " " + path + " failed: " + response.Status)
+}
@@ -0,0 +1,203 @@
+package keys
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// stubTransit pretends to be the parts of Vault's transit secrets engine that VaultProvider uses, mounted at transit.
+type stubTransit struct {
+	mutex    sync.Mutex
+	versions map[string][][]byte
+	deletion map[string]bool
+}
+
+func newStubTransit() *stubTransit {
+	return &stubTransit{versions: map[string][][]byte{}, deletion: map[string]bool{}}
+}
+
+func (s *stubTransit) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if r.Header.Get("X-Vault-Token") != "s.test" {
+		s.fail(w, http.StatusForbidden, "permission denied")
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/transit/")
+	parts := strings.Split(path, "/")
+
+	switch {
+	case r.Method == http.MethodGet && parts[0] == "keys" && len(parts) == 2:
+		if s.versions[parts[1]] == nil {
+			s.fail(w, http.StatusNotFound, "")
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"name": parts[1]}})
+	case r.Method == http.MethodPost && parts[0] == "keys" && len(parts) == 2:
+		var options struct {
+			Type       string `json:"type"`
+			Exportable bool   `json:"exportable"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&options); err != nil || options.Type != "aes256-gcm96" || !options.Exportable {
+			s.fail(w, http.StatusBadRequest, "expected an exportable aes256-gcm96 key")
+			return
+		}
+		if s.versions[parts[1]] == nil {
+			s.versions[parts[1]] = [][]byte{newTransitKey()}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && parts[0] == "keys" && len(parts) == 3 && parts[2] == "rotate":
+		if s.versions[parts[1]] == nil {
+			s.fail(w, http.StatusNotFound, "")
+			return
+		}
+		s.versions[parts[1]] = append(s.versions[parts[1]], newTransitKey())
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && parts[0] == "keys" && len(parts) == 3 && parts[2] == "config":
+		if s.versions[parts[1]] == nil {
+			s.fail(w, http.StatusNotFound, "")
+			return
+		}
+		s.deletion[parts[1]] = true
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodDelete && parts[0] == "keys" && len(parts) == 2:
+		if !s.deletion[parts[1]] {
+			s.fail(w, http.StatusBadRequest, "deletion is not allowed for this key")
+			return
+		}
+		delete(s.versions, parts[1])
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && len(parts) == 4 && parts[0] == "export" && parts[1] == "encryption-key" && parts[3] == "latest":
+		versions := s.versions[parts[2]]
+		if versions == nil {
+			s.fail(w, http.StatusNotFound, "")
+			return
+		}
+		latest := strconv.Itoa(len(versions))
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{
+			"keys": map[string]string{latest: base64.StdEncoding.EncodeToString(versions[len(versions)-1])}}})
+	default:
+		s.fail(w, http.StatusMethodNotAllowed, "unsupported request "+r.Method+" "+r.URL.Path)
+	}
+}
+
+func (s *stubTransit) fail(w http.ResponseWriter, status int, message string) {
+	w.WriteHeader(status)
+	errs := []string{}
+	if message != "" {
+		errs = append(errs, message)
+	}
+	json.NewEncoder(w).Encode(map[string][]string{"errors": errs})
+}
+
+func newTransitKey() []byte {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}
+
+func TestVaultProvider(t *testing.T) {
+	t.Parallel()
+	stub := newStubTransit()
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	provider, err := NewVaultProvider(server.URL, "s.test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = provider.GetKey("vol1"); err == nil {
+		t.Error("Getting a key that was never created should fail")
+	}
+
+	created, err := provider.CreateKey("vol1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(created) != 32 {
+		t.Error("Expected a 32 byte aes256-gcm96 key, got ", len(created))
+	}
+
+	key, err := provider.GetKey("vol1")
+	if err != nil || !bytes.Equal(key, created) {
+		t.Error("Expected the created key, got ", key, err)
+	}
+
+	if _, err = provider.CreateKey("vol1"); err == nil {
+		t.Error("A key should not be created twice")
+	}
+
+	rotated, err := provider.Rotate("vol1")
+	if err != nil || bytes.Equal(rotated, created) {
+		t.Fatal("Expected a new key, got ", rotated, err)
+	}
+
+	key, err = provider.GetKey("vol1")
+	if err != nil || !bytes.Equal(key, rotated) {
+		t.Error("Expected the rotated key, got ", key, err)
+	}
+
+	if _, err = provider.Rotate("missing"); err == nil {
+		t.Error("Rotating a key that does not exist should fail")
+	}
+
+	if err = provider.DeleteKey("vol1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = provider.GetKey("vol1"); err == nil {
+		t.Error("A deleted key should be gone")
+	}
+
+	if err = provider.DeleteKey("vol1"); err != nil {
+		t.Error("Deleting a key twice should succeed, got ", err)
+	}
+
+	if _, err = provider.CreateKey("vol/1"); err == nil {
+		t.Error("Key IDs that are not a single path segment should fail")
+	}
+}
+
+func TestVaultProvider_errors(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(newStubTransit())
+	defer server.Close()
+
+	provider, err := NewVaultProvider(server.URL+"/", "s.wrong", "transit")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = provider.CreateKey("vol1")
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Error("Expected the error returned by vault, got ", err)
+	}
+
+	invalid := []struct {
+		address string
+		token   string
+	}{
+		{"", "s.test"},
+		{"vault:8200", "s.test"},
+		{"unix:///run/vault.sock", "s.test"},
+		{server.URL, ""},
+	}
+
+	for _, test := range invalid {
+		if _, err = NewVaultProvider(test.address, test.token, ""); err == nil {
+			t.Error("Creating a provider for ", test.address, " with token ", test.token, " should fail")
+		}
+	}
+}
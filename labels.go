@@ -0,0 +1,49 @@
+package main
+
+import (
+	"github.com/golang/glog"
+	"github.com/mellanox-senior-design/docker-volume-rdma/db"
+	"github.com/mellanox-senior-design/docker-volume-rdma/engine"
+	"github.com/mellanox-senior-design/docker-volume-rdma/reaper"
+)
+
+// Docker keeps the labels of a volume to itself, volume plugins are only sent its name and options. So the labels
+// are copied from the Docker daemon when it reports that one of our volumes was created, and for every volume when
+// docker-volume-rdma starts, in case any were created while it was not watching.
+
+// recordLabels saves the labels Docker has for a particular volume.
+func recordLabels(containers engine.Client, database db.VolumeDatabase, volumeName string) error {
+	vol, err := containers.Volume(volumeName)
+	if err != nil {
+		return err
+	}
+
+	return database.SetLabels(volumeName, vol.Labels)
+}
+
+// syncLabels saves the labels Docker has for every one of the plugin's volumes, skipping those that docker-volume-rdma
+// has no record of. Volumes that Docker has no labels for are left alone, as Docker can not change the labels of a
+// volume once it is created, and those restored through the admin API keep their archived labels.
+func syncLabels(containers engine.Client, stale reaper.Reaper) error {
+	volumes, err := containers.Volumes("")
+	if err != nil {
+		return err
+	}
+
+	for _, vol := range volumes {
+		if !stale.IsPlugin(vol.Driver) || len(vol.Labels) == 0 {
+			continue
+		}
+
+		if _, err = stale.Driver.VolumeDatabase.Get(vol.Name); err != nil {
+			glog.Warning("Docker has a volume ", vol.Name, " that docker-volume-rdma does not know about")
+			continue
+		}
+
+		if err = stale.Driver.VolumeDatabase.SetLabels(vol.Name, vol.Labels); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
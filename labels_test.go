@@ -0,0 +1,76 @@
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/db"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"github.com/mellanox-senior-design/docker-volume-rdma/engine"
+	"github.com/mellanox-senior-design/docker-volume-rdma/events"
+	"github.com/mellanox-senior-design/docker-volume-rdma/reaper"
+	"github.com/mellanox-senior-design/docker-volume-rdma/throttle"
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
+)
+
+func TestLabels(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-labels")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// A Docker daemon with a labelled volume of this plugin, another of the local driver, and one this plugin lost.
+	listener, err := net.Listen("unix", path.Join(tempDir, "docker.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/volumes":
+			w.Write([]byte(`{"Volumes": [
+				{"Name": "first", "Driver": "rdma:latest", "Labels": {"team": "ml"}},
+				{"Name": "local", "Driver": "local", "Labels": {"team": "web"}},
+				{"Name": "lost", "Driver": "rdma", "Labels": {"team": "ml"}}]}`))
+		case "/volumes/second":
+			w.Write([]byte(`{"Name": "second", "Driver": "rdma", "Labels": {"team": "ml", "env": "prod"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "no such volume"}`))
+		}
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	containers, err := engine.NewClient("unix://" + path.Join(tempDir, "docker.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	driver := drivers.NewRDMAVolumeDriver(drivers.NewOnDiskStorageController(path.Join(tempDir, "volumes")), db.NewInMemoryVolumeDatabase())
+	for _, name := range []string{"first", "second"} {
+		driver.Create(volume.Request{Name: name})
+	}
+
+	stale := reaper.NewReaper(driver, containers, "rdma")
+	if err = syncLabels(containers, stale); err != nil {
+		t.Fatal(err)
+	}
+
+	if labels, _ := driver.VolumeDatabase.Labels("first"); labels["team"] != "ml" {
+		t.Error("The labels of the plugin's volumes should be recorded, got ", labels)
+	}
+
+	throttler := throttle.NewThrottler(driver, containers, "rdma", path.Join(tempDir, "cgroup"))
+	handleDockerEvent(containers, stale, throttler, events.Event{Type: "volume", Action: "create", Actor: events.Actor{ID: "second", Attributes: map[string]string{"driver": "rdma"}}})
+	if labels, _ := driver.VolumeDatabase.Labels("second"); len(labels) != 2 || labels["env"] != "prod" {
+		t.Error("The labels of a created volume should be recorded, got ", labels)
+	}
+}
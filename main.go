@@ -3,17 +3,37 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"flag"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
-	"github.com/docker/go-plugins-helpers/volume"
 	"github.com/golang/glog"
+	"github.com/mellanox-senior-design/docker-volume-rdma/admin"
+	"github.com/mellanox-senior-design/docker-volume-rdma/audit"
+	"github.com/mellanox-senior-design/docker-volume-rdma/backup"
+	"github.com/mellanox-senior-design/docker-volume-rdma/config"
+	"github.com/mellanox-senior-design/docker-volume-rdma/csiplugin"
 	"github.com/mellanox-senior-design/docker-volume-rdma/db"
 	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"github.com/mellanox-senior-design/docker-volume-rdma/engine"
+	"github.com/mellanox-senior-design/docker-volume-rdma/flexvolume"
+	"github.com/mellanox-senior-design/docker-volume-rdma/janitor"
+	"github.com/mellanox-senior-design/docker-volume-rdma/mtls"
+	"github.com/mellanox-senior-design/docker-volume-rdma/reaper"
+	"github.com/mellanox-senior-design/docker-volume-rdma/throttle"
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
+	"github.com/mellanox-senior-design/docker-volume-rdma/webhook"
+
+	// Registers the external storage controller.
+	_ "github.com/mellanox-senior-design/docker-volume-rdma/drivers/external"
 )
 
 // Name of the plugin for use in Docker CLI
@@ -22,17 +42,66 @@ var pluginName string
 // Port to launch service on.
 var httpPort int
 
-// Volume Flags
+// Volume Database Flags, the settings of each database are defined from the database registry.
 var volumeDatabaseDriver string
-var volumeDatabasePath string
-var volumeDatabaseHost string
-var volumeDatabaseUsername string
-var volumeDatabasePassword string
-var volumeDatabaseSchema string
+var volumeDatabaseFlags = map[string]*string{}
 
-// Storage Flags
+// Storage Controller Flags, the settings of each controller are defined from the storage controller registry.
 var storageControllerDriver string
-var storageControllerPath string
+var storageControllerFlags = map[string]*string{}
+
+// Path to the configuration file defining named backends.
+var configPath string
+
+// Frontend Flags, the Docker volume plugin protocol and the Container Storage Interface may be served together.
+var dockerPlugin bool
+var csiEndpoint string
+var csiName string
+var csiNodeID string
+var csiFabric string
+
+// FlexVolume Flags, used by the flexvolume subcommand.
+var flexVolumeDaemon string
+var flexVolumeStatePath string
+
+// Admin Flags, the daemon serves the admin API on -admin-address and the admin subcommands use it at -admin-url.
+var adminAddress string
+var adminURL string
+var adminTokenFile string
+var adminTokensPath string
+var s3Endpoint string
+var s3Region string
+
+// Reaper Flags, stale mount requests are released by checking the running containers with the Docker daemon.
+var dockerHost string
+var reapInterval time.Duration
+var watchEvents bool
+
+// Throttling Flags, the I/O limits of volumes are written to the cgroups of the containers using them.
+var cgroupRoot string
+
+// Security Flags, the plugin's tcp port only serves clients presenting a certificate signed by -tls-ca, the admin API
+// presents -tls-cert, and denied calls to either API and every volume operation are recorded in -audit-log.
+var tlsCA string
+var tlsCert string
+var tlsKey string
+var tlsDockerCert string
+var tlsDockerKey string
+var auditLogPath string
+var auditLogMaxMB int64
+var auditLogBackups int
+
+// Tenant Flags, volumes are divided between tenants by a label or a name prefix and limited to each tenant's quota.
+var tenantLabel string
+var tenantSeparator string
+var tenantQuotasPath string
+
+// Janitor Flags, volumes created with a ttl or expire-after-unmount option are removed once they expire.
+var expireInterval time.Duration
+var expireDryRun bool
+
+// Webhook Flags, the lifecycle events of volumes are posted to the endpoints in the -webhooks file.
+var webhooksPath string
 
 func init() {
 	// Configure application flags.
@@ -40,16 +109,103 @@ func init() {
 	flag.IntVar(&httpPort, "port", 8080, "tcp/ip port to serve volume driver on")
 
 	// Volume Database Flags
-	flag.StringVar(&volumeDatabaseDriver, "db", "sqlite", "set the database backend used to store volume metadata: [sqlite, mysql, in-memory]")
-	flag.StringVar(&volumeDatabasePath, "dbpath", "", "set the database storage path")
-	flag.StringVar(&volumeDatabaseHost, "dbhost", "", "set the database host (default is '' or localhost:3306)")
-	flag.StringVar(&volumeDatabaseUsername, "dbuser", "", "set the database username (default is root)")
-	flag.StringVar(&volumeDatabasePassword, "dbpass", "", "set the database password (optional)")
-	flag.StringVar(&volumeDatabaseSchema, "dbschema", "", "set the database schema (required)")
+	databases := db.Registered()
+	flag.StringVar(&volumeDatabaseDriver, "db", "sqlite", "set the database backend used to store volume metadata: ["+strings.Join(databases, ", ")+"]")
+	databaseOptions := map[string][]config.Option{}
+	for _, name := range databases {
+		factory, _ := db.Lookup(name)
+		databaseOptions[name] = factory.Options
+	}
+	defineOptionFlags(volumeDatabaseFlags, databases, databaseOptions)
 
 	// Storage Controller Flags
-	flag.StringVar(&storageControllerDriver, "sc", "glusterfs", "set the storage backend used to store volume data: [glusterfs, on-disk]")
-	flag.StringVar(&storageControllerPath, "scpath", "", "set the storage path used to know where to put the volumes on the host")
+	storageControllers := drivers.Registered()
+	flag.StringVar(&storageControllerDriver, "sc", "glusterfs", "set the storage backend used to store volume data: ["+strings.Join(storageControllers, ", ")+"]")
+	storageControllerOptions := map[string][]config.Option{}
+	for _, name := range storageControllers {
+		factory, _ := drivers.Lookup(name)
+		storageControllerOptions[name] = factory.Options
+	}
+	defineOptionFlags(storageControllerFlags, storageControllers, storageControllerOptions)
+
+	// Configuration File Flags
+	flag.StringVar(&configPath, "config", "", "set the configuration file defining named backends, replacing the -sc flags (optional)")
+
+	// Frontend Flags
+	flag.BoolVar(&dockerPlugin, "docker-plugin", true, "serve the Docker volume plugin protocol on -port")
+	flag.StringVar(&csiEndpoint, "csi-endpoint", "", "serve the Container Storage Interface on this endpoint, e.g. unix:///run/csi/csi.sock (optional)")
+	flag.StringVar(&csiName, "csi-name", "docker-volume-rdma.mellanox-senior-design.github.io", "name of the plugin reported over the Container Storage Interface")
+	flag.StringVar(&csiNodeID, "csi-node-id", "", "ID of this host reported over the Container Storage Interface (default is the host name)")
+	flag.StringVar(&csiFabric, "csi-fabric", "", "name of the RDMA fabric segment this host is attached to, reported as its topology (optional)")
+
+	// FlexVolume Flags
+	flag.StringVar(&flexVolumeDaemon, "flexvolume-daemon", "", "url of the plugin daemon that flexvolume mounts through, e.g. http://localhost:8080 (default is to use -db and -sc directly)")
+	flag.StringVar(&flexVolumeStatePath, "flexvolume-state", flexvolume.DefaultStatePath, "directory flexvolume remembers the volume mounted at each mount dir in")
+
+	// Admin Flags
+	flag.StringVar(&adminAddress, "admin-address", "", "serve the admin API on this address, e.g. 127.0.0.1:8081, which must be a loopback address unless -tls-cert is set (optional)")
+	flag.StringVar(&adminURL, "admin-url", "", "url of the admin API the volumes and mounts subcommands use, e.g. http://127.0.0.1:8081 (default is to use -db and -sc directly)")
+	flag.StringVar(&adminTokenFile, "admin-token-file", "", "file holding the token that authenticates requests to the admin API")
+	flag.StringVar(&adminTokensPath, "admin-tokens", "", "file of \"<role> <name> <token>\" lines granting tokens the read-only, operator or admin role, replacing -admin-token-file when serving the admin API (optional)")
+	flag.StringVar(&s3Endpoint, "s3-endpoint", "http://localhost:9000", "S3-compatible endpoint that backups to s3:// urls are stored in, keys are read from AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	flag.StringVar(&s3Region, "s3-region", backup.DefaultRegion, "region of -s3-endpoint")
+
+	// Reaper Flags
+	flag.StringVar(&dockerHost, "docker-host", engine.DefaultHost, "address of the Docker daemon, used to find mount requests of containers that no longer exist")
+	flag.DurationVar(&reapInterval, "reap-interval", time.Minute, "how often to release mount requests of containers that are no longer running, 0 disables")
+	flag.BoolVar(&watchEvents, "watch-events", true, "release mount requests as soon as the Docker daemon reports that their container or volume is gone")
+
+	// Throttling Flags
+	flag.StringVar(&cgroupRoot, "cgroup-root", throttle.DefaultCgroupRoot, "cgroup v2 hierarchy that the I/O limits of volumes are applied in when their containers start")
+
+	// Security Flags
+	flag.StringVar(&tlsCA, "tls-ca", "", "ca that clients of the plugin's tcp port must present a certificate signed by, enabling mutual tls (optional)")
+	flag.StringVar(&tlsCert, "tls-cert", "", "certificate the plugin's tcp port presents when -tls-ca is set, and the admin API presents whenever it is set")
+	flag.StringVar(&tlsKey, "tls-key", "", "key of -tls-cert")
+	flag.StringVar(&tlsDockerCert, "tls-docker-cert", "", "certificate the Docker daemon presents, written with -tls-ca to the plugin's spec file in "+mtls.DefaultSpecDir+" (optional)")
+	flag.StringVar(&tlsDockerKey, "tls-docker-key", "", "key of -tls-docker-cert")
+	flag.StringVar(&auditLogPath, "audit-log", "", "file that volume operations and calls to the admin API are appended to as json, denied calls are always logged (optional)")
+	flag.Int64Var(&auditLogMaxMB, "audit-log-max-mb", 100, "rotate -audit-log once it grows past this many megabytes, 0 never rotates it")
+	flag.IntVar(&auditLogBackups, "audit-log-backups", 5, "number of rotated -audit-log files to keep")
+
+	// Tenant Flags
+	flag.StringVar(&tenantLabel, "tenant-label", "", "take the tenant of each volume from this label, which must also be given as a volume option (optional)")
+	flag.StringVar(&tenantSeparator, "tenant-separator", "", "take the tenant of each volume from the start of its name, up to this separator (optional)")
+	flag.StringVar(&tenantQuotasPath, "tenant-quotas", "", "file limiting the number and total size of the volumes of each tenant (optional)")
+
+	// Janitor Flags
+	flag.DurationVar(&expireInterval, "expire-interval", 5*time.Minute, "how often to remove volumes that have passed their ttl or expire-after-unmount option, 0 disables")
+	flag.BoolVar(&expireDryRun, "expire-dry-run", false, "only log the expired volumes that would be removed")
+
+	// Webhook Flags
+	flag.StringVar(&webhooksPath, "webhooks", "", "file of endpoints that are sent signed events when volumes are created, first mounted, last unmounted or removed (optional)")
+}
+
+// defineOptionFlags defines a flag for every option of the named backends, noting which backends use it in its
+// usage, and stores the flag values in flags by option name.
+func defineOptionFlags(flags map[string]*string, names []string, options map[string][]config.Option) {
+	var order []string
+	described := map[string]config.Option{}
+	users := map[string][]string{}
+	for _, name := range names {
+		for _, option := range options[name] {
+			if _, exists := described[option.Name]; !exists {
+				described[option.Name] = option
+				order = append(order, option.Name)
+			}
+
+			user := name
+			if option.Default != "" {
+				user += ", default is " + option.Default
+			}
+			users[option.Name] = append(users[option.Name], user)
+		}
+	}
+
+	for _, name := range order {
+		usage := described[name].Description + " (" + strings.Join(users[name], ", ") + ")"
+		flags[name] = flag.String(name, "", usage)
+	}
 }
 
 // Configure and start the docker volume plugin server.
@@ -58,6 +214,14 @@ func main() {
 	// Parse flags as glog needs the flags to be solidified before starting.
 	flag.Parse()
 
+	// Subcommands run once and exit rather than serving, e.g. "flexvolume mount ..." or "volumes ls".
+	switch flag.Arg(0) {
+	case "flexvolume":
+		os.Exit(runFlexVolume(flag.Args()[1:]))
+	case "volumes", "mounts", "tenants", "audit":
+		os.Exit(runAdmin(flag.Args()))
+	}
+
 	// Convert port to string, and print startup message.
 	port := strconv.Itoa(httpPort)
 
@@ -78,8 +242,7 @@ func main() {
 		if err == nil {
 			defer driver.Disconnect()
 
-			glog.Info("Running! http://localhost:" + port)
-			err = handler.ServeTCP(pluginName, ":"+port, nil)
+			err = serve(*driver, handler, port)
 		}
 	}
 
@@ -87,6 +250,133 @@ func main() {
 	glog.Fatal(err)
 }
 
+// serve the Docker volume plugin protocol and the Container Storage Interface, as enabled, until either fails.
+func serve(driver drivers.RDMAVolumeDriver, handler *volume.Handler, port string) error {
+	if !dockerPlugin && csiEndpoint == "" {
+		return errors.New("nothing to serve, please enable -docker-plugin or set -csi-endpoint")
+	}
+
+	// Denied calls are logged even if no audit log is kept.
+	auditLog, _ := driver.Audit.(audit.Log)
+	defer auditLog.Close()
+
+	// Events are also queued by the flexvolume and admin subcommands, which leave their delivery to the daemon.
+	if notifier, ok := driver.Notifier.(webhook.Notifier); ok {
+		go notifier.Run(nil)
+	}
+
+	errs := make(chan error, 3)
+	if adminAddress != "" {
+		secure := tlsCert != "" && tlsKey != ""
+		if err := checkAdminAddress(adminAddress, secure); err != nil {
+			return err
+		}
+
+		tokens, err := readAdminTokens()
+		if err != nil {
+			return err
+		}
+
+		adminHandler := admin.NewHandler(admin.Service{Driver: driver, Audit: auditLog}, tokens, auditLog)
+		go func() {
+			if secure {
+				glog.Info("Serving the admin API on https://", adminAddress)
+				errs <- http.ListenAndServeTLS(adminAddress, tlsCert, tlsKey, adminHandler)
+			} else {
+				glog.Info("Serving the admin API on http://", adminAddress)
+				errs <- http.ListenAndServe(adminAddress, adminHandler)
+			}
+		}()
+	}
+
+	if csiEndpoint != "" {
+		server, err := csiplugin.NewServer(csiName, csiNodeID, csiFabric, driver)
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			errs <- csiplugin.Serve(csiEndpoint, server)
+		}()
+	}
+
+	if expireInterval > 0 {
+		glog.Info("Removing expired volumes every ", expireInterval)
+		go janitor.NewJanitor(driver, expireDryRun).Run(expireInterval, nil)
+	}
+
+	if dockerPlugin && (reapInterval > 0 || watchEvents) {
+		containers, err := engine.NewClient(dockerHost)
+		if err != nil {
+			return err
+		}
+
+		stale := reaper.NewReaper(driver, containers, pluginName)
+		throttler := throttle.NewThrottler(driver, containers, pluginName, cgroupRoot)
+		go func() {
+			if err := throttler.ApplyRunning(); err != nil {
+				glog.Error("Unable to limit the I/O of running containers: ", err)
+			}
+
+			if err := syncLabels(containers, stale); err != nil {
+				glog.Error("Unable to record the labels of volumes: ", err)
+			}
+		}()
+
+		if reapInterval > 0 {
+			glog.Info("Releasing stale mount requests every ", reapInterval)
+			go stale.Run(reapInterval, nil)
+		}
+
+		if watchEvents {
+			watchDockerEvents(containers, stale, throttler)
+		}
+	}
+
+	if dockerPlugin && tlsCA != "" {
+		listener, err := listenTLS(port, auditLog)
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			glog.Info("Running! https://localhost:" + port)
+			errs <- handler.Serve(listener)
+		}()
+	} else if dockerPlugin {
+		go func() {
+			glog.Info("Running! http://localhost:" + port)
+			errs <- handler.ServeTCP(pluginName, ":"+port, "", nil)
+		}()
+	}
+
+	return <-errs
+}
+
+// listenTLS listens on port for clients presenting a certificate signed by -tls-ca, auditing those that do not. Docker
+// is told to present -tls-docker-cert if it is set, otherwise the plugin's spec file must be written by hand.
+func listenTLS(port string, auditLog audit.Log) (net.Listener, error) {
+	config, err := mtls.NewServerConfig(tlsCA, tlsCert, tlsKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if tlsDockerCert != "" {
+		// The plugin's certificate must be valid for localhost, as that is where Docker reaches it.
+		err = mtls.WriteSpec(mtls.DefaultSpecDir, pluginName, "localhost:"+port, tlsCA, tlsDockerCert, tlsDockerKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return nil, err
+	}
+
+	return mtls.NewListener(listener, config, auditLog), nil
+}
+
 func configure() (*drivers.RDMAVolumeDriver, *volume.Handler, error) {
 	// Create and begin serving volume driver on tcp/ip port, httpPort.
 	volumeDatabase, err := getDatabaseConnection()
@@ -94,16 +384,61 @@ func configure() (*drivers.RDMAVolumeDriver, *volume.Handler, error) {
 		return nil, nil, err
 	}
 
-	// Configure Storage Controller
-	storageController, err := getStorageConnection()
-	if err != nil {
-		return nil, nil, err
+	// Configure Storage Controllers
+	var driver drivers.RDMAVolumeDriver
+	if configPath != "" {
+		backends, defaultBackend, err := getBackends(configPath)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		driver, err = drivers.NewMultiBackendRDMAVolumeDriver(backends, defaultBackend, volumeDatabase)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		storageController, err := getStorageConnection(storageControllerDriver, flagValues(storageControllerFlags))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		driver = drivers.NewRDMAVolumeDriver(storageController, volumeDatabase)
+	}
+
+	// Divide volumes between tenants
+	if tenantLabel != "" || tenantSeparator != "" {
+		driver.Tenants, err = getTenants(tenantLabel, tenantSeparator, tenantQuotasPath)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else if tenantQuotasPath != "" {
+		return nil, nil, errors.New("-tenant-quotas requires -tenant-label or -tenant-separator")
+	}
+
+	// Audit volume operations
+	if auditLogPath != "" {
+		auditLog, err := audit.NewLog(auditLogPath, auditLogMaxMB<<20, auditLogBackups)
+		if err != nil {
+			return nil, nil, err
+		}
+		driver.Audit = auditLog
+	}
+
+	// Notify endpoints of volume lifecycle events
+	if webhooksPath != "" {
+		notifier, err := getNotifier(webhooksPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		driver.Notifier = notifier
 	}
 
 	// Print startup message and start server
 	glog.Info("Connecting to services ...")
-	driver := drivers.NewRDMAVolumeDriver(storageController, volumeDatabase)
 	handler := volume.NewHandler(driver)
+	handler.HandleFunc("/Plugin.Health", func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, driver.Health())
+	})
 
 	return &driver, handler, nil
 }
@@ -111,75 +446,71 @@ func configure() (*drivers.RDMAVolumeDriver, *volume.Handler, error) {
 // GetDatabaseConnection returns the database connection that was requested on the command line.
 func getDatabaseConnection() (db.VolumeDatabase, error) {
 	glog.Info("Attempting to use the ", volumeDatabaseDriver, " volume driver.")
-	switch volumeDatabaseDriver {
-	case "in-memory":
-		validateDatabaseFlags(true, true, false, false, false, false)
-		return db.NewInMemoryVolumeDatabase(), nil
-
-	case "sqlite":
-		validateDatabaseFlags(false, true, false, false, false, false)
-		return db.NewSQLiteVolumeDatabase(volumeDatabasePath), nil
 
-	case "mysql":
-		validateDatabaseFlags(false, false, true, true, true, true)
-		return db.NewMySQLVolumeDatabase(volumeDatabaseHost, volumeDatabaseUsername, volumeDatabasePassword, volumeDatabaseSchema)
+	factory, exists := db.Lookup(volumeDatabaseDriver)
+	if !exists {
+		return nil, errors.New("unsupported database, please choose " + strings.Join(db.Registered(), ", "))
+	}
 
-	default:
-		return nil, errors.New("unsupported database, please choose sqlite, mysql, or in-memory")
+	settings := flagValues(volumeDatabaseFlags)
+	err := validateSettings("Volume Driver", volumeDatabaseDriver, factory.Options, factory.Lenient, settings)
+	if err != nil {
+		return nil, err
 	}
+
+	return factory.New(config.NewValues(factory.Options, settings))
 }
 
-func validateDatabaseFlags(fatal bool, path bool, host bool, username bool, password bool, schema bool) {
+// getStorageConnection returns the storage controller named by driver, configured with settings.
+func getStorageConnection(driver string, settings map[string]string) (drivers.StorageController, error) {
+	glog.Info("Attempting to use the ", driver, " storage controller.")
 
-	var errors bool
-	noteErrorFunc := func(name string, value string, used bool) {
-		if !used && value != "" {
-			glog.Warning("Volume Driver: ", volumeDatabaseDriver, " does not support ", name, ".")
-			errors = true
-		}
+	factory, exists := drivers.Lookup(driver)
+	if !exists {
+		return nil, errors.New("unsupported storage controller, please choose " + strings.Join(drivers.Registered(), ", "))
 	}
 
-	noteErrorFunc("-dbpath", volumeDatabasePath, path)
-	noteErrorFunc("-dbhost", volumeDatabaseHost, host)
-	noteErrorFunc("-dbuser", volumeDatabaseUsername, username)
-	noteErrorFunc("-dbpass", volumeDatabasePassword, password)
-	noteErrorFunc("-dbschema", volumeDatabaseSchema, schema)
-
-	if errors && fatal {
-		glog.Fatal("Invalid flag(s) were passed, are you using the correct volume driver?")
+	err := validateSettings("Storage Controller", driver, factory.Options, factory.Lenient, settings)
+	if err != nil {
+		return nil, err
 	}
+
+	return factory.New(config.NewValues(factory.Options, settings))
 }
 
-func getStorageConnection() (drivers.StorageController, error) {
-	glog.Info("Attempting to use the ", storageControllerDriver, " storage controller.")
+// flagValues returns the values of flags by name.
+func flagValues(flags map[string]*string) map[string]string {
+	values := map[string]string{}
+	for name, value := range flags {
+		values[name] = *value
+	}
 
-	switch storageControllerDriver {
-	case "on-disk":
-		validateStorageFlags(true, true)
-		return drivers.NewOnDiskStorageController(storageControllerPath), nil
+	return values
+}
 
-	case "glusterfs":
-		validateStorageFlags(false, false)
-		return drivers.NewGlusterStorageController(), nil
+// validateSettings warns about every setting the backend does not support, failing unless the backend is lenient.
+func validateSettings(kind string, driver string, options []config.Option, lenient bool, settings map[string]string) error {
+	unsupported := config.Unsupported(options, settings)
+	for _, name := range unsupported {
+		glog.Warning(kind, ": ", driver, " does not support -", name, ".")
+	}
 
-	default:
-		return nil, errors.New("unsupported storage controller, please choose glusterfs or on-disk")
+	if len(unsupported) > 0 && !lenient {
+		return errors.New("invalid flag(s) were passed, are you using the correct " + strings.ToLower(kind) + "?")
 	}
-}
 
-func validateStorageFlags(fatal bool, path bool) {
+	return nil
+}
 
-	var errors bool
-	noteErrorFunc := func(name string, value string, used bool) {
-		if !used && value != "" {
-			glog.Warning("Storage Controller: ", volumeDatabaseDriver, " does not support ", name, ".")
-			errors = true
-		}
+// writeHealth writes the health of the driver as json, responding with an error status if it is unhealthy.
+func writeHealth(w http.ResponseWriter, health drivers.HealthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	if health.Err != "" {
+		w.WriteHeader(http.StatusServiceUnavailable)
 	}
 
-	noteErrorFunc("-dbpath", storageControllerPath, path)
-
-	if errors && fatal {
-		glog.Fatal("Invalid flag(s) were passed, are you using the correct storage controller?")
+	err := json.NewEncoder(w).Encode(health)
+	if err != nil {
+		glog.Error(err)
 	}
 }
@@ -7,14 +7,15 @@ import (
 	"io/ioutil"
 	"math/rand"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"strconv"
 	"testing"
 
 	"github.com/docker/docker/pkg/namesgenerator"
-	"github.com/docker/go-plugins-helpers/volume"
 	"github.com/mellanox-senior-design/docker-volume-rdma/db"
 	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
 )
 
 func configureTest(t *testing.T) (*drivers.RDMAVolumeDriver, *volume.Handler, string, string) {
@@ -273,3 +274,87 @@ func TestGetStorageConnection_glusterfs(t *testing.T) {
 		t.Fatal("Configured Driver's StorageController was not an drivers.GlusterStorageController")
 	}
 }
+
+func TestGetStorageConnection_lvm(t *testing.T) {
+	// Test LVMStorageController Volume Database
+	// Configure flags.
+	flag.Set("sc", "lvm")
+	flag.Set("sc-vg", "vg")
+	flag.Set("sc-thinpool", "pool")
+	flag.Parse()
+	defer flag.Set("sc-vg", "")
+	defer flag.Set("sc-thinpool", "")
+
+	// Configure driver and handler.
+	configuredDriver, _, err := configure()
+	if err != nil {
+		t.Error(err)
+	}
+
+	// Ensure that we are using an lvm storage controller, if this fails, check for flag parsing.
+	sc, ok := configuredDriver.StorageController.(drivers.LVMStorageController)
+	if !ok {
+		t.Fatal("Configured Driver's StorageController was not an drivers.LVMStorageController")
+	}
+
+	if sc.VolumeGroup != "vg" || sc.ThinPool != "pool" {
+		t.Fatal("Configured Driver's StorageController was not configured with the thin pool vg/pool")
+	}
+}
+
+func TestWriteHealth(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	writeHealth(recorder, drivers.HealthResponse{Err: "thin pool is full"})
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Error("Unhealthy responses should use status ", http.StatusServiceUnavailable, " not ", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	writeHealth(recorder, drivers.HealthResponse{})
+	if recorder.Code != http.StatusOK {
+		t.Error("Healthy responses should use status ", http.StatusOK, " not ", recorder.Code)
+	}
+}
+
+func TestGetStorageConnection_rbd(t *testing.T) {
+	// Test RBDStorageController Volume Database
+	// Configure flags.
+	flag.Set("sc", "rbd")
+	flag.Set("sc-pool", "volumes")
+	flag.Parse()
+	defer flag.Set("sc-pool", "")
+
+	// Configure driver and handler.
+	configuredDriver, _, err := configure()
+	if err != nil {
+		t.Error(err)
+	}
+
+	// Ensure that we are using an rbd storage controller, if this fails, check for flag parsing.
+	sc, ok := configuredDriver.StorageController.(drivers.RBDStorageController)
+	if !ok {
+		t.Fatal("Configured Driver's StorageController was not an drivers.RBDStorageController")
+	}
+
+	if sc.Pool != "volumes" {
+		t.Fatal("Configured Driver's StorageController was not configured with the pool volumes")
+	}
+}
+
+func TestGetStorageConnection_tmpfs(t *testing.T) {
+	// Test TmpfsStorageController Volume Database
+	// Configure flags.
+	flag.Set("sc", "tmpfs")
+	flag.Parse()
+
+	// Configure driver and handler.
+	configuredDriver, _, err := configure()
+	if err != nil {
+		t.Error(err)
+	}
+
+	// Ensure that we are using a tmpfs storage controller, if this fails, check for flag parsing.
+	if _, ok := configuredDriver.StorageController.(drivers.TmpfsStorageController); !ok {
+		t.Fatal("Configured Driver's StorageController was not an drivers.TmpfsStorageController")
+	}
+}
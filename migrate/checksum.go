@@ -0,0 +1,89 @@
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Checksum returns a SHA-256 digest of the names, types, permissions, symbolic link targets and contents of the
+// directories, files and symbolic links in dir, the ones that Copy copies. The permissions of dir itself are left out,
+// as they are usually chosen by the storage controller that mounted it.
+func Checksum(dir string) (string, error) {
+	digest := sha256.New()
+	err := filepath.Walk(dir, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relative, err := filepath.Rel(dir, name)
+		if err != nil || relative == "." {
+			return err
+		}
+
+		mode := info.Mode()
+		switch {
+		case mode.IsDir():
+			writeField(digest, "d", relative, mode.Perm().String())
+		case mode.IsRegular():
+			writeField(digest, "f", relative, mode.Perm().String(), strconv.FormatInt(info.Size(), 10))
+			return hashContents(digest, name)
+		case mode&os.ModeSymlink != 0:
+			link, err := os.Readlink(name)
+			if err != nil {
+				return err
+			}
+			writeField(digest, "l", relative, link)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+// Verify returns an error unless source and target have the same checksum.
+func Verify(source string, target string) error {
+	sourceChecksum, err := Checksum(source)
+	if err != nil {
+		return err
+	}
+
+	targetChecksum, err := Checksum(target)
+	if err != nil {
+		return err
+	}
+
+	if sourceChecksum != targetChecksum {
+		return errors.New("checksum of the copy, " + targetChecksum + ", does not match the original, " + sourceChecksum)
+	}
+
+	return nil
+}
+
+// writeField adds NUL terminated fields to digest, so that no two entries hash the same fields.
+func writeField(digest hash.Hash, fields ...string) {
+	for _, field := range fields {
+		io.WriteString(digest, field)
+		digest.Write([]byte{0})
+	}
+}
+
+func hashContents(digest hash.Hash, name string) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(digest, file)
+	return err
+}
@@ -0,0 +1,66 @@
+package migrate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChecksum(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		name   string
+		change func(dir string) error
+		same   bool
+	}{
+		{"unchanged", func(dir string) error { return nil }, true},
+		{"root permissions", func(dir string) error { return os.Chmod(dir, 0700) }, true},
+		{"contents", func(dir string) error {
+			return ioutil.WriteFile(filepath.Join(dir, "top.txt"), []byte("top levEl"), 0644)
+		}, false},
+		{"permissions", func(dir string) error { return os.Chmod(filepath.Join(dir, "bin/run.sh"), 0644) }, false},
+		{"renamed", func(dir string) error {
+			return os.Rename(filepath.Join(dir, "bin/empty"), filepath.Join(dir, "bin/empty2"))
+		}, false},
+		{"link target", func(dir string) error {
+			os.Remove(filepath.Join(dir, "run"))
+			return os.Symlink("top.txt", filepath.Join(dir, "run"))
+		}, false},
+		{"new directory", func(dir string) error { return os.Mkdir(filepath.Join(dir, "new"), 0755) }, false},
+	}
+
+	for _, test := range tests {
+		tempDir, err := ioutil.TempDir("", "docker-volume-rdma-checksum")
+		if err != nil {
+			t.Fatal("Unable to create temp dir! ", err)
+		}
+
+		original := filepath.Join(tempDir, "original")
+		changed := filepath.Join(tempDir, "changed")
+		writeTree(t, original)
+		writeTree(t, changed)
+
+		if err = test.change(changed); err != nil {
+			t.Fatal(test.name, ": ", err)
+		}
+
+		err = Verify(original, changed)
+		if test.same && err != nil {
+			t.Error(test.name, " should not change the checksum, got ", err)
+		} else if !test.same && err == nil {
+			t.Error(test.name, " should change the checksum")
+		}
+
+		os.RemoveAll(tempDir)
+	}
+}
+
+func TestChecksum_missing(t *testing.T) {
+	t.Parallel()
+
+	if _, err := Checksum("/nonexistent/docker-volume-rdma"); err == nil {
+		t.Error("The checksum of a missing directory should fail")
+	}
+}
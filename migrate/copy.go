@@ -0,0 +1,178 @@
+// Package migrate copies the files of a volume from one mountpoint to another, reporting its progress as it goes, and
+// verifies that the copy holds the same files as the original.
+package migrate
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/golang/glog"
+)
+
+// reportInterval is the number of bytes copied between progress reports while a large file is copied.
+const reportInterval = 64 << 20
+
+// Progress describes how much of a volume has been copied.
+type Progress struct {
+	Files      int
+	Bytes      int64
+	TotalFiles int
+	TotalBytes int64
+}
+
+// Percent returns how much of the volume has been copied, by size, as a percentage.
+func (p Progress) Percent() float64 {
+	if p.TotalBytes == 0 {
+		return 100
+	}
+
+	return float64(p.Bytes) * 100 / float64(p.TotalBytes)
+}
+
+// Copy copies the directories, files and symbolic links in source to target, calling report, if not nil, after each
+// file and periodically while large files are copied. Permissions and modification times are kept, as is ownership
+// when run as root. Other files, such as sockets, are skipped.
+func Copy(source string, target string, report func(Progress)) error {
+	progress, err := measure(source)
+	if err != nil {
+		return err
+	}
+
+	if report == nil {
+		report = func(Progress) {}
+	}
+	report(progress)
+
+	return filepath.Walk(source, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relative, err := filepath.Rel(source, name)
+		if err != nil {
+			return err
+		}
+		destination := filepath.Join(target, relative)
+
+		switch mode := info.Mode(); {
+		case mode.IsDir():
+			// The umask may have narrowed the permissions of a new directory.
+			err = os.Mkdir(destination, mode.Perm())
+			if err == nil || os.IsExist(err) {
+				err = os.Chmod(destination, mode.Perm())
+			}
+		case mode.IsRegular():
+			err = copyFile(name, destination, info, &progress, report)
+		case mode&os.ModeSymlink != 0:
+			var link string
+			link, err = os.Readlink(name)
+			if err == nil {
+				os.Remove(destination)
+				err = os.Symlink(link, destination)
+			}
+		default:
+			glog.Warning("Skipping ", name, " as it is not a file, directory or symbolic link")
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return chown(name, destination)
+	})
+}
+
+// measure counts the files in dir and their total size.
+func measure(dir string) (Progress, error) {
+	var progress Progress
+	err := filepath.Walk(dir, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.Mode().IsRegular() {
+			progress.TotalFiles++
+			progress.TotalBytes += info.Size()
+		}
+
+		return nil
+	})
+
+	return progress, err
+}
+
+// copyFile copies the contents, permissions and modification time of a regular file, adding it to progress.
+func copyFile(name string, destination string, info os.FileInfo, progress *Progress, report func(Progress)) error {
+	in, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(destination, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(&progressWriter{Writer: out, progress: progress, report: report}, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	// The file may have existed already, with other permissions, if the copy is being retried.
+	err = os.Chmod(destination, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	progress.Files++
+	report(*progress)
+
+	return os.Chtimes(destination, info.ModTime(), info.ModTime())
+}
+
+// chown gives destination the owner of name. Ownership can only be changed by root.
+func chown(name string, destination string) error {
+	if os.Geteuid() != 0 {
+		return nil
+	}
+
+	info, err := os.Lstat(name)
+	if err != nil {
+		return err
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+
+	return os.Lchown(destination, int(stat.Uid), int(stat.Gid))
+}
+
+// progressWriter adds the bytes written to progress, reporting it every reportInterval bytes.
+type progressWriter struct {
+	io.Writer
+	progress   *Progress
+	report     func(Progress)
+	unreported int64
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.Writer.Write(b)
+
+	p.progress.Bytes += int64(n)
+	p.unreported += int64(n)
+	if p.unreported >= reportInterval {
+		p.unreported = 0
+		p.report(*p.progress)
+	}
+
+	return n, err
+}
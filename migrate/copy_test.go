@@ -0,0 +1,103 @@
+package migrate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTree creates a directory, files with several permissions and a symbolic link under dir.
+func writeTree(t *testing.T, dir string) {
+	files := []struct {
+		name     string
+		contents string
+		mode     os.FileMode
+	}{
+		{"top.txt", "top level", 0644},
+		{"bin/run.sh", "#!/bin/sh\necho hi\n", 0755},
+		{"bin/empty", "", 0600},
+		{"deep/er/still/data.bin", string(make([]byte, 4096)), 0640},
+	}
+
+	for _, file := range files {
+		name := filepath.Join(dir, file.name)
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(name, []byte(file.contents), file.mode); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Chmod(name, file.mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.Chmod(filepath.Join(dir, "deep/er"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink("bin/run.sh", filepath.Join(dir, "run")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	t.Parallel()
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-migrate")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	source := filepath.Join(tempDir, "source")
+	target := filepath.Join(tempDir, "target")
+	writeTree(t, source)
+	if err = os.Mkdir(target, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var reports []Progress
+	err = Copy(source, target, func(progress Progress) { reports = append(reports, progress) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reports) != 5 || reports[0].Files != 0 {
+		t.Fatal("Expected a report before copying and after each of the 4 files, got ", reports)
+	}
+
+	last := reports[len(reports)-1]
+	if last.Files != 4 || last.TotalFiles != 4 || last.Bytes != last.TotalBytes || last.Percent() != 100 {
+		t.Error("Expected every file to have been copied, got ", last)
+	}
+
+	if err = Verify(source, target); err != nil {
+		t.Error(err)
+	}
+
+	info, err := os.Stat(filepath.Join(target, "deep/er"))
+	if err != nil || info.Mode().Perm() != 0700 {
+		t.Error("The permissions of directories should be copied, got ", info, err)
+	}
+
+	link, err := os.Readlink(filepath.Join(target, "run"))
+	if err != nil || link != "bin/run.sh" {
+		t.Error("Symbolic links should be copied as links, got ", link, err)
+	}
+
+	// Copying again, e.g. after an interrupted migration, overwrites the earlier copy.
+	if err = Copy(source, target, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCopy_missingSource(t *testing.T) {
+	t.Parallel()
+
+	if err := Copy("/nonexistent/docker-volume-rdma", os.TempDir(), nil); err == nil {
+		t.Error("Copying a missing directory should fail")
+	}
+}
@@ -0,0 +1,157 @@
+// Package mtls secures the Docker volume plugin protocol with mutual TLS, so that only clients holding a certificate
+// signed by a trusted CA, such as the Docker daemon, can create, mount and remove volumes over the plugin's tcp port.
+package mtls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/audit"
+)
+
+// DefaultSpecDir is where the Docker daemon looks for the spec files of plugins that are not managed by it.
+const DefaultSpecDir = "/etc/docker/plugins"
+
+// NewServerConfig creates the TLS configuration of a server presenting the certificate in certFile and keyFile, that
+// requires every client to present a certificate signed by the CA in caFile.
+func NewServerConfig(caFile string, certFile string, keyFile string) (*tls.Config, error) {
+	if caFile == "" || certFile == "" || keyFile == "" {
+		return nil, errors.New("mutual tls requires a ca, a certificate and a key")
+	}
+
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	pem, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(pem) {
+		return nil, errors.New(caFile + " does not contain a pem encoded certificate")
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+		MinVersion:   tls.VersionTLS12}, nil
+}
+
+// NewListener accepts connections from listener over TLS with config, recording every connection whose handshake
+// fails, e.g. because it presented no certificate or an untrusted one, in auditLog.
+func NewListener(listener net.Listener, config *tls.Config, auditLog audit.Log) net.Listener {
+	return auditedListener{Listener: listener, config: config, auditLog: auditLog}
+}
+
+type auditedListener struct {
+	net.Listener
+	config   *tls.Config
+	auditLog audit.Log
+}
+
+func (l auditedListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return &auditedConn{Conn: tls.Server(conn, l.config), auditLog: l.auditLog}, nil
+}
+
+// auditedConn audits a failed handshake, whether the http server starts it or the first read or write does.
+type auditedConn struct {
+	*tls.Conn
+	auditLog audit.Log
+
+	once sync.Once
+	err  error
+}
+
+func (c *auditedConn) Read(p []byte) (int, error) {
+	if err := c.HandshakeContext(context.Background()); err != nil {
+		return 0, err
+	}
+
+	return c.Conn.Read(p)
+}
+
+func (c *auditedConn) Write(p []byte) (int, error) {
+	if err := c.HandshakeContext(context.Background()); err != nil {
+		return 0, err
+	}
+
+	return c.Conn.Write(p)
+}
+
+func (c *auditedConn) HandshakeContext(ctx context.Context) error {
+	c.once.Do(func() {
+		c.err = c.Conn.HandshakeContext(ctx)
+
+		// Connections closed before saying anything, such as port checks, are not calls.
+		if c.err != nil && c.err != io.EOF {
+			c.auditLog.Record(audit.Entry{
+				API:    "plugin",
+				Action: "tls handshake",
+				Remote: c.RemoteAddr().String(),
+				Reason: c.err.Error()})
+		}
+	})
+
+	return c.err
+}
+
+// spec is the json spec file of a plugin, as read by the Docker daemon.
+type spec struct {
+	Name      string
+	Addr      string
+	TLSConfig specTLS
+}
+
+// specTLS is the certificate the Docker daemon presents to a plugin, and the CA it verifies the plugin with.
+type specTLS struct {
+	InsecureSkipVerify bool
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+}
+
+// WriteSpec tells the Docker daemon to reach the plugin pluginName at address over mutual TLS, verifying it with the
+// CA in caFile and presenting the certificate in certFile and keyFile. The daemon prefers a plain .spec file to a
+// .json one, so the plugin's .spec file, as written when it is served without TLS, is removed.
+func WriteSpec(dir string, pluginName string, address string, caFile string, certFile string, keyFile string) error {
+	contents, err := json.MarshalIndent(spec{
+		Name: pluginName,
+		Addr: "tcp://" + address,
+		TLSConfig: specTLS{
+			CAFile:   caFile,
+			CertFile: certFile,
+			KeyFile:  keyFile}}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(filepath.Join(dir, pluginName+".spec"))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, pluginName+".json"), append(contents, '\n'), 0644)
+}
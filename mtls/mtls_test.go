@@ -0,0 +1,283 @@
+package mtls
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/audit"
+)
+
+// testCA signs the certificates of a test.
+type testCA struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	pem         []byte
+}
+
+func newTestCA(t *testing.T, name string) testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return testCA{certificate: certificate, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue signs a certificate for name, returning its certificate and key pem encoded.
+func (c testCA) issue(t *testing.T, name string, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")}}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, c.certificate, &key.PublicKey, c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// writeFile writes contents to name in dir, returning its path.
+func writeFile(t *testing.T, dir string, name string, contents []byte) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, contents, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// readAudit waits for the audit log at path to hold count entries, returning them.
+func readAudit(t *testing.T, path string, count int) []audit.Entry {
+	var entries []audit.Entry
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		entries = nil
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var entry audit.Entry
+			if err = json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				t.Fatal(err)
+			}
+			entries = append(entries, entry)
+		}
+		file.Close()
+
+		if len(entries) >= count {
+			break
+		}
+	}
+
+	return entries
+}
+
+func TestListener(t *testing.T) {
+	t.Parallel()
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-mtls")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	ca := newTestCA(t, "docker-volume-rdma test ca")
+	serverCert, serverKey := ca.issue(t, "localhost", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.issue(t, "docker", x509.ExtKeyUsageClientAuth)
+	untrustedCert, untrustedKey := newTestCA(t, "untrusted ca").issue(t, "intruder", x509.ExtKeyUsageClientAuth)
+
+	config, err := NewServerConfig(
+		writeFile(t, tempDir, "ca.pem", ca.pem),
+		writeFile(t, tempDir, "server.pem", serverCert),
+		writeFile(t, tempDir, "server-key.pem", serverKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	auditPath := filepath.Join(tempDir, "audit.log")
+	auditLog, err := audit.NewLog(auditPath, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer auditLog.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &http.Server{ErrorLog: log.New(ioutil.Discard, "", 0), Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	})}
+	go server.Serve(NewListener(listener, config, auditLog))
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(ca.pem)
+	get := func(certPEM []byte, keyPEM []byte) error {
+		clientConfig := &tls.Config{RootCAs: roots}
+		if certPEM != nil {
+			certificate, err := tls.X509KeyPair(certPEM, keyPEM)
+			if err != nil {
+				t.Fatal(err)
+			}
+			clientConfig.Certificates = []tls.Certificate{certificate}
+		}
+
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientConfig}, Timeout: 5 * time.Second}
+		response, err := client.Post("https://"+listener.Addr().String()+"/VolumeDriver.List", "application/json", strings.NewReader("{}"))
+		if err != nil {
+			return err
+		}
+		response.Body.Close()
+		return nil
+	}
+
+	if err = get(clientCert, clientKey); err != nil {
+		t.Fatal("A client with a trusted certificate should be served, got ", err)
+	}
+
+	if err = get(nil, nil); err == nil {
+		t.Error("A client without a certificate should be refused")
+	}
+
+	if err = get(untrustedCert, untrustedKey); err == nil {
+		t.Error("A client with a certificate from another ca should be refused")
+	}
+
+	response, err := http.Post("http://"+listener.Addr().String()+"/VolumeDriver.List", "application/json", strings.NewReader("{}"))
+	if err == nil {
+		response.Body.Close()
+		if response.StatusCode == http.StatusOK {
+			t.Error("A client that does not use tls should be refused")
+		}
+	}
+
+	entries := readAudit(t, auditPath, 3)
+	if len(entries) != 3 {
+		t.Fatal("Expected every refused client to be audited, got ", entries)
+	}
+
+	for _, entry := range entries {
+		if entry.Allowed || entry.API != "plugin" || entry.Reason == "" || !strings.HasPrefix(entry.Remote, "127.0.0.1:") {
+			t.Error("Unexpected audit entry: ", entry)
+		}
+	}
+}
+
+func TestNewServerConfig_errors(t *testing.T) {
+	t.Parallel()
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-mtls")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	cert, key := newTestCA(t, "ca").issue(t, "localhost", x509.ExtKeyUsageServerAuth)
+	certFile := writeFile(t, tempDir, "server.pem", cert)
+	keyFile := writeFile(t, tempDir, "server-key.pem", key)
+
+	tests := []struct {
+		ca   string
+		cert string
+		key  string
+	}{
+		{"", certFile, keyFile},
+		{keyFile, certFile, keyFile},
+		{filepath.Join(tempDir, "missing.pem"), certFile, keyFile},
+		{certFile, keyFile, certFile},
+	}
+
+	for _, test := range tests {
+		if _, err = NewServerConfig(test.ca, test.cert, test.key); err == nil {
+			t.Error("Configuring the ca ", test.ca, " and certificate ", test.cert, " should fail")
+		}
+	}
+}
+
+func TestWriteSpec(t *testing.T) {
+	t.Parallel()
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-mtls")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	writeFile(t, tempDir, "rdma.spec", []byte("tcp://localhost:8080"))
+	if err = WriteSpec(tempDir, "rdma", "localhost:8080", "/etc/ca.pem", "/etc/docker.pem", "/etc/docker-key.pem"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = os.Stat(filepath.Join(tempDir, "rdma.spec")); !os.IsNotExist(err) {
+		t.Error("The plain spec file should be removed, as Docker would prefer it")
+	}
+
+	contents, err := ioutil.ReadFile(filepath.Join(tempDir, "rdma.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var written spec
+	if err = json.Unmarshal(contents, &written); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := spec{Name: "rdma", Addr: "tcp://localhost:8080", TLSConfig: specTLS{CAFile: "/etc/ca.pem", CertFile: "/etc/docker.pem", KeyFile: "/etc/docker-key.pem"}}
+	if written != expected {
+		t.Error("Expected ", expected, ", got ", written)
+	}
+}
@@ -0,0 +1,277 @@
+// Package reaper releases mount requests left behind by containers that stopped while docker-volume-rdma was not
+// running, so that their volumes can be unmounted and removed.
+package reaper
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"github.com/mellanox-senior-design/docker-volume-rdma/engine"
+)
+
+// dockerIDPattern matches the mount IDs Docker sends, which are container IDs or random IDs of the same form. Other
+// requesters, such as CSI staging paths or pod UIDs, are not Docker's to reap.
+var dockerIDPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// ContainerLister lists the running containers, it is implemented by engine.Client.
+type ContainerLister interface {
+	Containers() ([]engine.Container, error)
+}
+
+// Release is a mount request that was released.
+type Release struct {
+	Volume string
+	ID     string
+	Count  int
+}
+
+// Reaper compares the mount requests recorded in the volume database with the running containers. A request is stale
+// if its ID is not a running container and no running container uses its volume. Stale requests are only released
+// once they have been found stale twice in a row, by sweeps or reconciles, so that a container that is starting is
+// not mistaken for one that has gone.
+//
+// The IDs Docker mounts volumes with are random, so they can not be matched to the container that made them. A
+// container's volumes are instead found by inspecting it, and tracked until it is destroyed.
+type Reaper struct {
+	Driver     drivers.RDMAVolumeDriver
+	Containers ContainerLister
+	PluginName string
+
+	// suspects holds the requests last found stale, by volume then ID.
+	suspects map[string]map[string]bool
+
+	// tracked holds the volumes of this plugin that each container was seen to use, by container ID.
+	tracked map[string][]string
+	lock    *sync.Mutex
+}
+
+// NewReaper creates a Reaper for the volumes of driver, which Docker knows as the plugin pluginName.
+func NewReaper(driver drivers.RDMAVolumeDriver, containers ContainerLister, pluginName string) Reaper {
+	return Reaper{
+		Driver:     driver,
+		Containers: containers,
+		PluginName: pluginName,
+		suspects:   map[string]map[string]bool{},
+		tracked:    map[string][]string{},
+		lock:       &sync.Mutex{}}
+}
+
+// Run sweeps every interval until stop is closed.
+func (r Reaper) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if _, err := r.Sweep(); err != nil {
+				glog.Error("Unable to reap stale mounts: ", err)
+			}
+		}
+	}
+}
+
+// Sweep releases the requests that were stale on this and the last sweep, returning those it released. Nothing is
+// released if the running containers cannot be listed.
+func (r Reaper) Sweep() ([]Release, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	running, inUse, err := r.running()
+	if err != nil {
+		return nil, err
+	}
+
+	volumes, err := r.Driver.VolumeDatabase.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var released []Release
+	suspects := map[string]map[string]bool{}
+	for _, vol := range volumes {
+		if inUse[vol.Name] {
+			continue
+		}
+
+		mounts, err := r.Driver.VolumeDatabase.Mounts(vol.Name)
+		if err != nil {
+			glog.Error("Unable to list the mounts of ", vol.Name, ": ", err)
+			continue
+		}
+
+		for _, id := range sortedIDs(mounts) {
+			if running[id] || !dockerIDPattern.MatchString(id) {
+				continue
+			}
+
+			if !r.suspects[vol.Name][id] {
+				glog.Info("Mount request ", id, " on ", vol.Name, " has no running container, releasing it on the next sweep.")
+				if suspects[vol.Name] == nil {
+					suspects[vol.Name] = map[string]bool{}
+				}
+				suspects[vol.Name][id] = true
+				continue
+			}
+
+			err = r.Driver.Release(vol.Name, id)
+			if err != nil {
+				glog.Error("Unable to release mount request ", id, " on ", vol.Name, ": ", err)
+				continue
+			}
+
+			glog.Warning("Released ", mounts[id], " stale mount request(s) from ", id, " on ", vol.Name)
+			released = append(released, Release{Volume: vol.Name, ID: id, Count: mounts[id]})
+		}
+	}
+
+	// The suspects map is shared by every copy of the Reaper, so it is refilled rather than replaced.
+	for name := range r.suspects {
+		delete(r.suspects, name)
+	}
+	for name, ids := range suspects {
+		r.suspects[name] = ids
+	}
+
+	return released, nil
+}
+
+// Reconcile checks the requests on a particular volume without waiting for the next sweep, for when Docker has
+// reported that a container using it stopped or that the volume was removed. Like a sweep, it only releases requests
+// that were already found stale, so it is called twice, a little while apart, to release a request. Nothing is
+// released while a running container uses the volume.
+func (r Reaper) Reconcile(volumeName string) ([]Release, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	running, inUse, err := r.running()
+	if err != nil {
+		return nil, err
+	}
+
+	if inUse[volumeName] {
+		delete(r.suspects, volumeName)
+		return nil, nil
+	}
+
+	mounts, err := r.Driver.VolumeDatabase.Mounts(volumeName)
+	if err != nil {
+		return nil, err
+	}
+
+	var released []Release
+	var lastErr error
+	suspects := map[string]bool{}
+	for _, id := range sortedIDs(mounts) {
+		if running[id] || !dockerIDPattern.MatchString(id) {
+			continue
+		}
+
+		if !r.suspects[volumeName][id] {
+			glog.Info("Mount request ", id, " on ", volumeName, " is no longer in use, releasing it if it still is not shortly.")
+			suspects[id] = true
+			continue
+		}
+
+		if err = r.Driver.Release(volumeName, id); err != nil {
+			lastErr = err
+			suspects[id] = true
+			continue
+		}
+
+		glog.Warning("Released ", mounts[id], " mount request(s) from ", id, " on ", volumeName, " as it is no longer in use")
+		released = append(released, Release{Volume: volumeName, ID: id, Count: mounts[id]})
+	}
+
+	if len(suspects) == 0 {
+		delete(r.suspects, volumeName)
+	} else {
+		r.suspects[volumeName] = suspects
+	}
+
+	return released, lastErr
+}
+
+// Track records the volumes of this plugin among the mounts of a container, found by inspecting it, so that they are
+// known once it is destroyed and can no longer be inspected. It returns the names of the volumes.
+func (r Reaper) Track(containerID string, mounts []engine.MountPoint) []string {
+	var volumes []string
+	for _, mount := range mounts {
+		if mount.Type == "volume" && r.IsPlugin(mount.Driver) {
+			volumes = append(volumes, mount.Name)
+		}
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if len(volumes) == 0 {
+		delete(r.tracked, containerID)
+	} else {
+		r.tracked[containerID] = volumes
+	}
+
+	return volumes
+}
+
+// Tracked returns the volumes tracked for a container.
+func (r Reaper) Tracked(containerID string) []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.tracked[containerID]
+}
+
+// Forget returns the volumes tracked for a container that has been destroyed, and stops tracking it.
+func (r Reaper) Forget(containerID string) []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	volumes := r.tracked[containerID]
+	delete(r.tracked, containerID)
+
+	return volumes
+}
+
+// running returns the IDs of the running containers, and the names of the volumes they use from this plugin.
+func (r Reaper) running() (map[string]bool, map[string]bool, error) {
+	containers, err := r.Containers.Containers()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	running := map[string]bool{}
+	inUse := map[string]bool{}
+	for _, container := range containers {
+		running[container.ID] = true
+		for _, mount := range container.Mounts {
+			if mount.Type == "volume" && r.IsPlugin(mount.Driver) {
+				inUse[mount.Name] = true
+			}
+		}
+	}
+
+	return running, inUse, nil
+}
+
+// IsPlugin reports whether a volume driver named by Docker is this plugin, managed plugins are named with a tag.
+func (r Reaper) IsPlugin(driver string) bool {
+	return driver == r.PluginName || strings.TrimSuffix(driver, ":latest") == r.PluginName
+}
+
+func sortedIDs(mounts map[string]int) []string {
+	ids := make([]string, 0, len(mounts))
+	for id := range mounts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
@@ -0,0 +1,267 @@
+package reaper
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/db"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"github.com/mellanox-senior-design/docker-volume-rdma/engine"
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
+)
+
+// fakeContainers lists a fixed set of running containers.
+type fakeContainers struct {
+	containers []engine.Container
+	err        error
+}
+
+func (f *fakeContainers) Containers() ([]engine.Container, error) {
+	return f.containers, f.err
+}
+
+// dockerID returns a 64 character ID, like those Docker mounts volumes with.
+func dockerID(prefix string) string {
+	return prefix + strings.Repeat("0", 64-len(prefix))
+}
+
+func newTestReaper(t *testing.T) (Reaper, *fakeContainers, string) {
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-reaper")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+
+	driver := drivers.NewRDMAVolumeDriver(drivers.NewOnDiskStorageController(path.Join(tempDir, "volumes")), db.NewInMemoryVolumeDatabase())
+	err = driver.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mounts := []struct {
+		volume string
+		id     string
+	}{
+		{"stale", dockerID("dead")},
+		{"stale", dockerID("dead")},
+		{"stale", dockerID("a1")},
+		{"shared", dockerID("b2")},
+		{"csi", "csi-stage:node1:/var/lib/kubelet/staging"},
+	}
+
+	for _, mount := range mounts {
+		if _, err := driver.VolumeDatabase.Get(mount.volume); err != nil {
+			driver.Create(volume.Request{Name: mount.volume})
+		}
+
+		if response := driver.Mount(volume.MountRequest{Name: mount.volume, ID: mount.id}); response.Err != "" {
+			t.Fatal(response.Err)
+		}
+	}
+
+	containers := &fakeContainers{containers: []engine.Container{
+		{ID: dockerID("a1")},
+		{ID: dockerID("c3"), Mounts: []engine.MountPoint{{Type: "volume", Name: "shared", Driver: "rdma:latest"}}},
+	}}
+
+	return NewReaper(driver, containers, "rdma"), containers, tempDir
+}
+
+func TestSweep(t *testing.T) {
+	t.Parallel()
+	reaper, _, tempDir := newTestReaper(t)
+	defer os.RemoveAll(tempDir)
+
+	// The first sweep only notes the stale request.
+	released, err := reaper.Sweep()
+	if err != nil || len(released) != 0 {
+		t.Fatal("Nothing should be released on the first sweep, got ", released, err)
+	}
+
+	released, err = reaper.Sweep()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(released) != 1 || released[0] != (Release{Volume: "stale", ID: dockerID("dead"), Count: 2}) {
+		t.Fatal("Only the dead container's requests should be released, got ", released)
+	}
+
+	var tests = []struct {
+		volume string
+		id     string
+	}{
+		{"stale", dockerID("a1")},
+		{"shared", dockerID("b2")},
+		{"csi", "csi-stage:node1:/var/lib/kubelet/staging"},
+	}
+
+	for _, test := range tests {
+		mounts, err := reaper.Driver.VolumeDatabase.Mounts(test.volume)
+		if err != nil || mounts[test.id] != 1 {
+			t.Error(test.id, " on ", test.volume, " should not be released, got ", mounts, err)
+		}
+	}
+}
+
+func TestSweep_restarted(t *testing.T) {
+	t.Parallel()
+	reaper, containers, tempDir := newTestReaper(t)
+	defer os.RemoveAll(tempDir)
+
+	if _, err := reaper.Sweep(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The container came back before the next sweep.
+	containers.containers = append(containers.containers, engine.Container{ID: dockerID("dead")})
+
+	released, err := reaper.Sweep()
+	if err != nil || len(released) != 0 {
+		t.Error("A running container's requests should not be released, got ", released, err)
+	}
+}
+
+func TestSweep_engineError(t *testing.T) {
+	t.Parallel()
+	reaper, containers, tempDir := newTestReaper(t)
+	defer os.RemoveAll(tempDir)
+
+	reaper.Sweep()
+	containers.err = errors.New("docker is not running")
+
+	released, err := reaper.Sweep()
+	if err == nil || len(released) != 0 {
+		t.Error("Nothing should be released when the containers cannot be listed, got ", released, err)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	t.Parallel()
+	reaper, containers, tempDir := newTestReaper(t)
+	defer os.RemoveAll(tempDir)
+
+	// The running container using the shared volume protects its requests.
+	for check := 0; check < 2; check++ {
+		released, err := reaper.Reconcile("shared")
+		if err != nil || len(released) != 0 {
+			t.Error("Nothing should be released while the volume is in use, got ", released, err)
+		}
+	}
+
+	// The first check only notes the stale requests, no sweep is needed to release them on the second.
+	released, err := reaper.Reconcile("stale")
+	if err != nil || len(released) != 0 {
+		t.Error("Nothing should be released on the first check, got ", released, err)
+	}
+
+	released, err = reaper.Reconcile("stale")
+	if err != nil || len(released) != 1 || released[0].ID != dockerID("dead") {
+		t.Error("The dead container's requests should be released, got ", released, err)
+	}
+
+	containers.containers = nil
+	reaper.Reconcile("shared")
+	released, err = reaper.Reconcile("shared")
+	if err != nil || len(released) != 1 {
+		t.Error("The shared volume's request should be released once no container uses it, got ", released, err)
+	}
+}
+
+func TestReconcile_starting(t *testing.T) {
+	t.Parallel()
+	reaper, containers, tempDir := newTestReaper(t)
+	defer os.RemoveAll(tempDir)
+
+	// The request of a container that is starting is recorded before Docker lists the container as running.
+	containers.containers = nil
+	released, err := reaper.Reconcile("shared")
+	if err != nil || len(released) != 0 {
+		t.Fatal("Nothing should be released on the first check, got ", released, err)
+	}
+
+	containers.containers = []engine.Container{{ID: dockerID("c3"), Mounts: []engine.MountPoint{{Type: "volume", Name: "shared", Driver: "rdma"}}}}
+	released, err = reaper.Reconcile("shared")
+	if err != nil || len(released) != 0 {
+		t.Fatal("The started container's request should not be released, got ", released, err)
+	}
+
+	// It stops again, so the earlier check no longer counts.
+	containers.containers = nil
+	released, err = reaper.Reconcile("shared")
+	if err != nil || len(released) != 0 {
+		t.Error("A request should be found stale twice in a row before it is released, got ", released, err)
+	}
+}
+
+func TestTrack(t *testing.T) {
+	t.Parallel()
+	reaper, _, tempDir := newTestReaper(t)
+	defer os.RemoveAll(tempDir)
+
+	mounts := []engine.MountPoint{
+		{Type: "volume", Name: "stale", Driver: "rdma:latest"},
+		{Type: "volume", Name: "other", Driver: "local"},
+		{Type: "bind", Destination: "/data"},
+	}
+
+	volumes := reaper.Track(dockerID("a1"), mounts)
+	if len(volumes) != 1 || volumes[0] != "stale" {
+		t.Error("Only the plugin's volumes should be tracked, got ", volumes)
+	}
+
+	if volumes = reaper.Tracked(dockerID("a1")); len(volumes) != 1 {
+		t.Error("The container's volumes should be tracked, got ", volumes)
+	}
+
+	if volumes = reaper.Forget(dockerID("a1")); len(volumes) != 1 || volumes[0] != "stale" {
+		t.Error("Forget should return the container's volumes, got ", volumes)
+	}
+
+	if volumes = reaper.Tracked(dockerID("a1")); len(volumes) != 0 {
+		t.Error("A forgotten container should not be tracked, got ", volumes)
+	}
+}
+
+func TestSweep_dockerEngine(t *testing.T) {
+	t.Parallel()
+	reaper, _, tempDir := newTestReaper(t)
+	defer os.RemoveAll(tempDir)
+
+	// Serve the Engine API's container list on a unix socket, as the Docker daemon does.
+	socket := path.Join(tempDir, "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `[{"Id": %q, "State": "running"}]`, dockerID("a1"))
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	reaper.Containers, err = engine.NewClient("unix://" + socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reaper.Sweep()
+	released, err := reaper.Sweep()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without the container using it, the shared volume's request is stale too.
+	if len(released) != 2 || released[0].Volume == released[1].Volume {
+		t.Error("Expected the shared and dead requests to be released, got ", released)
+	}
+}
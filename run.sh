@@ -5,4 +5,4 @@ if [ -z "$GOPATH" ]; then
 fi
 
 cd "$GOPATH/src/github.com/mellanox-senior-design/docker-volume-rdma" || exit 1
-go run main.go -logtostderr=true "$@"
+go install && "$GOPATH/bin/docker-volume-rdma" -logtostderr=true "$@"
@@ -0,0 +1,244 @@
+// Package throttle limits the I/O that containers can do on the volumes they use, writing the limits requested when
+// each volume was created to the io.max file of each container's cgroup v2.
+package throttle
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/golang/glog"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"github.com/mellanox-senior-design/docker-volume-rdma/engine"
+)
+
+// DefaultCgroupRoot is where the cgroup v2 hierarchy is mounted on most hosts.
+const DefaultCgroupRoot = "/sys/fs/cgroup"
+
+// ContainerInspector finds the running containers, their volumes and their processes, it is implemented by
+// engine.Client.
+type ContainerInspector interface {
+	Containers() ([]engine.Container, error)
+	ContainerPid(id string) (int, error)
+}
+
+// Throttler applies the I/O limits of volumes to the containers that mount them. A container's cgroup only exists
+// once it has started, which is after Docker has asked for its volumes to be mounted, so limits are applied when
+// Docker reports that the container started and again whenever docker-volume-rdma starts.
+type Throttler struct {
+	Driver     drivers.RDMAVolumeDriver
+	Containers ContainerInspector
+	PluginName string
+	CgroupRoot string
+	ProcRoot   string
+
+	// Device returns the major:minor number of the block device that a mountpoint is on.
+	Device func(mountpoint string) (string, error)
+}
+
+// NewThrottler creates a Throttler for the volumes of driver, which Docker knows as pluginName, writing to the cgroup
+// hierarchy at cgroupRoot.
+func NewThrottler(driver drivers.RDMAVolumeDriver, containers ContainerInspector, pluginName string, cgroupRoot string) Throttler {
+	if cgroupRoot == "" {
+		cgroupRoot = DefaultCgroupRoot
+	}
+
+	return Throttler{
+		Driver:     driver,
+		Containers: containers,
+		PluginName: pluginName,
+		CgroupRoot: cgroupRoot,
+		ProcRoot:   "/proc",
+		Device:     blockDevice}
+}
+
+// ApplyContainer limits the I/O of a container on every limited volume among volumes, the names of the volumes of
+// this plugin found by inspecting it. The mount IDs Docker sends are random, so they can not name the container.
+func (t Throttler) ApplyContainer(id string, volumes []string) error {
+	limited, err := t.limitedVolumes()
+	if err != nil {
+		return err
+	}
+
+	for _, volumeName := range volumes {
+		limits, ok := limited[volumeName]
+		if !ok {
+			continue
+		}
+
+		err = t.Apply(volumeName, id, limits)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ApplyRunning limits the I/O of every running container on the limited volumes of this plugin that it uses, for
+// when docker-volume-rdma restarts.
+func (t Throttler) ApplyRunning() error {
+	limited, err := t.limitedVolumes()
+	if err != nil {
+		return err
+	}
+
+	containers, err := t.Containers.Containers()
+	if err != nil {
+		return err
+	}
+
+	for _, container := range containers {
+		for _, mount := range container.Mounts {
+			limits, ok := limited[mount.Name]
+			if !ok || mount.Type != "volume" || !t.isPlugin(mount.Driver) {
+				continue
+			}
+
+			if err = t.Apply(mount.Name, container.ID, limits); err != nil {
+				glog.Warning("Unable to limit the I/O of ", container.ID, " on ", mount.Name, ": ", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Apply writes the I/O limits of a volume to the cgroup of the container with id. Limits that are not set are
+// removed, so that changed limits take effect.
+func (t Throttler) Apply(volumeName string, id string, limits drivers.IOLimits) error {
+	mountpoint, err := t.Driver.VolumeDatabase.Path(volumeName)
+	if err != nil {
+		return err
+	}
+
+	if mountpoint == "" {
+		return errors.New("volume " + volumeName + " is not mounted")
+	}
+
+	device, err := t.Device(mountpoint)
+	if err != nil {
+		return err
+	}
+
+	pid, err := t.Containers.ContainerPid(id)
+	if err != nil {
+		return err
+	}
+
+	cgroup, err := t.cgroup(pid)
+	if err != nil {
+		return err
+	}
+
+	line := device + " " + ioMax(limits)
+	glog.Info("Limiting the I/O of ", id, " on ", volumeName, " to ", line)
+
+	file, err := os.OpenFile(filepath.Join(t.CgroupRoot, cgroup, "io.max"), os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.WriteString(line + "\n")
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// limitedVolumes returns the I/O limits of every volume that has any.
+func (t Throttler) limitedVolumes() (map[string]drivers.IOLimits, error) {
+	volumes, err := t.Driver.VolumeDatabase.List()
+	if err != nil {
+		return nil, err
+	}
+
+	limited := map[string]drivers.IOLimits{}
+	for _, vol := range volumes {
+		options, err := t.Driver.VolumeDatabase.Options(vol.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		limits, err := drivers.ParseIOLimits(options)
+		if err != nil {
+			glog.Error("Ignoring the I/O limits of ", vol.Name, ": ", err)
+			continue
+		}
+
+		if !limits.IsZero() {
+			limited[vol.Name] = limits
+		}
+	}
+
+	return limited, nil
+}
+
+// isPlugin reports whether a volume driver named by Docker is this plugin, managed plugins are named with a tag.
+func (t Throttler) isPlugin(driver string) bool {
+	return driver == t.PluginName || strings.TrimSuffix(driver, ":latest") == t.PluginName
+}
+
+// cgroup returns the path, within the cgroup v2 hierarchy, of the cgroup a process is in.
+func (t Throttler) cgroup(pid int) (string, error) {
+	file, err := os.Open(filepath.Join(t.ProcRoot, strconv.Itoa(pid), "cgroup"))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	// The unified hierarchy is listed with the hierarchy ID 0 and no controllers.
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "0::") {
+			return strings.TrimPrefix(scanner.Text(), "0::"), nil
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return "", err
+	}
+
+	return "", errors.New("process " + strconv.Itoa(pid) + " is not in a cgroup v2 hierarchy, I/O limits require cgroup v2")
+}
+
+// ioMax formats limits as the keys of a line of io.max.
+func ioMax(limits drivers.IOLimits) string {
+	rate := func(value int64) string {
+		if value == 0 {
+			return "max"
+		}
+		return strconv.FormatInt(value, 10)
+	}
+
+	return "rbps=" + rate(limits.ReadBPS) + " wbps=" + rate(limits.WriteBPS) + " riops=" + rate(limits.ReadIOPS) + " wiops=" + rate(limits.WriteIOPS)
+}
+
+// blockDevice returns the major:minor number of the device that a mountpoint's filesystem is on. Filesystems that are
+// not on a block device, such as tmpfs, have a major number of 0 and can not be limited.
+func blockDevice(mountpoint string) (string, error) {
+	info, err := os.Stat(mountpoint)
+	if err != nil {
+		return "", err
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", errors.New("unable to find the device of " + mountpoint)
+	}
+
+	// Decoded as glibc's major and minor macros do, as Linux splits both numbers across the device number.
+	dev := uint64(stat.Dev)
+	major := (dev>>8)&0xfff | (dev>>32)&0xfffff000
+	minor := dev&0xff | (dev>>12)&0xffffff00
+	if major == 0 {
+		return "", errors.New(mountpoint + " is not on a block device, its I/O can not be limited")
+	}
+
+	return strconv.FormatUint(major, 10) + ":" + strconv.FormatUint(minor, 10), nil
+}
@@ -0,0 +1,167 @@
+package throttle
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mellanox-senior-design/docker-volume-rdma/db"
+	"github.com/mellanox-senior-design/docker-volume-rdma/drivers"
+	"github.com/mellanox-senior-design/docker-volume-rdma/engine"
+	"github.com/mellanox-senior-design/docker-volume-rdma/volume"
+)
+
+// fakeContainers are the running containers, by ID, with their pids and mounts.
+type fakeContainers struct {
+	pids   map[string]int
+	mounts map[string][]engine.MountPoint
+}
+
+func (f fakeContainers) Containers() ([]engine.Container, error) {
+	var containers []engine.Container
+	for id := range f.pids {
+		containers = append(containers, engine.Container{ID: id, Mounts: f.mounts[id]})
+	}
+	return containers, nil
+}
+
+func (f fakeContainers) ContainerPid(id string) (int, error) {
+	pid, running := f.pids[id]
+	if !running {
+		return 0, errors.New("container " + id + " is not running")
+	}
+	return pid, nil
+}
+
+// newTestThrottler creates a Throttler on a fake host in a temporary directory, where container1 is running in a
+// cgroup v2 and the container "v1" is not.
+func newTestThrottler(t *testing.T, mounts map[string][]engine.MountPoint) (Throttler, string) {
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-throttle")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+
+	cgroup := "/system.slice/docker-" + strings.Repeat("1", 64) + ".scope"
+	for name, contents := range map[string]string{
+		"proc/100/cgroup":                  "0::" + cgroup + "\n",
+		"proc/200/cgroup":                  "12:blkio:/docker\n",
+		"cgroup" + cgroup + "/io.max":      "",
+		"cgroup/system.slice/other/io.max": "",
+	} {
+		name = filepath.Join(tempDir, name)
+		if err = os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(name, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	containers := fakeContainers{map[string]int{strings.Repeat("1", 64): 100, "v1": 200}, mounts}
+	driver := drivers.NewRDMAVolumeDriver(drivers.NewOnDiskStorageController(filepath.Join(tempDir, "volumes")), db.NewInMemoryVolumeDatabase())
+	throttler := NewThrottler(driver, containers, "rdma", filepath.Join(tempDir, "cgroup"))
+	throttler.ProcRoot = filepath.Join(tempDir, "proc")
+	throttler.Device = func(mountpoint string) (string, error) {
+		return "253:7", nil
+	}
+
+	return throttler, tempDir
+}
+
+func TestApplyContainer(t *testing.T) {
+	t.Parallel()
+	throttler, tempDir := newTestThrottler(t, nil)
+	defer os.RemoveAll(tempDir)
+
+	container1 := strings.Repeat("1", 64)
+	container2 := strings.Repeat("2", 64)
+	driver := throttler.Driver
+	driver.Create(volume.Request{Name: "limited", Options: map[string]string{drivers.ReadIOPSOption: "500", drivers.WriteBPSOption: "10M"}})
+	driver.Create(volume.Request{Name: "unlimited"})
+
+	// Docker mounts volumes with random IDs, not the IDs of the containers.
+	for i, name := range []string{"limited", "unlimited"} {
+		if response := driver.Mount(volume.MountRequest{Name: name, ID: strings.Repeat(string(rune('a'+i)), 64)}); response.Err != "" {
+			t.Fatal(response.Err)
+		}
+	}
+
+	err := throttler.ApplyContainer(container1, []string{"limited", "unlimited"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ioMax, err := ioutil.ReadFile(filepath.Join(tempDir, "cgroup/system.slice/docker-"+container1+".scope/io.max"))
+	if err != nil || string(ioMax) != "253:7 rbps=max wbps="+strconv.Itoa(10<<20)+" riops=500 wiops=max\n" {
+		t.Error("Expected the volume's limits to be written to the container's io.max, got ", string(ioMax), err)
+	}
+
+	// Containers without limited volumes are left alone, even if they are not running.
+	if err = throttler.ApplyContainer(container2, []string{"unlimited", "unknown"}); err != nil {
+		t.Error(err)
+	}
+
+	if err = throttler.ApplyContainer(container2, []string{"limited"}); err == nil {
+		t.Error("Limiting a container that is not running should fail")
+	}
+
+	// Processes outside of a cgroup v2 can not be limited.
+	if err = throttler.ApplyContainer("v1", []string{"limited"}); err == nil || !strings.Contains(err.Error(), "cgroup v2") {
+		t.Error("Expected cgroup v2 to be required, got ", err)
+	}
+}
+
+func TestApplyRunning(t *testing.T) {
+	t.Parallel()
+	container1 := strings.Repeat("1", 64)
+	throttler, tempDir := newTestThrottler(t, map[string][]engine.MountPoint{
+		container1: {
+			{Type: "volume", Name: "limited", Driver: "rdma:latest", Destination: "/data"},
+			{Type: "bind", Name: "", Destination: "/etc"},
+		},
+		// A local volume with the same name, which would fail to be limited as "v1" is not in a cgroup v2.
+		"v1": {{Type: "volume", Name: "limited", Driver: "local", Destination: "/data"}},
+	})
+	defer os.RemoveAll(tempDir)
+
+	driver := throttler.Driver
+	driver.Create(volume.Request{Name: "limited", Options: map[string]string{drivers.ReadBPSOption: "1k"}})
+	driver.Mount(volume.MountRequest{Name: "limited", ID: strings.Repeat("a", 64)})
+
+	err := throttler.ApplyRunning()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ioMax, err := ioutil.ReadFile(filepath.Join(tempDir, "cgroup/system.slice/docker-"+container1+".scope/io.max"))
+	if err != nil || string(ioMax) != "253:7 rbps=1024 wbps=max riops=max wiops=max\n" {
+		t.Error("Expected the volume's limits to be reapplied, got ", string(ioMax), err)
+	}
+}
+
+func TestIsPlugin(t *testing.T) {
+	t.Parallel()
+	throttler := Throttler{PluginName: "rdma"}
+
+	for driver, expected := range map[string]bool{"rdma": true, "rdma:latest": true, "local": false, "rdma:v2": false} {
+		if throttler.isPlugin(driver) != expected {
+			t.Error("Expected isPlugin(", driver, ") to be ", expected)
+		}
+	}
+}
+
+func TestBlockDevice(t *testing.T) {
+	t.Parallel()
+
+	if _, err := blockDevice("/proc"); err == nil {
+		t.Error("Filesystems that are not on a block device can not be limited")
+	}
+
+	if _, err := blockDevice("/does/not/exist"); err == nil {
+		t.Error("A mountpoint that does not exist has no device")
+	}
+}
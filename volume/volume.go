@@ -0,0 +1,150 @@
+// Package volume holds the requests and responses of the Docker volume plugin protocol as the drivers handle them, one
+// method per endpoint with errors returned in the response, and serves a Driver with the handler of
+// go-plugins-helpers. The requests and responses are encoded as the protocol defines them, whichever side decodes them.
+package volume
+
+import (
+	"errors"
+
+	"github.com/docker/go-plugins-helpers/volume"
+)
+
+// Request is a request to one of the endpoints that take a volume's name, and its options on create.
+type Request struct {
+	Name    string
+	Options map[string]string `json:"Opts,omitempty"`
+}
+
+// MountRequest is a request to mount a volume, ID identifies the request so that it can be unmounted.
+type MountRequest struct {
+	Name string
+	ID   string
+}
+
+// UnmountRequest is a request to unmount a volume, releasing the mount request with ID.
+type UnmountRequest struct {
+	Name string
+	ID   string
+}
+
+// Response is the response to every endpoint, with Err set if the request failed.
+type Response struct {
+	Mountpoint   string
+	Err          string
+	Volumes      []*Volume
+	Volume       *Volume
+	Capabilities Capability
+}
+
+// Volume is a volume listed or inspected by Docker.
+type Volume struct {
+	Name       string
+	Mountpoint string
+	Status     map[string]interface{} `json:",omitempty"`
+}
+
+// Capability is the scope of the volumes of a driver, local or global.
+type Capability struct {
+	Scope string
+}
+
+// Driver is implemented by volume drivers, and by clients of a driver served elsewhere.
+type Driver interface {
+	Create(Request) Response
+	List(Request) Response
+	Get(Request) Response
+	Remove(Request) Response
+	Path(Request) Response
+	Mount(MountRequest) Response
+	Unmount(UnmountRequest) Response
+	Capabilities(Request) Response
+}
+
+// Handler serves the Docker volume plugin protocol.
+type Handler = volume.Handler
+
+// NewHandler creates a Handler that serves driver.
+func NewHandler(driver Driver) *Handler {
+	return volume.NewHandler(pluginDriver{driver})
+}
+
+// pluginDriver adapts a Driver to the typed requests and responses of go-plugins-helpers.
+type pluginDriver struct {
+	driver Driver
+}
+
+func (p pluginDriver) Create(request *volume.CreateRequest) error {
+	return responseError(p.driver.Create(Request{Name: request.Name, Options: request.Options}))
+}
+
+func (p pluginDriver) List() (*volume.ListResponse, error) {
+	response := p.driver.List(Request{})
+	if err := responseError(response); err != nil {
+		return nil, err
+	}
+
+	volumes := make([]*volume.Volume, 0, len(response.Volumes))
+	for _, vol := range response.Volumes {
+		volumes = append(volumes, pluginVolume(vol))
+	}
+
+	return &volume.ListResponse{Volumes: volumes}, nil
+}
+
+func (p pluginDriver) Get(request *volume.GetRequest) (*volume.GetResponse, error) {
+	response := p.driver.Get(Request{Name: request.Name})
+	if err := responseError(response); err != nil {
+		return nil, err
+	}
+
+	return &volume.GetResponse{Volume: pluginVolume(response.Volume)}, nil
+}
+
+func (p pluginDriver) Remove(request *volume.RemoveRequest) error {
+	return responseError(p.driver.Remove(Request{Name: request.Name}))
+}
+
+func (p pluginDriver) Path(request *volume.PathRequest) (*volume.PathResponse, error) {
+	response := p.driver.Path(Request{Name: request.Name})
+	if err := responseError(response); err != nil {
+		return nil, err
+	}
+
+	return &volume.PathResponse{Mountpoint: response.Mountpoint}, nil
+}
+
+func (p pluginDriver) Mount(request *volume.MountRequest) (*volume.MountResponse, error) {
+	response := p.driver.Mount(MountRequest{Name: request.Name, ID: request.ID})
+	if err := responseError(response); err != nil {
+		return nil, err
+	}
+
+	return &volume.MountResponse{Mountpoint: response.Mountpoint}, nil
+}
+
+func (p pluginDriver) Unmount(request *volume.UnmountRequest) error {
+	return responseError(p.driver.Unmount(UnmountRequest{Name: request.Name, ID: request.ID}))
+}
+
+func (p pluginDriver) Capabilities() *volume.CapabilitiesResponse {
+	response := p.driver.Capabilities(Request{})
+	return &volume.CapabilitiesResponse{Capabilities: volume.Capability{Scope: response.Capabilities.Scope}}
+}
+
+// responseError returns the error in a response, if any.
+func responseError(response Response) error {
+	if response.Err != "" {
+		return errors.New(response.Err)
+	}
+
+	return nil
+}
+
+// pluginVolume converts a volume to the volume of go-plugins-helpers.
+func pluginVolume(vol *Volume) *volume.Volume {
+	if vol == nil {
+		return nil
+	}
+
+	return &volume.Volume{Name: vol.Name, Mountpoint: vol.Mountpoint, Status: vol.Status}
+}
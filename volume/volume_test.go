@@ -0,0 +1,117 @@
+package volume
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// fakeDriver holds mountpoints of volumes by name.
+type fakeDriver map[string]string
+
+func (f fakeDriver) Create(request Request) Response {
+	if request.Options["fail"] != "" {
+		return Response{Err: request.Options["fail"]}
+	}
+	f[request.Name] = "/mnt/" + request.Name
+	return Response{}
+}
+
+func (f fakeDriver) List(request Request) Response {
+	var volumes []*Volume
+	for name, mountpoint := range f {
+		volumes = append(volumes, &Volume{Name: name, Mountpoint: mountpoint})
+	}
+	return Response{Volumes: volumes}
+}
+
+func (f fakeDriver) Get(request Request) Response {
+	mountpoint, exists := f[request.Name]
+	if !exists {
+		return Response{Err: "volume " + request.Name + " does not exist"}
+	}
+	return Response{Volume: &Volume{Name: request.Name, Mountpoint: mountpoint, Status: map[string]interface{}{"Size": "1G"}}}
+}
+
+func (f fakeDriver) Remove(request Request) Response {
+	delete(f, request.Name)
+	return Response{}
+}
+
+func (f fakeDriver) Path(request Request) Response {
+	return Response{Mountpoint: f[request.Name]}
+}
+
+func (f fakeDriver) Mount(request MountRequest) Response {
+	if request.ID == "" {
+		return Response{Err: "a mount ID is required"}
+	}
+	return Response{Mountpoint: f[request.Name]}
+}
+
+func (f fakeDriver) Unmount(request UnmountRequest) Response {
+	return Response{}
+}
+
+func (f fakeDriver) Capabilities(request Request) Response {
+	return Response{Capabilities: Capability{Scope: "global"}}
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go NewHandler(fakeDriver{}).Serve(listener)
+	url := "http://" + listener.Addr().String()
+
+	call := func(endpoint string, body string) (int, Response) {
+		httpResponse, err := http.Post(url+endpoint, "application/vnd.docker.plugins.v1.2+json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer httpResponse.Body.Close()
+
+		var response Response
+		if err = json.NewDecoder(httpResponse.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+		return httpResponse.StatusCode, response
+	}
+
+	if status, response := call("/VolumeDriver.Create", `{"Name": "vol1", "Opts": {"size": "1G"}}`); status != http.StatusOK || response.Err != "" {
+		t.Error("Expected the volume to be created, got ", status, response)
+	}
+
+	if status, response := call("/VolumeDriver.Create", `{"Name": "vol2", "Opts": {"fail": "no space left"}}`); status == http.StatusOK || response.Err != "no space left" {
+		t.Error("Expected the driver's error to be returned, got ", status, response)
+	}
+
+	if _, response := call("/VolumeDriver.Get", `{"Name": "vol1"}`); response.Volume == nil || response.Volume.Mountpoint != "/mnt/vol1" || response.Volume.Status["Size"] != "1G" {
+		t.Error("Expected the volume to be inspected, got ", response)
+	}
+
+	if _, response := call("/VolumeDriver.List", `{}`); len(response.Volumes) != 1 || response.Volumes[0].Name != "vol1" {
+		t.Error("Expected the volume to be listed, got ", response)
+	}
+
+	if _, response := call("/VolumeDriver.Mount", `{"Name": "vol1", "ID": "abc"}`); response.Mountpoint != "/mnt/vol1" || response.Err != "" {
+		t.Error("Expected the volume to be mounted, got ", response)
+	}
+
+	if status, response := call("/VolumeDriver.Mount", `{"Name": "vol1"}`); status == http.StatusOK || response.Err == "" {
+		t.Error("Expected a mount without an ID to fail, got ", status, response)
+	}
+
+	if _, response := call("/VolumeDriver.Capabilities", `{}`); response.Capabilities.Scope != "global" {
+		t.Error("Expected the driver's scope, got ", response)
+	}
+
+	if status, response := call("/VolumeDriver.Remove", `{"Name": "vol1"}`); status != http.StatusOK || response.Err != "" {
+		t.Error("Expected the volume to be removed, got ", status, response)
+	}
+}
@@ -0,0 +1,141 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// deadDir is the directory of an outbox that deliveries are moved to once they run out of attempts.
+const deadDir = "dead"
+
+// Delivery is an event waiting to be delivered to an endpoint.
+type Delivery struct {
+	// ID orders deliveries by when their event happened, and names the delivery's file in the outbox.
+	ID       string
+	Endpoint string
+	Event    Event
+
+	Attempts    int
+	NextAttempt time.Time
+	LastErr     string `json:",omitempty"`
+}
+
+// Outbox keeps deliveries in a directory, one json file each, until they are delivered, so that events are not lost
+// when an endpoint is down or the daemon restarts.
+type Outbox struct {
+	Dir string
+}
+
+// NewOutbox creates an Outbox in dir, creating the directory if needed.
+func NewOutbox(dir string) (Outbox, error) {
+	if dir == "" {
+		return Outbox{}, errors.New("the webhook outbox needs a directory")
+	}
+
+	err := os.MkdirAll(filepath.Join(dir, deadDir), 0700)
+	if err != nil {
+		return Outbox{}, err
+	}
+
+	return Outbox{Dir: dir}, nil
+}
+
+// Add stores a delivery, replacing any earlier version of it. The delivery is written to a temporary file and
+// renamed, so that a crash never leaves half of one behind.
+func (o Outbox) Add(delivery Delivery) error {
+	if delivery.ID == "" || strings.ContainsAny(delivery.ID, "/.") {
+		return errors.New("invalid delivery ID: " + delivery.ID)
+	}
+
+	contents, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+
+	file, err := ioutil.TempFile(o.Dir, ".delivery-")
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(contents)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), o.path(delivery.ID))
+	}
+	if err != nil {
+		os.Remove(file.Name())
+	}
+
+	return err
+}
+
+// Update stores the attempts made at a delivery.
+func (o Outbox) Update(delivery Delivery) error {
+	return o.Add(delivery)
+}
+
+// Pending returns the deliveries in the outbox, oldest first. Files that can not be read are skipped.
+func (o Outbox) Pending() ([]Delivery, error) {
+	entries, err := ioutil.ReadDir(o.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := []Delivery{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(filepath.Join(o.Dir, entry.Name()))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var delivery Delivery
+		if err = json.Unmarshal(contents, &delivery); err != nil {
+			glog.Warning("Skipping ", entry.Name(), " in the webhook outbox, it can not be parsed: ", err)
+			continue
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].ID < deliveries[j].ID })
+	return deliveries, nil
+}
+
+// Remove drops a delivery that was delivered.
+func (o Outbox) Remove(delivery Delivery) error {
+	err := os.Remove(o.path(delivery.ID))
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+// Bury moves a delivery that ran out of attempts aside, where it is kept for an operator to inspect.
+func (o Outbox) Bury(delivery Delivery) error {
+	return os.Rename(o.path(delivery.ID), filepath.Join(o.Dir, deadDir, delivery.ID+".json"))
+}
+
+// path returns the path of a delivery's file.
+func (o Outbox) path(id string) string {
+	return filepath.Join(o.Dir, id+".json")
+}
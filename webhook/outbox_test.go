@@ -0,0 +1,66 @@
+package webhook
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOutbox(t *testing.T) {
+	t.Parallel()
+	outboxDir, cleanUp := newTestOutboxDir(t)
+	defer cleanUp()
+
+	outbox, err := NewOutbox(outboxDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := Event{ID: "e1", Type: "volume.created", Volume: "vol1", Time: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	for _, id := range []string{"2", "1", "3"} {
+		if err = outbox.Add(Delivery{ID: id, Endpoint: "http://localhost:9000", Event: event}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = outbox.Add(Delivery{ID: "../escape"}); err == nil {
+		t.Error("Delivery IDs that are not file names should fail")
+	}
+
+	// Files that are not deliveries, or can not be parsed, are skipped.
+	if err = ioutil.WriteFile(filepath.Join(outboxDir, "4.json"), []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err := outbox.Pending()
+	if err != nil || len(pending) != 3 || pending[0].ID != "1" || pending[2].ID != "3" || pending[0].Event != event {
+		t.Fatal("Expected three deliveries in order, got ", pending, err)
+	}
+
+	pending[0].Attempts = 2
+	if err = outbox.Update(pending[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = outbox.Remove(pending[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = outbox.Remove(pending[1]); err != nil {
+		t.Error("Removing a delivery twice should succeed, got ", err)
+	}
+
+	if err = outbox.Bury(pending[2]); err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err = outbox.Pending()
+	if err != nil || len(pending) != 1 || pending[0].Attempts != 2 {
+		t.Error("Expected only the updated delivery to be pending, got ", pending, err)
+	}
+
+	if _, err = NewOutbox(""); err == nil {
+		t.Error("An outbox without a directory should fail")
+	}
+}
@@ -0,0 +1,332 @@
+// Package webhook tells other systems about the lifecycle of volumes, posting a signed json event to each configured
+// endpoint when a volume is created, first mounted, last unmounted or removed. Events are kept in an outbox on disk
+// until they are delivered, and retried with backoff while an endpoint is down.
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// The headers of each delivery. The signature is "sha256=" followed by the hex encoded HMAC-SHA256 of the body, keyed
+// with the endpoint's secret.
+const (
+	SignatureHeader = "X-Docker-Volume-RDMA-Signature"
+	EventHeader     = "X-Docker-Volume-RDMA-Event"
+	DeliveryHeader  = "X-Docker-Volume-RDMA-Delivery"
+)
+
+// Defaults of a Notifier, an endpoint that is down is retried for a little over two days before its deliveries are
+// given up on.
+const (
+	DefaultTimeout     = 10 * time.Second
+	DefaultMinBackoff  = time.Second
+	DefaultMaxBackoff  = 10 * time.Minute
+	DefaultMaxAttempts = 300
+	DefaultOutboxDir   = "/var/lib/docker-volume-rdma/outbox"
+
+	// pollInterval is how often the outbox is checked for deliveries added by other processes, such as flexvolume.
+	pollInterval = time.Minute
+)
+
+// Event is the body of a delivery.
+type Event struct {
+	ID     string
+	Type   string
+	Volume string
+	Host   string `json:",omitempty"`
+	Time   time.Time
+}
+
+// Endpoint is where events are posted. An endpoint receives every type of event unless it lists the ones it wants.
+type Endpoint struct {
+	URL    string
+	Secret string
+	Events []string
+}
+
+// wants reports whether an endpoint receives events of a type.
+func (e Endpoint) wants(eventType string) bool {
+	if len(e.Events) == 0 {
+		return true
+	}
+
+	for _, wanted := range e.Events {
+		if wanted == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Notifier adds events to an outbox and delivers them to its endpoints.
+type Notifier struct {
+	Endpoints []Endpoint
+	Outbox    Outbox
+	Client    *http.Client
+
+	// Failed deliveries are retried after MinBackoff, doubling up to MaxBackoff, until MaxAttempts have been made.
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+	MaxAttempts int
+
+	// wake tells Run that deliveries were added.
+	wake chan struct{}
+}
+
+// NewNotifier creates a Notifier that delivers events to endpoints through the outbox in outboxDir.
+func NewNotifier(endpoints []Endpoint, outboxDir string) (Notifier, error) {
+	if len(endpoints) == 0 {
+		return Notifier{}, errors.New("no webhook endpoints are configured")
+	}
+
+	for _, endpoint := range endpoints {
+		if endpoint.URL == "" || endpoint.Secret == "" {
+			return Notifier{}, errors.New("every webhook endpoint needs a url and a secret")
+		}
+	}
+
+	outbox, err := NewOutbox(outboxDir)
+	if err != nil {
+		return Notifier{}, err
+	}
+
+	return Notifier{
+		Endpoints:   endpoints,
+		Outbox:      outbox,
+		Client:      &http.Client{Timeout: DefaultTimeout},
+		MinBackoff:  DefaultMinBackoff,
+		MaxBackoff:  DefaultMaxBackoff,
+		MaxAttempts: DefaultMaxAttempts,
+		wake:        make(chan struct{}, 1)}, nil
+}
+
+// Notify adds an event of eventType about a volume to the outbox, for each endpoint that wants it.
+func (n Notifier) Notify(eventType string, volumeName string) error {
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+
+	host, _ := os.Hostname()
+	event := Event{ID: id, Type: eventType, Volume: volumeName, Host: host, Time: time.Now().UTC()}
+
+	for i, endpoint := range n.Endpoints {
+		if !endpoint.wants(eventType) {
+			continue
+		}
+
+		// IDs start with the event's time, zero padded, so that deliveries sort in the order their events happened.
+		delivery := Delivery{
+			ID:          padTime(event.Time) + "-" + id + "-" + strconv.Itoa(i),
+			Endpoint:    endpoint.URL,
+			Event:       event,
+			NextAttempt: event.Time}
+		if err = n.Outbox.Add(delivery); err != nil {
+			return err
+		}
+	}
+
+	select {
+	case n.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Run delivers events until stop is closed, as they are added and as their retries come due.
+func (n Notifier) Run(stop <-chan struct{}) {
+	for {
+		next, err := n.Flush(time.Now())
+		if err != nil {
+			glog.Error("Unable to deliver webhooks: ", err)
+		}
+
+		wait := pollInterval
+		if !next.IsZero() && time.Until(next) < wait {
+			wait = time.Until(next)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-n.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// Flush attempts every delivery that is due at now, returning when the next retry is due, or the zero time if none
+// are waiting. Each endpoint receives its events in order, so an endpoint's deliveries wait behind its first failure.
+func (n Notifier) Flush(now time.Time) (time.Time, error) {
+	deliveries, err := n.Outbox.Pending()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var next time.Time
+	waiting := map[string]bool{}
+	for _, delivery := range deliveries {
+		endpoint, configured := n.endpoint(delivery.Endpoint)
+		if !configured {
+			glog.Warning("Burying webhook delivery ", delivery.ID, ", ", delivery.Endpoint, " is no longer configured")
+			if err = n.Outbox.Bury(delivery); err != nil {
+				return time.Time{}, err
+			}
+			continue
+		}
+
+		if waiting[delivery.Endpoint] {
+			continue
+		}
+
+		if delivery.NextAttempt.After(now) {
+			waiting[delivery.Endpoint] = true
+			next = earliest(next, delivery.NextAttempt)
+			continue
+		}
+
+		err = n.deliver(endpoint, delivery)
+		if err == nil {
+			if err = n.Outbox.Remove(delivery); err != nil {
+				return time.Time{}, err
+			}
+			continue
+		}
+
+		delivery.Attempts++
+		delivery.LastErr = err.Error()
+		if delivery.Attempts >= n.MaxAttempts {
+			glog.Error("Giving up on webhook delivery ", delivery.ID, " to ", delivery.Endpoint, " after ", delivery.Attempts, " attempts: ", err)
+			if err = n.Outbox.Bury(delivery); err != nil {
+				return time.Time{}, err
+			}
+			continue
+		}
+
+		delivery.NextAttempt = now.Add(n.backoff(delivery.Attempts))
+		glog.Warning("Webhook delivery ", delivery.ID, " to ", delivery.Endpoint, " failed, retrying at ", delivery.NextAttempt, ": ", err)
+		if err = n.Outbox.Update(delivery); err != nil {
+			return time.Time{}, err
+		}
+
+		waiting[delivery.Endpoint] = true
+		next = earliest(next, delivery.NextAttempt)
+	}
+
+	return next, nil
+}
+
+// deliver posts a delivery's event to its endpoint, which must answer with a 2xx status.
+func (n Notifier) deliver(endpoint Endpoint, delivery Delivery) error {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(SignatureHeader, Sign(endpoint.Secret, body))
+	request.Header.Set(EventHeader, delivery.Event.Type)
+	request.Header.Set(DeliveryHeader, delivery.Event.ID)
+
+	response, err := n.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(response.Body, 64*1024))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return errors.New(endpoint.URL + " answered " + response.Status)
+	}
+
+	return nil
+}
+
+// endpoint returns the configured endpoint with url.
+func (n Notifier) endpoint(url string) (Endpoint, bool) {
+	for _, endpoint := range n.Endpoints {
+		if endpoint.URL == url {
+			return endpoint, true
+		}
+	}
+
+	return Endpoint{}, false
+}
+
+// backoff returns how long to wait after a delivery's attempts have failed.
+func (n Notifier) backoff(attempts int) time.Duration {
+	backoff := n.MinBackoff
+	for i := 1; i < attempts && backoff < n.MaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > n.MaxBackoff {
+		return n.MaxBackoff
+	}
+
+	return backoff
+}
+
+// Sign returns the signature of a body for an endpoint with secret, as sent in SignatureHeader.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is the signature of body for an endpoint with secret, for receivers.
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// newID returns a random event ID.
+func newID() (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(id), nil
+}
+
+// padTime formats a time so that later times sort after earlier ones.
+func padTime(t time.Time) string {
+	nanos := strconv.FormatInt(t.UnixNano(), 10)
+	for len(nanos) < 20 {
+		nanos = "0" + nanos
+	}
+
+	return nanos
+}
+
+// earliest returns the earlier of two times, ignoring the zero time.
+func earliest(a time.Time, b time.Time) time.Time {
+	if a.IsZero() || b.Before(a) {
+		return b
+	}
+
+	return a
+}
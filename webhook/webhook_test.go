@@ -0,0 +1,309 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// receiver is a local endpoint that checks the signature of each delivery, failing the first failures it receives.
+type receiver struct {
+	mutex    sync.Mutex
+	secret   string
+	failures int
+	events   []Event
+	invalid  int
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, request *http.Request) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	body, err := ioutil.ReadAll(request.Body)
+	if err != nil || !Verify(r.secret, body, request.Header.Get(SignatureHeader)) {
+		r.invalid++
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	var event Event
+	if err = json.Unmarshal(body, &event); err != nil || request.Header.Get(EventHeader) != event.Type || request.Header.Get(DeliveryHeader) != event.ID {
+		r.invalid++
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	r.events = append(r.events, event)
+}
+
+func (r *receiver) received() []Event {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]Event{}, r.events...)
+}
+
+func newTestOutboxDir(t *testing.T) (string, func()) {
+	tempDir, err := ioutil.TempDir("", "docker-volume-rdma-webhook")
+	if err != nil {
+		t.Fatal("Unable to create temp dir! ", err)
+	}
+
+	return filepath.Join(tempDir, "outbox"), func() { os.RemoveAll(tempDir) }
+}
+
+// newListener listens on the address of a test server's url.
+func newListener(url string) (net.Listener, error) {
+	return net.Listen("tcp", strings.TrimPrefix(url, "http://"))
+}
+
+func TestNotifier(t *testing.T) {
+	t.Parallel()
+	outboxDir, cleanUp := newTestOutboxDir(t)
+	defer cleanUp()
+
+	all := &receiver{secret: "s3cret", failures: 2}
+	allServer := httptest.NewServer(all)
+	defer allServer.Close()
+
+	removals := &receiver{secret: "other"}
+	removalsServer := httptest.NewServer(removals)
+	defer removalsServer.Close()
+
+	notifier, err := NewNotifier([]Endpoint{
+		{URL: allServer.URL, Secret: "s3cret"},
+		{URL: removalsServer.URL, Secret: "other", Events: []string{"volume.removed"}},
+	}, outboxDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, eventType := range []string{"volume.created", "volume.mounted", "volume.removed"} {
+		if err = notifier.Notify(eventType, "vol1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The first endpoint fails twice, holding back its later events so that they arrive in order.
+	now := time.Now()
+	next, err := notifier.Flush(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !next.Equal(now.Add(time.Second)) || len(all.received()) != 0 || len(removals.received()) != 1 {
+		t.Fatal("Expected a retry in a second and only the removal delivered, got ", next.Sub(now), all.received(), removals.received())
+	}
+
+	next, err = notifier.Flush(now.Add(time.Second))
+	if err != nil || !next.Equal(now.Add(3*time.Second)) {
+		t.Fatal("Expected the retry after the second failure to wait twice as long, got ", next.Sub(now), err)
+	}
+
+	if next, err = notifier.Flush(now.Add(3 * time.Second)); err != nil || !next.IsZero() {
+		t.Fatal("Expected every event to be delivered, got ", next, err)
+	}
+
+	events := all.received()
+	if len(events) != 3 || events[0].Type != "volume.created" || events[1].Type != "volume.mounted" || events[2].Type != "volume.removed" {
+		t.Fatal("Expected every event in order, got ", events)
+	}
+
+	if events[0].Volume != "vol1" || events[0].ID == "" || events[0].Time.IsZero() || events[2].ID != removals.received()[0].ID {
+		t.Error("Unexpected events: ", events, removals.received())
+	}
+
+	if all.invalid != 0 || removals.invalid != 0 {
+		t.Error("Every delivery should be signed with its endpoint's secret")
+	}
+
+	if pending, err := notifier.Outbox.Pending(); err != nil || len(pending) != 0 {
+		t.Error("Delivered events should leave the outbox, got ", pending, err)
+	}
+}
+
+func TestNotifier_outbox(t *testing.T) {
+	t.Parallel()
+	outboxDir, cleanUp := newTestOutboxDir(t)
+	defer cleanUp()
+
+	endpoint := &receiver{secret: "s3cret"}
+	server := httptest.NewServer(endpoint)
+	url := server.URL
+	server.Close()
+
+	down, err := NewNotifier([]Endpoint{{URL: url, Secret: "s3cret"}}, outboxDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	down.MaxAttempts = 3
+
+	if err = down.Notify("volume.created", "vol1"); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	next, err := down.Flush(now)
+	if err != nil || next.IsZero() {
+		t.Fatal("Expected a retry while the endpoint is down, got ", next, err)
+	}
+
+	pending, err := down.Outbox.Pending()
+	if err != nil || len(pending) != 1 || pending[0].Attempts != 1 || pending[0].LastErr == "" {
+		t.Fatal("Expected the failed attempt to be recorded, got ", pending, err)
+	}
+
+	// The endpoint comes back on the same address, and the daemon restarts with a new notifier.
+	listener, err := newListener(url)
+	if err != nil {
+		t.Skip("Unable to listen on ", url, " again: ", err)
+	}
+	server = &httptest.Server{Listener: listener, Config: &http.Server{Handler: endpoint}}
+	server.Start()
+	defer server.Close()
+
+	restarted, err := NewNotifier([]Endpoint{{URL: url, Secret: "s3cret"}}, outboxDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = restarted.Flush(now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if events := endpoint.received(); len(events) != 1 || events[0].ID != pending[0].Event.ID {
+		t.Error("Expected the event kept in the outbox to be delivered, got ", events)
+	}
+}
+
+func TestNotifier_giveUp(t *testing.T) {
+	t.Parallel()
+	outboxDir, cleanUp := newTestOutboxDir(t)
+	defer cleanUp()
+
+	endpoint := &receiver{secret: "s3cret", failures: 10}
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+
+	notifier, err := NewNotifier([]Endpoint{{URL: server.URL, Secret: "s3cret"}}, outboxDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notifier.MaxAttempts = 2
+
+	notifier.Notify("volume.created", "vol1")
+	notifier.Notify("volume.removed", "vol1")
+
+	now := time.Now()
+	for i := 0; i < 4; i++ {
+		now = now.Add(time.Hour)
+		if _, err = notifier.Flush(now); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if pending, _ := notifier.Outbox.Pending(); len(pending) != 0 {
+		t.Error("Deliveries should be given up on after their attempts, got ", pending)
+	}
+
+	dead, err := ioutil.ReadDir(filepath.Join(outboxDir, deadDir))
+	if err != nil || len(dead) != 2 {
+		t.Error("Expected both deliveries to be kept aside, got ", dead, err)
+	}
+
+	// Deliveries to endpoints that are no longer configured are put aside too.
+	notifier.Notify("volume.created", "vol2")
+	notifier.Endpoints = []Endpoint{{URL: server.URL + "/moved", Secret: "s3cret"}}
+	if _, err = notifier.Flush(now); err != nil {
+		t.Fatal(err)
+	}
+
+	if dead, _ = ioutil.ReadDir(filepath.Join(outboxDir, deadDir)); len(dead) != 3 {
+		t.Error("Expected the delivery to the removed endpoint to be kept aside, got ", dead)
+	}
+}
+
+func TestNotifier_run(t *testing.T) {
+	t.Parallel()
+	outboxDir, cleanUp := newTestOutboxDir(t)
+	defer cleanUp()
+
+	endpoint := &receiver{secret: "s3cret", failures: 1}
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+
+	notifier, err := NewNotifier([]Endpoint{{URL: server.URL, Secret: "s3cret"}}, outboxDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notifier.MinBackoff = 10 * time.Millisecond
+
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		notifier.Run(stop)
+		close(stopped)
+	}()
+
+	notifier.Notify("volume.mounted", "vol1")
+	for deadline := time.Now().Add(5 * time.Second); len(endpoint.received()) == 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	close(stop)
+	<-stopped
+
+	if events := endpoint.received(); len(events) != 1 || events[0].Type != "volume.mounted" {
+		t.Error("Expected the event to be delivered after a retry, got ", events)
+	}
+}
+
+func TestNewNotifier_errors(t *testing.T) {
+	t.Parallel()
+	outboxDir, cleanUp := newTestOutboxDir(t)
+	defer cleanUp()
+
+	tests := []struct {
+		endpoints []Endpoint
+		outbox    string
+	}{
+		{nil, outboxDir},
+		{[]Endpoint{{URL: "http://localhost:9000"}}, outboxDir},
+		{[]Endpoint{{Secret: "s3cret"}}, outboxDir},
+		{[]Endpoint{{URL: "http://localhost:9000", Secret: "s3cret"}}, ""},
+	}
+
+	for _, test := range tests {
+		if _, err := NewNotifier(test.endpoints, test.outbox); err == nil {
+			t.Error("Creating a notifier for ", test.endpoints, " in ", test.outbox, " should fail")
+		}
+	}
+}
+
+func TestSign(t *testing.T) {
+	t.Parallel()
+	body := []byte(`{"Type":"volume.created"}`)
+	signature := Sign("s3cret", body)
+
+	if !Verify("s3cret", body, signature) {
+		t.Error("A body should verify with its own signature")
+	}
+
+	if Verify("other", body, signature) || Verify("s3cret", []byte(`{"Type":"volume.removed"}`), signature) {
+		t.Error("A signature should only verify its body with its secret")
+	}
+}